@@ -4,17 +4,34 @@ package main
 import (
 	"database/sql"
 	"log"
+	"new-year-role-game-backend/internal/auctions"
 	"new-year-role-game-backend/internal/config"
+	"new-year-role-game-backend/internal/contracts"
 	"new-year-role-game-backend/internal/database"
+	"new-year-role-game-backend/internal/events"
 	"new-year-role-game-backend/internal/handlers"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/market"
 	"new-year-role-game-backend/internal/middleware"
+	"new-year-role-game-backend/internal/storage/postgres"
+	"new-year-role-game-backend/internal/trades"
 	"new-year-role-game-backend/internal/workers"
+	"new-year-role-game-backend/migrations"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// ./api scenario validate <file> проверяет файл без подключения к базе
+	if len(os.Args) > 2 && os.Args[1] == "scenario" && os.Args[2] == "validate" {
+		if err := runScenario(nil, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	cfg := config.LoadConfig()
 
 	db, err := database.Connect(cfg.DatabaseURL)
@@ -23,10 +40,82 @@ func main() {
 	}
 	defer db.Close()
 
-	// Создаем schedulers для точных таймеров
-	effectsScheduler := workers.NewEffectsScheduler(db)
-	contractScheduler := workers.NewContractScheduler(db)
-	debtScheduler := workers.NewDebtScheduler(db)
+	migrator, err := database.NewMigrator(db, migrations.Files, migrations.Seeds)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	// ./api migrate [up|down [N]|status|seed] - управление схемой без запуска сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(migrator, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Сервер не стартует на базе, которая новее бинарника или отстаёт от него
+	if cfg.AutoMigrate {
+		if _, err := migrator.Up(); err != nil {
+			log.Fatal("Failed to apply migrations: ", err)
+		}
+	} else {
+		pending, err := migrator.Check()
+		if err != nil {
+			log.Fatal("Database schema check failed: ", err)
+		}
+		if pending > 0 {
+			log.Fatalf("Database schema is behind this binary by %d migrations, run ./api migrate up", pending)
+		}
+	}
+
+	// ./api scenario import|export - импорт и экспорт сценария на актуальной схеме
+	if len(os.Args) > 1 && os.Args[1] == "scenario" {
+		if err := runScenario(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// ./api report [file] - итоги текущего прогона в JSON или HTML
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if err := runReport(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Broker раздаёт игрокам события, которые handlers и schedulers публикуют через pg_notify
+	eventsBroker := events.NewBroker(cfg.DatabaseURL)
+	eventsBroker.StartWithRetry()
+	defer eventsBroker.Stop()
+
+	// Общая для всех экземпляров API очередь отложенных задач в PostgreSQL
+	jobQueue := jobs.NewQueue(db, time.Duration(cfg.JobsPollInterval)*time.Second)
+
+	// Хранилище поверх той же БД: таймеры ставятся в очередь в транзакциях handlers
+	store := postgres.New(db, jobQueue)
+
+	// Создаем schedulers (они регистрируют свои типы задач в очереди)
+	effectsScheduler := workers.NewEffectsScheduler(db, jobQueue)
+	contractService := contracts.NewService(effectsScheduler)
+	contractScheduler := workers.NewContractScheduler(db, jobQueue, contractService)
+	debtScheduler := workers.NewDebtScheduler(db, jobQueue)
+	marketService := market.NewService(effectsScheduler)
+	marketScheduler := workers.NewMarketScheduler(db, jobQueue, marketService)
+	tradeService := trades.NewService(effectsScheduler)
+	tradeScheduler := workers.NewTradeScheduler(db, jobQueue, tradeService)
+	auctionService := auctions.NewService(effectsScheduler)
+	auctionScheduler := workers.NewAuctionScheduler(db, jobQueue, auctionService)
+	leaderboardSnapshotter := workers.NewLeaderboardSnapshotter(db, jobQueue,
+		time.Duration(cfg.LeaderboardInterval)*time.Second)
+
+	jobQueue.Start()
+	defer jobQueue.Stop()
+
+	// Снимки таблицы лидеров планируются всегда, а делаются только пока игра идёт
+	if err := leaderboardSnapshotter.Start(); err != nil {
+		log.Printf("Warning: Failed to schedule leaderboard snapshots: %v", err)
+	}
 
 	// Проверяем, активна ли игра, и запускаем schedulers если да
 	if isGameActive(db) {
@@ -46,6 +135,18 @@ func main() {
 			log.Printf("Warning: Failed to start debt scheduler: %v", err)
 		}
 
+		if err := marketScheduler.Start(); err != nil {
+			log.Printf("Warning: Failed to start market scheduler: %v", err)
+		}
+
+		if err := tradeScheduler.Start(); err != nil {
+			log.Printf("Warning: Failed to start trade scheduler: %v", err)
+		}
+
+		if err := auctionScheduler.Start(); err != nil {
+			log.Printf("Warning: Failed to start auction scheduler: %v", err)
+		}
+
 		// // Запускаем workers как fallback (подстраховка)
 		// if !effectsWorker.IsRunning() {
 		// 	log.Println("Starting effects worker as fallback...")
@@ -58,23 +159,38 @@ func main() {
 		// }
 	}
 
-	contractsHandlerWithShedular := handlers.NewContractHandlerWithScheduler(db, contractScheduler)
+	contractsHandlerWithShedular := handlers.NewContractHandlerWithScheduler(store, contractService)
 
 	r := gin.Default()
 
 	r.Use(middleware.CORS())
+	r.Use(middleware.Metrics())
+
+	// Метрики для Prometheus
+	metricsHandler := handlers.NewMetricsHandler(db, cfg.MetricsToken)
+	r.GET("/metrics", metricsHandler.GetMetrics)
 
 	api := r.Group("/api")
 	{
 		auth := api.Group("/auth")
 		{
-			authHandler := handlers.NewAuthHandler(db, cfg.JWTKey)
+			authHandler := handlers.NewAuthHandler(db, cfg.JWTKey,
+				time.Duration(cfg.AccessTokenTTLMinutes)*time.Minute,
+				time.Duration(cfg.RefreshTokenTTLHours)*time.Hour)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", middleware.AuthMiddleware(cfg.JWTKey, db), authHandler.Logout)
+			auth.POST("/stream-ticket", middleware.AuthMiddleware(cfg.JWTKey, db), authHandler.StreamTicket)
 		}
+
+		// Поток событий (SSE) - вместо заголовка можно передать билет в ?ticket=
+		eventsHandler := handlers.NewEventsHandler(db, eventsBroker)
+		api.GET("/events", middleware.StreamAuthMiddleware(cfg.JWTKey, db), eventsHandler.Stream)
+
 		protected := api.Group("")
 		gameHandler := handlers.NewGameHandler(db)
 		protected.GET("/game/status", gameHandler.GetGameStatus)
-		protected.Use(middleware.AuthMiddleware(cfg.JWTKey))
+		protected.Use(middleware.AuthMiddleware(cfg.JWTKey, db))
 		{
 			playerHandler := handlers.NewPlayerHandler(db)
 			protected.GET("/player/me", playerHandler.GetPlayerInfo)
@@ -85,19 +201,36 @@ func main() {
 			protected.GET("/player/faction", factionHandler.GetPlayerFaction)
 			protected.GET("/factions", factionHandler.GetAllFactions)
 			protected.PUT("/player/faction", factionHandler.ChangeFaction)
+			protected.GET("/player/faction/treasury", factionHandler.GetFactionTreasury)
+			protected.POST("/player/faction/treasury/deposit", factionHandler.DepositToTreasury)
+			protected.POST("/player/faction/treasury/withdraw", factionHandler.WithdrawFromTreasury)
+			protected.POST("/player/faction/treasury/pay", factionHandler.PayFromTreasury)
 
 			goalHandler := handlers.NewGoalHandler(db)
 			protected.GET("/player/goals", goalHandler.GetPersonalGoals)
 			protected.GET("/player/faction/goals", goalHandler.GetFactionGoals)
 			protected.PUT("/goals/:id/toggle", goalHandler.ToggleGoalCompletion)
 
-			itemHandler := handlers.NewItemHandlerWithScheduler(db, effectsScheduler)
+			taskHandler := handlers.NewTaskHandler(db)
+			protected.GET("/player/tasks", taskHandler.GetPlayerTasks)
+			protected.PUT("/tasks/:id/toggle", taskHandler.ToggleTaskCompletion)
+
+			raceHandler := handlers.NewRaceHandler(db)
+			protected.GET("/player/race", raceHandler.GetPlayerRace)
+
+			leaderboardHandler := handlers.NewLeaderboardHandler(db)
+			protected.GET("/leaderboard", leaderboardHandler.GetLeaderboard)
+
+			historyHandler := handlers.NewHistoryHandler(db)
+			protected.GET("/player/history", historyHandler.GetPlayerHistory)
+
+			itemHandler := handlers.NewItemHandlerWithScheduler(db, store, effectsScheduler)
 			protected.GET("/player/inventory", itemHandler.GetPlayerInventory)
 			protected.POST("/player/transfer/item", itemHandler.TransferItem)
 			protected.POST("/player/transfer/money", itemHandler.TransferMoney)
 			protected.GET("/player/items/effects/status", itemHandler.GetItemEffectsStatus)
 
-			abilityHandler := handlers.NewAbilityHandler(db)
+			abilityHandler := handlers.NewAbilityHandler(db, store)
 			protected.GET("/player/abilities", abilityHandler.GetPlayerAbilities)
 			protected.POST("/abilities/:id/use", abilityHandler.UseAbility)
 
@@ -107,20 +240,57 @@ func main() {
 			protected.POST("/contracts/:id/sign", contractsHandlerWithShedular.SignContract)
 
 			// Долговые расписки с scheduler
-			debtHandler := handlers.NewDebtHandler(db, debtScheduler)
+			debtHandler := handlers.NewDebtHandler(db, store, debtScheduler)
 			protected.GET("/player/debts", debtHandler.GetPlayerDebts)
 			protected.POST("/debts/create", debtHandler.CreateDebtReceipt)
 			protected.POST("/debts/:id/return", debtHandler.ReturnDebt)
+
+			// Рынок предметов
+			marketHandler := handlers.NewMarketHandler(db, store, marketService)
+			protected.GET("/market", marketHandler.GetMarket)
+			protected.GET("/player/listings", marketHandler.GetPlayerListings)
+			protected.POST("/market/listings", marketHandler.CreateListing)
+			protected.POST("/market/listings/:id/buy", marketHandler.BuyListing)
+			protected.POST("/market/listings/:id/cancel", marketHandler.CancelListing)
+
+			// Обмен между игроками
+			tradeHandler := handlers.NewTradeHandler(db, store, tradeService)
+			protected.GET("/player/trades", tradeHandler.GetPlayerTrades)
+			protected.POST("/trades", tradeHandler.CreateTrade)
+			protected.POST("/trades/:id/accept", tradeHandler.AcceptTrade)
+			protected.POST("/trades/:id/decline", tradeHandler.DeclineTrade)
+			protected.POST("/trades/:id/cancel", tradeHandler.CancelTrade)
+			protected.POST("/trades/:id/counter", tradeHandler.CounterTrade)
+
+			// Аукционы мастера
+			auctionHandler := handlers.NewAuctionHandler(db, store, auctionService)
+			protected.GET("/auctions", auctionHandler.GetAuctions)
+			protected.POST("/auctions/:id/bid", auctionHandler.PlaceBid)
 		}
 
 		// Admin endpoints - требуют роль администратора
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(cfg.JWTKey))
+		admin.Use(middleware.AuthMiddleware(cfg.JWTKey, db))
 		admin.Use(middleware.AdminMiddleware())
 		{
-			adminHandler := handlers.NewAdminHandler(db, effectsScheduler, contractScheduler)
+			adminHandler := handlers.NewAdminHandler(db, effectsScheduler, contractScheduler, debtScheduler, marketScheduler, tradeScheduler, auctionScheduler)
 			admin.POST("/game/start", adminHandler.StartGame)
 			admin.POST("/game/end", adminHandler.EndGame)
+			admin.POST("/game/pause", adminHandler.PauseGame)
+			admin.POST("/game/resume", adminHandler.ResumeGame)
+			admin.GET("/stats", adminHandler.GetGameStats)
+			admin.GET("/schedulers", adminHandler.GetSchedulers)
+
+			// Архив прогонов и сброс игры
+			adminRunHandler := handlers.NewAdminRunHandler(db, effectsScheduler, contractScheduler, debtScheduler, marketScheduler, tradeScheduler, auctionScheduler)
+			admin.POST("/game/baseline", adminRunHandler.CaptureBaseline)
+			admin.POST("/game/reset", adminRunHandler.ResetGame)
+			admin.GET("/runs", adminRunHandler.GetGameRuns)
+			admin.GET("/runs/:id/:table", adminRunHandler.GetGameRunRows)
+
+			// Итоги прогона
+			adminReportHandler := handlers.NewAdminReportHandler(db)
+			admin.GET("/report", adminReportHandler.GetReport)
 
 			adminContractHandler := handlers.NewAdminContractHandler(db)
 			admin.GET("/contracts/settings", adminContractHandler.GetContractSettings)
@@ -133,6 +303,78 @@ func main() {
 			adminDebtHandler := handlers.NewAdminDebtHandler(db)
 			admin.GET("/debts/settings", adminDebtHandler.GetDebtPenaltySettings)
 			admin.PUT("/debts/penalties", adminDebtHandler.UpdateDebtPenaltySettings)
+
+			// Задачи игроков
+			adminTaskHandler := handlers.NewAdminTaskHandler(db)
+			admin.GET("/tasks", adminTaskHandler.GetAllTasks)
+			admin.POST("/tasks", adminTaskHandler.CreateTask)
+			admin.PUT("/tasks/:id", adminTaskHandler.UpdateTask)
+			admin.PUT("/tasks/:id/assign", adminTaskHandler.AssignTask)
+			admin.DELETE("/tasks/:id", adminTaskHandler.DeleteTask)
+
+			// Гонка целей
+			adminRaceHandler := handlers.NewAdminRaceHandler(db)
+			admin.GET("/race/rounds", adminRaceHandler.GetRaceRounds)
+			admin.POST("/race/triggers/:id/start", adminRaceHandler.StartRaceRound)
+			admin.POST("/race/rounds/:id/cancel", adminRaceHandler.CancelRaceRound)
+
+			// Персонажи
+			adminPlayerHandler := handlers.NewAdminPlayerHandler(db)
+			admin.GET("/players", adminPlayerHandler.GetAllPlayers)
+			admin.POST("/players", adminPlayerHandler.CreatePlayer)
+			admin.GET("/players/:id", adminPlayerHandler.GetPlayer)
+			admin.PUT("/players/:id", adminPlayerHandler.UpdatePlayer)
+			admin.DELETE("/players/:id", adminPlayerHandler.DeletePlayer)
+
+			// Фракции
+			adminFactionHandler := handlers.NewAdminFactionHandler(db)
+			admin.GET("/factions", adminFactionHandler.GetAllFactions)
+			admin.POST("/factions", adminFactionHandler.CreateFaction)
+			admin.PUT("/factions/:id", adminFactionHandler.UpdateFaction)
+			admin.DELETE("/factions/:id", adminFactionHandler.DeleteFaction)
+
+			// Ручные начисления и штрафы
+			adminBalanceHandler := handlers.NewAdminBalanceHandler(db)
+			admin.POST("/players/:id/adjust", adminBalanceHandler.AdjustPlayerBalance)
+			admin.POST("/factions/:id/adjust", adminBalanceHandler.AdjustFactionBalance)
+
+			// Каталог предметов и эффектов, выдача предметов
+			adminItemHandler := handlers.NewAdminItemHandler(db, effectsScheduler)
+			admin.GET("/items", adminItemHandler.GetItemsCatalog)
+			admin.POST("/items", adminItemHandler.CreateItem)
+			admin.PUT("/items/:id", adminItemHandler.UpdateItem)
+			admin.DELETE("/items/:id", adminItemHandler.DeleteItem)
+			admin.POST("/items/:id/effects/:effect_id", adminItemHandler.AttachEffect)
+			admin.DELETE("/items/:id/effects/:effect_id", adminItemHandler.DetachEffect)
+			admin.GET("/item-templates", adminItemHandler.GetItemTemplates)
+			admin.POST("/item-templates", adminItemHandler.CreateItemTemplate)
+			admin.DELETE("/item-templates/:id", adminItemHandler.DeleteItemTemplate)
+			admin.GET("/effects", adminItemHandler.GetEffects)
+			admin.POST("/effects", adminItemHandler.CreateEffect)
+			admin.DELETE("/effects/:id", adminItemHandler.DeleteEffect)
+			admin.POST("/players/:id/items", adminItemHandler.GrantItem)
+			admin.DELETE("/players/:id/items/:item_id", adminItemHandler.RevokeItem)
+
+			// Аукционы
+			adminAuctionHandler := handlers.NewAdminAuctionHandler(db, store, auctionService)
+			admin.GET("/auctions", adminAuctionHandler.GetAuctions)
+			admin.POST("/auctions", adminAuctionHandler.CreateAuction)
+			admin.POST("/auctions/:id/cancel", adminAuctionHandler.CancelAuction)
+
+			// Учётные записи
+			adminUserHandler := handlers.NewAdminUserHandler(db)
+			admin.GET("/users", adminUserHandler.GetAllUsers)
+			admin.POST("/users", adminUserHandler.CreateUser)
+			admin.PUT("/users/:id", adminUserHandler.UpdateUser)
+			admin.DELETE("/users/:id", adminUserHandler.DeleteUser)
+			admin.POST("/players/:id/password", adminUserHandler.ResetPlayerPassword)
+			admin.POST("/players/:id/sessions/revoke", adminUserHandler.RevokePlayerSessions)
+			admin.GET("/users/:id/sessions", adminUserHandler.GetUserSessions)
+			admin.POST("/users/:id/sessions/revoke", adminUserHandler.RevokeUserSessions)
+
+			// История игроков
+			adminHistoryHandler := handlers.NewHistoryHandler(db)
+			admin.GET("/players/:id/history", adminHistoryHandler.GetPlayerHistoryAdmin)
 		}
 	}
 
@@ -142,19 +384,19 @@ func main() {
 	}
 }
 
-// isGameActive проверяет, активна ли игра
+// isGameActive проверяет, активна ли игра (начата, не завершена и не на паузе)
 func isGameActive(db *sql.DB) bool {
-	var gameStarted, gameEnded *time.Time
+	var gameStarted, gameEnded, pausedAt *time.Time
 	err := db.QueryRow(`
-		SELECT game_started_at, game_ended_at
+		SELECT game_started_at, game_ended_at, paused_at
 		FROM game_timeline
 		ORDER BY id DESC
 		LIMIT 1
-	`).Scan(&gameStarted, &gameEnded)
+	`).Scan(&gameStarted, &gameEnded, &pausedAt)
 
 	if err != nil {
 		return false
 	}
 
-	return gameStarted != nil && gameEnded == nil
+	return gameStarted != nil && gameEnded == nil && pausedAt == nil
 }
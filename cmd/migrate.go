@@ -0,0 +1,80 @@
+// cmd/migrate.go
+package main
+
+import (
+	"fmt"
+	"new-year-role-game-backend/internal/database"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runMigrate выполняет подкоманду migrate:
+//
+//	migrate [up]      - применить все неприменённые миграции
+//	migrate down [N]  - откатить N последних миграций (по умолчанию 1)
+//	migrate status    - показать состояние версий
+//	migrate seed      - загрузить демонстрационные данные
+func runMigrate(migrator *database.Migrator, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d migrations, schema is at version %d\n", count, migrator.Latest())
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+
+		count, err := migrator.Down(steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migrations\n", count)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\t")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				appliedAt += " (modified)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+
+	case "seed":
+		count, err := migrator.Seed()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied %d seed files\n", count)
+
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down, status or seed)", command)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// cmd/report.go
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"new-year-role-game-backend/internal/report"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runReport выполняет подкоманду report:
+//
+//	report          - итоги текущего прогона в JSON в stdout
+//	report <file>   - записать итоги в файл (.html или .json)
+func runReport(db *sql.DB, args []string) error {
+	r, err := report.Build(db)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return writeReportJSON(os.Stdout, r)
+	}
+
+	path := args[0]
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".html" && ext != ".json" {
+		return fmt.Errorf("unsupported report format %q, expected .html or .json", ext)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if ext == ".html" {
+		err = r.WriteHTML(file)
+	} else {
+		err = writeReportJSON(file, r)
+	}
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Report written to %s\n", path)
+	return nil
+}
+
+func writeReportJSON(w io.Writer, r *report.Report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
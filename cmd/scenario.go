@@ -0,0 +1,86 @@
+// cmd/scenario.go
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"new-year-role-game-backend/internal/scenario"
+	"os"
+)
+
+// runScenario выполняет подкоманду scenario:
+//
+//	scenario validate <file>  - проверить файл сценария (база не нужна)
+//	scenario import <file>    - создать сценарий в пустой базе
+//	scenario export [file]    - выгрузить базу в файл (.json, .yaml) или в stdout в YAML
+func runScenario(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected scenario command: validate, import or export")
+	}
+
+	switch args[0] {
+	case "validate":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: scenario validate <file>")
+		}
+
+		s, err := scenario.Load(args[1])
+		if err != nil {
+			return err
+		}
+		if err := scenario.Validate(s); err != nil {
+			return err
+		}
+		fmt.Printf("Scenario is valid: %d factions, %d players, %d users, %d items, %d abilities, %d goals\n",
+			len(s.Factions), len(s.Players), len(s.Users), len(s.Items), len(s.Abilities), len(s.Goals))
+
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: scenario import <file>")
+		}
+
+		s, err := scenario.Load(args[1])
+		if err != nil {
+			return err
+		}
+		summary, err := scenario.Import(db, s)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d factions, %d players, %d users, %d items, %d abilities, %d goals\n",
+			summary.Factions, summary.Players, summary.Users, summary.Items, summary.Abilities, summary.Goals)
+
+	case "export":
+		s, err := scenario.Export(db)
+		if err != nil {
+			return err
+		}
+
+		if len(args) < 2 {
+			data, err := scenario.Encode(s, scenario.FormatYAML)
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+
+		format, err := scenario.FormatFromPath(args[1])
+		if err != nil {
+			return err
+		}
+		data, err := scenario.Encode(s, format)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(args[1], data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Exported scenario to %s\n", args[1])
+
+	default:
+		return fmt.Errorf("unknown scenario command %q (expected validate, import or export)", args[0])
+	}
+
+	return nil
+}
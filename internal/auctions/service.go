@@ -0,0 +1,381 @@
+// internal/auctions/service.go
+package auctions
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+// Аукционы мастера: предмет без владельца продаётся за ставки игроков.
+// Деньги лидирующей ставки списываются сразу (резерв), перебитый лидер получает их обратно.
+// Ставка принимается, только если она не меньше текущей + шаг и поступила до срока закрытия,
+// поэтому у аукциона всегда один лидер, а поздняя ставка отклоняется, даже если задача закрытия
+// ещё не выполнилась. Все переходы аукциона выполняются только здесь
+
+// JobType - тип задач очереди для закрытия аукционов
+const JobType = "auction"
+
+// Job - payload задачи аукциона
+type Job struct {
+	AuctionID int `json:"auction_id"`
+}
+
+// JobKey - ключ задачи закрытия аукциона в очереди
+func JobKey(auctionID int) string {
+	return fmt.Sprintf("auction:%d", auctionID)
+}
+
+var (
+	ErrNotFound          = errors.New("auction not found")
+	ErrItemNotFound      = errors.New("item not found")
+	ErrItemUnavailable   = errors.New("item belongs to a player or is already on sale")
+	ErrInvalidCloseTime  = errors.New("auction must close in the future")
+	ErrNotActive         = errors.New("auction is not active")
+	ErrClosed            = errors.New("auction is closed for bids")
+	ErrBidTooLow         = errors.New("bid is too low")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// ItemTimers запускает таймеры эффектов предмета у победителя
+type ItemTimers interface {
+	AttachItemEffects(tx storage.Tx, playerID, itemID int, baseTime time.Time) error
+}
+
+// Service - операции над аукционами в транзакции вызывающего
+type Service struct {
+	timers ItemTimers
+}
+
+func NewService(timers ItemTimers) *Service {
+	return &Service{timers: timers}
+}
+
+// MinimumBid - наименьшая ставка, которую аукцион примет после ставки leading (nil - ставок нет)
+func MinimumBid(auction *storage.Auction, leading *storage.AuctionBid) int {
+	if leading == nil {
+		return auction.StartPrice
+	}
+	return leading.Amount + auction.MinIncrement
+}
+
+// Create открывает аукцион на предмет без владельца и планирует его закрытие
+func (s *Service) Create(tx storage.Tx, itemID, startPrice, minIncrement int, closesAt, now time.Time) (*storage.Auction, error) {
+	if !closesAt.After(now) {
+		return nil, ErrInvalidCloseTime
+	}
+
+	free, err := tx.Items().LockIfFree(itemID)
+	if err == storage.ErrNotFound {
+		return nil, ErrItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check item: %w", err)
+	}
+	if !free {
+		return nil, ErrItemUnavailable
+	}
+
+	auction := storage.Auction{
+		Status:       "active",
+		ItemID:       itemID,
+		StartPrice:   startPrice,
+		MinIncrement: minIncrement,
+		ClosesAt:     closesAt,
+	}
+
+	auction.ID, err = tx.Auctions().Create(auction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auction: %w", err)
+	}
+
+	if err = tx.Timers().Schedule(JobType, JobKey(auction.ID), closesAt, Job{AuctionID: auction.ID}); err != nil {
+		return nil, fmt.Errorf("failed to schedule auction close: %w", err)
+	}
+
+	if err = s.publish(tx, events.TypeAuctionCreated, &auction, nil); err != nil {
+		return nil, err
+	}
+
+	return &auction, nil
+}
+
+// Bid принимает ставку: деньги игрока резервируются, предыдущий лидер получает свою ставку обратно
+func (s *Service) Bid(tx storage.Tx, auctionID, playerID, amount int, now time.Time) (*storage.AuctionBid, error) {
+	auction, err := s.load(tx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if auction.Status != "active" {
+		return nil, ErrNotActive
+	}
+	// Срок проверяется по времени транзакции: ставка в момент закрытия и позже не принимается
+	if !now.Before(auction.ClosesAt) {
+		return nil, ErrClosed
+	}
+
+	leading, err := s.leadingBid(tx, auction.ID)
+	if err != nil {
+		return nil, err
+	}
+	if amount < MinimumBid(auction, leading) {
+		return nil, ErrBidTooLow
+	}
+
+	// Блокируем игроков всегда в одном порядке, чтобы встречные ставки не взаимоблокировались
+	playerIDs := []int{playerID}
+	if leading != nil && leading.PlayerID != playerID {
+		playerIDs = append(playerIDs, leading.PlayerID)
+		if playerIDs[1] < playerIDs[0] {
+			playerIDs[0], playerIDs[1] = playerIDs[1], playerIDs[0]
+		}
+	}
+	var bidder *storage.Player
+	for _, id := range playerIDs {
+		player, err := tx.Players().GetForUpdate(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch player %d: %w", id, err)
+		}
+		if id == playerID {
+			bidder = player
+		}
+	}
+
+	// Лидер может повысить свою ставку: прежняя возвращается, и проверяется вся новая сумма
+	available := bidder.Money
+	if leading != nil {
+		if leading.PlayerID == playerID {
+			available += leading.Amount
+		}
+		if available < amount {
+			return nil, ErrInsufficientFunds
+		}
+		if err = s.refund(tx, auction, leading, "outbid"); err != nil {
+			return nil, err
+		}
+	} else if available < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	if _, err = tx.Players().TakeMoney(playerID, amount); err != nil {
+		return nil, fmt.Errorf("failed to reserve bid: %w", err)
+	}
+
+	bidID, err := tx.Auctions().AddBid(auction.ID, playerID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record bid: %w", err)
+	}
+
+	err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		FromPlayerID:    &playerID,
+		Amount:          amount,
+		TransactionType: "auction",
+		ReferenceID:     auction.ID,
+		ReferenceType:   "auction",
+		Description:     fmt.Sprintf("Auction #%d bid reserved: %d", auction.ID, amount),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record money transaction: %w", err)
+	}
+
+	bid := &storage.AuctionBid{
+		ID:        bidID,
+		AuctionID: auction.ID,
+		PlayerID:  playerID,
+		Amount:    amount,
+		Status:    "leading",
+	}
+
+	if leading != nil && leading.PlayerID != playerID {
+		err = tx.Events().PublishBalances(playerID, leading.PlayerID)
+	} else {
+		err = tx.Events().PublishBalances(playerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish events: %w", err)
+	}
+	if err = s.publish(tx, events.TypeAuctionBid, auction, bid); err != nil {
+		return nil, err
+	}
+
+	return bid, nil
+}
+
+// refund возвращает деньги ставки игроку и помечает ставку статусом status
+func (s *Service) refund(tx storage.Tx, auction *storage.Auction, bid *storage.AuctionBid, status string) error {
+	if err := tx.Players().AddMoney(bid.PlayerID, bid.Amount); err != nil {
+		return fmt.Errorf("failed to refund bid: %w", err)
+	}
+	if err := tx.Auctions().SetBidStatus(bid.ID, status); err != nil {
+		return fmt.Errorf("failed to update bid: %w", err)
+	}
+
+	err := tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		ToPlayerID:      &bid.PlayerID,
+		Amount:          bid.Amount,
+		TransactionType: "auction",
+		ReferenceID:     auction.ID,
+		ReferenceType:   "auction",
+		Description:     fmt.Sprintf("Auction #%d bid refunded (%s): %d", auction.ID, status, bid.Amount),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record money transaction: %w", err)
+	}
+	return nil
+}
+
+// CloseAuction - закрытие аукциона по таймеру очереди: предмет получает лидер.
+// Аукцион, который уже закрыт, пропускается без ошибки
+func (s *Service) CloseAuction(tx storage.Tx, auctionID int, now time.Time) error {
+	auction, err := s.load(tx, auctionID)
+	if err == ErrNotFound {
+		log.Printf("Auction #%d no longer exists, skipping", auctionID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if auction.Status != "active" {
+		log.Printf("Auction #%d is no longer active (status: %s), skipping", auctionID, auction.Status)
+		return nil
+	}
+
+	leading, err := s.leadingBid(tx, auction.ID)
+	if err != nil {
+		return err
+	}
+
+	if leading == nil {
+		return s.close(tx, auction, "unsold", nil, now)
+	}
+
+	// Деньги победителя уже списаны при ставке, остаётся передать предмет
+	item, err := tx.Items().Get(auction.ItemID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch item for auction #%d: %w", auction.ID, err)
+	}
+
+	if err = tx.Items().Give(auction.ItemID, leading.PlayerID); err != nil {
+		return fmt.Errorf("failed to give item to winner: %w", err)
+	}
+	if err = s.timers.AttachItemEffects(tx, leading.PlayerID, auction.ItemID, now); err != nil {
+		return err
+	}
+	if err = tx.Auctions().SetBidStatus(leading.ID, "won"); err != nil {
+		return fmt.Errorf("failed to update bid: %w", err)
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		ToPlayerID:      &leading.PlayerID,
+		ItemID:          auction.ItemID,
+		TransactionType: "auction",
+		ReferenceID:     auction.ID,
+		ReferenceType:   "auction",
+		Description:     fmt.Sprintf("Item won at auction for %d: %s", leading.Amount, item.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	if err = tx.Events().PublishItemMoved(auction.ItemID, nil, &leading.PlayerID, "auction"); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+
+	leading.Status = "won"
+	return s.close(tx, auction, "sold", leading, now)
+}
+
+// Cancel - мастер отменяет аукцион, лидер получает свою ставку обратно
+func (s *Service) Cancel(tx storage.Tx, auctionID int, now time.Time) (*storage.Auction, error) {
+	auction, err := s.load(tx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if auction.Status != "active" {
+		return nil, ErrNotActive
+	}
+
+	leading, err := s.leadingBid(tx, auction.ID)
+	if err != nil {
+		return nil, err
+	}
+	if leading != nil {
+		if err = s.refund(tx, auction, leading, "refunded"); err != nil {
+			return nil, err
+		}
+		if err = tx.Events().PublishBalances(leading.PlayerID); err != nil {
+			return nil, fmt.Errorf("failed to publish events: %w", err)
+		}
+	}
+
+	if err = s.close(tx, auction, "cancelled", nil, now); err != nil {
+		return nil, err
+	}
+	return auction, nil
+}
+
+// close закрывает аукцион со статусом status и снимает его таймер. winner - только для 'sold'
+func (s *Service) close(tx storage.Tx, auction *storage.Auction, status string, winner *storage.AuctionBid, now time.Time) error {
+	var winnerID, finalPrice *int
+	if winner != nil {
+		winnerID, finalPrice = &winner.PlayerID, &winner.Amount
+	}
+
+	if err := tx.Auctions().Close(auction.ID, status, winnerID, finalPrice, now); err != nil {
+		return fmt.Errorf("failed to close auction #%d: %w", auction.ID, err)
+	}
+
+	// При отмене таймер ещё в очереди; для задачи очереди отмена ничего не меняет
+	if err := tx.Timers().Cancel(JobKey(auction.ID)); err != nil {
+		return fmt.Errorf("failed to cancel auction timer: %w", err)
+	}
+
+	auction.Status = status
+	return s.publish(tx, events.TypeAuctionClosed, auction, winner)
+}
+
+func (s *Service) load(tx storage.Tx, auctionID int) (*storage.Auction, error) {
+	auction, err := tx.Auctions().GetForUpdate(auctionID)
+	if err == storage.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch auction #%d: %w", auctionID, err)
+	}
+	return auction, nil
+}
+
+// leadingBid возвращает лидирующую ставку или nil, если ставок нет
+func (s *Service) leadingBid(tx storage.Tx, auctionID int) (*storage.AuctionBid, error) {
+	bid, err := tx.Auctions().LeadingBid(auctionID)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leading bid: %w", err)
+	}
+	return bid, nil
+}
+
+// publish отправляет изменение аукциона всем игрокам: аукцион общий
+func (s *Service) publish(tx storage.Tx, eventType string, auction *storage.Auction, bid *storage.AuctionBid) error {
+	data := events.AuctionChanged{
+		AuctionID: auction.ID,
+		ItemID:    auction.ItemID,
+		Status:    auction.Status,
+	}
+	if bid != nil {
+		data.LeaderID = &bid.PlayerID
+		data.Amount = &bid.Amount
+	}
+
+	if err := tx.Events().Publish(eventType, nil, data); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// internal/auth/password.go
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength - минимальная длина нового пароля
+const MinPasswordLength = 6
+
+// MaxPasswordLength - bcrypt не принимает пароли длиннее 72 байт
+const MaxPasswordLength = 72
+
+// ValidatePasswordLength проверяет длину нового пароля. Текст ошибки можно отдавать клиенту
+func ValidatePasswordLength(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("Password must be at least %d characters", MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("Password must be at most %d bytes", MaxPasswordLength)
+	}
+	return nil
+}
+
+// HashPassword возвращает bcrypt-хеш пароля (соль генерируется автоматически)
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsHashed проверяет, что в базе лежит bcrypt-хеш, а не пароль в открытом виде
+func IsHashed(stored string) bool {
+	if len(stored) != 60 {
+		return false
+	}
+	return strings.HasPrefix(stored, "$2a$") ||
+		strings.HasPrefix(stored, "$2b$") ||
+		strings.HasPrefix(stored, "$2y$")
+}
+
+// CheckPassword сравнивает введённый пароль с сохранённым значением.
+// Второе значение = true, если пароль совпал, но хранится в открытом виде
+// и его нужно заменить хешем.
+func CheckPassword(stored, password string) (ok bool, needsUpgrade bool) {
+	if IsHashed(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+	}
+
+	// Старые записи из сида хранят пароль открытым текстом
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1 {
+		return true, true
+	}
+	return false, false
+}
@@ -11,9 +11,15 @@ type Config struct {
 	DatabaseURL             string
 	JWTKey                  string
 	Port                    string
-	EffectsWorkerInterval   int  // в секундах
-	ContractsWorkerInterval int  // в секундах
-	ContractsAutoComplete   bool // автоматически завершать истекшие договоры
+	EffectsWorkerInterval   int    // в секундах
+	ContractsWorkerInterval int    // в секундах
+	ContractsAutoComplete   bool   // автоматически завершать истекшие договоры
+	AccessTokenTTLMinutes   int    // время жизни access-токена в минутах
+	RefreshTokenTTLHours    int    // время жизни refresh-токена (и сессии без активности) в часах
+	JobsPollInterval        int    // как часто очередь задач проверяет наступившие задачи, в секундах
+	MetricsToken            string // если задан, /metrics требует Authorization: Bearer <token>
+	AutoMigrate             bool   // применять миграции схемы при старте сервера
+	LeaderboardInterval     int    // как часто сохраняются снимки таблицы лидеров, в секундах
 }
 
 func LoadConfig() *Config {
@@ -54,6 +60,48 @@ func LoadConfig() *Config {
 		contractsAutoComplete = envAutoComplete == "true" || envAutoComplete == "1"
 	}
 
+	// Время жизни access-токена (по умолчанию 15 минут)
+	accessTokenTTL := 15
+	if envTTL := os.Getenv("ACCESS_TOKEN_TTL_MINUTES"); envTTL != "" {
+		if ttl, err := strconv.Atoi(envTTL); err == nil && ttl > 0 {
+			accessTokenTTL = ttl
+		}
+	}
+
+	// Время жизни refresh-токена (по умолчанию 72 часа)
+	refreshTokenTTL := 72
+	if envTTL := os.Getenv("REFRESH_TOKEN_TTL_HOURS"); envTTL != "" {
+		if ttl, err := strconv.Atoi(envTTL); err == nil && ttl > 0 {
+			refreshTokenTTL = ttl
+		}
+	}
+
+	// Интервал опроса очереди отложенных задач (по умолчанию 1 секунда)
+	jobsPollInterval := 1
+	if envInterval := os.Getenv("JOBS_POLL_INTERVAL"); envInterval != "" {
+		if interval, err := strconv.Atoi(envInterval); err == nil && interval > 0 {
+			jobsPollInterval = interval
+		}
+	}
+
+	// Токен для /metrics (по умолчанию метрики доступны без авторизации)
+	metricsToken := os.Getenv("METRICS_TOKEN")
+
+	// Применение миграций при старте (по умолчанию true). При false сервер только проверяет,
+	// что схема актуальна, а миграции применяются командой ./api migrate up
+	autoMigrate := true
+	if envAutoMigrate := os.Getenv("AUTO_MIGRATE"); envAutoMigrate != "" {
+		autoMigrate = envAutoMigrate == "true" || envAutoMigrate == "1"
+	}
+
+	// Интервал снимков таблицы лидеров (по умолчанию 300 секунд = 5 минут)
+	leaderboardInterval := 300
+	if envInterval := os.Getenv("LEADERBOARD_SNAPSHOT_INTERVAL"); envInterval != "" {
+		if interval, err := strconv.Atoi(envInterval); err == nil && interval > 0 {
+			leaderboardInterval = interval
+		}
+	}
+
 	return &Config{
 		DatabaseURL:             databaseURL,
 		JWTKey:                  jwtKey,
@@ -61,5 +109,11 @@ func LoadConfig() *Config {
 		EffectsWorkerInterval:   effectsWorkerInterval,
 		ContractsWorkerInterval: contractsWorkerInterval,
 		ContractsAutoComplete:   contractsAutoComplete,
+		AccessTokenTTLMinutes:   accessTokenTTL,
+		RefreshTokenTTLHours:    refreshTokenTTL,
+		JobsPollInterval:        jobsPollInterval,
+		MetricsToken:            metricsToken,
+		AutoMigrate:             autoMigrate,
+		LeaderboardInterval:     leaderboardInterval,
 	}
 }
@@ -0,0 +1,427 @@
+// internal/contracts/service.go
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+// Жизненный цикл договора: pending -> signed -> completed или terminated.
+// Подписание, завершение (ручное и по таймеру), расторжение, награды и штрафы
+// за конфликт фракций выполняются только здесь, поэтому handlers и scheduler
+// не могут разойтись в том, как платят по договору
+
+// JobType - тип задач очереди для автоматического завершения договоров
+const JobType = "contract"
+
+// Job - payload задачи договора
+type Job struct {
+	ContractID int `json:"contract_id"`
+}
+
+// JobKey - ключ задачи завершения договора в очереди
+func JobKey(contractID int) string {
+	return fmt.Sprintf("contract:%d", contractID)
+}
+
+var (
+	ErrNotFound      = errors.New("contract not found")
+	ErrNotCustomer   = errors.New("only customer can perform this action")
+	ErrNotPending    = errors.New("contract is not in pending status")
+	ErrNotSigned     = errors.New("contract is not in signed status")
+	ErrNotExpired    = errors.New("contract has not expired yet")
+	ErrNotTerminable = errors.New("contract cannot be terminated")
+)
+
+// ItemGranter выдаёт игроку новый экземпляр предмета по шаблону вместе с таймерами его эффектов
+type ItemGranter interface {
+	SpawnItemTx(tx storage.Tx, templateID, playerID int, baseTime time.Time) (*storage.Item, error)
+}
+
+// Service - операции над договорами в транзакции вызывающего
+type Service struct {
+	items ItemGranter
+}
+
+func NewService(items ItemGranter) *Service {
+	return &Service{items: items}
+}
+
+// Penalty - штраф, применённый при подписании
+type Penalty struct {
+	Money     int
+	Influence int
+}
+
+// Sign подписывает договор от имени заказчика и планирует его автоматическое завершение.
+// Если у заказчика уже есть подписанный договор с игроком другой фракции, сначала списывается штраф
+func (s *Service) Sign(tx storage.Tx, contractID, customerID int, now time.Time) (*storage.Contract, *Penalty, error) {
+	contract, err := s.load(tx, contractID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if contract.CustomerPlayerID != customerID {
+		return nil, nil, ErrNotCustomer
+	}
+	if contract.Status != "pending" {
+		return nil, nil, ErrNotPending
+	}
+
+	customer, err := tx.Players().Get(customerID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch customer faction: %w", err)
+	}
+
+	// Проверяем наличие активных договоров с другими фракциями
+	var penalty *Penalty
+	if customer.FactionID != nil {
+		conflictingFactionID, err := tx.Contracts().ConflictingFaction(customerID, *customer.FactionID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check faction conflicts: %w", err)
+		}
+
+		if conflictingFactionID != nil {
+			if penalty, err = s.applyFactionPenalty(tx, contractID, customerID); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	expiresAt := now.Add(time.Duration(contract.DurationSeconds) * time.Second)
+
+	if err = tx.Contracts().Sign(contractID, now, expiresAt, customer.FactionID); err != nil {
+		return nil, nil, fmt.Errorf("failed to sign contract: %w", err)
+	}
+
+	// Точный таймер завершения попадёт в очередь вместе с подписанием
+	if err = tx.Timers().Schedule(JobType, JobKey(contractID), expiresAt, Job{ContractID: contractID}); err != nil {
+		return nil, nil, fmt.Errorf("failed to schedule contract completion: %w", err)
+	}
+
+	contract.Status = "signed"
+	contract.ExpiresAt = &expiresAt
+	contract.CustomerFactionID = customer.FactionID
+
+	if err = s.publish(tx, events.TypeContractSigned, contract); err != nil {
+		return nil, nil, err
+	}
+
+	return contract, penalty, nil
+}
+
+// applyFactionPenalty списывает штраф за конфликт фракций по настройкам contract_penalty_settings
+func (s *Service) applyFactionPenalty(tx storage.Tx, contractID, playerID int) (*Penalty, error) {
+	moneyPenalty, influencePenalty, err := tx.Contracts().PenaltySettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch penalty settings: %w", err)
+	}
+
+	// Снимаем деньги
+	if moneyPenalty > 0 {
+		if _, err = tx.Players().TakeMoney(playerID, moneyPenalty); err != nil {
+			return nil, fmt.Errorf("failed to apply money penalty: %w", err)
+		}
+
+		err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+			FromPlayerID:    &playerID,
+			Amount:          -moneyPenalty,
+			TransactionType: "contract",
+			ReferenceID:     contractID,
+			ReferenceType:   "contract",
+			Description:     "Faction conflict penalty",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record money penalty: %w", err)
+		}
+	}
+
+	// Снимаем влияние
+	if influencePenalty > 0 {
+		if _, err = tx.Players().TakeInfluence(playerID, influencePenalty); err != nil {
+			return nil, fmt.Errorf("failed to apply influence penalty: %w", err)
+		}
+
+		err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+			PlayerID:        playerID,
+			Amount:          -influencePenalty,
+			TransactionType: "contract",
+			ReferenceID:     contractID,
+			ReferenceType:   "contract",
+			Description:     "Faction conflict penalty",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record influence penalty: %w", err)
+		}
+	}
+
+	// Записываем штраф
+	err = tx.Contracts().RecordPenalty(storage.ContractPenalty{
+		PlayerID:         playerID,
+		ContractID:       contractID,
+		ViolationType:    "faction_conflict",
+		MoneyPenalty:     moneyPenalty,
+		InfluencePenalty: influencePenalty,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record penalty: %w", err)
+	}
+
+	if influencePenalty > 0 {
+		err = tx.Events().Publish(events.TypePenaltyApplied, []int{playerID}, events.PenaltyApplied{
+			PlayerID:      playerID,
+			Influence:     influencePenalty,
+			ReferenceID:   contractID,
+			ReferenceType: "contract",
+		})
+	}
+	if err == nil && (moneyPenalty > 0 || influencePenalty > 0) {
+		err = tx.Events().PublishBalances(playerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish events: %w", err)
+	}
+
+	return &Penalty{Money: moneyPenalty, Influence: influencePenalty}, nil
+}
+
+// CompleteByCustomer - ручное завершение договора заказчиком после истечения срока
+func (s *Service) CompleteByCustomer(tx storage.Tx, contractID, customerID int, now time.Time) (*storage.Contract, error) {
+	contract, err := s.load(tx, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	if contract.CustomerPlayerID != customerID {
+		return nil, ErrNotCustomer
+	}
+	if contract.Status != "signed" {
+		return nil, ErrNotSigned
+	}
+	if contract.ExpiresAt == nil || now.Before(*contract.ExpiresAt) {
+		return nil, ErrNotExpired
+	}
+
+	return contract, s.complete(tx, contract, now)
+}
+
+// CompleteExpired - автоматическое завершение по таймеру очереди.
+// Договор, который уже завершён или расторгнут, пропускается без ошибки
+func (s *Service) CompleteExpired(tx storage.Tx, contractID int, now time.Time) error {
+	contract, err := s.load(tx, contractID)
+	if err == ErrNotFound {
+		log.Printf("Contract #%d no longer exists, skipping", contractID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if contract.Status != "signed" {
+		log.Printf("Contract #%d is no longer signed (status: %s), skipping", contractID, contract.Status)
+		return nil
+	}
+
+	return s.complete(tx, contract, now)
+}
+
+// complete выдаёт награды, отмечает договор завершённым и снимает его таймер
+func (s *Service) complete(tx storage.Tx, contract *storage.Contract, now time.Time) error {
+	if err := s.distributeRewards(tx, contract, now); err != nil {
+		return fmt.Errorf("failed to distribute rewards for contract #%d: %w", contract.ID, err)
+	}
+
+	if err := tx.Contracts().Complete(contract.ID, now); err != nil {
+		return fmt.Errorf("failed to complete contract #%d: %w", contract.ID, err)
+	}
+
+	// При ручном завершении таймер ещё в очереди; для задачи очереди отмена ничего не меняет
+	if err := tx.Timers().Cancel(JobKey(contract.ID)); err != nil {
+		return fmt.Errorf("failed to cancel contract timer: %w", err)
+	}
+
+	contract.Status = "completed"
+	return s.publish(tx, events.TypeContractCompleted, contract)
+}
+
+// Terminate расторгает договор в статусе pending или signed без выплат
+func (s *Service) Terminate(tx storage.Tx, contractID int, reason string, now time.Time) (*storage.Contract, error) {
+	contract, err := s.load(tx, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	if contract.Status != "pending" && contract.Status != "signed" {
+		return nil, ErrNotTerminable
+	}
+
+	if err = tx.Contracts().Terminate(contractID, now); err != nil {
+		return nil, fmt.Errorf("failed to terminate contract: %w", err)
+	}
+
+	// Причина расторжения хранится в журнале денег нулевой записью
+	err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		Amount:          0,
+		TransactionType: "contract",
+		ReferenceID:     contractID,
+		ReferenceType:   "contract",
+		Description:     reason,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record termination: %w", err)
+	}
+
+	if err = tx.Timers().Cancel(JobKey(contractID)); err != nil {
+		return nil, fmt.Errorf("failed to cancel contract timer: %w", err)
+	}
+
+	contract.Status = "terminated"
+	if err = s.publish(tx, events.TypeContractTerminated, contract); err != nil {
+		return nil, err
+	}
+
+	return contract, nil
+}
+
+// distributeRewards выдаёт награды согласно типу договора:
+// type1 - деньги обеим сторонам и предмет фракции заказчику, type2 - деньги исполнителю
+func (s *Service) distributeRewards(tx storage.Tx, contract *storage.Contract, now time.Time) error {
+	description := fmt.Sprintf("Contract %d completion reward", contract.ID)
+
+	giveMoney := func(playerID, amount int, who string) error {
+		if amount <= 0 {
+			return nil
+		}
+
+		if err := tx.Players().AddMoney(playerID, amount); err != nil {
+			return fmt.Errorf("failed to give money to %s: %w", who, err)
+		}
+
+		err := tx.Ledger().RecordMoney(storage.MoneyTransaction{
+			ToPlayerID:      &playerID,
+			Amount:          amount,
+			TransactionType: "contract",
+			ReferenceID:     contract.ID,
+			ReferenceType:   "contract",
+			Description:     description,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record %s money transaction: %w", who, err)
+		}
+		return nil
+	}
+
+	switch contract.ContractType {
+	case "type1":
+		if err := giveMoney(contract.CustomerPlayerID, contract.MoneyRewardCustomer, "customer"); err != nil {
+			return err
+		}
+		if err := giveMoney(contract.ExecutorPlayerID, contract.MoneyRewardExecutor, "executor"); err != nil {
+			return err
+		}
+
+		// Предмет и деньги в казну получает фракция заказчика на момент подписания
+		if contract.CustomerFactionID != nil {
+			if err := s.grantFactionItem(tx, contract, *contract.CustomerFactionID, description, now); err != nil {
+				return err
+			}
+			if err := giveTreasury(tx, contract, *contract.CustomerFactionID, description); err != nil {
+				return err
+			}
+		}
+
+	case "type2":
+		if err := giveMoney(contract.ExecutorPlayerID, contract.MoneyRewardExecutor, "executor"); err != nil {
+			return err
+		}
+	}
+
+	return tx.Events().PublishBalances(contract.CustomerPlayerID, contract.ExecutorPlayerID)
+}
+
+// giveTreasury зачисляет награду type1 в казну фракции заказчика
+func giveTreasury(tx storage.Tx, contract *storage.Contract, factionID int, description string) error {
+	if contract.MoneyRewardFaction <= 0 {
+		return nil
+	}
+
+	if err := tx.Factions().AddTreasury(factionID, contract.MoneyRewardFaction); err != nil {
+		return fmt.Errorf("failed to give money to faction treasury: %w", err)
+	}
+
+	err := tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		ToFactionID:     &factionID,
+		Amount:          contract.MoneyRewardFaction,
+		TransactionType: "contract",
+		ReferenceID:     contract.ID,
+		ReferenceType:   "contract",
+		Description:     description,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record treasury money transaction: %w", err)
+	}
+
+	return tx.Events().PublishTreasury(factionID)
+}
+
+// grantFactionItem выдаёт заказчику type1 предмет из contract_type1_settings его фракции
+func (s *Service) grantFactionItem(tx storage.Tx, contract *storage.Contract, factionID int,
+	description string, now time.Time) error {
+	templateID, err := tx.Contracts().Type1ItemReward(factionID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch item reward settings: %w", err)
+	}
+
+	if templateID == nil || *templateID <= 0 {
+		return nil
+	}
+
+	// Награда - новый экземпляр предмета со своими таймерами эффектов
+	item, err := s.items.SpawnItemTx(tx, *templateID, contract.CustomerPlayerID, now)
+	if err != nil {
+		return fmt.Errorf("failed to give item to customer: %w", err)
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		ToPlayerID:      &contract.CustomerPlayerID,
+		ItemID:          item.ID,
+		TransactionType: "contract",
+		ReferenceID:     contract.ID,
+		ReferenceType:   "contract",
+		Description:     description,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	return tx.Events().PublishItemMoved(item.ID, nil, &contract.CustomerPlayerID, "contract")
+}
+
+func (s *Service) load(tx storage.Tx, contractID int) (*storage.Contract, error) {
+	contract, err := tx.Contracts().GetForUpdate(contractID)
+	if err == storage.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contract #%d: %w", contractID, err)
+	}
+	return contract, nil
+}
+
+func (s *Service) publish(tx storage.Tx, eventType string, contract *storage.Contract) error {
+	err := tx.Events().Publish(eventType,
+		[]int{contract.CustomerPlayerID, contract.ExecutorPlayerID}, events.ContractChanged{
+			ContractID: contract.ID,
+			Status:     contract.Status,
+			CustomerID: contract.CustomerPlayerID,
+			ExecutorID: contract.ExecutorPlayerID,
+		})
+	if err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+// internal/contracts/service_test.go
+package contracts
+
+import (
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+// spawner выдаёт предмет без таймеров эффектов
+type spawner struct{}
+
+func (spawner) SpawnItemTx(tx storage.Tx, templateID, playerID int, baseTime time.Time) (*storage.Item, error) {
+	return tx.Items().Spawn(templateID, playerID)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// newStore - заказчик 1 и исполнитель 2 из фракции 1, игрок 3 из фракции 2
+func newStore() *memory.Store {
+	store := memory.New()
+	store.AddFaction(1, "North")
+	store.AddFaction(2, "South")
+	store.AddPlayer(storage.Player{ID: 1, CharacterName: "Customer", FactionID: intPtr(1), Money: 20, Influence: 10})
+	store.AddPlayer(storage.Player{ID: 2, CharacterName: "Executor", FactionID: intPtr(1), Money: 0})
+	store.AddPlayer(storage.Player{ID: 3, CharacterName: "Stranger", FactionID: intPtr(2)})
+	store.SetContractPenalties(50, 5)
+	return store
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name      string
+		seed      func(store *memory.Store)
+		caller    int
+		err       error
+		penalty   *Penalty
+		money     int // деньги заказчика после подписания
+		influence int
+	}{
+		{
+			name:      "success",
+			caller:    1,
+			money:     20,
+			influence: 10,
+		},
+		{
+			name: "faction conflict penalty takes no more than customer has",
+			seed: func(store *memory.Store) {
+				store.AddContract(storage.Contract{ID: 9, Status: "signed", ContractType: "type2",
+					CustomerPlayerID: 1, ExecutorPlayerID: 3})
+			},
+			caller:    1,
+			penalty:   &Penalty{Money: 50, Influence: 5},
+			money:     0,
+			influence: 5,
+		},
+		{
+			name:      "not customer",
+			caller:    2,
+			err:       ErrNotCustomer,
+			money:     20,
+			influence: 10,
+		},
+		{
+			name: "not pending",
+			seed: func(store *memory.Store) {
+				store.AddContract(storage.Contract{ID: 1, Status: "terminated", ContractType: "type2",
+					CustomerPlayerID: 1, ExecutorPlayerID: 2, DurationSeconds: 600})
+			},
+			caller:    1,
+			err:       ErrNotPending,
+			money:     20,
+			influence: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore()
+			store.AddContract(storage.Contract{ID: 1, Status: "pending", ContractType: "type2",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, DurationSeconds: 600})
+			if tt.seed != nil {
+				tt.seed(store)
+			}
+
+			service := NewService(spawner{})
+			now := time.Now()
+
+			tx, _ := store.Begin()
+			contract, penalty, err := service.Sign(tx, 1, tt.caller, now)
+			if err != tt.err {
+				tx.Rollback()
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			tx.Commit()
+
+			if (penalty == nil) != (tt.penalty == nil) || (penalty != nil && *penalty != *tt.penalty) {
+				t.Errorf("penalty = %+v, want %+v", penalty, tt.penalty)
+			}
+
+			customer, _ := store.Player(1)
+			if customer.Money != tt.money || customer.Influence != tt.influence {
+				t.Errorf("customer money = %d, influence = %d, want %d, %d",
+					customer.Money, customer.Influence, tt.money, tt.influence)
+			}
+
+			timer, scheduled := store.Timers()[JobKey(1)]
+			if tt.err != nil {
+				if scheduled {
+					t.Error("completion timer scheduled for unsigned contract")
+				}
+				return
+			}
+
+			if contract.Status != "signed" || contract.CustomerFactionID == nil || *contract.CustomerFactionID != 1 {
+				t.Errorf("contract = %+v, want signed with customer faction 1", contract)
+			}
+			if !scheduled || !timer.RunAt.Equal(now.Add(600*time.Second)) {
+				t.Errorf("completion timer = %+v (scheduled: %v), want run at expiry", timer, scheduled)
+			}
+			if tt.penalty != nil && len(store.ContractPenalties()) != 1 {
+				t.Errorf("recorded penalties = %d, want 1", len(store.ContractPenalties()))
+			}
+		})
+	}
+}
+
+func TestCompleteExpired(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract storage.Contract
+		runs     int
+		customer int // деньги заказчика после завершения
+		executor int
+		treasury int
+		items    int // предметов у заказчика
+		status   string
+	}{
+		{
+			name: "type1 rewards both sides and customer faction",
+			contract: storage.Contract{ID: 1, Status: "signed", ContractType: "type1",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, CustomerFactionID: intPtr(1),
+				MoneyRewardCustomer: 10, MoneyRewardExecutor: 15, MoneyRewardFaction: 30},
+			runs:     1,
+			customer: 30,
+			executor: 15,
+			treasury: 30,
+			items:    1,
+			status:   "completed",
+		},
+		{
+			name: "type2 rewards executor only",
+			contract: storage.Contract{ID: 1, Status: "signed", ContractType: "type2",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, CustomerFactionID: intPtr(1),
+				MoneyRewardCustomer: 10, MoneyRewardExecutor: 15, MoneyRewardFaction: 30},
+			runs:     1,
+			customer: 20,
+			executor: 15,
+			status:   "completed",
+		},
+		{
+			name: "replayed timer pays once",
+			contract: storage.Contract{ID: 1, Status: "signed", ContractType: "type1",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, CustomerFactionID: intPtr(1),
+				MoneyRewardCustomer: 10, MoneyRewardExecutor: 15, MoneyRewardFaction: 30},
+			runs:     3,
+			customer: 30,
+			executor: 15,
+			treasury: 30,
+			items:    1,
+			status:   "completed",
+		},
+		{
+			name: "terminated contract is skipped",
+			contract: storage.Contract{ID: 1, Status: "terminated", ContractType: "type1",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, CustomerFactionID: intPtr(1),
+				MoneyRewardCustomer: 10, MoneyRewardExecutor: 15, MoneyRewardFaction: 30},
+			runs:     1,
+			customer: 20,
+			status:   "terminated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore()
+			store.AddTemplate(memory.Template{ID: 4, Name: "Banner"})
+			store.SetType1ItemReward(1, 4)
+			store.AddContract(tt.contract)
+
+			service := NewService(spawner{})
+			for i := 0; i < tt.runs; i++ {
+				tx, _ := store.Begin()
+				if err := service.CompleteExpired(tx, 1, time.Now()); err != nil {
+					tx.Rollback()
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+				tx.Commit()
+			}
+
+			customer, _ := store.Player(1)
+			executor, _ := store.Player(2)
+			if customer.Money != tt.customer || executor.Money != tt.executor {
+				t.Errorf("money: customer = %d, executor = %d, want %d, %d",
+					customer.Money, executor.Money, tt.customer, tt.executor)
+			}
+			if treasury := store.Treasury(1); treasury != tt.treasury {
+				t.Errorf("treasury = %d, want %d", treasury, tt.treasury)
+			}
+			if items := len(store.ItemsOf(1)); items != tt.items {
+				t.Errorf("customer items = %d, want %d", items, tt.items)
+			}
+			if contract, _ := store.Contract(1); contract.Status != tt.status {
+				t.Errorf("status = %s, want %s", contract.Status, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+// internal/database/migrate.go
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyChecks - признаки миграций, которые мог применить docker-entrypoint-initdb.d.
+// База, созданная так, не имеет schema_migrations: версия 1 считается применённой, если есть players,
+// а остальные - только если в базе есть все их объекты
+var legacyChecks = map[int]string{
+	3: `SELECT to_regclass('user_sessions') IS NOT NULL AND to_regclass('refresh_tokens') IS NOT NULL`,
+	4: `SELECT to_regclass('scheduled_jobs') IS NOT NULL`,
+	5: `SELECT to_regclass('idx_money_transactions_from_player') IS NOT NULL
+		AND to_regclass('idx_money_transactions_to_player') IS NOT NULL
+		AND to_regclass('idx_item_transactions_from_player') IS NOT NULL
+		AND to_regclass('idx_item_transactions_to_player') IS NOT NULL
+		AND to_regclass('idx_influence_transactions_player') IS NOT NULL`,
+	// Перенос данных завершён, когда у items есть template_id, а у effects уже нет spawned_item_id
+	6: `SELECT to_regclass('item_templates') IS NOT NULL AND to_regclass('item_template_effects') IS NOT NULL
+		AND EXISTS(SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'items' AND column_name = 'template_id')
+		AND NOT EXISTS(SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'effects' AND column_name = 'spawned_item_id')`,
+	7: `SELECT COUNT(*) = 2 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'game_timeline'
+		  AND column_name IN ('paused_at', 'total_paused_seconds')`,
+	8: `SELECT to_regclass('game_runs') IS NOT NULL AND to_regclass('game_run_archive') IS NOT NULL
+		AND to_regclass('game_baseline') IS NOT NULL`,
+}
+
+// migrationLockID - ключ advisory lock, чтобы несколько экземпляров API не мигрировали одновременно
+const migrationLockID = 724_031_001
+
+// Migration - одна версия схемы из файлов NN-name.sql и NN-name.down.sql
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string // пустая - миграция необратима
+	Checksum string
+}
+
+// MigrationStatus - состояние версии в базе
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // файл изменён после применения
+}
+
+// Migrator применяет встроенные в бинарник миграции и seed-файлы
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	seeds      []Migration
+}
+
+func NewMigrator(db *sql.DB, migrationsFS, seedsFS fs.FS) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	seeds, err := loadMigrations(seedsFS, "seed")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, migrations: migrations, seeds: seeds}, nil
+}
+
+// loadMigrations читает файлы вида NN-name.sql (и NN-name.down.sql) из каталога dir
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(fileName, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		down := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		number, name, ok := strings.Cut(base, "-")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q (expected NN-name.sql)", fileName)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, name)
+		}
+
+		if down {
+			m.Down = string(content)
+		} else {
+			if m.Up != "" {
+				return nil, fmt.Errorf("duplicate migration %d", version)
+			}
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has only a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest - последняя версия, известная бинарнику
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// ensureTables создаёт служебные таблицы. Для базы, созданной через docker-entrypoint-initdb.d,
+// отмечает как применённые версию 1, версии, все объекты которых уже есть в базе (legacyChecks),
+// и seed, если в базе есть игроки
+func (m *Migrator) ensureTables() error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return tx.Commit()
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE schema_seeds (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		COMMENT ON TABLE schema_migrations IS 'Применённые версии схемы (./api migrate)';
+		COMMENT ON TABLE schema_seeds IS 'Применённые seed-файлы (./api migrate seed)';
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var legacy bool
+	err = tx.QueryRow(`SELECT to_regclass('players') IS NOT NULL`).Scan(&legacy)
+	if err != nil {
+		return err
+	}
+
+	if legacy {
+		baselined := make([]int, 0)
+		for _, migration := range m.migrations {
+			if migration.Version != 1 {
+				check, ok := legacyChecks[migration.Version]
+				if !ok {
+					continue
+				}
+
+				var present bool
+				if err = tx.QueryRow(check).Scan(&present); err != nil {
+					return fmt.Errorf("failed to detect migration %d: %w", migration.Version, err)
+				}
+				if !present {
+					continue
+				}
+			}
+
+			if err = recordVersion(tx, "schema_migrations", migration); err != nil {
+				return err
+			}
+			baselined = append(baselined, migration.Version)
+		}
+
+		// Старый 02-seed.sql выполнялся вместе со схемой
+		var hasPlayers bool
+		if err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM players)`).Scan(&hasPlayers); err != nil {
+			return err
+		}
+		if hasPlayers {
+			for _, seed := range m.seeds {
+				if err = recordVersion(tx, "schema_seeds", seed); err != nil {
+					return err
+				}
+			}
+		}
+
+		log.Printf("Existing database without schema_migrations: marked migrations %v as applied", baselined)
+	}
+
+	return tx.Commit()
+}
+
+func recordVersion(tx *sql.Tx, table string, migration Migration) error {
+	_, err := tx.Exec(`
+		INSERT INTO `+table+` (version, name, checksum)
+		VALUES ($1, $2, $3)
+	`, migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to record %s version %d: %w", table, migration.Version, err)
+	}
+	return nil
+}
+
+type appliedVersion struct {
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (m *Migrator) applied(table string) (map[int]appliedVersion, error) {
+	rows, err := m.db.Query(`SELECT version, name, checksum, applied_at FROM ` + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedVersion)
+	for rows.Next() {
+		var version int
+		var v appliedVersion
+		if err := rows.Scan(&version, &v.Name, &v.Checksum, &v.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = v
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Check сверяет базу с бинарником: ошибка, если в базе есть версии, которых бинарник не знает
+// (база новее кода), или применённый файл миграции был изменён. Возвращает число неприменённых версий
+func (m *Migrator) Check() (pending int, err error) {
+	if err = m.ensureTables(); err != nil {
+		return 0, err
+	}
+
+	applied, err := m.applied("schema_migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	return m.verify(applied)
+}
+
+func (m *Migrator) verify(applied map[int]appliedVersion) (int, error) {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, v := range applied {
+		migration, ok := known[version]
+		if !ok {
+			return 0, fmt.Errorf("database has migration %d (%s) unknown to this binary (latest %d): the database is ahead, deploy a newer build",
+				version, v.Name, m.Latest())
+		}
+		if migration.Checksum != v.Checksum {
+			return 0, fmt.Errorf("migration %d (%s) was modified after it was applied (checksum mismatch)",
+				version, migration.Name)
+		}
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+// Up применяет все неприменённые миграции по возрастанию версий, каждую в своей транзакции
+func (m *Migrator) Up() (int, error) {
+	if _, err := m.Check(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		done, err := m.apply("schema_migrations", migration)
+		if err != nil {
+			return count, err
+		}
+		if done {
+			log.Printf("Applied migration %d (%s)", migration.Version, migration.Name)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// apply выполняет миграцию под advisory lock, если её ещё нет в таблице учёта
+func (m *Migrator) apply(table string, migration Migration) (bool, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return false, err
+	}
+
+	// Другой экземпляр мог применить версию, пока мы ждали блокировку
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE version = $1)`, migration.Version).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	if _, err = tx.Exec(migration.Up); err != nil {
+		return false, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if err = recordVersion(tx, table, migration); err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(steps int) (int, error) {
+	if _, err := m.Check(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for count < steps {
+		migration, err := m.rollbackLatest()
+		if err != nil {
+			return count, err
+		}
+		if migration == nil {
+			break
+		}
+
+		log.Printf("Rolled back migration %d (%s)", migration.Version, migration.Name)
+		count++
+	}
+
+	return count, nil
+}
+
+// rollbackLatest откатывает последнюю применённую миграцию (nil - откатывать нечего)
+func (m *Migrator) rollbackLatest() (*Migration, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return nil, err
+	}
+
+	var version int
+	err = tx.QueryRow(`SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1`).Scan(&version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var migration *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			migration = &m.migrations[i]
+			break
+		}
+	}
+	if migration == nil {
+		return nil, fmt.Errorf("migration %d is unknown to this binary", version)
+	}
+	if migration.Down == "" {
+		return nil, fmt.Errorf("migration %d (%s) is irreversible", migration.Version, migration.Name)
+	}
+
+	if _, err = tx.Exec(migration.Down); err != nil {
+		return nil, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+
+	if _, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, version); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return migration, nil
+}
+
+// Status возвращает состояние всех версий, известных бинарнику
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTables(); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied("schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if v, ok := applied[migration.Version]; ok {
+			appliedAt := v.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = v.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Seed применяет ещё не применённые seed-файлы. Схема должна быть актуальной
+func (m *Migrator) Seed() (int, error) {
+	pending, err := m.Check()
+	if err != nil {
+		return 0, err
+	}
+	if pending > 0 {
+		return 0, fmt.Errorf("%d migrations are not applied yet, run migrate up first", pending)
+	}
+
+	count := 0
+	for _, seed := range m.seeds {
+		done, err := m.apply("schema_seeds", seed)
+		if err != nil {
+			return count, err
+		}
+		if done {
+			log.Printf("Applied seed %d (%s)", seed.Version, seed.Name)
+			count++
+		}
+	}
+
+	return count, nil
+}
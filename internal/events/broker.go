@@ -0,0 +1,241 @@
+// internal/events/broker.go
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// subscriptionBuffer - сколько событий может ждать медленного клиента,
+// прежде чем новые события для него начнут отбрасываться
+const subscriptionBuffer = 64
+
+// Задержка между попытками подключения в StartWithRetry: 1с, 2с, 4с, ... но не больше retryMaxDelay
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = time.Minute
+)
+
+var errBrokerStopped = errors.New("events broker is stopped")
+
+// Subscription - подписка одного клиента на события
+type Subscription struct {
+	Events   chan Event
+	playerID *int
+	isAdmin  bool
+}
+
+// wants проверяет, адресовано ли событие подписчику
+func (s *Subscription) wants(event Event) bool {
+	if len(event.PlayerIDs) == 0 || s.isAdmin {
+		return true
+	}
+	if s.playerID == nil {
+		return false
+	}
+	for _, id := range event.PlayerIDs {
+		if id == *s.playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// Broker слушает канал game_events в PostgreSQL и раздаёт события подписчикам
+type Broker struct {
+	databaseURL string
+	listener    *pq.Listener
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	done        chan struct{}
+	running     bool
+	quit        chan struct{} // закрывается в Stop и прерывает попытки подключения
+	stopped     bool
+}
+
+func NewBroker(databaseURL string) *Broker {
+	return &Broker{
+		databaseURL: databaseURL,
+		subscribers: make(map[*Subscription]struct{}),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start подключается к PostgreSQL и начинает слушать события
+func (b *Broker) Start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return errBrokerStopped
+	}
+	if b.running {
+		return nil
+	}
+
+	listener := pq.NewListener(b.databaseURL, 10*time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("Events listener: %v", err)
+			}
+		})
+
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	b.listener = listener
+	b.done = make(chan struct{})
+	b.running = true
+
+	go b.run(listener, b.done)
+
+	log.Printf("Events broker started, listening on channel %s", Channel)
+	return nil
+}
+
+// StartWithRetry запускает broker, а если PostgreSQL недоступен - повторяет попытки в фоне
+// с растущей задержкой, пока не подключится или не будет вызван Stop
+func (b *Broker) StartWithRetry() {
+	err := b.Start()
+	if err == nil {
+		return
+	}
+	log.Printf("Warning: Failed to start events broker: %v", err)
+
+	go func() {
+		delay := retryBaseDelay
+		for {
+			select {
+			case <-b.quit:
+				return
+			case <-time.After(delay):
+			}
+
+			err := b.Start()
+			if err == nil || err == errBrokerStopped {
+				return
+			}
+
+			delay *= 2
+			if delay > retryMaxDelay {
+				delay = retryMaxDelay
+			}
+			log.Printf("Warning: Failed to start events broker, retrying in %s: %v", delay, err)
+		}
+	}()
+}
+
+// Stop отключается от PostgreSQL и закрывает все подписки
+func (b *Broker) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.stopped {
+		b.stopped = true
+		close(b.quit)
+	}
+
+	if !b.running {
+		return
+	}
+
+	close(b.done)
+	b.listener.Close()
+	b.running = false
+
+	for sub := range b.subscribers {
+		close(sub.Events)
+		delete(b.subscribers, sub)
+	}
+
+	log.Println("Events broker stopped")
+}
+
+// Subscribe создаёт подписку игрока (или администратора - он получает все события)
+func (b *Broker) Subscribe(playerID *int, isAdmin bool) *Subscription {
+	sub := &Subscription{
+		Events:   make(chan Event, subscriptionBuffer),
+		playerID: playerID,
+		isAdmin:  isAdmin,
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe удаляет подписку
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		close(sub.Events)
+		delete(b.subscribers, sub)
+	}
+}
+
+// GetSubscribersCount возвращает количество подключённых клиентов
+func (b *Broker) GetSubscribersCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+func (b *Broker) run(listener *pq.Listener, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+
+		case notification, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+
+			// nil приходит после переподключения: события за время разрыва потеряны
+			if notification == nil {
+				b.dispatch(Event{Type: TypeResync, CreatedAt: time.Now()})
+				continue
+			}
+
+			var event Event
+			if err := json.Unmarshal([]byte(notification.Extra), &event); err != nil {
+				log.Printf("Events broker: failed to decode event: %v", err)
+				continue
+			}
+			b.dispatch(event)
+
+		case <-time.After(90 * time.Second):
+			// Проверяем, что соединение живо
+			go listener.Ping()
+		}
+	}
+}
+
+// dispatch раздаёт событие адресатам, не блокируясь на медленных клиентах
+func (b *Broker) dispatch(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.wants(event) {
+			continue
+		}
+
+		select {
+		case sub.Events <- event:
+		default:
+			log.Printf("Events broker: subscriber buffer is full, dropping %s event", event.Type)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// internal/events/events.go
+package events
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel - канал PostgreSQL, через который события доходят до Broker
+const Channel = "game_events"
+
+// maxPayloadSize - ограничение PostgreSQL на размер payload у NOTIFY
+const maxPayloadSize = 8000
+
+// Типы событий
+const (
+	TypeBalanceChanged     = "balance_changed"
+	TypeTreasuryChanged    = "treasury_changed"
+	TypeItemReceived       = "item_received"
+	TypeItemTransferred    = "item_transferred"
+	TypeContractSigned     = "contract_signed"
+	TypeContractCompleted  = "contract_completed"
+	TypeContractTerminated = "contract_terminated"
+	TypeDebtOverdue        = "debt_overdue"
+	TypeListingCreated     = "listing_created"
+	TypeListingClosed      = "listing_closed"
+	TypeTradeProposed      = "trade_proposed"
+	TypeTradeClosed        = "trade_closed"
+	TypeAuctionCreated     = "auction_created"
+	TypeAuctionBid         = "auction_bid"
+	TypeAuctionClosed      = "auction_closed"
+	TypePenaltyApplied     = "penalty_applied"
+	TypeGoalUnlocked       = "goal_unlocked"
+	TypeGameStarted        = "game_started"
+	TypeGameEnded          = "game_ended"
+	TypeGamePaused         = "game_paused"
+	TypeGameResumed        = "game_resumed"
+
+	// TypeResync отправляется клиентам после переподключения к БД:
+	// события за время разрыва потеряны, состояние нужно перечитать
+	TypeResync = "resync"
+)
+
+// Event - событие для игроков. Пустой PlayerIDs означает событие для всех
+type Event struct {
+	Type      string          `json:"type"`
+	PlayerIDs []int           `json:"player_ids,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Execer - *sql.DB или *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Querier - *sql.DB или *sql.Tx
+type Querier interface {
+	Execer
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// BalanceChanged - новый баланс игрока
+type BalanceChanged struct {
+	PlayerID  int `json:"player_id"`
+	Money     int `json:"money"`
+	Influence int `json:"influence"`
+}
+
+// TreasuryChanged - новый баланс казны фракции
+type TreasuryChanged struct {
+	FactionID int `json:"faction_id"`
+	Treasury  int `json:"treasury"`
+}
+
+// ItemMoved - предмет получен или передан
+type ItemMoved struct {
+	ItemID       int    `json:"item_id"`
+	FromPlayerID *int   `json:"from_player_id,omitempty"`
+	ToPlayerID   *int   `json:"to_player_id,omitempty"`
+	Source       string `json:"source"`
+}
+
+// ContractChanged - изменение статуса договора
+type ContractChanged struct {
+	ContractID int    `json:"contract_id"`
+	Status     string `json:"status"`
+	CustomerID int    `json:"customer_id"`
+	ExecutorID int    `json:"executor_id"`
+}
+
+// ListingChanged - лот рынка выставлен или закрыт (продан, отменён, истёк)
+type ListingChanged struct {
+	ListingID int    `json:"listing_id"`
+	ItemID    int    `json:"item_id"`
+	SellerID  int    `json:"seller_id"`
+	BuyerID   *int   `json:"buyer_id,omitempty"`
+	Price     int    `json:"price"`
+	Status    string `json:"status"`
+}
+
+// TradeChanged - предложение обмена создано или закрыто (принято, отклонено, отменено, истекло)
+type TradeChanged struct {
+	OfferID       int    `json:"offer_id"`
+	FromPlayerID  int    `json:"from_player_id"`
+	ToPlayerID    int    `json:"to_player_id"`
+	ParentOfferID *int   `json:"parent_offer_id,omitempty"`
+	Status        string `json:"status"`
+}
+
+// AuctionChanged - аукцион открыт, получил ставку или закрыт (продан, не продан, отменён).
+// LeaderID и Amount - лидер и текущая ставка, после продажи - победитель и цена
+type AuctionChanged struct {
+	AuctionID int    `json:"auction_id"`
+	ItemID    int    `json:"item_id"`
+	Status    string `json:"status"`
+	LeaderID  *int   `json:"leader_id,omitempty"`
+	Amount    *int   `json:"amount,omitempty"`
+}
+
+// DebtOverdue - долг просрочен, деньги списаны в пользу кредитора
+type DebtOverdue struct {
+	DebtID     int `json:"debt_id"`
+	LenderID   int `json:"lender_id"`
+	BorrowerID int `json:"borrower_id"`
+	Collected  int `json:"collected"`
+}
+
+// PenaltyApplied - игрок оштрафован на очки влияния
+type PenaltyApplied struct {
+	PlayerID      int    `json:"player_id"`
+	Influence     int    `json:"influence"`
+	ReferenceID   int    `json:"reference_id"`
+	ReferenceType string `json:"reference_type"`
+}
+
+// GoalUnlocked - личная цель игрока стала доступна
+type GoalUnlocked struct {
+	GoalID int    `json:"goal_id"`
+	Title  string `json:"title"`
+}
+
+// GameChanged - игра началась, закончилась, встала на паузу или возобновилась
+type GameChanged struct {
+	At time.Time `json:"at"`
+}
+
+// Publish отправляет событие через pg_notify. Если ex - транзакция,
+// PostgreSQL доставит событие только после её фиксации, а при откате отбросит
+func Publish(ex Execer, eventType string, playerIDs []int, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+
+	payload, err := json.Marshal(Event{
+		Type:      eventType,
+		PlayerIDs: playerIDs,
+		Data:      raw,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if len(payload) >= maxPayloadSize {
+		return fmt.Errorf("event %s payload is too large (%d bytes)", eventType, len(payload))
+	}
+
+	if _, err = ex.Exec(`SELECT pg_notify($1, $2)`, Channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish event %s: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// PublishBalances отправляет игрокам их текущий баланс
+func PublishBalances(q Querier, playerIDs ...int) error {
+	if len(playerIDs) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(`
+		SELECT id, money, influence
+		FROM players
+		WHERE id = ANY($1)
+	`, pq.Array(playerIDs))
+	if err != nil {
+		return fmt.Errorf("failed to fetch balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make([]BalanceChanged, 0, len(playerIDs))
+	for rows.Next() {
+		var b BalanceChanged
+		if err := rows.Scan(&b.PlayerID, &b.Money, &b.Influence); err != nil {
+			return fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range balances {
+		if err := Publish(q, TypeBalanceChanged, []int{b.PlayerID}, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishTreasury сообщает участникам фракции новый баланс казны
+func PublishTreasury(q Querier, factionID int) error {
+	rows, err := q.Query(`
+		SELECT f.treasury, p.id
+		FROM factions f
+		JOIN players p ON p.faction_id = f.id
+		WHERE f.id = $1
+	`, factionID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch treasury: %w", err)
+	}
+	defer rows.Close()
+
+	data := TreasuryChanged{FactionID: factionID}
+	memberIDs := make([]int, 0)
+	for rows.Next() {
+		var memberID int
+		if err := rows.Scan(&data.Treasury, &memberID); err != nil {
+			return fmt.Errorf("failed to scan treasury: %w", err)
+		}
+		memberIDs = append(memberIDs, memberID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Казну видят только участники: без участников сообщать некому
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	return Publish(q, TypeTreasuryChanged, memberIDs, data)
+}
+
+// PublishItemMoved сообщает получателю о новом предмете, а прежнему владельцу
+// (если он есть) - о том, что предмет ушёл
+func PublishItemMoved(ex Execer, itemID int, fromPlayerID, toPlayerID *int, source string) error {
+	data := ItemMoved{
+		ItemID:       itemID,
+		FromPlayerID: fromPlayerID,
+		ToPlayerID:   toPlayerID,
+		Source:       source,
+	}
+
+	if toPlayerID != nil {
+		if err := Publish(ex, TypeItemReceived, []int{*toPlayerID}, data); err != nil {
+			return err
+		}
+	}
+
+	if fromPlayerID != nil {
+		if err := Publish(ex, TypeItemTransferred, []int{*fromPlayerID}, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PublishGoalUnlocks отправляет события о целях, которые стали доступны
+// в текущей транзакции. Разблокировки создают триггеры на players.influence
+// и goals.is_completed, а unlocked_at у них равен времени начала транзакции
+func PublishGoalUnlocks(q Querier) error {
+	rows, err := q.Query(`
+		SELECT DISTINCT pvg.id, pvg.title, pvg.player_id
+		FROM goal_dependency_unlocks gdu
+		JOIN player_visible_goals pvg ON pvg.id = gdu.goal_id
+		WHERE gdu.unlocked_at = CURRENT_TIMESTAMP::timestamp
+		  AND pvg.player_id IS NOT NULL
+		  AND pvg.is_visible = true
+		  AND pvg.is_locked = false
+		  AND pvg.is_completed = false
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch unlocked goals: %w", err)
+	}
+	defer rows.Close()
+
+	type unlock struct {
+		playerID int
+		goal     GoalUnlocked
+	}
+
+	unlocks := make([]unlock, 0)
+	for rows.Next() {
+		var u unlock
+		if err := rows.Scan(&u.goal.GoalID, &u.goal.Title, &u.playerID); err != nil {
+			return fmt.Errorf("failed to scan unlocked goal: %w", err)
+		}
+		unlocks = append(unlocks, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, u := range unlocks {
+		if err := Publish(q, TypeGoalUnlocked, []int{u.playerID}, u.goal); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
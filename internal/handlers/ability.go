@@ -7,17 +7,25 @@ import (
 	"fmt"
 	"net/http"
 	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// pausedGameStartExpr - время начала игры, сдвинутое на все паузы (включая текущую, $1 - текущее время).
+// Задержка start_delay_minutes отсчитывается от него, то есть паузы в неё не входят
+const pausedGameStartExpr = `game_started_at
+	+ make_interval(secs => total_paused_seconds)
+	+ COALESCE($1::TIMESTAMP - paused_at, INTERVAL '0')`
+
 type AbilityHandler struct {
-	db *sql.DB
+	db    *sql.DB
+	store storage.Store
 }
 
-func NewAbilityHandler(db *sql.DB) *AbilityHandler {
-	return &AbilityHandler{db: db}
+func NewAbilityHandler(db *sql.DB, store storage.Store) *AbilityHandler {
+	return &AbilityHandler{db: db, store: store}
 }
 
 // GetPlayerAbilities возвращает все уникальные способности игрока
@@ -45,14 +53,14 @@ func (h *AbilityHandler) GetPlayerAbilities(c *gin.Context) {
 		return
 	}
 
-	// Получаем время начала игры для проверки start_delay
+	// Получаем время начала игры для проверки start_delay (сдвинутое на паузы)
 	var gameStartedAt *time.Time
 	err = h.db.QueryRow(`
-		SELECT game_started_at
+		SELECT `+pausedGameStartExpr+`
 		FROM game_timeline
 		ORDER BY id DESC
 		LIMIT 1
-	`).Scan(&gameStartedAt)
+	`, time.Now()).Scan(&gameStartedAt)
 
 	if err != nil && err != sql.ErrNoRows {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch game timeline"})
@@ -231,7 +239,7 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 	}
 
 	// Начинаем транзакцию
-	tx, err := h.db.Begin()
+	tx, err := h.store.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
@@ -239,49 +247,9 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 	defer tx.Rollback()
 
 	// Получаем информацию о способности
-	var ability models.Ability
-	var lastUsedAt *time.Time
-	err = tx.QueryRow(`
-		SELECT 
-			a.id,
-			a.player_id,
-			a.name,
-			a.ability_type,
-			a.cooldown_minutes,
-			a.start_delay_minutes,
-			a.required_influence_points,
-			a.is_unlocked,
-			a.influence_points_to_add,
-			a.influence_points_to_remove,
-			a.influence_points_to_self,
-			au.used_at
-		FROM abilities a
-		LEFT JOIN LATERAL (
-			SELECT used_at
-			FROM ability_usage
-			WHERE ability_id = a.id AND player_id = a.player_id
-			ORDER BY used_at DESC
-			LIMIT 1
-		) au ON true
-		WHERE a.id = $1 AND a.player_id = $2
-		FOR UPDATE OF a
-	`, abilityID, *playerID).Scan(
-		&ability.ID,
-		&ability.PlayerID,
-		&ability.Name,
-		&ability.AbilityType,
-		&ability.CooldownMinutes,
-		&ability.StartDelayMinutes,
-		&ability.RequiredInfluencePoints,
-		&ability.IsUnlocked,
-		&ability.InfluencePointsToAdd,
-		&ability.InfluencePointsToRemove,
-		&ability.InfluencePointsToSelf,
-		&lastUsedAt,
-	)
-
+	ability, lastUsedAt, err := tx.Abilities().GetForUpdate(abilityID, *playerID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Ability not found or does not belong to you"})
 			return
 		}
@@ -290,29 +258,22 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 	}
 
 	// Получаем текущее влияние и время начала игры для проверки доступности
-	var currentInfluence int
-	var gameStartedAt *time.Time
-
-	err = tx.QueryRow(`
-		SELECT p.influence, gt.game_started_at
-		FROM players p
-		LEFT JOIN LATERAL (
-			SELECT game_started_at
-			FROM game_timeline
-			ORDER BY id DESC
-			LIMIT 1
-		) gt ON true
-		WHERE p.id = $1
-	`, *playerID).Scan(&currentInfluence, &gameStartedAt)
+	player, err := tx.Players().Get(*playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch player data"})
+		return
+	}
 
+	gameStartedAt, err := tx.Game().StartedAt(time.Now())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch player data"})
 		return
 	}
+	currentInfluence := player.Influence
 
 	// Проверяем доступность способности
 	canUse, blockReason, _ := h.checkAbilityAvailability(
-		&ability,
+		ability,
 		currentInfluence,
 		gameStartedAt,
 		lastUsedAt,
@@ -330,6 +291,7 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 
 	// Выполняем способность в зависимости от типа
 	var response models.UseAbilityResponse
+	var affectedPlayers []int // игроки, у которых изменилось влияние
 
 	switch ability.AbilityType {
 	case "reveal_info":
@@ -366,6 +328,7 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 			return
 		}
 		response.Message = fmt.Sprintf("Successfully added %d influence points to target player", *ability.InfluencePointsToAdd)
+		affectedPlayers = []int{*req.TargetPlayerID}
 
 	case "transfer_influence":
 		if req.TargetPlayerID == nil {
@@ -383,12 +346,24 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 			return
 		}
 		response.Message = fmt.Sprintf("Successfully transferred influence: removed %d from target, added %d to yourself", *ability.InfluencePointsToRemove, *ability.InfluencePointsToSelf)
+		affectedPlayers = []int{*playerID, *req.TargetPlayerID}
 
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unknown ability type"})
 		return
 	}
 
+	if len(affectedPlayers) > 0 {
+		err = tx.Events().PublishBalances(affectedPlayers...)
+		if err == nil {
+			err = tx.Events().PublishGoalUnlocks()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+			return
+		}
+	}
+
 	// Фиксируем транзакцию
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -399,25 +374,20 @@ func (h *AbilityHandler) UseAbility(c *gin.Context) {
 }
 
 // executeRevealInfo выполняет способность раскрытия информации
-func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID int, infoCategory string, abilityID int) (int, *models.RevealedInfoData, error) {
+func (h *AbilityHandler) executeRevealInfo(tx storage.Tx, playerID, targetPlayerID int, infoCategory string, abilityID int) (int, *models.RevealedInfoData, error) {
 	// Проверяем, что целевой игрок существует
-	var targetExists bool
-	err := tx.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)
-	`, targetPlayerID).Scan(&targetExists)
-
-	if err != nil || !targetExists {
+	target, err := tx.Players().Get(targetPlayerID)
+	if err != nil {
 		return 0, nil, fmt.Errorf("Target player not found")
 	}
 
 	// Записываем использование способности
-	var usageID int
-	err = tx.QueryRow(`
-		INSERT INTO ability_usage (player_id, ability_id, target_player_id, info_category, used_at)
-		VALUES ($1, $2, $3, $4, NOW())
-		RETURNING id
-	`, playerID, abilityID, targetPlayerID, infoCategory).Scan(&usageID)
-
+	usageID, err := tx.Abilities().RecordUsage(storage.AbilityUsage{
+		PlayerID:       playerID,
+		AbilityID:      abilityID,
+		TargetPlayerID: targetPlayerID,
+		InfoCategory:   &infoCategory,
+	})
 	if err != nil {
 		return 0, nil, fmt.Errorf("Failed to record ability usage")
 	}
@@ -428,24 +398,11 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 
 	switch infoCategory {
 	case "faction":
-		var factionID *int
-		var factionName *string
-		err = tx.QueryRow(`
-			SELECT p.faction_id, f.name
-			FROM players p
-			LEFT JOIN factions f ON p.faction_id = f.id
-			WHERE p.id = $1
-		`, targetPlayerID).Scan(&factionID, &factionName)
-
-		if err != nil {
-			return 0, nil, fmt.Errorf("Failed to fetch faction info")
-		}
-
 		revealedData.InfoType = "faction"
-		if factionID != nil && factionName != nil {
+		if target.FactionID != nil && target.FactionName != nil {
 			revealedData.Data = map[string]interface{}{
-				"faction_id":   *factionID,
-				"faction_name": *factionName,
+				"faction_id":   *target.FactionID,
+				"faction_name": *target.FactionName,
 			}
 		} else {
 			revealedData.Data = map[string]interface{}{
@@ -456,19 +413,9 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 
 	case "goal":
 		// Выбираем случайную личную цель целевого игрока
-		var goalID int
-		var goalTitle string
-		var goalDescription *string
-		err = tx.QueryRow(`
-			SELECT id, title, description
-			FROM goals
-			WHERE player_id = $1 AND goal_type = 'personal'
-			ORDER BY RANDOM()
-			LIMIT 1
-		`, targetPlayerID).Scan(&goalID, &goalTitle, &goalDescription)
-
+		goal, err := tx.Goals().RandomPersonal(targetPlayerID)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == storage.ErrNotFound {
 				return 0, nil, fmt.Errorf("Target player has no personal goals")
 			}
 			return 0, nil, fmt.Errorf("Failed to fetch goal info")
@@ -476,27 +423,16 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 
 		revealedData.InfoType = "goal"
 		revealedData.Data = map[string]interface{}{
-			"goal_id":          goalID,
-			"goal_title":       goalTitle,
-			"goal_description": goalDescription,
+			"goal_id":          goal.ID,
+			"goal_title":       goal.Title,
+			"goal_description": goal.Description,
 		}
 
 	case "item":
 		// Выбираем случайный предмет целевого игрока
-		var itemID int
-		var itemName string
-		var itemDescription *string
-		err = tx.QueryRow(`
-			SELECT i.id, i.name, i.description
-			FROM player_items pi
-			JOIN items i ON pi.item_id = i.id
-			WHERE pi.player_id = $1
-			ORDER BY RANDOM()
-			LIMIT 1
-		`, targetPlayerID).Scan(&itemID, &itemName, &itemDescription)
-
+		item, err := tx.Items().RandomOwned(targetPlayerID)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == storage.ErrNotFound {
 				return 0, nil, fmt.Errorf("Target player has no items")
 			}
 			return 0, nil, fmt.Errorf("Failed to fetch item info")
@@ -504,9 +440,9 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 
 		revealedData.InfoType = "item"
 		revealedData.Data = map[string]interface{}{
-			"item_id":          itemID,
-			"item_name":        itemName,
-			"item_description": itemDescription,
+			"item_id":          item.ID,
+			"item_name":        item.Name,
+			"item_description": item.Description,
 		}
 
 	default:
@@ -520,11 +456,13 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 	}
 
 	// Сохраняем раскрытую информацию
-	_, err = tx.Exec(`
-		INSERT INTO revealed_info (revealer_player_id, target_player_id, info_type, revealed_data, ability_usage_id)
-		VALUES ($1, $2, $3, $4, $5)
-	`, playerID, targetPlayerID, infoCategory, revealedJSON, usageID)
-
+	err = tx.Abilities().SaveRevealedInfo(storage.RevealedInfo{
+		RevealerPlayerID: playerID,
+		TargetPlayerID:   targetPlayerID,
+		InfoType:         infoCategory,
+		Data:             revealedJSON,
+		AbilityUsageID:   usageID,
+	})
 	if err != nil {
 		return 0, nil, fmt.Errorf("Failed to save revealed info")
 	}
@@ -533,46 +471,37 @@ func (h *AbilityHandler) executeRevealInfo(tx *sql.Tx, playerID, targetPlayerID
 }
 
 // executeAddInfluence выполняет способность начисления влияния
-func (h *AbilityHandler) executeAddInfluence(tx *sql.Tx, playerID, targetPlayerID, points, abilityID int) (int, error) {
+func (h *AbilityHandler) executeAddInfluence(tx storage.Tx, playerID, targetPlayerID, points, abilityID int) (int, error) {
 	// Проверяем, что целевой игрок существует
-	var targetExists bool
-	err := tx.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)
-	`, targetPlayerID).Scan(&targetExists)
-
+	targetExists, err := tx.Players().Exists(targetPlayerID)
 	if err != nil || !targetExists {
 		return 0, fmt.Errorf("Target player not found")
 	}
 
 	// Записываем использование способности
-	var usageID int
-	err = tx.QueryRow(`
-		INSERT INTO ability_usage (player_id, ability_id, target_player_id, used_at)
-		VALUES ($1, $2, $3, NOW())
-		RETURNING id
-	`, playerID, abilityID, targetPlayerID).Scan(&usageID)
-
+	usageID, err := tx.Abilities().RecordUsage(storage.AbilityUsage{
+		PlayerID:       playerID,
+		AbilityID:      abilityID,
+		TargetPlayerID: targetPlayerID,
+	})
 	if err != nil {
 		return 0, fmt.Errorf("Failed to record ability usage")
 	}
 
 	// Начисляем влияние целевому игроку
-	_, err = tx.Exec(`
-		UPDATE players
-		SET influence = influence + $1
-		WHERE id = $2
-	`, points, targetPlayerID)
-
-	if err != nil {
+	if err = tx.Players().AddInfluence(targetPlayerID, points); err != nil {
 		return 0, fmt.Errorf("Failed to add influence")
 	}
 
 	// Записываем транзакцию влияния
-	_, err = tx.Exec(`
-		INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
-		VALUES ($1, $2, 'ability', $3, 'ability', $4)
-	`, targetPlayerID, points, abilityID, fmt.Sprintf("Received %d influence from ability", points))
-
+	err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+		PlayerID:        targetPlayerID,
+		Amount:          points,
+		TransactionType: "ability",
+		ReferenceID:     abilityID,
+		ReferenceType:   "ability",
+		Description:     fmt.Sprintf("Received %d influence from ability", points),
+	})
 	if err != nil {
 		return 0, fmt.Errorf("Failed to record influence transaction")
 	}
@@ -581,67 +510,53 @@ func (h *AbilityHandler) executeAddInfluence(tx *sql.Tx, playerID, targetPlayerI
 }
 
 // executeTransferInfluence выполняет способность переноса влияния
-func (h *AbilityHandler) executeTransferInfluence(tx *sql.Tx, playerID, targetPlayerID, pointsToRemove, pointsToSelf, abilityID int) (int, error) {
+func (h *AbilityHandler) executeTransferInfluence(tx storage.Tx, playerID, targetPlayerID, pointsToRemove, pointsToSelf, abilityID int) (int, error) {
 	// Проверяем, что целевой игрок существует
-	var targetExists bool
-	var targetInfluence int
-	err := tx.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1), 
-		       COALESCE((SELECT influence FROM players WHERE id = $1), 0)
-	`, targetPlayerID).Scan(&targetExists, &targetInfluence)
-
+	targetExists, err := tx.Players().Exists(targetPlayerID)
 	if err != nil || !targetExists {
 		return 0, fmt.Errorf("Target player not found")
 	}
 
 	// Записываем использование способности
-	var usageID int
-	err = tx.QueryRow(`
-		INSERT INTO ability_usage (player_id, ability_id, target_player_id, used_at)
-		VALUES ($1, $2, $3, NOW())
-		RETURNING id
-	`, playerID, abilityID, targetPlayerID).Scan(&usageID)
-
+	usageID, err := tx.Abilities().RecordUsage(storage.AbilityUsage{
+		PlayerID:       playerID,
+		AbilityID:      abilityID,
+		TargetPlayerID: targetPlayerID,
+	})
 	if err != nil {
 		return 0, fmt.Errorf("Failed to record ability usage")
 	}
 
-	// Снимаем влияние у целевого игрока
-	_, err = tx.Exec(`
-		UPDATE players
-		SET influence = GREATEST(0, influence - $1)
-		WHERE id = $2
-	`, pointsToRemove, targetPlayerID)
-
+	// Снимаем влияние у целевого игрока (не больше, чем у него есть)
+	actualRemoved, err := tx.Players().TakeInfluence(targetPlayerID, pointsToRemove)
 	if err != nil {
 		return 0, fmt.Errorf("Failed to remove influence from target")
 	}
 
 	// Начисляем влияние себе
-	_, err = tx.Exec(`
-		UPDATE players
-		SET influence = influence + $1
-		WHERE id = $2
-	`, pointsToSelf, playerID)
-
-	if err != nil {
+	if err = tx.Players().AddInfluence(playerID, pointsToSelf); err != nil {
 		return 0, fmt.Errorf("Failed to add influence to self")
 	}
 
 	// Записываем транзакции влияния
-	actualRemoved := pointsToRemove
-	if targetInfluence < pointsToRemove {
-		actualRemoved = targetInfluence
+	err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+		PlayerID:        targetPlayerID,
+		Amount:          -actualRemoved,
+		TransactionType: "ability",
+		ReferenceID:     abilityID,
+		ReferenceType:   "ability",
+		Description:     fmt.Sprintf("Lost %d influence from ability", actualRemoved),
+	})
+	if err == nil {
+		err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+			PlayerID:        playerID,
+			Amount:          pointsToSelf,
+			TransactionType: "ability",
+			ReferenceID:     abilityID,
+			ReferenceType:   "ability",
+			Description:     fmt.Sprintf("Gained %d influence from ability", pointsToSelf),
+		})
 	}
-
-	_, err = tx.Exec(`
-		INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
-		VALUES 
-			($1, $2, 'ability', $3, 'ability', $4),
-			($5, $6, 'ability', $3, 'ability', $7)
-	`, targetPlayerID, -actualRemoved, abilityID, fmt.Sprintf("Lost %d influence from ability", actualRemoved),
-		playerID, pointsToSelf, fmt.Sprintf("Gained %d influence from ability", pointsToSelf))
-
 	if err != nil {
 		return 0, fmt.Errorf("Failed to record influence transactions")
 	}
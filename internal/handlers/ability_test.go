@@ -0,0 +1,142 @@
+// internal/handlers/ability_test.go
+package handlers
+
+import (
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUseAbility(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		ability   models.Ability
+		lastUsed  time.Duration // сколько назад способность использовалась, 0 - не использовалась
+		body      models.UseAbilityRequest
+		status    int
+		err       string
+		influence map[int]int
+	}{
+		{
+			name: "add influence",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence", IsUnlocked: true,
+				InfluencePointsToAdd: intPtr(3)},
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusOK,
+			influence: map[int]int{1: 10, 2: 8},
+		},
+		{
+			name: "transfer influence takes no more than target has",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "transfer_influence", IsUnlocked: true,
+				InfluencePointsToRemove: intPtr(10), InfluencePointsToSelf: intPtr(4)},
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusOK,
+			influence: map[int]int{1: 14, 2: 0},
+		},
+		{
+			name: "not enough influence to unlock",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence",
+				RequiredInfluencePoints: intPtr(20), InfluencePointsToAdd: intPtr(3)},
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusForbidden,
+			err:       "Требуется больше очков влияния для разблокировки",
+			influence: map[int]int{1: 10, 2: 5},
+		},
+		{
+			name: "start delay",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence", IsUnlocked: true,
+				StartDelayMinutes: intPtr(180), InfluencePointsToAdd: intPtr(3)},
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusForbidden,
+			err:       "Способность станет доступна позже",
+			influence: map[int]int{1: 10, 2: 5},
+		},
+		{
+			name: "cooldown",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence", IsUnlocked: true,
+				CooldownMinutes: intPtr(60), InfluencePointsToAdd: intPtr(3)},
+			lastUsed:  10 * time.Minute,
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusForbidden,
+			err:       "Способность на перезарядке",
+			influence: map[int]int{1: 10, 2: 5},
+		},
+		{
+			name: "cooldown passed",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence", IsUnlocked: true,
+				CooldownMinutes: intPtr(60), InfluencePointsToAdd: intPtr(3)},
+			lastUsed:  90 * time.Minute,
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusOK,
+			influence: map[int]int{1: 10, 2: 8},
+		},
+		{
+			name: "ability of another player",
+			ability: models.Ability{ID: 1, PlayerID: 2, AbilityType: "add_influence", IsUnlocked: true,
+				InfluencePointsToAdd: intPtr(3)},
+			body:      models.UseAbilityRequest{TargetPlayerID: intPtr(2)},
+			status:    http.StatusNotFound,
+			err:       "Ability not found or does not belong to you",
+			influence: map[int]int{1: 10, 2: 5},
+		},
+		{
+			name: "missing target",
+			ability: models.Ability{ID: 1, PlayerID: 1, AbilityType: "add_influence", IsUnlocked: true,
+				InfluencePointsToAdd: intPtr(3)},
+			status:    http.StatusBadRequest,
+			err:       "target_player_id is required for add_influence",
+			influence: map[int]int{1: 10, 2: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			store.SetGame(now.Add(-2*time.Hour), nil, 0)
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Alice", Influence: 10})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Bob", Influence: 5})
+			store.AddAbility(tt.ability)
+			if tt.lastUsed > 0 {
+				store.AddAbilityUsage(tt.ability.PlayerID, tt.ability.ID, now.Add(-tt.lastUsed))
+			}
+
+			h := NewAbilityHandler(nil, store)
+			w := perform(t, h.UseAbility, 1, gin.Params{{Key: "id", Value: "1"}}, tt.body)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.status, w.Body.String())
+			}
+			if tt.err != "" {
+				if got := responseError(t, w); got != tt.err {
+					t.Errorf("error = %q, want %q", got, tt.err)
+				}
+			}
+
+			for playerID, want := range tt.influence {
+				player, _ := store.Player(playerID)
+				if player.Influence != want {
+					t.Errorf("player %d influence = %d, want %d", playerID, player.Influence, want)
+				}
+			}
+
+			// Неудачная попытка не должна запускать перезарядку
+			wantUsages := 0
+			if tt.lastUsed > 0 {
+				wantUsages++
+			}
+			if tt.status == http.StatusOK {
+				wantUsages++
+			}
+			if got := len(store.AbilityUsages()); got != wantUsages {
+				t.Errorf("recorded usages = %d, want %d", got, wantUsages)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/jobs"
 	"new-year-role-game-backend/internal/workers"
 	"time"
 
@@ -14,14 +16,24 @@ type AdminHandler struct {
 	db                *sql.DB
 	effectsScheduler  *workers.EffectsScheduler
 	contractScheduler *workers.ContractScheduler
+	debtScheduler     *workers.DebtScheduler
+	marketScheduler   *workers.MarketScheduler
+	tradeScheduler    *workers.TradeScheduler
+	auctionScheduler  *workers.AuctionScheduler
 }
 
 func NewAdminHandler(db *sql.DB, effectsScheduler *workers.EffectsScheduler,
-	contractScheduler *workers.ContractScheduler) *AdminHandler {
+	contractScheduler *workers.ContractScheduler, debtScheduler *workers.DebtScheduler,
+	marketScheduler *workers.MarketScheduler, tradeScheduler *workers.TradeScheduler,
+	auctionScheduler *workers.AuctionScheduler) *AdminHandler {
 	return &AdminHandler{
 		db:                db,
 		effectsScheduler:  effectsScheduler,
 		contractScheduler: contractScheduler,
+		debtScheduler:     debtScheduler,
+		marketScheduler:   marketScheduler,
+		tradeScheduler:    tradeScheduler,
+		auctionScheduler:  auctionScheduler,
 	}
 }
 
@@ -57,6 +69,12 @@ func (h *AdminHandler) StartGame(c *gin.Context) {
 
 	now := time.Now()
 
+	// Перед первым стартом запоминаем стартовое состояние для сброса игры
+	if err = ensureBaseline(tx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture baseline"})
+		return
+	}
+
 	// Создаем новую запись о начале игры
 	_, err = tx.Exec(`
 		INSERT INTO game_timeline (game_started_at)
@@ -88,6 +106,17 @@ func (h *AdminHandler) StartGame(c *gin.Context) {
 		return
 	}
 
+	// Первая точка графиков таблицы лидеров - состояние на старте
+	if err = workers.TakeLeaderboardSnapshot(tx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save leaderboard snapshot"})
+		return
+	}
+
+	if err = events.Publish(tx, events.TypeGameStarted, nil, events.GameChanged{At: now}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
@@ -104,6 +133,22 @@ func (h *AdminHandler) StartGame(c *gin.Context) {
 		schedulerErrors = append(schedulerErrors, "contracts: "+err.Error())
 	}
 
+	if err := h.debtScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "debts: "+err.Error())
+	}
+
+	if err := h.marketScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "market: "+err.Error())
+	}
+
+	if err := h.tradeScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "trades: "+err.Error())
+	}
+
+	if err := h.auctionScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "auctions: "+err.Error())
+	}
+
 	// Запускаем workers как fallback (подстраховка)
 	// if !h.effectsWorker.IsRunning() {
 	// 	go h.effectsWorker.Start()
@@ -119,6 +164,10 @@ func (h *AdminHandler) StartGame(c *gin.Context) {
 		"schedulers": gin.H{
 			"effects_scheduled":   h.effectsScheduler.GetScheduledCount(),
 			"contracts_scheduled": h.contractScheduler.GetScheduledCount(),
+			"debts_scheduled":     h.debtScheduler.GetScheduledCount(),
+			"listings_scheduled":  h.marketScheduler.GetScheduledCount(),
+			"trades_scheduled":    h.tradeScheduler.GetScheduledCount(),
+			"auctions_scheduled":  h.auctionScheduler.GetScheduledCount(),
 		},
 		// "workers": gin.H{
 		// 	"effects_running":   h.effectsWorker.IsRunning(),
@@ -168,10 +217,39 @@ func (h *AdminHandler) EndGame(c *gin.Context) {
 
 	now := time.Now()
 
-	// Завершаем игру
-	_, err = h.db.Exec(`
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Непроданные лоты снимаются с рынка, предметы возвращаются продавцам
+	if _, err = h.marketScheduler.CloseActive(tx, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close market listings"})
+		return
+	}
+
+	// Аукционы закрываются досрочно: предмет получает текущий лидер
+	if _, err = h.auctionScheduler.CloseActive(tx, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close auctions"})
+		return
+	}
+
+	// Последняя точка графиков таблицы лидеров - итог игры
+	if err = workers.TakeLeaderboardSnapshot(tx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save leaderboard snapshot"})
+		return
+	}
+
+	// Завершаем игру; незакрытая пауза засчитывается в total_paused_seconds
+	_, err = tx.Exec(`
 		UPDATE game_timeline
-		SET game_ended_at = $1
+		SET game_ended_at = $1,
+		    total_paused_seconds = total_paused_seconds +
+		        COALESCE(EXTRACT(EPOCH FROM ($1 - paused_at))::INTEGER, 0),
+		    paused_at = NULL
 		WHERE id = $2
 	`, now, gameID)
 
@@ -180,9 +258,23 @@ func (h *AdminHandler) EndGame(c *gin.Context) {
 		return
 	}
 
+	if err = events.Publish(tx, events.TypeGameEnded, nil, events.GameChanged{At: now}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
 	// Останавливаем все schedulers
 	h.effectsScheduler.Stop()
 	h.contractScheduler.Stop()
+	h.debtScheduler.Stop()
+	h.marketScheduler.Stop()
+	h.tradeScheduler.Stop()
+	h.auctionScheduler.Stop()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Game ended successfully",
@@ -192,12 +284,273 @@ func (h *AdminHandler) EndGame(c *gin.Context) {
 	})
 }
 
+// PauseGame ставит игру на паузу: таймеры договоров, долгов и эффектов останавливаются
+func (h *AdminHandler) PauseGame(c *gin.Context) {
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var gameID int
+	var gameStarted, gameEnded, pausedAt *time.Time
+	err = tx.QueryRow(`
+		SELECT id, game_started_at, game_ended_at, paused_at
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+		FOR UPDATE
+	`).Scan(&gameID, &gameStarted, &gameEnded, &pausedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err == sql.ErrNoRows || gameStarted == nil || gameEnded != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Game is not running"})
+		return
+	}
+
+	if pausedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Game is already paused"})
+		return
+	}
+
+	now := time.Now()
+
+	_, err = tx.Exec(`UPDATE game_timeline SET paused_at = $1 WHERE id = $2`, now, gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause game"})
+		return
+	}
+
+	if err = events.Publish(tx, events.TypeGamePaused, nil, events.GameChanged{At: now}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// Отменяем задачи очереди. Задачи, созданные во время паузы, schedulers пропускают
+	h.effectsScheduler.Stop()
+	h.contractScheduler.Stop()
+	h.debtScheduler.Stop()
+	h.marketScheduler.Stop()
+	h.tradeScheduler.Stop()
+	h.auctionScheduler.Stop()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Game paused successfully",
+		"paused_at": now,
+	})
+}
+
+// ResumeGame снимает игру с паузы. Сроки договоров и долгов и время последнего
+// выполнения эффектов сдвигаются на длительность паузы, после чего schedulers
+// заново ставят задачи в очередь
+func (h *AdminHandler) ResumeGame(c *gin.Context) {
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var gameID int
+	var gameEnded, pausedAt *time.Time
+	err = tx.QueryRow(`
+		SELECT id, game_ended_at, paused_at
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+		FOR UPDATE
+	`).Scan(&gameID, &gameEnded, &pausedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err == sql.ErrNoRows || gameEnded != nil || pausedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Game is not paused"})
+		return
+	}
+
+	now := time.Now()
+
+	// Срок сдвигается на время паузы, прошедшее после начала отсчёта:
+	// у договора, подписанного во время паузы, - только на остаток паузы
+	result, err := tx.Exec(`
+		UPDATE contracts
+		SET expires_at = expires_at + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, signed_at))
+		WHERE status = 'signed' AND expires_at IS NOT NULL
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift contract deadlines"})
+		return
+	}
+	contractsShifted, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		UPDATE debt_receipts
+		SET return_deadline = return_deadline + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, created_at))
+		WHERE is_returned = false AND penalty_applied = false
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift debt deadlines"})
+		return
+	}
+	debtsShifted, _ := result.RowsAffected()
+
+	// Следующее выполнение эффекта = last_executed_at + период, поэтому достаточно сдвинуть last_executed_at
+	result, err = tx.Exec(`
+		UPDATE item_effect_executions
+		SET last_executed_at = last_executed_at + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, last_executed_at))
+		WHERE last_executed_at IS NOT NULL
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift effect timers"})
+		return
+	}
+	effectsShifted, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		UPDATE market_listings
+		SET expires_at = expires_at + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, created_at))
+		WHERE status = 'active'
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift listing deadlines"})
+		return
+	}
+	listingsShifted, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		UPDATE trade_offers
+		SET expires_at = expires_at + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, created_at))
+		WHERE status = 'pending'
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift trade offer deadlines"})
+		return
+	}
+	tradesShifted, _ := result.RowsAffected()
+
+	result, err = tx.Exec(`
+		UPDATE auctions
+		SET closes_at = closes_at + ($1::TIMESTAMP - GREATEST($2::TIMESTAMP, created_at))
+		WHERE status = 'active'
+	`, now, *pausedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to shift auction deadlines"})
+		return
+	}
+	auctionsShifted, _ := result.RowsAffected()
+
+	_, err = tx.Exec(`
+		UPDATE game_timeline
+		SET total_paused_seconds = total_paused_seconds + EXTRACT(EPOCH FROM ($1 - paused_at))::INTEGER,
+		    paused_at = NULL
+		WHERE id = $2
+	`, now, gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume game"})
+		return
+	}
+
+	if err = events.Publish(tx, events.TypeGameResumed, nil, events.GameChanged{At: now}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// Задачи, поставленные во время паузы, хранят старое время - отменяем всё
+	// и восстанавливаем очередь по сдвинутым срокам
+	h.effectsScheduler.Stop()
+	h.contractScheduler.Stop()
+	h.debtScheduler.Stop()
+	h.marketScheduler.Stop()
+	h.tradeScheduler.Stop()
+	h.auctionScheduler.Stop()
+
+	var schedulerErrors []string
+
+	if err := h.effectsScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "effects: "+err.Error())
+	}
+
+	if err := h.contractScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "contracts: "+err.Error())
+	}
+
+	if err := h.debtScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "debts: "+err.Error())
+	}
+
+	if err := h.marketScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "market: "+err.Error())
+	}
+
+	if err := h.tradeScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "trades: "+err.Error())
+	}
+
+	if err := h.auctionScheduler.Start(); err != nil {
+		schedulerErrors = append(schedulerErrors, "auctions: "+err.Error())
+	}
+
+	pausedFor := now.Sub(*pausedAt)
+
+	response := gin.H{
+		"message":    "Game resumed successfully",
+		"resumed_at": now,
+		"paused_for": pausedFor.Round(time.Second).String(),
+		"shifted": gin.H{
+			"contracts": contractsShifted,
+			"debts":     debtsShifted,
+			"effects":   effectsShifted,
+			"listings":  listingsShifted,
+			"trades":    tradesShifted,
+			"auctions":  auctionsShifted,
+		},
+		"schedulers": gin.H{
+			"effects_scheduled":   h.effectsScheduler.GetScheduledCount(),
+			"contracts_scheduled": h.contractScheduler.GetScheduledCount(),
+			"debts_scheduled":     h.debtScheduler.GetScheduledCount(),
+			"listings_scheduled":  h.marketScheduler.GetScheduledCount(),
+			"trades_scheduled":    h.tradeScheduler.GetScheduledCount(),
+			"auctions_scheduled":  h.auctionScheduler.GetScheduledCount(),
+		},
+	}
+
+	if len(schedulerErrors) > 0 {
+		response["scheduler_warnings"] = schedulerErrors
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // GetGameStats возвращает детальную статистику игры
 func (h *AdminHandler) GetGameStats(c *gin.Context) {
 	stats := gin.H{
 		"schedulers": gin.H{
 			"effects_scheduled":   h.effectsScheduler.GetScheduledCount(),
 			"contracts_scheduled": h.contractScheduler.GetScheduledCount(),
+			"debts_scheduled":     h.debtScheduler.GetScheduledCount(),
+			"listings_scheduled":  h.marketScheduler.GetScheduledCount(),
+			"trades_scheduled":    h.tradeScheduler.GetScheduledCount(),
+			"auctions_scheduled":  h.auctionScheduler.GetScheduledCount(),
 		},
 		// "workers": gin.H{
 		// 	"effects_running":   h.effectsWorker.IsRunning(),
@@ -241,6 +594,31 @@ func (h *AdminHandler) GetGameStats(c *gin.Context) {
 		}
 	}
 
+	// Статистика по долговым распискам
+	var debtStats struct {
+		Active    int
+		Returned  int
+		Penalized int
+		Overdue   int // срок истёк, а штраф не начислен (должно быть 0 при работающем scheduler)
+	}
+
+	err = h.db.QueryRow(`
+		SELECT 
+			COUNT(*) FILTER (WHERE is_returned = false AND penalty_applied = false) as active,
+			COUNT(*) FILTER (WHERE is_returned = true) as returned,
+			COUNT(*) FILTER (WHERE penalty_applied = true) as penalized,
+			COUNT(*) FILTER (WHERE is_returned = false AND penalty_applied = false
+				AND return_deadline <= NOW()) as overdue
+		FROM debt_receipts
+	`).Scan(&debtStats.Active, &debtStats.Returned, &debtStats.Penalized, &debtStats.Overdue)
+
+	if err == nil {
+		stats["debts"] = debtStats
+		if debtStats.Overdue > 0 {
+			stats["debt_warning"] = "There are overdue debt receipts without penalty yet"
+		}
+	}
+
 	// Статистика по игрокам и предметам
 	var playerStats struct {
 		TotalPlayers     int
@@ -268,25 +646,31 @@ func (h *AdminHandler) GetGameStats(c *gin.Context) {
 
 	// Информация об игре
 	var gameInfo struct {
-		Status    string
-		StartedAt *time.Time
-		EndedAt   *time.Time
-		Duration  *string
+		Status        string
+		StartedAt     *time.Time
+		EndedAt       *time.Time
+		PausedAt      *time.Time
+		PausedSeconds int
+		Duration      *string
 	}
 
 	var gameStarted, gameEnded *time.Time
 	err = h.db.QueryRow(`
-		SELECT game_started_at, game_ended_at
+		SELECT game_started_at, game_ended_at, paused_at, total_paused_seconds
 		FROM game_timeline
 		ORDER BY id DESC
 		LIMIT 1
-	`).Scan(&gameStarted, &gameEnded)
+	`).Scan(&gameStarted, &gameEnded, &gameInfo.PausedAt, &gameInfo.PausedSeconds)
 
 	if err == nil {
 		gameInfo.StartedAt = gameStarted
 		gameInfo.EndedAt = gameEnded
 
-		if gameStarted != nil && gameEnded == nil {
+		if gameStarted != nil && gameEnded == nil && gameInfo.PausedAt != nil {
+			gameInfo.Status = "paused"
+			duration := time.Since(*gameStarted).String()
+			gameInfo.Duration = &duration
+		} else if gameStarted != nil && gameEnded == nil {
 			gameInfo.Status = "running"
 			duration := time.Since(*gameStarted).String()
 			gameInfo.Duration = &duration
@@ -303,3 +687,60 @@ func (h *AdminHandler) GetGameStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetSchedulers возвращает ожидающие таймеры эффектов, договоров и долгов, время
+// последнего срабатывания и последнюю ошибку, а также сверку очереди с игровыми таблицами.
+// ?type=effect|contract|debt|market|trade|auction - только один scheduler
+func (h *AdminHandler) GetSchedulers(c *gin.Context) {
+	filter := c.Query("type")
+	if filter != "" && filter != "effect" && filter != "contract" && filter != "debt" && filter != "market" &&
+		filter != "trade" && filter != "auction" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scheduler type"})
+		return
+	}
+
+	type inspector struct {
+		name    string
+		inspect func(gameRunning bool) (*jobs.Inspection, error)
+	}
+
+	inspectors := []inspector{
+		{"effect", h.effectsScheduler.Inspect},
+		{"contract", h.contractScheduler.Inspect},
+		{"debt", h.debtScheduler.Inspect},
+		{"market", h.marketScheduler.Inspect},
+		{"trade", h.tradeScheduler.Inspect},
+		{"auction", h.auctionScheduler.Inspect},
+	}
+
+	// Пока игра не идёт, в очереди не должно быть ни одной задачи
+	gameRunning, err := gameIsActive(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check game status"})
+		return
+	}
+
+	schedulers := make([]*jobs.Inspection, 0, len(inspectors))
+	inSync := true
+
+	for _, insp := range inspectors {
+		if filter != "" && filter != insp.name {
+			continue
+		}
+
+		inspection, err := insp.inspect(gameRunning)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inspect " + insp.name + " scheduler"})
+			return
+		}
+
+		inSync = inSync && inspection.Drift.InSync
+		schedulers = append(schedulers, inspection)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_running": gameRunning,
+		"in_sync":      inSync,
+		"schedulers":   schedulers,
+	})
+}
@@ -0,0 +1,164 @@
+// internal/handlers/admin_auction.go
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/auctions"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuctionHandler - аукционы мастера: создание, просмотр ставок и отмена
+type AdminAuctionHandler struct {
+	db       *sql.DB
+	store    storage.Store
+	auctions *auctions.Service
+}
+
+func NewAdminAuctionHandler(db *sql.DB, store storage.Store, service *auctions.Service) *AdminAuctionHandler {
+	return &AdminAuctionHandler{
+		db:       db,
+		store:    store,
+		auctions: service,
+	}
+}
+
+// GetAuctions возвращает все аукционы текущего прогона со всеми ставками
+func (h *AdminAuctionHandler) GetAuctions(c *gin.Context) {
+	result, err := fetchAuctions(h.db, 0, `TRUE`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auctions"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT b.auction_id, b.id, b.player_id, p.character_name, b.amount, b.status, b.created_at
+		FROM auction_bids b
+		JOIN players p ON p.id = b.player_id
+		ORDER BY b.id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bids"})
+		return
+	}
+	defer rows.Close()
+
+	bidsByAuction := make(map[int][]models.AuctionBid)
+	for rows.Next() {
+		var auctionID int
+		var bid models.AuctionBid
+		err := rows.Scan(&auctionID, &bid.ID, &bid.PlayerID, &bid.PlayerName, &bid.Amount, &bid.Status, &bid.CreatedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan bid"})
+			return
+		}
+		bidsByAuction[auctionID] = append(bidsByAuction[auctionID], bid)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	for i := range result {
+		result[i].Bids = make([]models.AuctionBid, 0)
+		if bids, ok := bidsByAuction[result[i].ID]; ok {
+			result[i].Bids = bids
+		}
+	}
+
+	c.JSON(http.StatusOK, models.AuctionsResponse{Auctions: result})
+}
+
+// CreateAuction открывает аукцион на предмет без владельца
+func (h *AdminAuctionHandler) CreateAuction(c *gin.Context) {
+	var req models.CreateAuctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	auction, err := h.auctions.Create(tx, req.ItemID, req.StartPrice, req.MinIncrement, req.ClosesAt, time.Now())
+	if err != nil {
+		switch err {
+		case auctions.ErrItemNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		case auctions.ErrItemUnavailable:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item belongs to a player or is already on sale"})
+		case auctions.ErrInvalidCloseTime:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "closes_at must be in the future"})
+		default:
+			log.Printf("Failed to create auction for item %d: %v", req.ItemID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create auction"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Auction created successfully",
+		"auction_id": auction.ID,
+		"item_id":    auction.ItemID,
+		"closes_at":  auction.ClosesAt,
+	})
+}
+
+// CancelAuction отменяет аукцион, лидер получает свою ставку обратно
+func (h *AdminAuctionHandler) CancelAuction(c *gin.Context) {
+	auctionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err = h.auctions.Cancel(tx, auctionID, time.Now()); err != nil {
+		switch err {
+		case auctions.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Auction not found"})
+		case auctions.ErrNotActive:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Auction is not active"})
+		default:
+			log.Printf("Failed to cancel auction #%d: %v", auctionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel auction"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Auction cancelled successfully",
+		"auction_id": auctionID,
+	})
+}
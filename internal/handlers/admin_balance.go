@@ -0,0 +1,364 @@
+// internal/handlers/admin_balance.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminBalanceHandler - ручные начисления и штрафы от мастеров игры.
+// Каждое изменение попадает в журналы транзакций с типом 'admin'
+type AdminBalanceHandler struct {
+	db *sql.DB
+}
+
+func NewAdminBalanceHandler(db *sql.DB) *AdminBalanceHandler {
+	return &AdminBalanceHandler{db: db}
+}
+
+// AdjustPlayerBalance начисляет или списывает деньги или влияние игрока
+func (h *AdminBalanceHandler) AdjustPlayerBalance(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	req, ok := bindAdjustBalanceRequest(c)
+	if !ok {
+		return
+	}
+
+	if req.Resource == models.ResourceFactionInfluence || req.Resource == models.ResourceFactionTreasury {
+		c.JSON(http.StatusBadRequest, gin.H{"error": req.Resource + " can only be adjusted for a faction"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	adjustment, status, msg := adjustPlayerBalance(tx, playerID, req, nil)
+	if status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	err = events.PublishBalances(tx, playerID)
+	if err == nil {
+		err = events.PublishGoalUnlocks(tx)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AdjustBalanceResponse{
+		Resource:    req.Resource,
+		Requested:   req.Amount,
+		Reason:      req.Reason,
+		Adjustments: []models.BalanceAdjustment{adjustment},
+	})
+}
+
+// AdjustFactionBalance изменяет собственное влияние фракции (faction_influence),
+// казну фракции (faction_treasury) или деньги/влияние каждого её участника на одну и ту же сумму
+func (h *AdminBalanceHandler) AdjustFactionBalance(c *gin.Context) {
+	factionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid faction ID"})
+		return
+	}
+
+	req, ok := bindAdjustBalanceRequest(c)
+	if !ok {
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Блокируем фракцию
+	var factionInfluence, treasury int
+	err = tx.QueryRow(`
+		SELECT COALESCE(faction_influence, 0), treasury FROM factions WHERE id = $1 FOR UPDATE
+	`, factionID).Scan(&factionInfluence, &treasury)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Faction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	adjustments := make([]models.BalanceAdjustment, 0)
+
+	if req.Resource == models.ResourceFactionInfluence {
+		err = tx.QueryRow(`
+			UPDATE factions
+			SET faction_influence = COALESCE(faction_influence, 0) + $1
+			WHERE id = $2
+			RETURNING faction_influence
+		`, req.Amount, factionID).Scan(&factionInfluence)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction influence"})
+			return
+		}
+
+		// У фракционного влияния нет игрока - фракция указывается в reference
+		_, err = tx.Exec(`
+			INSERT INTO influence_transactions (amount, transaction_type, reference_id, reference_type, description)
+			VALUES ($1, 'admin', $2, 'faction', $3)
+		`, req.Amount, factionID, adminReasonDescription(req.Reason))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record influence transaction"})
+			return
+		}
+
+		adjustments = append(adjustments, models.BalanceAdjustment{
+			FactionID: &factionID,
+			Amount:    req.Amount,
+			Balance:   factionInfluence,
+		})
+	} else if req.Resource == models.ResourceFactionTreasury {
+		// Казна, как и деньги игрока, не может уйти в минус (CHECK treasury >= 0)
+		amount := req.Amount
+		if treasury+amount < 0 {
+			if !req.Clamp {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Insufficient funds in faction treasury (has %d)", treasury)})
+				return
+			}
+			amount = -treasury
+		}
+
+		if amount != 0 {
+			_, err = tx.Exec(`UPDATE factions SET treasury = treasury + $1 WHERE id = $2`, amount, factionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction treasury"})
+				return
+			}
+
+			// Начисление пишется как входящий перевод в казну, штраф - как исходящий с отрицательной суммой
+			if amount > 0 {
+				_, err = tx.Exec(`
+					INSERT INTO money_transactions (to_faction_id, amount, transaction_type, reference_id, reference_type, description)
+					VALUES ($1, $2, 'admin', $1, 'faction', $3)
+				`, factionID, amount, adminReasonDescription(req.Reason))
+			} else {
+				_, err = tx.Exec(`
+					INSERT INTO money_transactions (from_faction_id, amount, transaction_type, reference_id, reference_type, description)
+					VALUES ($1, $2, 'admin', $1, 'faction', $3)
+				`, factionID, amount, adminReasonDescription(req.Reason))
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record money transaction"})
+				return
+			}
+
+			if err = events.PublishTreasury(tx, factionID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+				return
+			}
+		}
+
+		adjustments = append(adjustments, models.BalanceAdjustment{
+			FactionID: &factionID,
+			Amount:    amount,
+			Balance:   treasury + amount,
+		})
+	} else {
+		rows, err := tx.Query(`
+			SELECT id FROM players WHERE faction_id = $1 ORDER BY id
+		`, factionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch faction members"})
+			return
+		}
+
+		memberIDs := make([]int, 0)
+		for rows.Next() {
+			var memberID int
+			if err := rows.Scan(&memberID); err != nil {
+				rows.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan faction member"})
+				return
+			}
+			memberIDs = append(memberIDs, memberID)
+		}
+		rows.Close()
+
+		if err = rows.Err(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if len(memberIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Faction has no members"})
+			return
+		}
+
+		// Без clamp штраф применяется ко всем участникам или ни к кому
+		for _, memberID := range memberIDs {
+			adjustment, status, msg := adjustPlayerBalance(tx, memberID, req, &factionID)
+			if status != 0 {
+				c.JSON(status, gin.H{"error": msg})
+				return
+			}
+			adjustments = append(adjustments, adjustment)
+		}
+
+		err = events.PublishBalances(tx, memberIDs...)
+		if err == nil {
+			err = events.PublishGoalUnlocks(tx)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AdjustBalanceResponse{
+		Resource:    req.Resource,
+		Requested:   req.Amount,
+		Reason:      req.Reason,
+		Adjustments: adjustments,
+	})
+}
+
+// bindAdjustBalanceRequest разбирает тело запроса; при ошибке сам отвечает клиенту
+func bindAdjustBalanceRequest(c *gin.Context) (models.AdjustBalanceRequest, bool) {
+	var req models.AdjustBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return req, false
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason cannot be empty"})
+		return req, false
+	}
+
+	return req, true
+}
+
+// adjustPlayerBalance изменяет деньги или влияние игрока и пишет запись в журнал.
+// Деньги не могут уйти в минус (CHECK money >= 0): без clamp такой штраф отклоняется,
+// с clamp списывается всё, что есть. factionID задаётся для начислений всей фракции.
+// Возвращает HTTP-статус и текст ошибки (0 - успех)
+func adjustPlayerBalance(tx *sql.Tx, playerID int, req models.AdjustBalanceRequest, factionID *int) (models.BalanceAdjustment, int, string) {
+	adjustment := models.BalanceAdjustment{PlayerID: &playerID}
+
+	var money, influence int
+	err := tx.QueryRow(`
+		SELECT COALESCE(money, 0), COALESCE(influence, 0) FROM players WHERE id = $1 FOR UPDATE
+	`, playerID).Scan(&money, &influence)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return adjustment, http.StatusNotFound, "Player not found"
+		}
+		return adjustment, http.StatusInternalServerError, "Database error"
+	}
+
+	var referenceType *string
+	if factionID != nil {
+		faction := "faction"
+		referenceType = &faction
+	}
+	description := adminReasonDescription(req.Reason)
+
+	amount := req.Amount
+	switch req.Resource {
+	case models.ResourceMoney:
+		if money+amount < 0 {
+			if !req.Clamp {
+				return adjustment, http.StatusBadRequest, fmt.Sprintf("Insufficient funds for player %d (has %d)", playerID, money)
+			}
+			amount = -money
+		}
+
+		adjustment.Amount = amount
+		adjustment.Balance = money + amount
+		if amount == 0 {
+			return adjustment, 0, ""
+		}
+
+		_, err = tx.Exec(`UPDATE players SET money = money + $1 WHERE id = $2`, amount, playerID)
+		if err != nil {
+			return adjustment, http.StatusInternalServerError, "Failed to update player money"
+		}
+
+		// Начисление пишется как входящий перевод, штраф - как исходящий с отрицательной суммой
+		if amount > 0 {
+			_, err = tx.Exec(`
+				INSERT INTO money_transactions (to_player_id, amount, transaction_type, reference_id, reference_type, description)
+				VALUES ($1, $2, 'admin', $3, $4, $5)
+			`, playerID, amount, factionID, referenceType, description)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO money_transactions (from_player_id, amount, transaction_type, reference_id, reference_type, description)
+				VALUES ($1, $2, 'admin', $3, $4, $5)
+			`, playerID, amount, factionID, referenceType, description)
+		}
+		if err != nil {
+			return adjustment, http.StatusInternalServerError, "Failed to record money transaction"
+		}
+
+	case models.ResourceInfluence:
+		adjustment.Amount = amount
+		adjustment.Balance = influence + amount
+
+		_, err = tx.Exec(`UPDATE players SET influence = influence + $1 WHERE id = $2`, amount, playerID)
+		if err != nil {
+			return adjustment, http.StatusInternalServerError, "Failed to update player influence"
+		}
+
+		// ВАЖНО: После изменения influence срабатывает триггер unlock_goal_dependencies_on_influence_change
+		_, err = tx.Exec(`
+			INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
+			VALUES ($1, $2, 'admin', $3, $4, $5)
+		`, playerID, amount, factionID, referenceType, description)
+		if err != nil {
+			return adjustment, http.StatusInternalServerError, "Failed to record influence transaction"
+		}
+
+	default:
+		return adjustment, http.StatusBadRequest, "Unsupported resource"
+	}
+
+	return adjustment, 0, ""
+}
+
+// adminReasonDescription - описание записи журнала для ручного изменения
+func adminReasonDescription(reason string) string {
+	return "Game master: " + reason
+}
@@ -23,7 +23,7 @@ func (h *AdminContractHandler) GetContractSettings(c *gin.Context) {
 
 	// Получаем настройки Type 1
 	err := h.db.QueryRow(`
-		SELECT id, money_reward_customer, money_reward_executor, updated_at
+		SELECT id, money_reward_customer, money_reward_executor, money_reward_faction, updated_at
 		FROM contract_type1_reward_settings
 		ORDER BY id DESC
 		LIMIT 1
@@ -31,6 +31,7 @@ func (h *AdminContractHandler) GetContractSettings(c *gin.Context) {
 		&settings.Type1Rewards.ID,
 		&settings.Type1Rewards.MoneyRewardCustomer,
 		&settings.Type1Rewards.MoneyRewardExecutor,
+		&settings.Type1Rewards.MoneyRewardFaction,
 		&settings.Type1Rewards.UpdatedAt,
 	)
 
@@ -88,9 +89,10 @@ func (h *AdminContractHandler) UpdateContractType1Rewards(c *gin.Context) {
 		UPDATE contract_type1_reward_settings
 		SET money_reward_customer = $1,
 		    money_reward_executor = $2,
+		    money_reward_faction = COALESCE($3, money_reward_faction),
 		    updated_at = NOW()
 		WHERE id = (SELECT id FROM contract_type1_reward_settings ORDER BY id DESC LIMIT 1)
-	`, req.MoneyRewardCustomer, req.MoneyRewardExecutor)
+	`, req.MoneyRewardCustomer, req.MoneyRewardExecutor, req.MoneyRewardFaction)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update Type 1 rewards"})
@@ -100,7 +102,7 @@ func (h *AdminContractHandler) UpdateContractType1Rewards(c *gin.Context) {
 	// Возвращаем обновленные настройки
 	var settings models.ContractType1RewardSettings
 	err = h.db.QueryRow(`
-		SELECT id, money_reward_customer, money_reward_executor, updated_at
+		SELECT id, money_reward_customer, money_reward_executor, money_reward_faction, updated_at
 		FROM contract_type1_reward_settings
 		ORDER BY id DESC
 		LIMIT 1
@@ -108,6 +110,7 @@ func (h *AdminContractHandler) UpdateContractType1Rewards(c *gin.Context) {
 		&settings.ID,
 		&settings.MoneyRewardCustomer,
 		&settings.MoneyRewardExecutor,
+		&settings.MoneyRewardFaction,
 		&settings.UpdatedAt,
 	)
 
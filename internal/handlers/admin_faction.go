@@ -0,0 +1,362 @@
+// internal/handlers/admin_faction.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminFactionHandler struct {
+	db *sql.DB
+}
+
+func NewAdminFactionHandler(db *sql.DB) *AdminFactionHandler {
+	return &AdminFactionHandler{db: db}
+}
+
+// GetAllFactions возвращает все фракции без учёта видимости состава
+func (h *AdminFactionHandler) GetAllFactions(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT
+			id,
+			name,
+			description,
+			COALESCE(faction_influence, 0),
+			treasury,
+			COALESCE(is_composition_visible_to_all, false),
+			leader_player_id
+		FROM factions
+		ORDER BY name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch factions"})
+		return
+	}
+	defer rows.Close()
+
+	factions := make([]models.Faction, 0)
+	for rows.Next() {
+		var faction models.Faction
+		err := rows.Scan(
+			&faction.ID,
+			&faction.Name,
+			&faction.Description,
+			&faction.FactionInfluence,
+			&faction.Treasury,
+			&faction.IsCompositionVisibleToAll,
+			&faction.LeaderPlayerID,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan faction"})
+			return
+		}
+		factions = append(factions, faction)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AdminFactionsResponse{Factions: factions})
+}
+
+// CreateFaction создает фракцию; лидер, если задан, переводится в неё
+func (h *AdminFactionHandler) CreateFaction(c *gin.Context) {
+	var req models.CreateFactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if taken, err := factionNameTaken(tx, req.Name, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Faction with this name already exists"})
+		return
+	}
+
+	var factionID int
+	err = tx.QueryRow(`
+		INSERT INTO factions (name, description, is_composition_visible_to_all)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, req.Name, req.Description, req.IsCompositionVisibleToAll).Scan(&factionID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create faction"})
+		return
+	}
+
+	if req.LeaderPlayerID != nil {
+		if status, msg := setFactionLeader(tx, factionID, *req.LeaderPlayerID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	faction, err := h.getFaction(factionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created faction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, faction)
+}
+
+// UpdateFaction изменяет название, описание, видимость состава и лидера фракции
+func (h *AdminFactionHandler) UpdateFaction(c *gin.Context) {
+	factionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid faction ID"})
+		return
+	}
+
+	var req models.UpdateFactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Name == nil && req.Description == nil &&
+		req.IsCompositionVisibleToAll == nil && req.LeaderPlayerID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		req.Name = &trimmed
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Блокируем фракцию
+	var lockedID int
+	err = tx.QueryRow(`SELECT id FROM factions WHERE id = $1 FOR UPDATE`, factionID).Scan(&lockedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Faction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if req.Name != nil {
+		if taken, err := factionNameTaken(tx, *req.Name, factionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Faction with this name already exists"})
+			return
+		}
+	}
+
+	// COALESCE оставляет текущее значение для незаданных полей
+	_, err = tx.Exec(`
+		UPDATE factions
+		SET name = COALESCE($1, name),
+		    description = COALESCE($2, description),
+		    is_composition_visible_to_all = COALESCE($3, is_composition_visible_to_all)
+		WHERE id = $4
+	`, req.Name, req.Description, req.IsCompositionVisibleToAll, factionID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction"})
+		return
+	}
+
+	if req.LeaderPlayerID != nil {
+		if *req.LeaderPlayerID == 0 {
+			_, err = tx.Exec(`UPDATE factions SET leader_player_id = NULL WHERE id = $1`, factionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction leader"})
+				return
+			}
+		} else if status, msg := setFactionLeader(tx, factionID, *req.LeaderPlayerID); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	faction, err := h.getFaction(factionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated faction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, faction)
+}
+
+// DeleteFaction удаляет фракцию; её участники становятся нейтральными
+func (h *AdminFactionHandler) DeleteFaction(c *gin.Context) {
+	factionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid faction ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Явно выводим участников из фракции, чтобы вернуть их количество
+	result, err := tx.Exec(`UPDATE players SET faction_id = NULL WHERE faction_id = $1`, factionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction members"})
+		return
+	}
+
+	membersReleased, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	result, err = tx.Exec(`DELETE FROM factions WHERE id = $1`, factionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete faction"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Faction not found"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Faction deleted successfully",
+		"faction_id":       factionID,
+		"members_released": membersReleased,
+	})
+}
+
+func (h *AdminFactionHandler) getFaction(factionID int) (*models.Faction, error) {
+	var faction models.Faction
+	err := h.db.QueryRow(`
+		SELECT
+			id,
+			name,
+			description,
+			COALESCE(faction_influence, 0),
+			treasury,
+			COALESCE(is_composition_visible_to_all, false),
+			leader_player_id
+		FROM factions
+		WHERE id = $1
+	`, factionID).Scan(
+		&faction.ID,
+		&faction.Name,
+		&faction.Description,
+		&faction.FactionInfluence,
+		&faction.Treasury,
+		&faction.IsCompositionVisibleToAll,
+		&faction.LeaderPlayerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &faction, nil
+}
+
+// setFactionLeader назначает лидера фракции. Игрок из другой фракции переводится
+// в эту и перестаёт быть лидером прежней. Возвращает HTTP-статус и текст ошибки (0 - успех)
+func setFactionLeader(tx *sql.Tx, factionID, playerID int) (int, string) {
+	var currentFactionID *int
+	err := tx.QueryRow(`
+		SELECT faction_id FROM players WHERE id = $1 FOR UPDATE
+	`, playerID).Scan(&currentFactionID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return http.StatusNotFound, "Leader player not found"
+		}
+		return http.StatusInternalServerError, "Database error"
+	}
+
+	if currentFactionID == nil || *currentFactionID != factionID {
+		_, err = tx.Exec(`
+			UPDATE factions SET leader_player_id = NULL WHERE leader_player_id = $1
+		`, playerID)
+		if err != nil {
+			return http.StatusInternalServerError, "Failed to update faction leader"
+		}
+
+		_, err = tx.Exec(`UPDATE players SET faction_id = $1 WHERE id = $2`, factionID, playerID)
+		if err != nil {
+			return http.StatusInternalServerError, "Failed to move leader to faction"
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE factions SET leader_player_id = $1 WHERE id = $2`, playerID, factionID)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to update faction leader"
+	}
+
+	return 0, ""
+}
+
+// factionNameTaken проверяет, занято ли название другой фракцией
+func factionNameTaken(tx *sql.Tx, name string, exceptID int) (bool, error) {
+	var taken bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM factions WHERE name = $1 AND id <> $2)
+	`, name, exceptID).Scan(&taken)
+	return taken, err
+}
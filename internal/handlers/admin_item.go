@@ -0,0 +1,1024 @@
+// internal/handlers/admin_item.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/workers"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminItemHandler - каталог предметов и эффектов, выдача предметов игрокам.
+// Все изменения сразу отражаются в таймерах EffectsScheduler
+type AdminItemHandler struct {
+	db        *sql.DB
+	scheduler *workers.EffectsScheduler
+}
+
+func NewAdminItemHandler(db *sql.DB, scheduler *workers.EffectsScheduler) *AdminItemHandler {
+	return &AdminItemHandler{
+		db:        db,
+		scheduler: scheduler,
+	}
+}
+
+// GetItemsCatalog возвращает все предметы с эффектами и количеством владельцев
+func (h *AdminItemHandler) GetItemsCatalog(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT
+			i.id,
+			i.name,
+			i.description,
+			i.created_at,
+			(SELECT COUNT(*) FROM player_items pi WHERE pi.item_id = i.id)
+		FROM items i
+		ORDER BY i.id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch items"})
+		return
+	}
+	defer rows.Close()
+
+	items := make([]models.CatalogItem, 0)
+	for rows.Next() {
+		var item models.CatalogItem
+		err := rows.Scan(&item.ID, &item.Name, &item.Description, &item.CreatedAt, &item.HoldersCount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan item"})
+			return
+		}
+		item.Effects = make([]models.Effect, 0)
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	effectsByItem, err := h.getEffectsByItem()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item effects"})
+		return
+	}
+
+	for i := range items {
+		if effects, ok := effectsByItem[items[i].ID]; ok {
+			items[i].Effects = effects
+		}
+	}
+
+	c.JSON(http.StatusOK, models.ItemsCatalogResponse{Items: items})
+}
+
+// CreateItem создает предмет и, если переданы effect_ids, сразу привязывает эффекты
+func (h *AdminItemHandler) CreateItem(c *gin.Context) {
+	var req models.CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var item models.CatalogItem
+	err = tx.QueryRow(`
+		INSERT INTO items (name, description)
+		VALUES ($1, $2)
+		RETURNING id, name, description, created_at
+	`, req.Name, req.Description).Scan(&item.ID, &item.Name, &item.Description, &item.CreatedAt)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item"})
+		return
+	}
+
+	for _, effectID := range req.EffectIDs {
+		if ok, err := effectExists(tx, effectID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Effect %d not found", effectID)})
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO item_effects (item_id, effect_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, item.ID, effectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach effect"})
+			return
+		}
+	}
+
+	item.Effects, err = getItemEffectsTx(tx, item.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item effects"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// UpdateItem изменяет название и/или описание предмета
+func (h *AdminItemHandler) UpdateItem(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var req models.UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Name == nil && req.Description == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		req.Name = &trimmed
+	}
+
+	// COALESCE оставляет текущее значение для незаданных полей
+	var item models.CatalogItem
+	err = h.db.QueryRow(`
+		UPDATE items
+		SET name = COALESCE($1, name),
+		    description = COALESCE($2, description)
+		WHERE id = $3
+		RETURNING id, name, description, created_at
+	`, req.Name, req.Description, itemID).Scan(&item.ID, &item.Name, &item.Description, &item.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+// DeleteItem удаляет экземпляр предмета (и из инвентаря владельца)
+func (h *AdminItemHandler) DeleteItem(c *gin.Context) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Запоминаем владельцев, чтобы после удаления отменить их таймеры
+	holders, err := itemHolderIDs(tx, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item holders"})
+		return
+	}
+
+	result, err := tx.Exec(`DELETE FROM items WHERE id = $1`, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	for _, playerID := range holders {
+		holderID := playerID
+		if err = events.PublishItemMoved(tx, itemID, &holderID, nil, "admin"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	for _, playerID := range holders {
+		h.scheduler.CancelAllEffectsForItem(playerID, itemID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Item deleted successfully",
+		"item_id":         itemID,
+		"holders_removed": len(holders),
+	})
+}
+
+// GetEffects возвращает все эффекты каталога
+func (h *AdminItemHandler) GetEffects(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT
+			id,
+			description,
+			effect_type,
+			generated_resource,
+			operation,
+			value,
+			spawned_template_id,
+			period_seconds
+		FROM effects
+		ORDER BY id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch effects"})
+		return
+	}
+	defer rows.Close()
+
+	effects := make([]models.Effect, 0)
+	for rows.Next() {
+		var effect models.Effect
+		err := rows.Scan(
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan effect"})
+			return
+		}
+		effects = append(effects, effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.EffectsResponse{Effects: effects})
+}
+
+// CreateEffect создает эффект. К предмету он привязывается отдельным запросом
+func (h *AdminItemHandler) CreateEffect(c *gin.Context) {
+	var req models.CreateEffectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Те же правила, что и в CHECK таблицы effects
+	var generatedResource *string
+	switch req.EffectType {
+	case "generate_money", "generate_influence":
+		if req.Value == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Value is required for generate effects"})
+			return
+		}
+		if req.SpawnedTemplateID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Spawned template is only allowed for spawn_item effects"})
+			return
+		}
+		resource := strings.TrimPrefix(req.EffectType, "generate_")
+		generatedResource = &resource
+
+	case "spawn_item":
+		if req.SpawnedTemplateID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Spawned template is required for spawn_item effects"})
+			return
+		}
+
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effect type, expected generate_money, generate_influence or spawn_item"})
+		return
+	}
+
+	operation := "add"
+	if req.Operation != nil {
+		operation = *req.Operation
+	}
+	if operation != "add" && operation != "mul" && operation != "sub" && operation != "div" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid operation, expected add, mul, sub or div"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if req.SpawnedTemplateID != nil {
+		if ok, err := templateExists(tx, *req.SpawnedTemplateID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Spawned template not found"})
+			return
+		}
+	}
+
+	var effect models.Effect
+	err = tx.QueryRow(`
+		INSERT INTO effects (description, effect_type, generated_resource, operation, value, spawned_template_id, period_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, description, effect_type, generated_resource, operation, value, spawned_template_id, period_seconds
+	`, req.Description, req.EffectType, generatedResource, operation, req.Value,
+		req.SpawnedTemplateID, req.PeriodSeconds).Scan(
+		&effect.ID,
+		&effect.Description,
+		&effect.EffectType,
+		&effect.GeneratedResource,
+		&effect.Operation,
+		&effect.Value,
+		&effect.SpawnedTemplateID,
+		&effect.PeriodSeconds,
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create effect"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, effect)
+}
+
+// DeleteEffect удаляет эффект из каталога и отменяет его таймеры у всех игроков
+func (h *AdminItemHandler) DeleteEffect(c *gin.Context) {
+	effectID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effect ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	itemIDs, err := effectItemIDs(tx, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch effect items"})
+		return
+	}
+
+	var timersCancelled int64
+	for _, itemID := range itemIDs {
+		cancelled, err := h.scheduler.CancelEffectForHolders(tx, itemID, effectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel effect timers"})
+			return
+		}
+		timersCancelled += cancelled
+	}
+
+	// item_effects и item_effect_executions удаляются каскадно
+	result, err := tx.Exec(`DELETE FROM effects WHERE id = $1`, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete effect"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Effect not found"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Effect deleted successfully",
+		"effect_id":        effectID,
+		"timers_cancelled": timersCancelled,
+	})
+}
+
+// AttachEffect привязывает эффект к предмету. Если игра идёт, таймеры
+// сразу запускаются у всех владельцев предмета
+func (h *AdminItemHandler) AttachEffect(c *gin.Context) {
+	itemID, effectID, ok := parseItemEffectParams(c)
+	if !ok {
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if ok, err := itemExists(tx, itemID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	if ok, err := effectExists(tx, effectID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Effect not found"})
+		return
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO item_effects (item_id, effect_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, itemID, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach effect"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Effect is already attached to this item"})
+		return
+	}
+
+	active, err := gameIsActive(tx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// До начала игры таймеры не нужны - их запустит StartGame
+	timersStarted := 0
+	if active {
+		timersStarted, err = h.scheduler.InitializeEffectForHolders(tx, itemID, effectID, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize effect timers"})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Effect attached successfully",
+		"item_id":        itemID,
+		"effect_id":      effectID,
+		"timers_started": timersStarted,
+	})
+}
+
+// DetachEffect отвязывает эффект от предмета и отменяет его таймеры у владельцев
+func (h *AdminItemHandler) DetachEffect(c *gin.Context) {
+	itemID, effectID, ok := parseItemEffectParams(c)
+	if !ok {
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE FROM item_effects WHERE item_id = $1 AND effect_id = $2
+	`, itemID, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detach effect"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Effect is not attached to this item"})
+		return
+	}
+
+	timersCancelled, err := h.scheduler.CancelEffectForHolders(tx, itemID, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel effect timers"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM item_effect_executions WHERE item_id = $1 AND effect_id = $2
+	`, itemID, effectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clean up effect timers"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Effect detached successfully",
+		"item_id":          itemID,
+		"effect_id":        effectID,
+		"timers_cancelled": timersCancelled,
+	})
+}
+
+// GrantItem выдаёт предмет игроку (например, находку по ходу игры)
+func (h *AdminItemHandler) GrantItem(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	var req models.GrantItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if (req.ItemID == nil) == (req.TemplateID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of item_id and template_id is required"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if ok, err := playerExists(tx, playerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+
+	active, err := gameIsActive(tx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var itemID int
+	var itemName string
+	if req.TemplateID != nil {
+		if ok, err := templateExists(tx, *req.TemplateID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item template not found"})
+			return
+		}
+
+		// До начала игры таймеры не нужны - их запустит StartGame
+		if active {
+			itemID, itemName, err = h.scheduler.SpawnItem(tx, *req.TemplateID, playerID, time.Now())
+		} else {
+			itemID, itemName, err = workers.SpawnItemInstance(tx, *req.TemplateID, playerID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item"})
+			return
+		}
+	} else {
+		itemID = *req.ItemID
+
+		var ownerID *int
+		err = tx.QueryRow(`
+			SELECT i.name, pi.player_id
+			FROM items i
+			LEFT JOIN player_items pi ON pi.item_id = i.id
+			WHERE i.id = $1
+			FOR UPDATE OF i
+		`, itemID).Scan(&itemName, &ownerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item info"})
+			return
+		}
+
+		// Экземпляр может принадлежать только одному игроку
+		if ownerID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item already belongs to a player"})
+			return
+		}
+
+		// Предмет на рынке или аукционе без владельца, но уже обещан продавцу или победителю
+		var onSale bool
+		err = tx.QueryRow(`
+			SELECT EXISTS(SELECT 1 FROM market_listings WHERE item_id = $1 AND status = 'active')
+				OR EXISTS(SELECT 1 FROM auctions WHERE item_id = $1 AND status = 'active')
+		`, itemID).Scan(&onSale)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if onSale {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item is on the market or at an auction"})
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO player_items (player_id, item_id)
+			VALUES ($1, $2)
+		`, playerID, itemID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to inventory"})
+			return
+		}
+
+		if active {
+			if err = h.scheduler.InitializeItemEffects(tx, playerID, itemID, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize effect timers"})
+				return
+			}
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO item_transactions (to_player_id, item_id, transaction_type, description)
+		VALUES ($1, $2, 'admin_grant', $3)
+	`, playerID, itemID, "Granted by game master: "+itemName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		return
+	}
+
+	if err = events.PublishItemMoved(tx, itemID, nil, &playerID, "admin"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Item granted successfully",
+		"player_id": playerID,
+		"item_id":   itemID,
+	})
+}
+
+// RevokeItem забирает предмет у игрока и отменяет таймеры его эффектов
+func (h *AdminItemHandler) RevokeItem(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	itemID, err := strconv.Atoi(c.Param("item_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		DELETE FROM player_items WHERE player_id = $1 AND item_id = $2
+	`, playerID, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from inventory"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in player inventory"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM item_effect_executions WHERE player_id = $1 AND item_id = $2
+	`, playerID, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clean up effect timers"})
+		return
+	}
+
+	var itemName string
+	if err = tx.QueryRow(`SELECT name FROM items WHERE id = $1`, itemID).Scan(&itemName); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item info"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO item_transactions (from_player_id, item_id, transaction_type, description)
+		VALUES ($1, $2, 'admin_revoke', $3)
+	`, playerID, itemID, "Revoked by game master: "+itemName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		return
+	}
+
+	if err = events.PublishItemMoved(tx, itemID, &playerID, nil, "admin"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// ВАЖНО: отменяем таймеры эффектов предмета у бывшего владельца
+	h.scheduler.CancelAllEffectsForItem(playerID, itemID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Item revoked successfully",
+		"player_id": playerID,
+		"item_id":   itemID,
+	})
+}
+
+// getEffectsByItem возвращает эффекты всех предметов, сгруппированные по item_id
+func (h *AdminItemHandler) getEffectsByItem() (map[int][]models.Effect, error) {
+	rows, err := h.db.Query(`
+		SELECT
+			ie.item_id,
+			e.id,
+			e.description,
+			e.effect_type,
+			e.generated_resource,
+			e.operation,
+			e.value,
+			e.spawned_template_id,
+			e.period_seconds
+		FROM item_effects ie
+		JOIN effects e ON ie.effect_id = e.id
+		ORDER BY ie.item_id, e.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effectsByItem := make(map[int][]models.Effect)
+	for rows.Next() {
+		var itemID int
+		var effect models.Effect
+		err := rows.Scan(
+			&itemID,
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		effectsByItem[itemID] = append(effectsByItem[itemID], effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effectsByItem, nil
+}
+
+// getItemEffectsTx возвращает эффекты предмета в рамках транзакции
+func getItemEffectsTx(tx *sql.Tx, itemID int) ([]models.Effect, error) {
+	rows, err := tx.Query(`
+		SELECT
+			e.id,
+			e.description,
+			e.effect_type,
+			e.generated_resource,
+			e.operation,
+			e.value,
+			e.spawned_template_id,
+			e.period_seconds
+		FROM item_effects ie
+		JOIN effects e ON ie.effect_id = e.id
+		WHERE ie.item_id = $1
+		ORDER BY e.id
+	`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effects := make([]models.Effect, 0)
+	for rows.Next() {
+		var effect models.Effect
+		err := rows.Scan(
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		effects = append(effects, effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effects, nil
+}
+
+// parseItemEffectParams разбирает :id и :effect_id; при ошибке сам отвечает клиенту
+func parseItemEffectParams(c *gin.Context) (int, int, bool) {
+	itemID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return 0, 0, false
+	}
+
+	effectID, err := strconv.Atoi(c.Param("effect_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid effect ID"})
+		return 0, 0, false
+	}
+
+	return itemID, effectID, true
+}
+
+// itemHolderIDs возвращает игроков, у которых есть предмет
+func itemHolderIDs(tx *sql.Tx, itemID int) ([]int, error) {
+	rows, err := tx.Query(`SELECT player_id FROM player_items WHERE item_id = $1`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := make([]int, 0)
+	for rows.Next() {
+		var playerID int
+		if err := rows.Scan(&playerID); err != nil {
+			return nil, err
+		}
+		holders = append(holders, playerID)
+	}
+
+	return holders, rows.Err()
+}
+
+// effectItemIDs возвращает предметы, к которым привязан эффект
+func effectItemIDs(tx *sql.Tx, effectID int) ([]int, error) {
+	rows, err := tx.Query(`SELECT item_id FROM item_effects WHERE effect_id = $1`, effectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	itemIDs := make([]int, 0)
+	for rows.Next() {
+		var itemID int
+		if err := rows.Scan(&itemID); err != nil {
+			return nil, err
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+
+	return itemIDs, rows.Err()
+}
+
+// itemExists проверяет существование предмета
+func itemExists(tx *sql.Tx, itemID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM items WHERE id = $1)
+	`, itemID).Scan(&exists)
+	return exists, err
+}
+
+// effectExists проверяет существование эффекта
+func effectExists(tx *sql.Tx, effectID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM effects WHERE id = $1)
+	`, effectID).Scan(&exists)
+	return exists, err
+}
+
+// gameIsActive проверяет, идёт ли игра. На паузе таймеры не запускаются -
+// их восстановит ResumeGame
+func gameIsActive(tx queryRower) (bool, error) {
+	var gameStarted, gameEnded, pausedAt *time.Time
+	err := tx.QueryRow(`
+		SELECT game_started_at, game_ended_at, paused_at
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&gameStarted, &gameEnded, &pausedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return gameStarted != nil && gameEnded == nil && pausedAt == nil, nil
+}
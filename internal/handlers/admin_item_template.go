@@ -0,0 +1,323 @@
+// internal/handlers/admin_item_template.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetItemTemplates возвращает шаблоны предметов с эффектами и количеством экземпляров
+func (h *AdminItemHandler) GetItemTemplates(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT
+			t.id,
+			t.name,
+			t.description,
+			t.created_at,
+			(SELECT COUNT(*) FROM items i WHERE i.template_id = t.id)
+		FROM item_templates t
+		ORDER BY t.id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item templates"})
+		return
+	}
+	defer rows.Close()
+
+	templates := make([]models.ItemTemplate, 0)
+	for rows.Next() {
+		var template models.ItemTemplate
+		err := rows.Scan(&template.ID, &template.Name, &template.Description, &template.CreatedAt, &template.InstancesCount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan item template"})
+			return
+		}
+		template.Effects = make([]models.Effect, 0)
+		templates = append(templates, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	effectsByTemplate, err := h.getEffectsByTemplate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template effects"})
+		return
+	}
+
+	for i := range templates {
+		if effects, ok := effectsByTemplate[templates[i].ID]; ok {
+			templates[i].Effects = effects
+		}
+	}
+
+	c.JSON(http.StatusOK, models.ItemTemplatesResponse{Templates: templates})
+}
+
+// CreateItemTemplate создает шаблон предмета с набором эффектов.
+// Эффекты копируются в каждый новый экземпляр при его создании
+func (h *AdminItemHandler) CreateItemTemplate(c *gin.Context) {
+	var req models.CreateItemTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var template models.ItemTemplate
+	err = tx.QueryRow(`
+		INSERT INTO item_templates (name, description)
+		VALUES ($1, $2)
+		RETURNING id, name, description, created_at
+	`, req.Name, req.Description).Scan(&template.ID, &template.Name, &template.Description, &template.CreatedAt)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item template"})
+		return
+	}
+
+	for _, effectID := range req.EffectIDs {
+		if ok, err := effectExists(tx, effectID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Effect %d not found", effectID)})
+			return
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO item_template_effects (template_id, effect_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, template.ID, effectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach effect"})
+			return
+		}
+	}
+
+	template.Effects, err = getTemplateEffectsTx(tx, template.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template effects"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// DeleteItemTemplate удаляет шаблон. Созданные по нему экземпляры остаются у владельцев,
+// а эффекты порождения, ссылающиеся на шаблон, удаляются вместе с ним
+func (h *AdminItemHandler) DeleteItemTemplate(c *gin.Context) {
+	templateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Таймеры удаляемых эффектов порождения нужно отменить до каскадного удаления
+	rows, err := tx.Query(`SELECT id FROM effects WHERE spawned_template_id = $1`, templateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch template effects"})
+		return
+	}
+
+	spawnEffectIDs := make([]int, 0)
+	for rows.Next() {
+		var effectID int
+		if err := rows.Scan(&effectID); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		spawnEffectIDs = append(spawnEffectIDs, effectID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var cancelledJobs int64
+	for _, effectID := range spawnEffectIDs {
+		itemIDs, err := effectItemIDs(tx, effectID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		for _, itemID := range itemIDs {
+			cancelled, err := h.scheduler.CancelEffectForHolders(tx, itemID, effectID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel effect timers"})
+				return
+			}
+			cancelledJobs += cancelled
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM item_templates WHERE id = $1`, templateID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item template"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item template not found"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Item template deleted successfully",
+		"template_id":     templateID,
+		"effects_deleted": len(spawnEffectIDs),
+		"jobs_cancelled":  cancelledJobs,
+	})
+}
+
+// getEffectsByTemplate возвращает эффекты всех шаблонов, сгруппированные по template_id
+func (h *AdminItemHandler) getEffectsByTemplate() (map[int][]models.Effect, error) {
+	rows, err := h.db.Query(`
+		SELECT
+			te.template_id,
+			e.id,
+			e.description,
+			e.effect_type,
+			e.generated_resource,
+			e.operation,
+			e.value,
+			e.spawned_template_id,
+			e.period_seconds
+		FROM item_template_effects te
+		JOIN effects e ON te.effect_id = e.id
+		ORDER BY te.template_id, e.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effectsByTemplate := make(map[int][]models.Effect)
+	for rows.Next() {
+		var templateID int
+		var effect models.Effect
+		err := rows.Scan(
+			&templateID,
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		effectsByTemplate[templateID] = append(effectsByTemplate[templateID], effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effectsByTemplate, nil
+}
+
+// getTemplateEffectsTx возвращает эффекты шаблона в рамках транзакции
+func getTemplateEffectsTx(tx *sql.Tx, templateID int) ([]models.Effect, error) {
+	rows, err := tx.Query(`
+		SELECT
+			e.id,
+			e.description,
+			e.effect_type,
+			e.generated_resource,
+			e.operation,
+			e.value,
+			e.spawned_template_id,
+			e.period_seconds
+		FROM item_template_effects te
+		JOIN effects e ON te.effect_id = e.id
+		WHERE te.template_id = $1
+		ORDER BY e.id
+	`, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effects := make([]models.Effect, 0)
+	for rows.Next() {
+		var effect models.Effect
+		err := rows.Scan(
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		effects = append(effects, effect)
+	}
+
+	return effects, rows.Err()
+}
+
+// templateExists проверяет существование шаблона предмета
+func templateExists(tx *sql.Tx, templateID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM item_templates WHERE id = $1)
+	`, templateID).Scan(&exists)
+	return exists, err
+}
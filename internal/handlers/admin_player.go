@@ -0,0 +1,418 @@
+// internal/handlers/admin_player.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/auth"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminPlayerHandler struct {
+	db *sql.DB
+}
+
+func NewAdminPlayerHandler(db *sql.DB) *AdminPlayerHandler {
+	return &AdminPlayerHandler{db: db}
+}
+
+// GetAllPlayers возвращает всех игроков с балансами (без аватаров)
+func (h *AdminPlayerHandler) GetAllPlayers(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT
+			p.id,
+			p.character_name,
+			p.role,
+			p.character_story,
+			p.faction_id,
+			f.name,
+			COALESCE(p.can_change_faction, false),
+			COALESCE(p.money, 0),
+			COALESCE(p.influence, 0)
+		FROM players p
+		LEFT JOIN factions f ON f.id = p.faction_id
+		ORDER BY p.id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch players"})
+		return
+	}
+	defer rows.Close()
+
+	players := make([]models.AdminPlayer, 0)
+	for rows.Next() {
+		var player models.AdminPlayer
+		err := rows.Scan(
+			&player.ID,
+			&player.CharacterName,
+			&player.Role,
+			&player.CharacterStory,
+			&player.FactionID,
+			&player.FactionName,
+			&player.CanChangeFaction,
+			&player.Money,
+			&player.Influence,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan player"})
+			return
+		}
+		players = append(players, player)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AdminPlayersResponse{Players: players})
+}
+
+// GetPlayer возвращает полную карточку игрока
+func (h *AdminPlayerHandler) GetPlayer(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	player, err := h.getPlayer(playerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch player"})
+		return
+	}
+
+	c.JSON(http.StatusOK, player)
+}
+
+// CreatePlayer создает персонажа со стартовыми деньгами и влиянием
+func (h *AdminPlayerHandler) CreatePlayer(c *gin.Context) {
+	var req models.CreatePlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.CharacterName = strings.TrimSpace(req.CharacterName)
+	if req.CharacterName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Character name cannot be empty"})
+		return
+	}
+
+	req.Role = strings.TrimSpace(req.Role)
+	if req.Role == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role cannot be empty"})
+		return
+	}
+
+	if req.Money < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Money cannot be negative"})
+		return
+	}
+
+	if err := auth.ValidatePasswordLength(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if req.Avatar != nil && *req.Avatar == "" {
+		req.Avatar = nil
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if req.FactionID != nil {
+		if ok, err := factionExists(tx, *req.FactionID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Faction not found"})
+			return
+		}
+	}
+
+	var playerID int
+	err = tx.QueryRow(`
+		INSERT INTO players (
+			character_name, password, character_story, role,
+			money, influence, faction_id, can_change_faction, avatar
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`, req.CharacterName, hash, req.CharacterStory, req.Role,
+		req.Money, req.Influence, req.FactionID, req.CanChangeFaction, req.Avatar).Scan(&playerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create player"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	player, err := h.getPlayer(playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created player"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, player)
+}
+
+// UpdatePlayer изменяет анкету игрока, фракцию и право её смены
+func (h *AdminPlayerHandler) UpdatePlayer(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	var req models.UpdatePlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.CharacterName == nil && req.Role == nil && req.CharacterStory == nil &&
+		req.FactionID == nil && req.CanChangeFaction == nil && req.Avatar == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	if req.CharacterName != nil {
+		trimmed := strings.TrimSpace(*req.CharacterName)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Character name cannot be empty"})
+			return
+		}
+		req.CharacterName = &trimmed
+	}
+
+	if req.Role != nil {
+		trimmed := strings.TrimSpace(*req.Role)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role cannot be empty"})
+			return
+		}
+		req.Role = &trimmed
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Блокируем игрока
+	var currentFactionID *int
+	err = tx.QueryRow(`
+		SELECT faction_id FROM players WHERE id = $1 FOR UPDATE
+	`, playerID).Scan(&currentFactionID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	newFactionID := currentFactionID
+	if req.FactionID != nil {
+		if *req.FactionID == 0 {
+			newFactionID = nil
+		} else {
+			if ok, err := factionExists(tx, *req.FactionID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			} else if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Faction not found"})
+				return
+			}
+			newFactionID = req.FactionID
+		}
+	}
+
+	// ВАЖНО: игрок, ушедший из фракции, перестаёт быть её лидером
+	if currentFactionID != nil && (newFactionID == nil || *newFactionID != *currentFactionID) {
+		_, err = tx.Exec(`
+			UPDATE factions SET leader_player_id = NULL
+			WHERE id = $1 AND leader_player_id = $2
+		`, *currentFactionID, playerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction leader"})
+			return
+		}
+	}
+
+	// COALESCE оставляет текущее значение для незаданных полей, пустой аватар удаляет его
+	_, err = tx.Exec(`
+		UPDATE players
+		SET character_name = COALESCE($1, character_name),
+		    role = COALESCE($2, role),
+		    character_story = COALESCE($3, character_story),
+		    faction_id = $4,
+		    can_change_faction = COALESCE($5, can_change_faction),
+		    avatar = CASE WHEN $6::TEXT IS NULL THEN avatar ELSE NULLIF($6, '') END
+		WHERE id = $7
+	`, req.CharacterName, req.Role, req.CharacterStory, newFactionID,
+		req.CanChangeFaction, req.Avatar, playerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update player"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	player, err := h.getPlayer(playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated player"})
+		return
+	}
+
+	c.JSON(http.StatusOK, player)
+}
+
+// DeletePlayer удаляет персонажа вместе с его предметами, целями, договорами и долгами.
+// Учётные записи игрока остаются, но отвязываются от персонажа и теряют сессии
+func (h *AdminPlayerHandler) DeletePlayer(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var lockedID int
+	err = tx.QueryRow(`SELECT id FROM players WHERE id = $1 FOR UPDATE`, playerID).Scan(&lockedID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// В токенах остался player_id - отзываем сессии до того, как отвяжем учётные записи
+	sessionsRevoked, err := revokePlayerSessions(tx, playerID, "player_deleted")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	result, err := tx.Exec(`UPDATE users SET player_id = NULL WHERE player_id = $1`, playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink users"})
+		return
+	}
+
+	usersUnlinked, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Остальное удаляется каскадно, журналы сохраняются с player_id = NULL.
+	// Задачи schedulers по удалённым предметам, договорам и долгам завершатся сами
+	if _, err = tx.Exec(`DELETE FROM players WHERE id = $1`, playerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete player"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Player deleted successfully",
+		"player_id":        playerID,
+		"users_unlinked":   usersUnlinked,
+		"sessions_revoked": sessionsRevoked,
+	})
+}
+
+// getPlayer возвращает карточку игрока вместе с аватаром
+func (h *AdminPlayerHandler) getPlayer(playerID int) (*models.AdminPlayer, error) {
+	var player models.AdminPlayer
+	err := h.db.QueryRow(`
+		SELECT
+			p.id,
+			p.character_name,
+			p.role,
+			p.character_story,
+			p.faction_id,
+			f.name,
+			COALESCE(p.can_change_faction, false),
+			COALESCE(p.money, 0),
+			COALESCE(p.influence, 0),
+			p.avatar
+		FROM players p
+		LEFT JOIN factions f ON f.id = p.faction_id
+		WHERE p.id = $1
+	`, playerID).Scan(
+		&player.ID,
+		&player.CharacterName,
+		&player.Role,
+		&player.CharacterStory,
+		&player.FactionID,
+		&player.FactionName,
+		&player.CanChangeFaction,
+		&player.Money,
+		&player.Influence,
+		&player.Avatar,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+// factionExists проверяет существование фракции
+func factionExists(tx *sql.Tx, factionID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM factions WHERE id = $1)
+	`, factionID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,139 @@
+// internal/handlers/admin_race.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRaceHandler struct {
+	db *sql.DB
+}
+
+func NewAdminRaceHandler(db *sql.DB) *AdminRaceHandler {
+	return &AdminRaceHandler{db: db}
+}
+
+// GetRaceRounds возвращает все раунды гонок (опционально - одного триггера)
+func (h *AdminRaceHandler) GetRaceRounds(c *gin.Context) {
+	var triggerID *int
+	if triggerIDParam := c.Query("trigger_id"); triggerIDParam != "" {
+		id, err := strconv.Atoi(triggerIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trigger ID"})
+			return
+		}
+		triggerID = &id
+	}
+
+	rounds, err := fetchRaceRounds(h.db, triggerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch race rounds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RaceRoundsResponse{Rounds: rounds})
+}
+
+// StartRaceRound принудительно открывает следующий раунд гонки,
+// не дожидаясь выполнения задач участниками
+func (h *AdminRaceHandler) StartRaceRound(c *gin.Context) {
+	triggerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trigger ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	roundID, err := openNextRaceRound(tx, triggerID, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, errRaceTriggerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Race trigger not found"})
+		case errors.Is(err, errRaceTriggerInactive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Race trigger is not active"})
+		case errors.Is(err, errRaceRoundInProgress):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Race round is already active"})
+		case errors.Is(err, errRaceNoMoreRounds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "No predefined goals for the next round"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start race round"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Race round started",
+		"round_id": roundID,
+	})
+}
+
+// CancelRaceRound отменяет ожидающий или активный раунд гонки
+func (h *AdminRaceHandler) CancelRaceRound(c *gin.Context) {
+	roundID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid round ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM goal_race_rounds WHERE id = $1 FOR UPDATE
+	`, roundID).Scan(&status)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Race round not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if status != "pending" && status != "active" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Race round is already " + status})
+		return
+	}
+
+	if err = cancelRaceRound(tx, roundID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel race round"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Race round cancelled",
+		"round_id": roundID,
+	})
+}
@@ -0,0 +1,47 @@
+// internal/handlers/admin_report.go
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/report"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminReportHandler struct {
+	db *sql.DB
+}
+
+func NewAdminReportHandler(db *sql.DB) *AdminReportHandler {
+	return &AdminReportHandler{db: db}
+}
+
+// GetReport возвращает итоги текущего прогона: format=json (по умолчанию) или format=html.
+// После сброса игры данные прогона уходят в архив, поэтому отчёт нужно получить до сброса
+func (h *AdminReportHandler) GetReport(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, expected json or html"})
+		return
+	}
+
+	r, err := report.Build(h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build report"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, r)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := r.WriteHTML(&page); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render report"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,606 @@
+// internal/handlers/admin_run.go
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/workers"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultArchiveLimit = 100
+	maxArchiveLimit     = 1000
+)
+
+// archivedTable - таблица, строки которой сохраняются в game_run_archive или game_baseline.
+// row - выражение над строкой t (по умолчанию to_jsonb(t)), order - порядок строк в архиве (по умолчанию t.id)
+type archivedTable struct {
+	name  string
+	row   string
+	order string
+}
+
+func (t archivedTable) rowExpr() string {
+	if t.row == "" {
+		return "to_jsonb(t)"
+	}
+	return t.row
+}
+
+func (t archivedTable) orderExpr() string {
+	if t.order == "" {
+		return "t.id"
+	}
+	return t.order
+}
+
+// runTables - данные одного прогона. При сбросе архивируются и очищаются.
+// Порядок не важен: очистка идёт одним TRUNCATE
+var runTables = []archivedTable{
+	{name: "game_timeline"},
+	{name: "money_transactions"},
+	{name: "item_transactions"},
+	{name: "influence_transactions"},
+	{name: "contracts"},
+	{name: "contract_penalties"},
+	{name: "debt_receipts"},
+	{name: "market_listings"},
+	{name: "trade_offers"},
+	{name: "trade_offer_items", order: "t.offer_id, t.item_id"},
+	{name: "auctions"},
+	{name: "auction_bids"},
+	{name: "goal_completion_history"},
+	{name: "goal_dependency_unlocks"},
+	{name: "ability_usage"},
+	{name: "revealed_info"},
+	{name: "task_completion_history"},
+	{name: "goal_race_rounds"},
+	{name: "goal_race_round_participants"},
+	{name: "goal_race_round_goals"},
+	{name: "item_effect_executions"},
+	{name: "leaderboard_snapshots"},
+	{name: "player_standings_history", order: "t.snapshot_id, t.player_id"},
+	{name: "faction_standings_history", order: "t.snapshot_id, t.faction_id"},
+}
+
+// stateTables - итоговое состояние сценария. При сбросе сохраняется в архив
+// как снимок и возвращается к стартовому состоянию из game_baseline
+var stateTables = []archivedTable{
+	{name: "players", row: "to_jsonb(t) - 'password' - 'avatar'"},
+	{name: "factions"},
+	{name: "items"},
+	{name: "item_effects", order: "t.item_id, t.effect_id"},
+	{name: "player_items"},
+	{name: "goals"},
+	{name: "abilities"},
+	{name: "tasks"},
+	{name: "goal_race_triggers"},
+}
+
+// baselineTables - стартовое состояние. Разблокировки целей, которые действуют
+// с самого начала игры, тоже относятся к нему
+var baselineTables = append(append([]archivedTable{}, stateTables...),
+	archivedTable{name: "goal_dependency_unlocks"})
+
+// AdminRunHandler - архив прогонов и сброс игры к стартовому состоянию
+type AdminRunHandler struct {
+	db                *sql.DB
+	effectsScheduler  *workers.EffectsScheduler
+	contractScheduler *workers.ContractScheduler
+	debtScheduler     *workers.DebtScheduler
+	marketScheduler   *workers.MarketScheduler
+	tradeScheduler    *workers.TradeScheduler
+	auctionScheduler  *workers.AuctionScheduler
+}
+
+func NewAdminRunHandler(db *sql.DB, effectsScheduler *workers.EffectsScheduler,
+	contractScheduler *workers.ContractScheduler, debtScheduler *workers.DebtScheduler,
+	marketScheduler *workers.MarketScheduler, tradeScheduler *workers.TradeScheduler,
+	auctionScheduler *workers.AuctionScheduler) *AdminRunHandler {
+	return &AdminRunHandler{
+		db:                db,
+		effectsScheduler:  effectsScheduler,
+		contractScheduler: contractScheduler,
+		debtScheduler:     debtScheduler,
+		marketScheduler:   marketScheduler,
+		tradeScheduler:    tradeScheduler,
+		auctionScheduler:  auctionScheduler,
+	}
+}
+
+// CaptureBaseline сохраняет текущее состояние как стартовое.
+// Нужно после правки сценария между прогонами; перед первым стартом игры снимок делается сам
+func (h *AdminRunHandler) CaptureBaseline(c *gin.Context) {
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if started, err := gameStarted(tx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if started {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Baseline can only be captured before the game starts"})
+		return
+	}
+
+	counts, err := captureBaseline(tx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to capture baseline"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Baseline captured successfully",
+		"tables":  counts,
+	})
+}
+
+// ResetGame архивирует текущий прогон под новым run_id, очищает его данные
+// и возвращает игроков, предметы, цели и способности к стартовому состоянию
+func (h *AdminRunHandler) ResetGame(c *gin.Context) {
+	var req models.ResetGameRequest
+	// Тело необязательное
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var startedAt, endedAt *time.Time
+	var pausedSeconds int
+	err = tx.QueryRow(`
+		SELECT game_started_at, game_ended_at, total_paused_seconds
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+		FOR UPDATE
+	`).Scan(&startedAt, &endedAt, &pausedSeconds)
+
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if startedAt != nil && endedAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End the game before resetting it"})
+		return
+	}
+
+	var hasBaseline bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM game_baseline)`).Scan(&hasBaseline)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !hasBaseline {
+		c.JSON(http.StatusConflict, gin.H{"error": "Starting state is not captured, use POST /api/admin/game/baseline"})
+		return
+	}
+
+	var run models.GameRun
+	err = tx.QueryRow(`
+		INSERT INTO game_runs (started_at, ended_at, paused_seconds, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, started_at, ended_at, paused_seconds, note, archived_at
+	`, startedAt, endedAt, pausedSeconds, req.Note).Scan(
+		&run.ID,
+		&run.StartedAt,
+		&run.EndedAt,
+		&run.PausedSeconds,
+		&run.Note,
+		&run.ArchivedAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create game run"})
+		return
+	}
+
+	run.Tables = make(map[string]int)
+	for _, table := range append(append([]archivedTable{}, runTables...), stateTables...) {
+		result, err := tx.Exec(`
+			INSERT INTO game_run_archive (run_id, table_name, row_data)
+			SELECT $1, $2, `+table.rowExpr()+`
+			FROM `+table.name+` t
+			ORDER BY `+table.orderExpr()+`
+		`, run.ID, table.name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive " + table.name})
+			return
+		}
+
+		archived, err := result.RowsAffected()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		run.Tables[table.name] = int(archived)
+	}
+
+	names := make([]string, 0, len(runTables))
+	for _, table := range runTables {
+		names = append(names, table.name)
+	}
+
+	// ВАЖНО: TRUNCATE очищает все таблицы прогона разом, поэтому внешние ключи между ними не мешают.
+	// Счётчики id не сбрасываются, чтобы id в архиве не пересекались между прогонами
+	if _, err = tx.Exec(`TRUNCATE ` + strings.Join(names, ", ")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear game run data"})
+		return
+	}
+
+	if err = restoreBaseline(tx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore starting state"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	// Таймеры прошлого прогона больше не нужны
+	h.effectsScheduler.Stop()
+	h.contractScheduler.Stop()
+	h.debtScheduler.Stop()
+	h.marketScheduler.Stop()
+	h.tradeScheduler.Stop()
+	h.auctionScheduler.Stop()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Game reset successfully",
+		"run":     run,
+	})
+}
+
+// GetGameRuns возвращает архивированные прогоны с количеством строк по таблицам
+func (h *AdminRunHandler) GetGameRuns(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT id, started_at, ended_at, paused_seconds, note, archived_at
+		FROM game_runs
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch game runs"})
+		return
+	}
+	defer rows.Close()
+
+	runs := make([]models.GameRun, 0)
+	runIndex := make(map[int]int)
+	for rows.Next() {
+		var run models.GameRun
+		err := rows.Scan(&run.ID, &run.StartedAt, &run.EndedAt, &run.PausedSeconds, &run.Note, &run.ArchivedAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan game run"})
+			return
+		}
+		run.Tables = make(map[string]int)
+		runIndex[run.ID] = len(runs)
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	countRows, err := h.db.Query(`
+		SELECT run_id, table_name, COUNT(*)
+		FROM game_run_archive
+		GROUP BY run_id, table_name
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archive stats"})
+		return
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var runID, count int
+		var tableName string
+		if err := countRows.Scan(&runID, &tableName, &count); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan archive stats"})
+			return
+		}
+		if i, ok := runIndex[runID]; ok {
+			runs[i].Tables[tableName] = count
+		}
+	}
+
+	if err = countRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	var baselineCapturedAt *time.Time
+	err = h.db.QueryRow(`SELECT MAX(captured_at) FROM game_baseline`).Scan(&baselineCapturedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GameRunsResponse{
+		Runs:               runs,
+		BaselineCapturedAt: baselineCapturedAt,
+	})
+}
+
+// GetGameRunRows возвращает строки одной таблицы из архива прогона (?limit=&offset=)
+func (h *AdminRunHandler) GetGameRunRows(c *gin.Context) {
+	runID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	table := c.Param("table")
+	if !isArchivedTable(table) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown archive table"})
+		return
+	}
+
+	limit := defaultArchiveLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		if limit > maxArchiveLimit {
+			limit = maxArchiveLimit
+		}
+	}
+
+	offset := 0
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+	}
+
+	var runExists bool
+	err = h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM game_runs WHERE id = $1)`, runID).Scan(&runExists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !runExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Game run not found"})
+		return
+	}
+
+	var total int
+	err = h.db.QueryRow(`
+		SELECT COUNT(*) FROM game_run_archive WHERE run_id = $1 AND table_name = $2
+	`, runID, table).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT row_data
+		FROM game_run_archive
+		WHERE run_id = $1 AND table_name = $2
+		ORDER BY id
+		LIMIT $3 OFFSET $4
+	`, runID, table, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archived rows"})
+		return
+	}
+	defer rows.Close()
+
+	archived := make([]json.RawMessage, 0)
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan archived row"})
+			return
+		}
+		archived = append(archived, json.RawMessage(row))
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.GameRunRowsResponse{
+		RunID:  runID,
+		Table:  table,
+		Rows:   archived,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// captureBaseline заменяет стартовое состояние текущим. Возвращает количество строк по таблицам
+func captureBaseline(tx *sql.Tx) (map[string]int, error) {
+	if _, err := tx.Exec(`DELETE FROM game_baseline`); err != nil {
+		return nil, fmt.Errorf("failed to clear baseline: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, table := range baselineTables {
+		result, err := tx.Exec(`
+			INSERT INTO game_baseline (table_name, row_data)
+			SELECT $1, `+table.rowExpr()+`
+			FROM `+table.name+` t
+		`, table.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture %s: %w", table.name, err)
+		}
+
+		captured, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		counts[table.name] = int(captured)
+	}
+
+	return counts, nil
+}
+
+// ensureBaseline сохраняет стартовое состояние, если его ещё нет (перед первым стартом игры)
+func ensureBaseline(tx *sql.Tx) error {
+	var hasBaseline bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM game_baseline)`).Scan(&hasBaseline); err != nil {
+		return err
+	}
+
+	if hasBaseline {
+		return nil
+	}
+
+	_, err := captureBaseline(tx)
+	return err
+}
+
+// baselineRows - подзапрос со строками таблицы из стартового состояния
+func baselineRows(table string) string {
+	return `(SELECT (jsonb_populate_record(NULL::` + table + `, row_data)).*
+		FROM game_baseline WHERE table_name = '` + table + `')`
+}
+
+// restoreBaseline возвращает сценарий к стартовому состоянию. Сущности, созданные
+// во время прогона (порождённые предметы, цели гонки), удаляются; удалённые во время
+// прогона предметы возвращаются. Ссылки на удалённых игроков, фракции и эффекты пропускаются
+func restoreBaseline(tx *sql.Tx) error {
+	statements := []struct {
+		name  string
+		query string
+	}{
+		{"players", `
+			UPDATE players p
+			SET money = b.money,
+			    influence = b.influence,
+			    faction_id = (SELECT f.id FROM factions f WHERE f.id = b.faction_id),
+			    can_change_faction = b.can_change_faction
+			FROM ` + baselineRows("players") + ` b
+			WHERE p.id = b.id`},
+		{"factions", `
+			UPDATE factions f
+			SET faction_influence = b.faction_influence,
+			    treasury = COALESCE(b.treasury, 0),
+			    leader_player_id = (SELECT p.id FROM players p WHERE p.id = b.leader_player_id)
+			FROM ` + baselineRows("factions") + ` b
+			WHERE f.id = b.id`},
+		{"player_items", `DELETE FROM player_items`},
+		{"items", `
+			DELETE FROM items
+			WHERE id NOT IN (SELECT id FROM ` + baselineRows("items") + ` b)`},
+		{"items", `
+			INSERT INTO items (id, name, description, created_at, template_id)
+			SELECT b.id, b.name, b.description, b.created_at,
+			       (SELECT t.id FROM item_templates t WHERE t.id = b.template_id)
+			FROM ` + baselineRows("items") + ` b
+			ON CONFLICT (id) DO NOTHING`},
+		{"item_effects", `
+			DELETE FROM item_effects`},
+		{"item_effects", `
+			INSERT INTO item_effects (item_id, effect_id)
+			SELECT b.item_id, b.effect_id
+			FROM ` + baselineRows("item_effects") + ` b
+			WHERE b.effect_id IN (SELECT id FROM effects)`},
+		{"player_items", `
+			INSERT INTO player_items (id, player_id, item_id, acquired_at)
+			SELECT b.id, b.player_id, b.item_id, b.acquired_at
+			FROM ` + baselineRows("player_items") + ` b
+			WHERE b.player_id IN (SELECT id FROM players)`},
+		{"goals", `
+			DELETE FROM goals
+			WHERE id NOT IN (SELECT id FROM ` + baselineRows("goals") + ` b)`},
+		{"goals", `
+			UPDATE goals g
+			SET is_completed = b.is_completed,
+			    completed_at = b.completed_at
+			FROM ` + baselineRows("goals") + ` b
+			WHERE g.id = b.id`},
+		{"abilities", `
+			UPDATE abilities a
+			SET is_unlocked = b.is_unlocked
+			FROM ` + baselineRows("abilities") + ` b
+			WHERE a.id = b.id`},
+		{"tasks", `
+			UPDATE tasks t
+			SET is_completed = b.is_completed,
+			    completed_at = b.completed_at
+			FROM ` + baselineRows("tasks") + ` b
+			WHERE t.id = b.id`},
+		{"goal_race_triggers", `
+			UPDATE goal_race_triggers t
+			SET is_active = b.is_active
+			FROM ` + baselineRows("goal_race_triggers") + ` b
+			WHERE t.id = b.id`},
+		// ВАЖНО: триггеры на players.influence и goals.is_completed уже добавили разблокировки
+		// во время восстановления - заменяем их стартовыми
+		{"goal_dependency_unlocks", `DELETE FROM goal_dependency_unlocks`},
+		{"goal_dependency_unlocks", `
+			INSERT INTO goal_dependency_unlocks (id, goal_id, dependency_id, player_id, unlocked_at)
+			SELECT b.id, b.goal_id, b.dependency_id, b.player_id, b.unlocked_at
+			FROM ` + baselineRows("goal_dependency_unlocks") + ` b
+			WHERE b.dependency_id IN (SELECT id FROM goal_dependencies)
+			  AND b.player_id IN (SELECT id FROM players)`},
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", statement.name, err)
+		}
+	}
+
+	return nil
+}
+
+// isArchivedTable проверяет, что таблица есть в архиве прогонов
+func isArchivedTable(name string) bool {
+	for _, table := range append(append([]archivedTable{}, runTables...), stateTables...) {
+		if table.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gameStarted проверяет, начата ли игра в текущем прогоне (в том числе завершённая)
+func gameStarted(tx *sql.Tx) (bool, error) {
+	var started bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM game_timeline WHERE game_started_at IS NOT NULL)
+	`).Scan(&started)
+	return started, err
+}
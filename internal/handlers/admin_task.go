@@ -0,0 +1,324 @@
+// internal/handlers/admin_task.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminTaskHandler struct {
+	db *sql.DB
+}
+
+func NewAdminTaskHandler(db *sql.DB) *AdminTaskHandler {
+	return &AdminTaskHandler{db: db}
+}
+
+// GetAllTasks возвращает все задачи (опционально - только задачи указанного игрока)
+func (h *AdminTaskHandler) GetAllTasks(c *gin.Context) {
+	query := `
+		SELECT
+			t.id,
+			t.player_id,
+			p.character_name,
+			t.title,
+			t.description,
+			t.is_completed,
+			t.completed_at,
+			t.created_at
+		FROM tasks t
+		LEFT JOIN players p ON p.id = t.player_id
+	`
+	args := []interface{}{}
+
+	if playerIDParam := c.Query("player_id"); playerIDParam != "" {
+		playerID, err := strconv.Atoi(playerIDParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+			return
+		}
+		query += " WHERE t.player_id = $1"
+		args = append(args, playerID)
+	}
+
+	query += " ORDER BY t.player_id NULLS FIRST, t.created_at ASC"
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	defer rows.Close()
+
+	tasks := make([]models.Task, 0)
+	for rows.Next() {
+		var task models.Task
+		err := rows.Scan(
+			&task.ID,
+			&task.PlayerID,
+			&task.PlayerName,
+			&task.Title,
+			&task.Description,
+			&task.IsCompleted,
+			&task.CompletedAt,
+			&task.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan task"})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TasksResponse{Tasks: tasks})
+}
+
+// CreateTask создает новую задачу
+func (h *AdminTaskHandler) CreateTask(c *gin.Context) {
+	var req models.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if req.PlayerID != nil {
+		if ok, err := playerExists(tx, *req.PlayerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+	}
+
+	var task models.Task
+	err = tx.QueryRow(`
+		INSERT INTO tasks (player_id, title, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, player_id, title, description, is_completed, completed_at, created_at
+	`, req.PlayerID, req.Title, req.Description).Scan(
+		&task.ID,
+		&task.PlayerID,
+		&task.Title,
+		&task.Description,
+		&task.IsCompleted,
+		&task.CompletedAt,
+		&task.CreatedAt,
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, task)
+}
+
+// UpdateTask изменяет название и/или описание задачи
+func (h *AdminTaskHandler) UpdateTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.UpdateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Title == nil && req.Description == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	if req.Title != nil {
+		trimmed := strings.TrimSpace(*req.Title)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title cannot be empty"})
+			return
+		}
+		req.Title = &trimmed
+	}
+
+	// COALESCE оставляет текущее значение для незаданных полей
+	var task models.Task
+	err = h.db.QueryRow(`
+		UPDATE tasks
+		SET title = COALESCE($1, title),
+		    description = COALESCE($2, description)
+		WHERE id = $3
+		RETURNING id, player_id, title, description, is_completed, completed_at, created_at
+	`, req.Title, req.Description, taskID).Scan(
+		&task.ID,
+		&task.PlayerID,
+		&task.Title,
+		&task.Description,
+		&task.IsCompleted,
+		&task.CompletedAt,
+		&task.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// AssignTask назначает задачу игроку или снимает назначение (player_id = null)
+func (h *AdminTaskHandler) AssignTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.AssignTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Блокируем задачу
+	var currentPlayerID *int
+	err = tx.QueryRow(`
+		SELECT player_id FROM tasks WHERE id = $1 FOR UPDATE
+	`, taskID).Scan(&currentPlayerID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if req.PlayerID != nil {
+		if ok, err := playerExists(tx, *req.PlayerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+	}
+
+	// ВАЖНО: при смене владельца сбрасываем выполнение, иначе новый игрок
+	// получит в статистику чужую выполненную задачу
+	var task models.Task
+	err = tx.QueryRow(`
+		UPDATE tasks
+		SET player_id = $1,
+		    is_completed = CASE WHEN player_id IS NOT DISTINCT FROM $1 THEN is_completed ELSE false END,
+		    completed_at = CASE WHEN player_id IS NOT DISTINCT FROM $1 THEN completed_at ELSE NULL END
+		WHERE id = $2
+		RETURNING id, player_id, title, description, is_completed, completed_at, created_at
+	`, req.PlayerID, taskID).Scan(
+		&task.ID,
+		&task.PlayerID,
+		&task.Title,
+		&task.Description,
+		&task.IsCompleted,
+		&task.CompletedAt,
+		&task.CreatedAt,
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign task"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// DeleteTask удаляет задачу вместе с историей её выполнения
+func (h *AdminTaskHandler) DeleteTask(c *gin.Context) {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM tasks WHERE id = $1`, taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete task"})
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Task deleted successfully",
+		"task_id": taskID,
+	})
+}
+
+// playerExists проверяет существование игрока
+func playerExists(tx *sql.Tx, playerID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)
+	`, playerID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,553 @@
+// internal/handlers/admin_user.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/auth"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminUserHandler struct {
+	db *sql.DB
+}
+
+func NewAdminUserHandler(db *sql.DB) *AdminUserHandler {
+	return &AdminUserHandler{db: db}
+}
+
+// ResetPlayerPassword задаёт новый пароль игроку и всем его учётным записям
+func (h *AdminUserHandler) ResetPlayerPassword(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := auth.ValidatePasswordLength(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE players SET password = $1 WHERE id = $2`, hash, playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update player password"})
+		return
+	}
+
+	if affected, err := result.RowsAffected(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+
+	result, err = tx.Exec(`UPDATE users SET password = $1 WHERE player_id = $2`, hash, playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user password"})
+		return
+	}
+
+	usersUpdated, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Старый пароль мог утечь - выкидываем все открытые сессии игрока
+	sessionsRevoked, err := revokePlayerSessions(tx, playerID, "password_reset")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Password reset successfully",
+		"player_id":        playerID,
+		"users_updated":    usersUpdated,
+		"sessions_revoked": sessionsRevoked,
+	})
+}
+
+// GetUserSessions возвращает сессии пользователя
+func (h *AdminUserHandler) GetUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT
+			id,
+			user_id,
+			user_agent,
+			ip_address,
+			created_at,
+			last_used_at,
+			expires_at,
+			revoked_at,
+			revoke_reason,
+			revoked_at IS NULL AND expires_at > NOW()
+		FROM user_sessions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	defer rows.Close()
+
+	sessions := make([]models.UserSession, 0)
+	for rows.Next() {
+		var session models.UserSession
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.RevokeReason,
+			&session.IsActive,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan session"})
+			return
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UserSessionsResponse{Sessions: sessions})
+}
+
+// RevokeUserSessions отзывает все сессии пользователя (например, при потере телефона)
+func (h *AdminUserHandler) RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	revoked, err := revokeSessions(tx, userID, "admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Sessions revoked",
+		"user_id":          userID,
+		"sessions_revoked": revoked,
+	})
+}
+
+// RevokePlayerSessions отзывает сессии всех учётных записей игрока
+func (h *AdminUserHandler) RevokePlayerSessions(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if ok, err := playerExists(tx, playerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+
+	revoked, err := revokePlayerSessions(tx, playerID, "admin")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Sessions revoked",
+		"player_id":        playerID,
+		"sessions_revoked": revoked,
+	})
+}
+
+// GetAllUsers возвращает все учётные записи
+func (h *AdminUserHandler) GetAllUsers(c *gin.Context) {
+	rows, err := h.db.Query(`
+		SELECT id, username, player_id, COALESCE(is_admin, false)
+		FROM users
+		ORDER BY id
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.PlayerID, &user.IsAdmin); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan user"})
+			return
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UsersResponse{Users: users})
+}
+
+// CreateUser создает учётную запись (для игрока или администратора)
+func (h *AdminUserHandler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username cannot be empty"})
+		return
+	}
+
+	if err := auth.ValidatePasswordLength(req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	if taken, err := usernameTaken(tx, req.Username, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	} else if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	if req.PlayerID != nil {
+		if ok, err := playerExists(tx, *req.PlayerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+			return
+		}
+	}
+
+	var user models.User
+	err = tx.QueryRow(`
+		INSERT INTO users (username, password, player_id, is_admin)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, username, player_id, is_admin
+	`, req.Username, hash, req.PlayerID, req.IsAdmin).Scan(
+		&user.ID,
+		&user.Username,
+		&user.PlayerID,
+		&user.IsAdmin,
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser изменяет логин, пароль, персонажа или права учётной записи.
+// Смена пароля, персонажа или прав отзывает сессии: в старых токенах остались прежние данные
+func (h *AdminUserHandler) UpdateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.Username == nil && req.Password == nil && req.PlayerID == nil && req.IsAdmin == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	if req.Username != nil {
+		trimmed := strings.TrimSpace(*req.Username)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Username cannot be empty"})
+			return
+		}
+		req.Username = &trimmed
+	}
+
+	// Администратор не может случайно лишить прав сам себя
+	if req.IsAdmin != nil && !*req.IsAdmin && c.GetInt("user_id") == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove your own admin rights"})
+		return
+	}
+
+	var hash *string
+	if req.Password != nil {
+		if err := auth.ValidatePasswordLength(*req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		hashed, err := auth.HashPassword(*req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		hash = &hashed
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Блокируем учётную запись
+	var currentPlayerID *int
+	var currentIsAdmin bool
+	err = tx.QueryRow(`
+		SELECT player_id, COALESCE(is_admin, false) FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&currentPlayerID, &currentIsAdmin)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if req.Username != nil {
+		if taken, err := usernameTaken(tx, *req.Username, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		} else if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+			return
+		}
+	}
+
+	newPlayerID := currentPlayerID
+	if req.PlayerID != nil {
+		if *req.PlayerID == 0 {
+			newPlayerID = nil
+		} else {
+			if ok, err := playerExists(tx, *req.PlayerID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			} else if !ok {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+				return
+			}
+			newPlayerID = req.PlayerID
+		}
+	}
+
+	var user models.User
+	err = tx.QueryRow(`
+		UPDATE users
+		SET username = COALESCE($1, username),
+		    password = COALESCE($2, password),
+		    player_id = $3,
+		    is_admin = COALESCE($4, is_admin)
+		WHERE id = $5
+		RETURNING id, username, player_id, is_admin
+	`, req.Username, hash, newPlayerID, req.IsAdmin, userID).Scan(
+		&user.ID,
+		&user.Username,
+		&user.PlayerID,
+		&user.IsAdmin,
+	)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	playerChanged := (currentPlayerID == nil) != (newPlayerID == nil) ||
+		(currentPlayerID != nil && newPlayerID != nil && *currentPlayerID != *newPlayerID)
+
+	var sessionsRevoked int64
+	if hash != nil {
+		sessionsRevoked, err = revokeSessions(tx, userID, "password_reset")
+	} else if playerChanged || user.IsAdmin != currentIsAdmin {
+		sessionsRevoked, err = revokeSessions(tx, userID, "admin")
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":             user,
+		"sessions_revoked": sessionsRevoked,
+	})
+}
+
+// DeleteUser удаляет учётную запись вместе с её сессиями. Персонаж остаётся
+func (h *AdminUserHandler) DeleteUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if c.GetInt("user_id") == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete your own account"})
+		return
+	}
+
+	result, err := h.db.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deleted successfully",
+		"user_id": userID,
+	})
+}
+
+// usernameTaken проверяет, занят ли логин другой учётной записью
+func usernameTaken(tx *sql.Tx, username string, exceptID int) (bool, error) {
+	var taken bool
+	err := tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 AND id <> $2)
+	`, username, exceptID).Scan(&taken)
+	return taken, err
+}
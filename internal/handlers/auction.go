@@ -0,0 +1,215 @@
+// internal/handlers/auction.go
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/auctions"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuctionHandler - ставки игроков на аукционах мастера. Резерв денег и закрытие
+// аукциона живут в auctions.Service, который вызывает и scheduler
+type AuctionHandler struct {
+	db       *sql.DB
+	store    storage.Store
+	auctions *auctions.Service
+}
+
+func NewAuctionHandler(db *sql.DB, store storage.Store, service *auctions.Service) *AuctionHandler {
+	return &AuctionHandler{
+		db:       db,
+		store:    store,
+		auctions: service,
+	}
+}
+
+// GetAuctions возвращает активные аукционы и закрытые за последние сутки
+func (h *AuctionHandler) GetAuctions(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	result, err := fetchAuctions(h.db, *playerID,
+		`a.status = 'active' OR a.closed_at > $1`, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch auctions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuctionsResponse{Auctions: result})
+}
+
+// fetchAuctions читает аукционы по условию where вместе с текущей ставкой и эффектами предметов.
+// playerID - текущий игрок (0 - администратор)
+func fetchAuctions(db *sql.DB, playerID int, where string, args ...interface{}) ([]models.Auction, error) {
+	rows, err := db.Query(`
+		SELECT
+			a.id,
+			a.item_id,
+			i.name,
+			i.description,
+			a.start_price,
+			a.min_increment,
+			a.status,
+			COALESCE(lb.amount, a.final_price),
+			COALESCE(lb.player_id, a.winner_player_id),
+			lp.character_name,
+			(SELECT COUNT(*) FROM auction_bids b WHERE b.auction_id = a.id),
+			a.created_at,
+			a.closes_at,
+			a.closed_at
+		FROM auctions a
+		JOIN items i ON i.id = a.item_id
+		LEFT JOIN auction_bids lb ON lb.auction_id = a.id AND lb.status = 'leading'
+		LEFT JOIN players lp ON lp.id = COALESCE(lb.player_id, a.winner_player_id)
+		WHERE `+where+`
+		ORDER BY (a.status = 'active') DESC, a.closes_at, a.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	result := make([]models.Auction, 0)
+	itemIDs := make([]int64, 0)
+	for rows.Next() {
+		var auction models.Auction
+		err := rows.Scan(
+			&auction.ID,
+			&auction.ItemID,
+			&auction.ItemName,
+			&auction.ItemDescription,
+			&auction.StartPrice,
+			&auction.MinIncrement,
+			&auction.Status,
+			&auction.CurrentBid,
+			&auction.LeaderPlayerID,
+			&auction.LeaderPlayerName,
+			&auction.BidsCount,
+			&auction.CreatedAt,
+			&auction.ClosesAt,
+			&auction.ClosedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		auction.IsLeader = auction.LeaderPlayerID != nil && *auction.LeaderPlayerID == playerID
+		auction.Effects = make([]models.Effect, 0)
+		if auction.Status == "active" {
+			minBid := auction.StartPrice
+			if auction.CurrentBid != nil {
+				minBid = *auction.CurrentBid + auction.MinIncrement
+			}
+			auction.MinBid = &minBid
+
+			remaining := int(auction.ClosesAt.Sub(now).Seconds())
+			if remaining < 0 {
+				remaining = 0
+			}
+			auction.TimeRemaining = &remaining
+		}
+
+		result = append(result, auction)
+		itemIDs = append(itemIDs, int64(auction.ItemID))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	effectsByItem, err := fetchEffectsByItems(db, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result {
+		if effects, ok := effectsByItem[result[i].ItemID]; ok {
+			result[i].Effects = effects
+		}
+	}
+
+	return result, nil
+}
+
+// PlaceBid - ставка игрока. Деньги резервируются сразу, перебитый лидер получает свою ставку обратно
+func (h *AuctionHandler) PlaceBid(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	auctionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid auction ID"})
+		return
+	}
+
+	var req models.PlaceBidRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	bid, err := h.auctions.Bid(tx, auctionID, *playerID, req.Amount, time.Now())
+	if err != nil {
+		switch err {
+		case auctions.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Auction not found"})
+		case auctions.ErrNotActive, auctions.ErrClosed:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Auction is closed"})
+		case auctions.ErrBidTooLow:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Bid is too low"})
+		case auctions.ErrInsufficientFunds:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds"})
+		default:
+			log.Printf("Failed to place bid on auction #%d: %v", auctionID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place bid"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Bid placed successfully",
+		"auction_id": auctionID,
+		"bid_id":     bid.ID,
+		"amount":     bid.Amount,
+	})
+}
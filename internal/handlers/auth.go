@@ -3,7 +3,9 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
+	"new-year-role-game-backend/internal/auth"
 	"new-year-role-game-backend/internal/middleware"
 	"new-year-role-game-backend/internal/models"
 	"time"
@@ -12,15 +14,22 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// streamTicketTTL - сколько живёт билет на поток событий: его хватает только на подключение
+const streamTicketTTL = time.Minute
+
 type AuthHandler struct {
-	db     *sql.DB
-	jwtKey string
+	db              *sql.DB
+	jwtKey          string
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
 }
 
-func NewAuthHandler(db *sql.DB, jwtKey string) *AuthHandler {
+func NewAuthHandler(db *sql.DB, jwtKey string, accessTokenTTL, refreshTokenTTL time.Duration) *AuthHandler {
 	return &AuthHandler{
-		db:     db,
-		jwtKey: jwtKey,
+		db:              db,
+		jwtKey:          jwtKey,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
 	}
 }
 
@@ -48,34 +57,365 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	if hashedPassword != req.Password {
+	ok, needsUpgrade := auth.CheckPassword(hashedPassword, req.Password)
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	token, err := h.generateToken(user.ID, user.PlayerID, user.IsAdmin)
+	// Пароль хранился открытым текстом - заменяем его хешем при первом входе
+	if needsUpgrade {
+		if err := h.upgradePassword(user.ID, user.PlayerID, req.Password); err != nil {
+			log.Printf("Warning: failed to upgrade password hash for user %d: %v", user.ID, err)
+		}
+	}
+
+	// Создаём сессию и первый refresh-токен
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var sessionID int
+	err = tx.QueryRow(`
+		INSERT INTO user_sessions (user_id, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, user.ID, c.Request.UserAgent(), c.ClientIP(), time.Now().Add(h.refreshTokenTTL)).Scan(&sessionID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(tx, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	token, expiresAt, err := h.generateToken(user.ID, user.PlayerID, user.IsAdmin, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        token,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		User:         user,
+	})
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов (старый refresh-токен сгорает)
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	var tokenID, sessionID, userID int
+	var tokenExpiresAt, sessionExpiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	err = tx.QueryRow(`
+		SELECT rt.id, rt.session_id, rt.expires_at, rt.used_at, s.user_id, s.expires_at, s.revoked_at
+		FROM refresh_tokens rt
+		JOIN user_sessions s ON s.id = rt.session_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt, s
+	`, auth.HashToken(req.RefreshToken)).Scan(
+		&tokenID,
+		&sessionID,
+		&tokenExpiresAt,
+		&usedAt,
+		&userID,
+		&sessionExpiresAt,
+		&revokedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if revokedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+		return
+	}
+
+	// ВАЖНО: повторное использование уже обменянного токена означает, что его украли -
+	// отзываем всю сессию, чтобы ни вор, ни владелец больше не могли ей пользоваться
+	if usedAt != nil {
+		_, err = tx.Exec(`
+			UPDATE user_sessions
+			SET revoked_at = NOW(), revoke_reason = 'token_reuse'
+			WHERE id = $1
+		`, sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+			return
+		}
+
+		log.Printf("Refresh token reuse detected for session %d (user %d), session revoked", sessionID, userID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token already used, session revoked"})
+		return
+	}
+
+	now := time.Now()
+	if now.After(tokenExpiresAt) || now.After(sessionExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
+		return
+	}
+
+	_, err = tx.Exec(`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, tokenID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate refresh token"})
+		return
+	}
+
+	// Берём актуальные данные пользователя - права могли измениться
+	var user models.User
+	err = tx.QueryRow(`
+		SELECT id, username, player_id, is_admin
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&user.ID, &user.Username, &user.PlayerID, &user.IsAdmin)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(tx, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	token, expiresAt, err := h.generateToken(user.ID, user.PlayerID, user.IsAdmin, sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        token,
+		ExpiresAt:    expiresAt,
+		RefreshToken: refreshToken,
+		User:         user,
 	})
 }
 
-func (h *AuthHandler) generateToken(userID int, playerID *int, isAdmin bool) (string, error) {
+// Logout отзывает текущую сессию
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in token"})
+		return
+	}
+
+	_, err := h.db.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = 'logout'
+		WHERE id = $1 AND revoked_at IS NULL
+	`, sessionID.(int))
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// StreamTicket выдаёт билет на подключение к потоку событий для текущей сессии.
+// Билет передаётся в ?ticket= вместо access-токена, принимается только потоком событий и только один раз
+func (h *AuthHandler) StreamTicket(c *gin.Context) {
+	value, _ := c.Get("player_id")
+	playerID, _ := value.(*int)
+	sessionID := c.GetInt("session_id")
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	ticketID, err := auth.GenerateTicketID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate ticket"})
+		return
+	}
+
+	// Заодно удаляем истёкшие билеты - они уже не будут приняты
+	if _, err = h.db.Exec(`DELETE FROM stream_tickets WHERE expires_at < NOW()`); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	_, err = h.db.Exec(`
+		INSERT INTO stream_tickets (id, session_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, ticketID, sessionID, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save ticket"})
+		return
+	}
+
 	claims := &middleware.Claims{
-		UserID:   userID,
-		PlayerID: playerID,
-		IsAdmin:  isAdmin,
+		UserID:    c.GetInt("user_id"),
+		PlayerID:  playerID,
+		IsAdmin:   c.GetBool("is_admin"),
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        ticketID,
+			Audience:  jwt.ClaimStrings{middleware.StreamTicketAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.jwtKey))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate ticket"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// issueRefreshToken создаёт новый refresh-токен сессии и продлевает её
+func (h *AuthHandler) issueRefreshToken(tx *sql.Tx, sessionID int) (string, error) {
+	refreshToken, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := time.Now().Add(h.refreshTokenTTL)
+
+	_, err = tx.Exec(`
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, sessionID, auth.HashToken(refreshToken), expiresAt)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_sessions
+		SET last_used_at = NOW(), expires_at = $1
+		WHERE id = $2
+	`, expiresAt, sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// upgradePassword заменяет открытый пароль пользователя и его персонажа на хеш
+func (h *AuthHandler) upgradePassword(userID int, playerID *int, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET password = $1 WHERE id = $2`, hash, userID)
+	if err != nil {
+		return err
+	}
+
+	// Сид дублирует пароль в players.password - хешируем и его, если он совпадает
+	if playerID != nil {
+		_, err = tx.Exec(`
+			UPDATE players SET password = $1
+			WHERE id = $2 AND password = $3
+		`, hash, *playerID, password)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (h *AuthHandler) generateToken(userID int, playerID *int, isAdmin bool, sessionID int) (string, time.Time, error) {
+	expiresAt := time.Now().Add(h.accessTokenTTL)
+	claims := &middleware.Claims{
+		UserID:    userID,
+		PlayerID:  playerID,
+		IsAdmin:   isAdmin,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(h.jwtKey))
+	signed, err := token.SignedString([]byte(h.jwtKey))
+	return signed, expiresAt, err
+}
+
+// revokeSessions отзывает все активные сессии пользователя
+func revokeSessions(tx *sql.Tx, userID int, reason string) (int64, error) {
+	result, err := tx.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = $1
+		WHERE user_id = $2 AND revoked_at IS NULL
+	`, reason, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// revokePlayerSessions отзывает сессии всех учётных записей, привязанных к игроку
+func revokePlayerSessions(tx *sql.Tx, playerID int, reason string) (int64, error) {
+	result, err := tx.Exec(`
+		UPDATE user_sessions
+		SET revoked_at = NOW(), revoke_reason = $1
+		WHERE revoked_at IS NULL
+		  AND user_id IN (SELECT id FROM users WHERE player_id = $2)
+	`, reason, playerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -49,6 +49,7 @@ func (h *ContractHandler) GetPlayerContracts(c *gin.Context) {
 			c.duration_seconds,
 			c.money_reward_customer,
 			c.money_reward_executor,
+			c.money_reward_faction,
 			c.created_at,
 			c.signed_at,
 			c.expires_at,
@@ -96,6 +97,7 @@ func (h *ContractHandler) GetPlayerContracts(c *gin.Context) {
 			&contract.DurationSeconds,
 			&contract.MoneyRewardCustomer,
 			&contract.MoneyRewardExecutor,
+			&contract.MoneyRewardFaction,
 			&contract.CreatedAt,
 			&contract.SignedAt,
 			&contract.ExpiresAt,
@@ -194,15 +196,15 @@ func (h *ContractHandler) CreateContract(c *gin.Context) {
 	}
 
 	// Получаем награды из настроек администратора в зависимости от типа договора
-	var moneyRewardCustomer, moneyRewardExecutor int
+	var moneyRewardCustomer, moneyRewardExecutor, moneyRewardFaction int
 
 	if req.ContractType == "type1" {
 		err = tx.QueryRow(`
-			SELECT money_reward_customer, money_reward_executor
+			SELECT money_reward_customer, money_reward_executor, money_reward_faction
 			FROM contract_type1_reward_settings
 			ORDER BY id DESC
 			LIMIT 1
-		`).Scan(&moneyRewardCustomer, &moneyRewardExecutor)
+		`).Scan(&moneyRewardCustomer, &moneyRewardExecutor, &moneyRewardFaction)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Contract Type 1 rewards not configured by admin"})
@@ -234,12 +236,13 @@ func (h *ContractHandler) CreateContract(c *gin.Context) {
 			duration_seconds,
 			money_reward_customer,
 			money_reward_executor,
+			money_reward_faction,
 			created_at
 		)
-		VALUES ($1, $2, $3, 'pending', $4, $5, $6, NOW())
+		VALUES ($1, $2, $3, 'pending', $4, $5, $6, $7, NOW())
 		RETURNING id
 	`, req.ContractType, req.CustomerPlayerID, *playerID, req.DurationSeconds,
-		moneyRewardCustomer, moneyRewardExecutor).Scan(&contractID)
+		moneyRewardCustomer, moneyRewardExecutor, moneyRewardFaction).Scan(&contractID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contract"})
@@ -270,6 +273,7 @@ func (h *ContractHandler) CreateContract(c *gin.Context) {
 			c.duration_seconds,
 			c.money_reward_customer,
 			c.money_reward_executor,
+			c.money_reward_faction,
 			c.created_at,
 			c.signed_at,
 			c.expires_at,
@@ -295,6 +299,7 @@ func (h *ContractHandler) CreateContract(c *gin.Context) {
 		&contract.DurationSeconds,
 		&contract.MoneyRewardCustomer,
 		&contract.MoneyRewardExecutor,
+		&contract.MoneyRewardFaction,
 		&contract.CreatedAt,
 		&contract.SignedAt,
 		&contract.ExpiresAt,
@@ -0,0 +1,63 @@
+// internal/handlers/contract_actions_test.go
+package handlers
+
+import (
+	"net/http"
+	"new-year-role-game-backend/internal/contracts"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"new-year-role-game-backend/internal/workers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSignContract(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     int
+		contractID string
+		status     string // статус договора до подписания
+		code       int
+		err        string
+	}{
+		{name: "success", caller: 1, contractID: "3", status: "pending", code: http.StatusOK},
+		{name: "not found", caller: 1, contractID: "4", status: "pending", code: http.StatusNotFound,
+			err: "Contract not found"},
+		{name: "executor cannot sign", caller: 2, contractID: "3", status: "pending", code: http.StatusForbidden,
+			err: "Only customer can sign the contract"},
+		{name: "already signed", caller: 1, contractID: "3", status: "signed", code: http.StatusBadRequest,
+			err: "Contract is not in pending status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Customer"})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Executor"})
+			store.AddContract(storage.Contract{ID: 3, Status: tt.status, ContractType: "type2",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, DurationSeconds: 600})
+
+			items := workers.NewEffectsScheduler(nil, jobs.NewQueue(nil, time.Second))
+			h := NewContractHandlerWithScheduler(store, contracts.NewService(items))
+			w := perform(t, h.SignContract, tt.caller, gin.Params{{Key: "id", Value: tt.contractID}}, nil)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.code, w.Body.String())
+			}
+			if tt.err != "" {
+				if got := responseError(t, w); got != tt.err {
+					t.Errorf("error = %q, want %q", got, tt.err)
+				}
+			}
+
+			contract, _ := store.Contract(3)
+			_, timer := store.Timers()[contracts.JobKey(3)]
+			if signed := tt.code == http.StatusOK; signed != timer || (signed && contract.Status != "signed") {
+				t.Errorf("contract status = %s, completion timer = %v", contract.Status, timer)
+			}
+		})
+	}
+}
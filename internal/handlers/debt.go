@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"new-year-role-game-backend/internal/events"
 	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
 	"new-year-role-game-backend/internal/workers"
 	"strconv"
 	"time"
@@ -15,12 +17,14 @@ import (
 
 type DebtHandler struct {
 	db        *sql.DB
+	store     storage.Store
 	scheduler *workers.DebtScheduler
 }
 
-func NewDebtHandler(db *sql.DB, scheduler *workers.DebtScheduler) *DebtHandler {
+func NewDebtHandler(db *sql.DB, store storage.Store, scheduler *workers.DebtScheduler) *DebtHandler {
 	return &DebtHandler{
 		db:        db,
+		store:     store,
 		scheduler: scheduler,
 	}
 }
@@ -296,6 +300,11 @@ func (h *DebtHandler) CreateDebtReceipt(c *gin.Context) {
 		return
 	}
 
+	if err = events.PublishBalances(tx, *playerID, req.BorrowerPlayerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
 	// Фиксируем транзакцию
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -383,7 +392,7 @@ func (h *DebtHandler) ReturnDebt(c *gin.Context) {
 	}
 
 	// Начинаем транзакцию
-	tx, err := h.db.Begin()
+	tx, err := h.store.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
@@ -391,31 +400,9 @@ func (h *DebtHandler) ReturnDebt(c *gin.Context) {
 	defer tx.Rollback()
 
 	// Получаем информацию о долговой расписке
-	var debt struct {
-		ID             int
-		LenderID       int
-		BorrowerID     int
-		ReturnAmount   int
-		IsReturned     bool
-		PenaltyApplied bool
-	}
-
-	err = tx.QueryRow(`
-		SELECT id, lender_player_id, borrower_player_id, return_amount, is_returned, penalty_applied
-		FROM debt_receipts
-		WHERE id = $1
-		FOR UPDATE
-	`, debtID).Scan(
-		&debt.ID,
-		&debt.LenderID,
-		&debt.BorrowerID,
-		&debt.ReturnAmount,
-		&debt.IsReturned,
-		&debt.PenaltyApplied,
-	)
-
+	debt, err := tx.Debts().GetForUpdate(debtID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Debt receipt not found"})
 			return
 		}
@@ -442,64 +429,49 @@ func (h *DebtHandler) ReturnDebt(c *gin.Context) {
 	}
 
 	// Проверяем баланс заемщика
-	var borrowerMoney int
-	var borrowerName string
-	err = tx.QueryRow(`
-		SELECT money, character_name
-		FROM players
-		WHERE id = $1
-		FOR UPDATE
-	`, debt.BorrowerID).Scan(&borrowerMoney, &borrowerName)
-
+	borrower, err := tx.Players().GetForUpdate(debt.BorrowerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
 
-	if borrowerMoney < debt.ReturnAmount {
+	if borrower.Money < debt.ReturnAmount {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":          "Borrower has insufficient funds",
 			"required":       debt.ReturnAmount,
-			"borrower_money": borrowerMoney,
+			"borrower_money": borrower.Money,
 		})
 		return
 	}
 
 	// Получаем имя кредитора
 	var lenderName string
-	tx.QueryRow(`SELECT character_name FROM players WHERE id = $1`, debt.LenderID).Scan(&lenderName)
+	if lender, err := tx.Players().Get(debt.LenderID); err == nil {
+		lenderName = lender.CharacterName
+	}
 
 	// Переводим деньги от заемщика к кредитору
-	_, err = tx.Exec(`
-		UPDATE players
-		SET money = money - $1
-		WHERE id = $2
-	`, debt.ReturnAmount, debt.BorrowerID)
-
-	if err != nil {
+	if err = tx.Players().AddMoney(debt.BorrowerID, -debt.ReturnAmount); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct money from borrower"})
 		return
 	}
 
-	_, err = tx.Exec(`
-		UPDATE players
-		SET money = money + $1
-		WHERE id = $2
-	`, debt.ReturnAmount, debt.LenderID)
-
-	if err != nil {
+	if err = tx.Players().AddMoney(debt.LenderID, debt.ReturnAmount); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add money to lender"})
 		return
 	}
 
 	// Записываем транзакцию
-	description := fmt.Sprintf("Debt return: %s → %s (debt #%d, amount: %d)",
-		borrowerName, lenderName, debtID, debt.ReturnAmount)
-	_, err = tx.Exec(`
-		INSERT INTO money_transactions (from_player_id, to_player_id, amount, transaction_type, reference_id, reference_type, description)
-		VALUES ($1, $2, $3, 'debt', $4, 'debt_receipt', $5)
-	`, debt.BorrowerID, debt.LenderID, debt.ReturnAmount, debtID, description)
-
+	err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		FromPlayerID:    &debt.BorrowerID,
+		ToPlayerID:      &debt.LenderID,
+		Amount:          debt.ReturnAmount,
+		TransactionType: "debt",
+		ReferenceID:     debtID,
+		ReferenceType:   "debt_receipt",
+		Description: fmt.Sprintf("Debt return: %s → %s (debt #%d, amount: %d)",
+			borrower.CharacterName, lenderName, debtID, debt.ReturnAmount),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
 		return
@@ -507,27 +479,28 @@ func (h *DebtHandler) ReturnDebt(c *gin.Context) {
 
 	// Отмечаем расписку как возвращенную
 	now := time.Now()
-	_, err = tx.Exec(`
-		UPDATE debt_receipts
-		SET is_returned = true,
-		    returned_at = $1
-		WHERE id = $2
-	`, now, debtID)
-
-	if err != nil {
+	if err = tx.Debts().MarkReturned(debtID, now); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update debt receipt"})
 		return
 	}
 
+	// Отменяем штраф в той же транзакции
+	if err = h.scheduler.CancelDebtTx(tx, debtID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel debt timer"})
+		return
+	}
+
+	if err = tx.Events().PublishBalances(debt.BorrowerID, debt.LenderID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
 	// Фиксируем транзакцию
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
-	// Отменяем таймер в scheduler
-	h.scheduler.CancelDebt(debtID)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Debt returned successfully",
 		"debt_id":     debtID,
@@ -0,0 +1,119 @@
+// internal/handlers/debt_test.go
+package handlers
+
+import (
+	"net/http"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"new-year-role-game-backend/internal/workers"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReturnDebt(t *testing.T) {
+	tests := []struct {
+		name     string
+		caller   int
+		debt     storage.Debt
+		borrower int // деньги заёмщика
+		status   int
+		err      string
+		money    map[int]int
+	}{
+		{
+			name:     "success",
+			caller:   1,
+			debt:     storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower: 50,
+			status:   http.StatusOK,
+			money:    map[int]int{1: 100, 2: 20},
+		},
+		{
+			name:     "insufficient funds",
+			caller:   1,
+			debt:     storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower: 20,
+			status:   http.StatusBadRequest,
+			err:      "Borrower has insufficient funds",
+			money:    map[int]int{1: 70, 2: 20},
+		},
+		{
+			name:     "borrower cannot confirm",
+			caller:   2,
+			debt:     storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower: 50,
+			status:   http.StatusForbidden,
+			err:      "Only lender can confirm debt return",
+			money:    map[int]int{1: 70, 2: 50},
+		},
+		{
+			name:     "already returned",
+			caller:   1,
+			debt:     storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30, IsReturned: true},
+			borrower: 50,
+			status:   http.StatusBadRequest,
+			err:      "Debt already returned",
+			money:    map[int]int{1: 70, 2: 50},
+		},
+		{
+			name:     "penalty already applied",
+			caller:   1,
+			debt:     storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30, PenaltyApplied: true},
+			borrower: 50,
+			status:   http.StatusBadRequest,
+			err:      "Penalty already applied, debt cannot be returned",
+			money:    map[int]int{1: 70, 2: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Lender", Money: 70})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Borrower", Money: tt.borrower})
+			tt.debt.ReturnDeadline = time.Now().Add(time.Hour)
+			store.AddDebt(tt.debt)
+
+			// Штраф по расписке стоит в очереди с момента выдачи
+			tx, _ := store.Begin()
+			tx.Timers().Schedule("debt", "debt:7", tt.debt.ReturnDeadline, nil)
+			tx.Commit()
+
+			scheduler := workers.NewDebtScheduler(nil, jobs.NewQueue(nil, time.Second))
+			h := NewDebtHandler(nil, store, scheduler)
+			w := perform(t, h.ReturnDebt, tt.caller, gin.Params{{Key: "id", Value: "7"}}, nil)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.status, w.Body.String())
+			}
+			if tt.err != "" {
+				if got := responseError(t, w); got != tt.err {
+					t.Errorf("error = %q, want %q", got, tt.err)
+				}
+			}
+
+			for playerID, want := range tt.money {
+				player, _ := store.Player(playerID)
+				if player.Money != want {
+					t.Errorf("player %d money = %d, want %d", playerID, player.Money, want)
+				}
+			}
+
+			debt, _ := store.Debt(7)
+			_, timer := store.Timers()["debt:7"]
+			if tt.status == http.StatusOK {
+				if !debt.IsReturned {
+					t.Error("debt is not marked returned")
+				}
+				if timer {
+					t.Error("debt penalty timer was not cancelled")
+				}
+			} else if !timer {
+				t.Error("debt penalty timer was cancelled by a failed return")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// internal/handlers/events.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval - как часто отправлять комментарий-пинг, чтобы прокси не закрывали соединение
+const heartbeatInterval = 25 * time.Second
+
+type EventsHandler struct {
+	db     *sql.DB
+	broker *events.Broker
+}
+
+func NewEventsHandler(db *sql.DB, broker *events.Broker) *EventsHandler {
+	return &EventsHandler{db: db, broker: broker}
+}
+
+// Stream отдаёт события игрока через Server-Sent Events.
+// Администратор (даже без персонажа) получает события всех игроков.
+// Сессия перепроверяется на каждом пинге: после выхода или отзыва сессии поток закрывается
+func (h *EventsHandler) Stream(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID, _ := playerIDInterface.(*int)
+	isAdmin := c.GetBool("is_admin")
+
+	if playerID == nil && !isAdmin {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	sub := h.broker.Subscribe(playerID, isAdmin)
+	defer h.broker.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // отключаем буферизацию в nginx
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Первое сообщение - чтобы клиент знал, что подписка активна
+	c.SSEvent("ready", gin.H{"player_id": playerID})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Broker остановлен
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+
+		case <-heartbeat.C:
+			active, err := middleware.SessionActive(h.db, c.GetInt("session_id"), c.GetInt("user_id"))
+			if err != nil {
+				// Временная ошибка базы не должна обрывать поток
+				log.Printf("Events stream: failed to check session: %v", err)
+			} else if !active {
+				c.SSEvent("session_expired", gin.H{"error": "Session expired or revoked"})
+				return false
+			}
+
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		}
+	})
+}
@@ -100,6 +100,7 @@ func (h *FactionHandler) GetAllFactions(c *gin.Context) {
 			f.faction_influence,
 			f.is_composition_visible_to_all,
 			f.leader_player_id,
+			f.treasury,
 			fti.total_influence
 		FROM factions f
 		LEFT JOIN faction_total_influence fti ON f.id = fti.faction_id
@@ -116,6 +117,7 @@ func (h *FactionHandler) GetAllFactions(c *gin.Context) {
 	for rows.Next() {
 		var faction models.FactionResponse
 		var totalInfluence *int
+		var treasury int
 
 		err := rows.Scan(
 			&faction.ID,
@@ -124,6 +126,7 @@ func (h *FactionHandler) GetAllFactions(c *gin.Context) {
 			&faction.FactionInfluence,
 			&faction.IsCompositionVisibleToAll,
 			&faction.LeaderPlayerID,
+			&treasury,
 			&totalInfluence,
 		)
 
@@ -146,6 +149,11 @@ func (h *FactionHandler) GetAllFactions(c *gin.Context) {
 		faction.IsCurrentPlayerLeader = faction.LeaderPlayerID != nil &&
 			*faction.LeaderPlayerID == *playerID
 
+		// Казну видят только участники фракции
+		if faction.IsCurrentPlayerMember {
+			faction.Treasury = &treasury
+		}
+
 		// Определяем, показывать ли состав фракции
 		canSeeComposition := faction.IsCompositionVisibleToAll || faction.IsCurrentPlayerMember
 
@@ -173,6 +181,7 @@ func (h *FactionHandler) GetAllFactions(c *gin.Context) {
 func (h *FactionHandler) getFactionInfo(factionID int, playerID *int) (*models.FactionResponse, error) {
 	var faction models.FactionResponse
 	var totalInfluence *int
+	var treasury int
 
 	err := h.db.QueryRow(`
 		SELECT 
@@ -182,6 +191,7 @@ func (h *FactionHandler) getFactionInfo(factionID int, playerID *int) (*models.F
 			f.faction_influence,
 			f.is_composition_visible_to_all,
 			f.leader_player_id,
+			f.treasury,
 			fti.total_influence
 		FROM factions f
 		LEFT JOIN faction_total_influence fti ON f.id = fti.faction_id
@@ -193,6 +203,7 @@ func (h *FactionHandler) getFactionInfo(factionID int, playerID *int) (*models.F
 		&faction.FactionInfluence,
 		&faction.IsCompositionVisibleToAll,
 		&faction.LeaderPlayerID,
+		&treasury,
 		&totalInfluence,
 	)
 
@@ -207,8 +218,9 @@ func (h *FactionHandler) getFactionInfo(factionID int, playerID *int) (*models.F
 		faction.TotalInfluence = faction.FactionInfluence
 	}
 
-	// Игрок является членом своей фракции
+	// Игрок является членом своей фракции и видит её казну
 	faction.IsCurrentPlayerMember = true
+	faction.Treasury = &treasury
 
 	// Проверяем, является ли игрок лидером
 	faction.IsCurrentPlayerLeader = faction.LeaderPlayerID != nil &&
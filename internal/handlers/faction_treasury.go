@@ -0,0 +1,404 @@
+// internal/handlers/faction_treasury.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Казна фракции: взносы участников, выплаты лидера. Все движения пишутся
+// в money_transactions с типом 'treasury' и ссылкой на фракцию
+
+// treasuryLock - заблокированная фракция игрока
+type treasuryLock struct {
+	FactionID      int
+	Treasury       int
+	LeaderPlayerID *int
+	PlayerName     string
+}
+
+// GetFactionTreasury возвращает казну фракции игрока и последние движения по ней
+func (h *FactionHandler) GetFactionTreasury(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var response models.FactionTreasuryResponse
+	var leaderPlayerID *int
+	err := h.db.QueryRow(`
+		SELECT f.id, f.treasury, f.leader_player_id
+		FROM players p
+		JOIN factions f ON f.id = p.faction_id
+		WHERE p.id = $1
+	`, *playerID).Scan(&response.FactionID, &response.Treasury, &leaderPlayerID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Player is not in a faction"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	response.IsCurrentPlayerLeader = leaderPlayerID != nil && *leaderPlayerID == *playerID
+
+	// Штрафы мастера пишутся с отрицательной суммой, выплаты - с положительной
+	rows, err := h.db.Query(`
+		SELECT
+			mt.id,
+			mt.transaction_type,
+			CASE WHEN mt.to_faction_id = $1 THEN 'in' ELSE 'out' END,
+			CASE WHEN mt.to_faction_id = $1 THEN mt.amount ELSE -ABS(mt.amount) END,
+			p.id,
+			p.character_name,
+			mt.description,
+			mt.created_at
+		FROM money_transactions mt
+		LEFT JOIN players p ON p.id = CASE WHEN mt.to_faction_id = $1 THEN mt.from_player_id ELSE mt.to_player_id END
+		WHERE mt.from_faction_id = $1 OR mt.to_faction_id = $1
+		ORDER BY mt.created_at DESC, mt.id DESC
+		LIMIT $2
+	`, response.FactionID, defaultHistoryLimit)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch treasury transactions"})
+		return
+	}
+	defer rows.Close()
+
+	response.Transactions = make([]models.FactionTreasuryTransaction, 0)
+	for rows.Next() {
+		var entry models.FactionTreasuryTransaction
+		err := rows.Scan(
+			&entry.ID,
+			&entry.TransactionType,
+			&entry.Direction,
+			&entry.Amount,
+			&entry.PlayerID,
+			&entry.PlayerName,
+			&entry.Description,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan treasury transaction"})
+			return
+		}
+		response.Transactions = append(response.Transactions, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DepositToTreasury переводит деньги участника в казну его фракции
+func (h *FactionHandler) DepositToTreasury(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var req models.TreasuryDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	lock, status, msg := lockPlayerTreasury(tx, *playerID)
+	if status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	var money int
+	err = tx.QueryRow(`
+		SELECT money FROM players WHERE id = $1 FOR UPDATE
+	`, *playerID).Scan(&money)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if money < req.Amount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE players SET money = money - $1 WHERE id = $2
+	`, req.Amount, *playerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deduct money"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE factions SET treasury = treasury + $1 WHERE id = $2
+	`, req.Amount, lock.FactionID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction treasury"})
+		return
+	}
+
+	description := fmt.Sprintf("%s deposited %d money to faction treasury", lock.PlayerName, req.Amount)
+	_, err = tx.Exec(`
+		INSERT INTO money_transactions (from_player_id, to_faction_id, amount, transaction_type, reference_id, reference_type, description)
+		VALUES ($1, $2, $3, 'treasury', $2, 'faction', $4)
+	`, *playerID, lock.FactionID, req.Amount, description)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		return
+	}
+
+	err = events.PublishBalances(tx, *playerID)
+	if err == nil {
+		err = events.PublishTreasury(tx, lock.FactionID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Money deposited to faction treasury",
+		"amount":      req.Amount,
+		"treasury":    lock.Treasury + req.Amount,
+		"new_balance": money - req.Amount,
+	})
+}
+
+// WithdrawFromTreasury - лидер забирает деньги из казны себе
+func (h *FactionHandler) WithdrawFromTreasury(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var req models.TreasuryWithdrawRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	h.payFromTreasury(c, *playerID, *playerID, req.Amount, nil)
+}
+
+// PayFromTreasury - лидер платит из казны любому игроку
+func (h *FactionHandler) PayFromTreasury(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var req models.TreasuryPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	h.payFromTreasury(c, *playerID, req.ToPlayerID, req.Amount, req.Reason)
+}
+
+// payFromTreasury списывает amount из казны фракции лидера leaderID и зачисляет игроку toPlayerID
+func (h *FactionHandler) payFromTreasury(c *gin.Context, leaderID, toPlayerID, amount int, reason *string) {
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	lock, status, msg := lockPlayerTreasury(tx, leaderID)
+	if status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	if lock.LeaderPlayerID == nil || *lock.LeaderPlayerID != leaderID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the faction leader can spend the treasury"})
+		return
+	}
+
+	if lock.Treasury < amount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds in faction treasury"})
+		return
+	}
+
+	// Блокируем получателя
+	var recipientName string
+	err = tx.QueryRow(`
+		SELECT character_name FROM players WHERE id = $1 FOR UPDATE
+	`, toPlayerID).Scan(&recipientName)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient player not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE factions SET treasury = treasury - $1 WHERE id = $2
+	`, amount, lock.FactionID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update faction treasury"})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE players SET money = money + $1 WHERE id = $2
+	`, amount, toPlayerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add money to recipient"})
+		return
+	}
+
+	var description string
+	if toPlayerID == leaderID {
+		description = fmt.Sprintf("%s withdrew %d money from faction treasury", lock.PlayerName, amount)
+	} else {
+		description = fmt.Sprintf("%s paid %d money from faction treasury to %s", lock.PlayerName, amount, recipientName)
+	}
+	if reason != nil && strings.TrimSpace(*reason) != "" {
+		description += ": " + strings.TrimSpace(*reason)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO money_transactions (from_faction_id, to_player_id, amount, transaction_type, reference_id, reference_type, description)
+		VALUES ($1, $2, $3, 'treasury', $1, 'faction', $4)
+	`, lock.FactionID, toPlayerID, amount, description)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		return
+	}
+
+	err = events.PublishBalances(tx, toPlayerID)
+	if err == nil {
+		err = events.PublishTreasury(tx, lock.FactionID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Money paid from faction treasury",
+		"amount":       amount,
+		"to_player_id": toPlayerID,
+		"treasury":     lock.Treasury - amount,
+	})
+}
+
+// lockPlayerTreasury блокирует фракцию игрока. Фракция блокируется раньше игроков,
+// как и в AdjustFactionBalance. Возвращает HTTP-статус и текст ошибки (0 - успех)
+func lockPlayerTreasury(tx *sql.Tx, playerID int) (treasuryLock, int, string) {
+	var lock treasuryLock
+	var factionID *int
+	err := tx.QueryRow(`
+		SELECT faction_id, character_name FROM players WHERE id = $1
+	`, playerID).Scan(&factionID, &lock.PlayerName)
+
+	if err != nil {
+		return lock, http.StatusInternalServerError, "Database error"
+	}
+
+	if factionID == nil {
+		return lock, http.StatusBadRequest, "Player is not in a faction"
+	}
+
+	err = tx.QueryRow(`
+		SELECT id, treasury, leader_player_id FROM factions WHERE id = $1 FOR UPDATE
+	`, *factionID).Scan(&lock.FactionID, &lock.Treasury, &lock.LeaderPlayerID)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return lock, http.StatusNotFound, "Faction not found"
+		}
+		return lock, http.StatusInternalServerError, "Database error"
+	}
+
+	// Игрок мог сменить фракцию, пока мы ждали блокировку
+	var stillMember bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1 AND faction_id = $2)
+	`, playerID, lock.FactionID).Scan(&stillMember)
+
+	if err != nil {
+		return lock, http.StatusInternalServerError, "Database error"
+	}
+
+	if !stillMember {
+		return lock, http.StatusConflict, "Player has left the faction"
+	}
+
+	return lock, 0, ""
+}
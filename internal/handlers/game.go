@@ -22,13 +22,14 @@ func NewGameHandler(db *sql.DB) *GameHandler {
 
 // GetGameStatus возвращает текущий статус игры
 func (h *GameHandler) GetGameStatus(c *gin.Context) {
-	var gameStarted, gameEnded *time.Time
+	var gameStarted, gameEnded, pausedAt *time.Time
+	var pausedSeconds int
 	err := h.db.QueryRow(`
-		SELECT game_started_at, game_ended_at
+		SELECT game_started_at, game_ended_at, paused_at, total_paused_seconds
 		FROM game_timeline
 		ORDER BY id DESC
 		LIMIT 1
-	`).Scan(&gameStarted, &gameEnded)
+	`).Scan(&gameStarted, &gameEnded, &pausedAt, &pausedSeconds)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -44,22 +45,37 @@ func (h *GameHandler) GetGameStatus(c *gin.Context) {
 	var status string
 	var duration *string
 
-	if gameStarted != nil && gameEnded == nil {
+	if gameStarted != nil && gameEnded == nil && pausedAt != nil {
+		// На паузе время игры стоит с момента paused_at
+		status = "paused"
+		duration = playedDuration(*gameStarted, *pausedAt, pausedSeconds)
+	} else if gameStarted != nil && gameEnded == nil {
 		status = "running"
-		d := time.Since(*gameStarted).String()
-		duration = &d
+		duration = playedDuration(*gameStarted, time.Now(), pausedSeconds)
 	} else if gameStarted != nil && gameEnded != nil {
+		// При завершении незакрытая пауза уже добавлена к total_paused_seconds
 		status = "ended"
-		d := gameEnded.Sub(*gameStarted).String()
-		duration = &d
+		duration = playedDuration(*gameStarted, *gameEnded, pausedSeconds)
 	} else {
 		status = "not_started"
 	}
 
 	c.JSON(http.StatusOK, models.GameStatusResponse{
-		Status:    status,
-		StartedAt: gameStarted,
-		EndedAt:   gameEnded,
-		Duration:  duration,
+		Status:        status,
+		StartedAt:     gameStarted,
+		EndedAt:       gameEnded,
+		PausedAt:      pausedAt,
+		PausedSeconds: pausedSeconds,
+		Duration:      duration,
 	})
 }
+
+// playedDuration - время игры от начала до until без учёта завершённых пауз
+func playedDuration(startedAt, until time.Time, pausedSeconds int) *string {
+	d := until.Sub(startedAt) - time.Duration(pausedSeconds)*time.Second
+	if d < 0 {
+		d = 0
+	}
+	str := d.String()
+	return &str
+}
@@ -0,0 +1,31 @@
+// internal/handlers/game_test.go
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayedDuration(t *testing.T) {
+	startedAt := time.Date(2026, 12, 31, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		until         time.Time
+		pausedSeconds int
+		want          string
+	}{
+		{name: "no pauses", until: startedAt.Add(2 * time.Hour), want: "2h0m0s"},
+		{name: "finished pauses subtracted", until: startedAt.Add(2 * time.Hour), pausedSeconds: 1800, want: "1h30m0s"},
+		{name: "paused right after start", until: startedAt, want: "0s"},
+		{name: "never negative", until: startedAt.Add(time.Minute), pausedSeconds: 120, want: "0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := *playedDuration(startedAt, tt.until, tt.pausedSeconds); got != tt.want {
+				t.Errorf("duration = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
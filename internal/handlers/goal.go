@@ -4,6 +4,7 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
+	"new-year-role-game-backend/internal/events"
 	"new-year-role-game-backend/internal/models"
 	"strconv"
 	"time"
@@ -46,9 +47,13 @@ func (h *GoalHandler) GetPersonalGoals(c *gin.Context) {
 			g.completed_at,
 			g.created_at,
 			pvg.is_visible,
-			pvg.is_locked
+			pvg.is_locked,
+			rg.round_id,
+			COALESCE(rg.is_accessible = false OR rr.status <> 'active', false) AS race_closed
 		FROM goals g
 		LEFT JOIN player_visible_goals pvg ON g.id = pvg.id
+		LEFT JOIN goal_race_round_goals rg ON rg.goal_id = g.id
+		LEFT JOIN goal_race_rounds rr ON rr.id = rg.round_id
 		WHERE g.goal_type = 'personal' AND g.player_id = $1
 		ORDER BY g.is_completed ASC, g.created_at ASC
 	`, *playerID)
@@ -62,6 +67,7 @@ func (h *GoalHandler) GetPersonalGoals(c *gin.Context) {
 	goals := make([]models.GoalWithLockStatus, 0)
 	for rows.Next() {
 		var goal models.GoalWithLockStatus
+		var raceClosed bool
 		err := rows.Scan(
 			&goal.ID,
 			&goal.Title,
@@ -74,12 +80,19 @@ func (h *GoalHandler) GetPersonalGoals(c *gin.Context) {
 			&goal.CreatedAt,
 			&goal.IsVisible,
 			&goal.IsLocked,
+			&goal.RaceRoundID,
+			&raceClosed,
 		)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan goal"})
 			return
 		}
 
+		// Цель гонки блокируется, когда раунд завершился
+		if raceClosed {
+			goal.IsLocked = true
+		}
+
 		// Показываем только видимые цели
 		if goal.IsVisible {
 			// Получаем информацию о зависимостях (как выполненных, так и невыполненных)
@@ -375,6 +388,18 @@ func (h *GoalHandler) ToggleGoalCompletion(c *gin.Context) {
 				return
 			}
 		}
+
+		// Цели гонки можно менять только пока их раунд активен
+		available, err := lockRaceGoal(tx, goalID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check goal race status"})
+			return
+		}
+
+		if !available {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Goal race round is over, this goal is no longer available"})
+			return
+		}
 	} else if goal.GoalType == "faction" {
 		// Командную цель может выполнять только лидер фракции
 		var isLeader bool
@@ -499,6 +524,28 @@ func (h *GoalHandler) ToggleGoalCompletion(c *gin.Context) {
 	// ВАЖНО: После обновления is_completed срабатывает триггер unlock_goal_dependencies_on_goal_completion
 	// который автоматически разблокирует зависимости других целей от этой цели
 
+	// Проверяем, не выиграл ли игрок раунд гонки целей
+	raceRoundWon := false
+	if isCompleted && goal.GoalType == "personal" {
+		raceRoundWon, err = finishRaceRoundIfWon(tx, goalID, *playerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal race"})
+			return
+		}
+	}
+
+	// Баланс меняется только у личных целей, а новые цели могли открыться в любом случае
+	if goal.GoalType == "personal" {
+		err = events.PublishBalances(tx, *playerID)
+	}
+	if err == nil {
+		err = events.PublishGoalUnlocks(tx)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
 	// Фиксируем транзакцию
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
@@ -513,5 +560,6 @@ func (h *GoalHandler) ToggleGoalCompletion(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":          message,
 		"influence_change": influenceChange,
+		"race_round_won":   raceRoundWon,
 	})
 }
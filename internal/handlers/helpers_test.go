@@ -0,0 +1,53 @@
+// internal/handlers/helpers_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// perform вызывает handler от имени игрока playerID с параметрами пути и JSON-телом
+func perform(t *testing.T, handler gin.HandlerFunc, playerID int, params gin.Params, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("failed to marshal request body: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(payload))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	c.Set("player_id", &playerID)
+
+	handler(c)
+	return w
+}
+
+// responseError возвращает поле error из ответа
+func responseError(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return resp.Error
+}
+
+func intPtr(v int) *int {
+	return &v
+}
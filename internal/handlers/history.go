@@ -0,0 +1,282 @@
+// internal/handlers/history.go
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// ledgerQuery объединяет три журнала в одну ленту с точки зрения игрока $1.
+// Сумма денег со стороны отправителя всегда отрицательная: штрафы пишутся
+// в money_transactions с отрицательной суммой, переводы - с положительной
+const ledgerQuery = `
+	WITH ledger AS (
+		SELECT
+			'money' AS kind,
+			mt.id,
+			mt.transaction_type,
+			CASE WHEN mt.to_player_id = $1 THEN 'in' ELSE 'out' END AS direction,
+			CASE WHEN mt.to_player_id = $1 THEN mt.amount ELSE -ABS(mt.amount) END AS amount,
+			NULL::INTEGER AS item_id,
+			NULL::VARCHAR AS item_name,
+			CASE WHEN mt.to_player_id = $1 THEN mt.from_player_id ELSE mt.to_player_id END AS counterparty_id,
+			mt.reference_id,
+			mt.reference_type,
+			mt.description,
+			mt.created_at
+		FROM money_transactions mt
+		WHERE mt.from_player_id = $1 OR mt.to_player_id = $1
+
+		UNION ALL
+
+		SELECT
+			'item',
+			it.id,
+			it.transaction_type,
+			CASE WHEN it.to_player_id = $1 THEN 'in' ELSE 'out' END,
+			NULL::INTEGER,
+			it.item_id,
+			i.name,
+			CASE WHEN it.to_player_id = $1 THEN it.from_player_id ELSE it.to_player_id END,
+			it.reference_id,
+			it.reference_type,
+			it.description,
+			it.created_at
+		FROM item_transactions it
+		LEFT JOIN items i ON i.id = it.item_id
+		WHERE it.from_player_id = $1 OR it.to_player_id = $1
+
+		UNION ALL
+
+		SELECT
+			'influence',
+			inf.id,
+			inf.transaction_type,
+			CASE WHEN inf.amount >= 0 THEN 'in' ELSE 'out' END,
+			inf.amount,
+			NULL::INTEGER,
+			NULL::VARCHAR,
+			NULL::INTEGER,
+			inf.reference_id,
+			inf.reference_type,
+			inf.description,
+			inf.created_at
+		FROM influence_transactions inf
+		WHERE inf.player_id = $1
+	)
+`
+
+// ledgerFilter - условия, общие для выборки и подсчёта ($2..$5)
+const ledgerFilter = `
+	WHERE ($2::VARCHAR IS NULL OR l.kind = $2)
+	  AND ($3::VARCHAR IS NULL OR l.transaction_type = $3)
+	  AND ($4::VARCHAR IS NULL OR l.reference_type = $4)
+	  AND ($5::INTEGER IS NULL OR l.reference_id = $5)
+`
+
+// historyFilter - параметры запроса истории
+type historyFilter struct {
+	Kind            *string
+	TransactionType *string
+	ReferenceType   *string
+	ReferenceID     *int
+	Limit           int
+	Offset          int
+}
+
+type HistoryHandler struct {
+	db *sql.DB
+}
+
+func NewHistoryHandler(db *sql.DB) *HistoryHandler {
+	return &HistoryHandler{db: db}
+}
+
+// GetPlayerHistory возвращает историю денег, предметов и влияния текущего игрока
+func (h *HistoryHandler) GetPlayerHistory(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	h.respondWithHistory(c, *playerID)
+}
+
+// GetPlayerHistoryAdmin возвращает историю любого игрока (для разбора споров)
+func (h *HistoryHandler) GetPlayerHistoryAdmin(c *gin.Context) {
+	playerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid player ID"})
+		return
+	}
+
+	var playerExists bool
+	err = h.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)`, playerID).Scan(&playerExists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if !playerExists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+		return
+	}
+
+	h.respondWithHistory(c, playerID)
+}
+
+func (h *HistoryHandler) respondWithHistory(c *gin.Context, playerID int) {
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, total, err := fetchPlayerHistory(h.db, playerID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PlayerHistoryResponse{
+		PlayerID: playerID,
+		Entries:  entries,
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+}
+
+// parseHistoryFilter разбирает ?kind=&type=&reference_type=&reference_id=&limit=&offset=
+func parseHistoryFilter(c *gin.Context) (historyFilter, error) {
+	filter := historyFilter{Limit: defaultHistoryLimit}
+
+	if kind := c.Query("kind"); kind != "" {
+		if kind != "money" && kind != "item" && kind != "influence" {
+			return filter, fmt.Errorf("Invalid kind, expected money, item or influence")
+		}
+		filter.Kind = &kind
+	}
+
+	if transactionType := c.Query("type"); transactionType != "" {
+		filter.TransactionType = &transactionType
+	}
+
+	if referenceType := c.Query("reference_type"); referenceType != "" {
+		filter.ReferenceType = &referenceType
+	}
+
+	if referenceIDParam := c.Query("reference_id"); referenceIDParam != "" {
+		referenceID, err := strconv.Atoi(referenceIDParam)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid reference ID")
+		}
+		filter.ReferenceID = &referenceID
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return filter, fmt.Errorf("Invalid limit")
+		}
+		if limit > maxHistoryLimit {
+			limit = maxHistoryLimit
+		}
+		filter.Limit = limit
+	}
+
+	if offsetParam := c.Query("offset"); offsetParam != "" {
+		offset, err := strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("Invalid offset")
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// fetchPlayerHistory возвращает страницу истории игрока (новые записи первыми) и общее число записей
+func fetchPlayerHistory(db *sql.DB, playerID int, filter historyFilter) ([]models.LedgerEntry, int, error) {
+	var total int
+	err := db.QueryRow(ledgerQuery+`
+		SELECT COUNT(*) FROM ledger l
+	`+ledgerFilter, playerID, filter.Kind, filter.TransactionType, filter.ReferenceType, filter.ReferenceID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(ledgerQuery+`
+		SELECT
+			l.kind,
+			l.id,
+			l.transaction_type,
+			l.direction,
+			l.amount,
+			l.item_id,
+			l.item_name,
+			l.counterparty_id,
+			p.character_name,
+			l.reference_id,
+			l.reference_type,
+			l.description,
+			l.created_at
+		FROM ledger l
+		LEFT JOIN players p ON p.id = l.counterparty_id
+	`+ledgerFilter+`
+		ORDER BY l.created_at DESC, l.kind, l.id DESC
+		LIMIT $6 OFFSET $7
+	`, playerID, filter.Kind, filter.TransactionType, filter.ReferenceType, filter.ReferenceID,
+		filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.LedgerEntry, 0)
+	for rows.Next() {
+		var entry models.LedgerEntry
+		err := rows.Scan(
+			&entry.Kind,
+			&entry.ID,
+			&entry.TransactionType,
+			&entry.Direction,
+			&entry.Amount,
+			&entry.ItemID,
+			&entry.ItemName,
+			&entry.CounterpartyID,
+			&entry.CounterpartyName,
+			&entry.ReferenceID,
+			&entry.ReferenceType,
+			&entry.Description,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
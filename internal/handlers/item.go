@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"new-year-role-game-backend/internal/events"
 	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
 	"new-year-role-game-backend/internal/workers"
 	"time"
 
@@ -15,12 +17,14 @@ import (
 
 type ItemHandlerWithScheduler struct {
 	db        *sql.DB
+	store     storage.Store
 	scheduler *workers.EffectsScheduler
 }
 
-func NewItemHandlerWithScheduler(db *sql.DB, scheduler *workers.EffectsScheduler) *ItemHandlerWithScheduler {
+func NewItemHandlerWithScheduler(db *sql.DB, store storage.Store, scheduler *workers.EffectsScheduler) *ItemHandlerWithScheduler {
 	return &ItemHandlerWithScheduler{
 		db:        db,
+		store:     store,
 		scheduler: scheduler,
 	}
 }
@@ -43,6 +47,7 @@ func (h *ItemHandlerWithScheduler) GetPlayerInventory(c *gin.Context) {
 	rows, err := h.db.Query(`
 		SELECT 
 			i.id,
+			i.template_id,
 			i.name,
 			i.description,
 			pi.acquired_at
@@ -63,6 +68,7 @@ func (h *ItemHandlerWithScheduler) GetPlayerInventory(c *gin.Context) {
 		var item models.Item
 		err := rows.Scan(
 			&item.ID,
+			&item.TemplateID,
 			&item.Name,
 			&item.Description,
 			&item.AcquiredAt,
@@ -101,7 +107,7 @@ func (h *ItemHandlerWithScheduler) getItemEffects(itemID int) ([]models.Effect,
 			e.generated_resource,
 			e.operation,
 			e.value,
-			e.spawned_item_id,
+			e.spawned_template_id,
 			e.period_seconds
 		FROM item_effects ie
 		JOIN effects e ON ie.effect_id = e.id
@@ -124,7 +130,7 @@ func (h *ItemHandlerWithScheduler) getItemEffects(itemID int) ([]models.Effect,
 			&effect.GeneratedResource,
 			&effect.Operation,
 			&effect.Value,
-			&effect.SpawnedItemID,
+			&effect.SpawnedTemplateID,
 			&effect.PeriodSeconds,
 		)
 		if err != nil {
@@ -167,7 +173,7 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 	}
 
 	// Начинаем транзакцию
-	tx, err := h.db.Begin()
+	tx, err := h.store.Begin()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
@@ -175,11 +181,7 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 	defer tx.Rollback()
 
 	// Проверяем, что получатель существует
-	var recipientExists bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)
-	`, req.ToPlayerID).Scan(&recipientExists)
-
+	recipientExists, err := tx.Players().Exists(req.ToPlayerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -191,14 +193,7 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 	}
 
 	// Проверяем, что у игрока есть этот предмет
-	var hasItem bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM player_items 
-			WHERE player_id = $1 AND item_id = $2
-		)
-	`, *playerID, req.ItemID).Scan(&hasItem)
-
+	hasItem, err := tx.Items().IsOwnedBy(*playerID, req.ItemID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
@@ -210,75 +205,42 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 	}
 
 	// Получаем название предмета для описания транзакции
-	var itemName string
-	err = tx.QueryRow(`
-		SELECT name FROM items WHERE id = $1
-	`, req.ItemID).Scan(&itemName)
-
+	item, err := tx.Items().Get(req.ItemID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item info"})
 		return
 	}
 
-	// Удаляем предмет у отправителя
-	_, err = tx.Exec(`
-		DELETE FROM player_items 
-		WHERE player_id = $1 AND item_id = $2
-	`, *playerID, req.ItemID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from inventory"})
-		return
-	}
-
-	// Добавляем предмет получателю
-	_, err = tx.Exec(`
-		INSERT INTO player_items (player_id, item_id)
-		VALUES ($1, $2)
-		ON CONFLICT (player_id, item_id) DO NOTHING
-	`, req.ToPlayerID, req.ItemID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to recipient"})
+	// Передаём экземпляр получателю (у экземпляра всегда один владелец)
+	if err = tx.Items().Move(req.ItemID, *playerID, req.ToPlayerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer item"})
 		return
 	}
 
-	now := time.Now()
-
-	// Инициализируем таймеры эффектов для нового владельца
-	_, err = tx.Exec(`
-		INSERT INTO item_effect_executions (player_id, item_id, effect_id, last_executed_at)
-		SELECT $1, ie.item_id, ie.effect_id, $2
-		FROM item_effects ie
-		WHERE ie.item_id = $3
-		ON CONFLICT (player_id, item_id, effect_id) 
-		DO UPDATE SET last_executed_at = $2
-	`, req.ToPlayerID, now, req.ItemID)
-
-	if err != nil {
+	// Переносим таймеры эффектов новому владельцу в той же транзакции
+	if err = h.scheduler.MoveItemEffects(tx, *playerID, req.ToPlayerID, req.ItemID, time.Now()); err != nil {
+		log.Printf("Failed to move effect timers for item %d: %v", req.ItemID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize effect timers for recipient"})
 		return
 	}
 
-	// Удаляем записи о выполнении эффектов у старого владельца
-	_, err = tx.Exec(`
-		DELETE FROM item_effect_executions
-		WHERE player_id = $1 AND item_id = $2
-	`, *playerID, req.ItemID)
-
+	// Записываем транзакцию
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		FromPlayerID:    playerID,
+		ToPlayerID:      &req.ToPlayerID,
+		ItemID:          req.ItemID,
+		TransactionType: "transfer",
+		Description:     "Item transfer: " + item.Name,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clean up old effect timers"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
 		return
 	}
 
-	// Записываем транзакцию
-	_, err = tx.Exec(`
-		INSERT INTO item_transactions (from_player_id, to_player_id, item_id, transaction_type, description)
-		VALUES ($1, $2, $3, 'transfer', $4)
-	`, *playerID, req.ToPlayerID, req.ItemID, "Item transfer: "+itemName)
-
+	// Уведомляем обоих игроков (события уйдут только после фиксации)
+	err = tx.Events().PublishItemMoved(req.ItemID, playerID, &req.ToPlayerID, "transfer")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record transaction"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
 		return
 	}
 
@@ -288,13 +250,6 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 		return
 	}
 
-	// ВАЖНО: Обновляем таймеры в scheduler
-	// 1. Отменяем таймеры у старого владельца
-	h.scheduler.CancelAllEffectsForItem(*playerID, req.ItemID)
-
-	// 2. Создаём новые таймеры для нового владельца
-	h.scheduleItemEffectsForPlayer(req.ToPlayerID, req.ItemID, now)
-
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "Item transferred successfully",
 		"item_id":      req.ItemID,
@@ -302,34 +257,6 @@ func (h *ItemHandlerWithScheduler) TransferItem(c *gin.Context) {
 	})
 }
 
-// scheduleItemEffectsForPlayer создаёт таймеры для всех эффектов предмета
-func (h *ItemHandlerWithScheduler) scheduleItemEffectsForPlayer(playerID, itemID int, baseTime time.Time) {
-	// Получаем все эффекты предмета
-	rows, err := h.db.Query(`
-		SELECT e.id, e.period_seconds
-		FROM item_effects ie
-		JOIN effects e ON ie.effect_id = e.id
-		WHERE ie.item_id = $1
-	`, itemID)
-	if err != nil {
-		log.Printf("Failed to load effects for item %d: %v", itemID, err)
-		return
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var effectID, periodSeconds int
-		if err := rows.Scan(&effectID, &periodSeconds); err != nil {
-			log.Printf("Failed to scan effect: %v", err)
-			continue
-		}
-
-		// Следующее выполнение = baseTime + period
-		nextExecutionTime := baseTime.Add(time.Duration(periodSeconds) * time.Second)
-		h.scheduler.ScheduleEffect(playerID, itemID, effectID, nextExecutionTime, periodSeconds)
-	}
-}
-
 // TransferMoney переводит деньги другому игроку
 func (h *ItemHandlerWithScheduler) TransferMoney(c *gin.Context) {
 	playerIDInterface, exists := c.Get("player_id")
@@ -440,6 +367,11 @@ func (h *ItemHandlerWithScheduler) TransferMoney(c *gin.Context) {
 		return
 	}
 
+	if err = events.PublishBalances(tx, *playerID, req.ToPlayerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish events"})
+		return
+	}
+
 	// Фиксируем транзакцию
 	if err = tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
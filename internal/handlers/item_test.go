@@ -0,0 +1,82 @@
+// internal/handlers/item_test.go
+package handlers
+
+import (
+	"net/http"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"new-year-role-game-backend/internal/workers"
+	"testing"
+	"time"
+)
+
+func TestTransferItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		itemID int
+		to     int
+		status int
+		err    string
+		owner  int
+	}{
+		{name: "success", itemID: 10, to: 2, status: http.StatusOK, owner: 2},
+		{name: "to yourself", itemID: 10, to: 1, status: http.StatusBadRequest,
+			err: "Cannot transfer item to yourself", owner: 1},
+		{name: "unknown recipient", itemID: 10, to: 99, status: http.StatusNotFound,
+			err: "Recipient player not found", owner: 1},
+		{name: "item of another player", itemID: 20, to: 2, status: http.StatusNotFound,
+			err: "Item not found in your inventory", owner: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Alice"})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Bob"})
+			store.AddPlayer(storage.Player{ID: 3, CharacterName: "Carol"})
+			store.AddEffect(storage.Effect{ID: 5, EffectType: "generate_money", PeriodSeconds: 60})
+			store.AddItem(storage.Item{ID: 10, Name: "Lamp"}, 1, 5)
+			store.AddItem(storage.Item{ID: 20, Name: "Ring"}, 3)
+
+			// Таймер эффекта у текущего владельца
+			tx, _ := store.Begin()
+			tx.Timers().Schedule("effect", "effect:1:10:5", time.Now().Add(time.Minute), nil)
+			tx.Commit()
+
+			scheduler := workers.NewEffectsScheduler(nil, jobs.NewQueue(nil, time.Second))
+			h := NewItemHandlerWithScheduler(nil, store, scheduler)
+			w := perform(t, h.TransferItem, 1, nil, models.TransferItemRequest{ItemID: tt.itemID, ToPlayerID: tt.to})
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body: %s)", w.Code, tt.status, w.Body.String())
+			}
+			if tt.err != "" {
+				if got := responseError(t, w); got != tt.err {
+					t.Errorf("error = %q, want %q", got, tt.err)
+				}
+			}
+
+			if owner := store.Owner(tt.itemID); owner != tt.owner {
+				t.Errorf("item %d owner = %d, want %d", tt.itemID, owner, tt.owner)
+			}
+
+			// Таймеры эффектов переходят к новому владельцу вместе с предметом
+			timers := store.Timers()
+			_, oldTimer := timers["effect:1:10:5"]
+			_, newTimer := timers["effect:2:10:5"]
+			if moved := tt.status == http.StatusOK; oldTimer == moved || newTimer != moved {
+				t.Errorf("effect timers: old = %v, new = %v, want moved = %v", oldTimer, newTimer, moved)
+			}
+
+			wantTransactions := 0
+			if tt.status == http.StatusOK {
+				wantTransactions = 1
+			}
+			if got := len(store.ItemTransactions()); got != wantTransactions {
+				t.Errorf("item transactions = %d, want %d", got, wantTransactions)
+			}
+		})
+	}
+}
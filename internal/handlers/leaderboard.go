@@ -0,0 +1,234 @@
+// internal/handlers/leaderboard.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// compositionVisibleSQL - виден ли состав фракции f запрашивающему:
+// $1 - запрашивает администратор, $2 - фракция запрашивающего игрока (NULL - нет фракции)
+const compositionVisibleSQL = `($1 OR COALESCE(f.is_composition_visible_to_all, false) OR f.id IS NOT DISTINCT FROM $2)`
+
+type LeaderboardHandler struct {
+	db *sql.DB
+}
+
+func NewLeaderboardHandler(db *sql.DB) *LeaderboardHandler {
+	return &LeaderboardHandler{db: db}
+}
+
+// GetLeaderboard возвращает текущие места фракций и игроков и историю по снимкам для графиков.
+// Принадлежность к фракции со скрытым составом видна только её участникам и администратору:
+// остальным не отдаются её деньги, численность и история, а её участники выглядят нейтральными.
+// Фильтр применяется в запросах, чтобы скрытые данные не попадали в handler
+func (h *LeaderboardHandler) GetLeaderboard(c *gin.Context) {
+	var since time.Time
+	if sinceParam := c.Query("since"); sinceParam != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected RFC3339 time"})
+			return
+		}
+		since = parsed
+	}
+
+	isAdmin := false
+	if value, exists := c.Get("is_admin"); exists {
+		isAdmin, _ = value.(bool)
+	}
+
+	// Фракция запрашивающего игрока (у администратора без персонажа её нет)
+	var viewerFactionID *int
+	if value, exists := c.Get("player_id"); exists {
+		if playerID, _ := value.(*int); playerID != nil {
+			err := h.db.QueryRow(`SELECT faction_id FROM players WHERE id = $1`, *playerID).Scan(&viewerFactionID)
+			if err != nil && err != sql.ErrNoRows {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+		}
+	}
+
+	response := models.LeaderboardResponse{
+		UpdatedAt:     time.Now(),
+		Factions:      make([]models.FactionStanding, 0),
+		Players:       make([]models.PlayerStanding, 0),
+		FactionSeries: make([]models.FactionSeries, 0),
+		PlayerSeries:  make([]models.PlayerSeries, 0),
+	}
+
+	// Текущие итоги фракций (как faction_total_influence, плюс деньги и численность)
+	rows, err := h.db.Query(`
+		SELECT
+			f.id,
+			f.name,
+			COALESCE(f.faction_influence, 0),
+			`+compositionVisibleSQL+` AS visible,
+			COALESCE(f.faction_influence, 0) + COALESCE(SUM(p.influence), 0) AS total_influence,
+			CASE WHEN `+compositionVisibleSQL+` THEN COALESCE(SUM(p.money), 0) END,
+			CASE WHEN `+compositionVisibleSQL+` THEN COUNT(p.id) END
+		FROM factions f
+		LEFT JOIN players p ON p.faction_id = f.id
+		GROUP BY f.id, f.name, f.faction_influence, f.is_composition_visible_to_all
+		ORDER BY total_influence DESC, f.name
+	`, isAdmin, viewerFactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch factions"})
+		return
+	}
+	defer rows.Close()
+
+	// История есть только у фракций, состав которых виден: по изменениям итога скрытой фракции
+	// и истории игроков можно было бы вычислить её участников
+	seriesIndex := make(map[int]int)
+	for rows.Next() {
+		var standing models.FactionStanding
+		var visible bool
+		if err := rows.Scan(&standing.FactionID, &standing.Name, &standing.FactionInfluence, &visible,
+			&standing.TotalInfluence, &standing.PlayersMoney, &standing.MembersCount); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan faction"})
+			return
+		}
+
+		standing.Rank = len(response.Factions) + 1
+		if last := len(response.Factions) - 1; last >= 0 && response.Factions[last].TotalInfluence == standing.TotalInfluence {
+			standing.Rank = response.Factions[last].Rank
+		}
+		response.Factions = append(response.Factions, standing)
+
+		if visible {
+			seriesIndex[standing.FactionID] = len(response.FactionSeries)
+			response.FactionSeries = append(response.FactionSeries, models.FactionSeries{
+				FactionID: standing.FactionID,
+				Name:      standing.Name,
+				Points:    make([]models.FactionSeriesPoint, 0),
+			})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch factions"})
+		return
+	}
+
+	// Текущие места игроков.
+	// ВАЖНО: участник скрытой фракции выглядит как нейтральный, иначе состав можно собрать по игрокам
+	rows, err = h.db.Query(`
+		SELECT
+			p.id,
+			p.character_name,
+			CASE WHEN `+compositionVisibleSQL+` THEN p.faction_id END,
+			COALESCE(p.money, 0),
+			COALESCE(p.influence, 0)
+		FROM players p
+		LEFT JOIN factions f ON f.id = p.faction_id
+		ORDER BY p.influence DESC, p.money DESC, p.character_name
+	`, isAdmin, viewerFactionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch players"})
+		return
+	}
+	defer rows.Close()
+
+	playerIndex := make(map[int]int)
+	for rows.Next() {
+		var standing models.PlayerStanding
+		if err := rows.Scan(&standing.PlayerID, &standing.CharacterName, &standing.FactionID,
+			&standing.Money, &standing.Influence); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan player"})
+			return
+		}
+
+		standing.Rank = len(response.Players) + 1
+		if last := len(response.Players) - 1; last >= 0 && response.Players[last].Influence == standing.Influence {
+			standing.Rank = response.Players[last].Rank
+		}
+
+		playerIndex[standing.PlayerID] = len(response.Players)
+		response.Players = append(response.Players, standing)
+		response.PlayerSeries = append(response.PlayerSeries, models.PlayerSeries{
+			PlayerID:      standing.PlayerID,
+			CharacterName: standing.CharacterName,
+			FactionID:     standing.FactionID,
+			Points:        make([]models.PlayerSeriesPoint, 0),
+		})
+	}
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch players"})
+		return
+	}
+
+	// История фракций по снимкам (только фракций с видимым составом)
+	rows, err = h.db.Query(`
+		SELECT h.faction_id, s.taken_at, h.total_influence, h.players_money
+		FROM faction_standings_history h
+		JOIN leaderboard_snapshots s ON s.id = h.snapshot_id
+		JOIN factions f ON f.id = h.faction_id
+		WHERE s.taken_at >= $3 AND `+compositionVisibleSQL+`
+		ORDER BY s.taken_at, h.faction_id
+	`, isAdmin, viewerFactionID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch faction history"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var factionID int
+		var point models.FactionSeriesPoint
+		if err := rows.Scan(&factionID, &point.TakenAt, &point.TotalInfluence, &point.PlayersMoney); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan faction history"})
+			return
+		}
+
+		index, exists := seriesIndex[factionID]
+		if !exists {
+			continue
+		}
+		response.FactionSeries[index].Points = append(response.FactionSeries[index].Points, point)
+	}
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch faction history"})
+		return
+	}
+
+	// История игроков по снимкам. Фракция игрока в прошлом не отдаётся:
+	// по ней можно было бы восстановить состав скрытой фракции
+	rows, err = h.db.Query(`
+		SELECT h.player_id, s.taken_at, h.money, h.influence
+		FROM player_standings_history h
+		JOIN leaderboard_snapshots s ON s.id = h.snapshot_id
+		WHERE s.taken_at >= $1
+		ORDER BY s.taken_at, h.player_id
+	`, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch player history"})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var playerID int
+		var point models.PlayerSeriesPoint
+		if err := rows.Scan(&playerID, &point.TakenAt, &point.Money, &point.Influence); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan player history"})
+			return
+		}
+
+		index, exists := playerIndex[playerID]
+		if !exists {
+			continue
+		}
+		response.PlayerSeries[index].Points = append(response.PlayerSeries[index].Points, point)
+	}
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch player history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
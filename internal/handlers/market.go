@@ -0,0 +1,389 @@
+// internal/handlers/market.go
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/market"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// MarketHandler - рынок предметов. Правила хранения предмета и расчёта
+// живут в market.Service, который вызывает и scheduler
+type MarketHandler struct {
+	db     *sql.DB
+	store  storage.Store
+	market *market.Service
+}
+
+func NewMarketHandler(db *sql.DB, store storage.Store, service *market.Service) *MarketHandler {
+	return &MarketHandler{
+		db:     db,
+		store:  store,
+		market: service,
+	}
+}
+
+// GetMarket возвращает все активные лоты
+func (h *MarketHandler) GetMarket(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	listings, err := h.fetchListings(*playerID, `ml.status = 'active'`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MarketListingsResponse{Listings: listings})
+}
+
+// GetPlayerListings возвращает лоты игрока во всех статусах и его покупки
+func (h *MarketHandler) GetPlayerListings(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	listings, err := h.fetchListings(*playerID, `(ml.seller_player_id = $1 OR ml.buyer_player_id = $1)`, *playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch listings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MarketListingsResponse{Listings: listings})
+}
+
+// fetchListings читает лоты по условию where вместе с эффектами предметов. playerID - текущий игрок
+func (h *MarketHandler) fetchListings(playerID int, where string, args ...interface{}) ([]models.MarketListing, error) {
+	rows, err := h.db.Query(`
+		SELECT
+			ml.id,
+			ml.seller_player_id,
+			seller.character_name,
+			ml.buyer_player_id,
+			buyer.character_name,
+			ml.item_id,
+			i.name,
+			i.description,
+			ml.price,
+			ml.status,
+			ml.created_at,
+			ml.expires_at,
+			ml.closed_at
+		FROM market_listings ml
+		JOIN players seller ON seller.id = ml.seller_player_id
+		LEFT JOIN players buyer ON buyer.id = ml.buyer_player_id
+		JOIN items i ON i.id = ml.item_id
+		WHERE `+where+`
+		ORDER BY ml.created_at DESC, ml.id DESC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	listings := make([]models.MarketListing, 0)
+	itemIDs := make([]int64, 0)
+	for rows.Next() {
+		var listing models.MarketListing
+		err := rows.Scan(
+			&listing.ID,
+			&listing.SellerPlayerID,
+			&listing.SellerPlayerName,
+			&listing.BuyerPlayerID,
+			&listing.BuyerPlayerName,
+			&listing.ItemID,
+			&listing.ItemName,
+			&listing.ItemDescription,
+			&listing.Price,
+			&listing.Status,
+			&listing.CreatedAt,
+			&listing.ExpiresAt,
+			&listing.ClosedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		listing.IsSeller = listing.SellerPlayerID == playerID
+		listing.Effects = make([]models.Effect, 0)
+		if listing.Status == "active" {
+			remaining := int(listing.ExpiresAt.Sub(now).Seconds())
+			if remaining < 0 {
+				remaining = 0
+			}
+			listing.TimeRemaining = &remaining
+		}
+
+		listings = append(listings, listing)
+		itemIDs = append(itemIDs, int64(listing.ItemID))
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Покупатель должен видеть, что покупает
+	effectsByItem, err := fetchEffectsByItems(h.db, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range listings {
+		if effects, ok := effectsByItem[listings[i].ItemID]; ok {
+			listings[i].Effects = effects
+		}
+	}
+
+	return listings, nil
+}
+
+// fetchEffectsByItems загружает эффекты предметов одним запросом
+func fetchEffectsByItems(db *sql.DB, itemIDs []int64) (map[int][]models.Effect, error) {
+	rows, err := db.Query(`
+		SELECT
+			ie.item_id,
+			e.id,
+			e.description,
+			e.effect_type,
+			e.generated_resource,
+			e.operation,
+			e.value,
+			e.spawned_template_id,
+			e.period_seconds
+		FROM item_effects ie
+		JOIN effects e ON ie.effect_id = e.id
+		WHERE ie.item_id = ANY($1)
+		ORDER BY e.id
+	`, pq.Array(itemIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effectsByItem := make(map[int][]models.Effect)
+	for rows.Next() {
+		var itemID int
+		var effect models.Effect
+		err := rows.Scan(
+			&itemID,
+			&effect.ID,
+			&effect.Description,
+			&effect.EffectType,
+			&effect.GeneratedResource,
+			&effect.Operation,
+			&effect.Value,
+			&effect.SpawnedTemplateID,
+			&effect.PeriodSeconds,
+		)
+		if err != nil {
+			return nil, err
+		}
+		effectsByItem[itemID] = append(effectsByItem[itemID], effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effectsByItem, nil
+}
+
+// CreateListing выставляет предмет из инвентаря на рынок. До покупки, отмены
+// или истечения срока предмет хранится у рынка
+func (h *MarketHandler) CreateListing(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var req models.CreateListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	listing, err := h.market.List(tx, *playerID, req.ItemID, req.Price, duration, time.Now())
+	if err != nil {
+		switch err {
+		case market.ErrItemNotOwned:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in your inventory"})
+		default:
+			log.Printf("Failed to list item %d: %v", req.ItemID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create listing"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Item listed successfully",
+		"listing_id": listing.ID,
+		"item_id":    listing.ItemID,
+		"price":      listing.Price,
+		"expires_at": listing.ExpiresAt,
+	})
+}
+
+// BuyListing - покупка лота: деньги и предмет переходят в одной транзакции
+func (h *MarketHandler) BuyListing(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	listingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	listing, err := h.market.Buy(tx, listingID, *playerID, time.Now())
+	if err != nil {
+		switch err {
+		case market.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		case market.ErrOwnListing:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot buy your own listing"})
+		case market.ErrNotActive:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Listing is not active"})
+		case market.ErrInsufficientFunds:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds"})
+		default:
+			log.Printf("Failed to buy listing #%d: %v", listingID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to buy listing"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Item bought successfully",
+		"listing_id": listing.ID,
+		"item_id":    listing.ItemID,
+		"price":      listing.Price,
+	})
+}
+
+// CancelListing - продавец снимает лот, предмет возвращается в его инвентарь
+func (h *MarketHandler) CancelListing(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	listingID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid listing ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	listing, err := h.market.Cancel(tx, listingID, *playerID, time.Now())
+	if err != nil {
+		switch err {
+		case market.ErrNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Listing not found"})
+		case market.ErrNotSeller:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only seller can cancel the listing"})
+		case market.ErrNotActive:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Listing is not active"})
+		default:
+			log.Printf("Failed to cancel listing #%d: %v", listingID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel listing"})
+		}
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Listing cancelled successfully",
+		"listing_id": listing.ID,
+		"item_id":    listing.ItemID,
+	})
+}
@@ -0,0 +1,165 @@
+// internal/handlers/metrics.go
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/metrics"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	db    *sql.DB
+	token string // если задан, /metrics требует Authorization: Bearer <token>
+}
+
+func NewMetricsHandler(db *sql.DB, token string) *MetricsHandler {
+	return &MetricsHandler{db: db, token: token}
+}
+
+// GetMetrics отдаёт метрики в формате Prometheus. Если часть значений из БД
+// получить не удалось, остальные метрики всё равно отдаются
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.token != "" {
+		expected := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+
+	metrics.WriteAll(&buf)
+	h.writePoolStats(&buf)
+
+	if err := h.writeSchedulerStats(&buf); err != nil {
+		log.Printf("Error collecting scheduler metrics: %v", err)
+	}
+
+	if err := h.writeEconomyStats(&buf); err != nil {
+		log.Printf("Error collecting economy metrics: %v", err)
+	}
+
+	c.Data(http.StatusOK, metrics.ContentType, buf.Bytes())
+}
+
+// writePoolStats - пул соединений database.Connect
+func (h *MetricsHandler) writePoolStats(buf *bytes.Buffer) {
+	stats := h.db.Stats()
+
+	metrics.WriteGauge(buf, "db_pool_max_open_connections", "Maximum number of open connections to the database",
+		metrics.Gauge{Value: float64(stats.MaxOpenConnections)})
+	metrics.WriteGauge(buf, "db_pool_connections", "Open connections by state",
+		metrics.Gauge{Labels: []string{"state", "in_use"}, Value: float64(stats.InUse)},
+		metrics.Gauge{Labels: []string{"state", "idle"}, Value: float64(stats.Idle)})
+	metrics.WriteGauge(buf, "db_pool_wait_count", "Total number of connections waited for",
+		metrics.Gauge{Value: float64(stats.WaitCount)})
+	metrics.WriteGauge(buf, "db_pool_wait_duration_seconds", "Total time blocked waiting for a new connection",
+		metrics.Gauge{Value: stats.WaitDuration.Seconds()})
+}
+
+// writeSchedulerStats - таймеры эффектов, договоров и долгов в очереди
+func (h *MetricsHandler) writeSchedulerStats(buf *bytes.Buffer) error {
+	rows, err := h.db.Query(`
+		SELECT
+			job_type,
+			COUNT(*) FILTER (WHERE status = 'pending') as pending,
+			COUNT(*) FILTER (WHERE status = 'pending' AND run_at <= NOW()) as overdue,
+			COUNT(*) FILTER (WHERE status = 'failed') as failed
+		FROM scheduled_jobs
+		GROUP BY job_type
+		ORDER BY job_type
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	pending := make([]metrics.Gauge, 0)
+	overdue := make([]metrics.Gauge, 0)
+	failed := make([]metrics.Gauge, 0)
+
+	for rows.Next() {
+		var jobType string
+		var pendingCount, overdueCount, failedCount int
+		if err := rows.Scan(&jobType, &pendingCount, &overdueCount, &failedCount); err != nil {
+			return err
+		}
+
+		labels := []string{"type", jobType}
+		pending = append(pending, metrics.Gauge{Labels: labels, Value: float64(pendingCount)})
+		overdue = append(overdue, metrics.Gauge{Labels: labels, Value: float64(overdueCount)})
+		failed = append(failed, metrics.Gauge{Labels: labels, Value: float64(failedCount)})
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	metrics.WriteGauge(buf, "scheduler_jobs_pending", "Scheduled timers waiting in the queue by type", pending...)
+	metrics.WriteGauge(buf, "scheduler_jobs_overdue", "Pending timers whose run time has already passed", overdue...)
+	metrics.WriteGauge(buf, "scheduler_jobs_failed", "Timers that exhausted all attempts", failed...)
+	return nil
+}
+
+// writeEconomyStats - деньги и влияние в игре
+func (h *MetricsHandler) writeEconomyStats(buf *bytes.Buffer) error {
+	var players, totalMoney, totalInfluence int
+	err := h.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(money), 0), COALESCE(SUM(influence), 0)
+		FROM players
+	`).Scan(&players, &totalMoney, &totalInfluence)
+	if err != nil {
+		return err
+	}
+
+	metrics.WriteGauge(buf, "game_players", "Number of players",
+		metrics.Gauge{Value: float64(players)})
+	metrics.WriteGauge(buf, "game_money_supply", "Total money held by players",
+		metrics.Gauge{Value: float64(totalMoney)})
+	metrics.WriteGauge(buf, "game_player_influence_total", "Total influence held by players",
+		metrics.Gauge{Value: float64(totalInfluence)})
+
+	rows, err := h.db.Query(`
+		SELECT fti.faction_id, fti.faction_name, fti.faction_own_influence, fti.total_influence, f.treasury
+		FROM faction_total_influence fti
+		JOIN factions f ON f.id = fti.faction_id
+		ORDER BY fti.faction_id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	own := make([]metrics.Gauge, 0)
+	total := make([]metrics.Gauge, 0)
+	treasuries := make([]metrics.Gauge, 0)
+
+	for rows.Next() {
+		var factionID, ownInfluence, totalInfluence, treasury int
+		var factionName string
+		if err := rows.Scan(&factionID, &factionName, &ownInfluence, &totalInfluence, &treasury); err != nil {
+			return err
+		}
+
+		labels := []string{"faction_id", strconv.Itoa(factionID), "faction", factionName}
+		own = append(own, metrics.Gauge{Labels: labels, Value: float64(ownInfluence)})
+		total = append(total, metrics.Gauge{Labels: labels, Value: float64(totalInfluence)})
+		treasuries = append(treasuries, metrics.Gauge{Labels: labels, Value: float64(treasury)})
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	metrics.WriteGauge(buf, "game_faction_own_influence", "Faction's own influence", own...)
+	metrics.WriteGauge(buf, "game_faction_total_influence", "Faction influence including its members", total...)
+	metrics.WriteGauge(buf, "game_faction_treasury", "Money in the faction treasury", treasuries...)
+	return nil
+}
@@ -0,0 +1,608 @@
+// internal/handlers/race.go
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Причины, по которым следующий раунд гонки не может быть открыт
+var (
+	errRaceTriggerNotFound = errors.New("race trigger not found")
+	errRaceTriggerInactive = errors.New("race trigger is not active")
+	errRaceRoundInProgress = errors.New("race round is already active")
+	errRaceNoMoreRounds    = errors.New("no predefined goals for the next round")
+	errRaceNotReady        = errors.New("participants have not completed enough tasks")
+)
+
+// isRaceNotReady - ошибка означает, что раунд просто пока нельзя открыть
+func isRaceNotReady(err error) bool {
+	return errors.Is(err, errRaceTriggerInactive) ||
+		errors.Is(err, errRaceRoundInProgress) ||
+		errors.Is(err, errRaceNoMoreRounds) ||
+		errors.Is(err, errRaceNotReady)
+}
+
+// queryRower - общий интерфейс *sql.DB и *sql.Tx для одиночных запросов
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type RaceHandler struct {
+	db *sql.DB
+}
+
+func NewRaceHandler(db *sql.DB) *RaceHandler {
+	return &RaceHandler{db: db}
+}
+
+// nextRaceRound определяет номер следующего раунда триггера.
+// Возвращает id уже поставленного в очередь (pending) раунда, если он есть,
+// и errRaceRoundInProgress, если у триггера есть активный раунд.
+// Отменённый раунд пропускается - следующим идёт раунд с большим номером.
+func nextRaceRound(q queryRower, triggerID int) (int, *int, error) {
+	var roundID, roundNumber int
+	var status string
+	err := q.QueryRow(`
+		SELECT id, round_number, status
+		FROM goal_race_rounds
+		WHERE trigger_id = $1
+		ORDER BY round_number DESC, id DESC
+		LIMIT 1
+	`, triggerID).Scan(&roundID, &roundNumber, &status)
+
+	if err == sql.ErrNoRows {
+		return 1, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to fetch last race round: %w", err)
+	}
+
+	switch status {
+	case "active":
+		return roundNumber, nil, errRaceRoundInProgress
+	case "pending":
+		return roundNumber, &roundID, nil
+	default:
+		return roundNumber + 1, nil, nil
+	}
+}
+
+// openNextRaceRound открывает следующий раунд гонки для триггера.
+// Без force раунд N открывается только когда каждый участник триггера выполнил
+// не меньше required_tasks_count * N задач. При открытии предопределённые цели
+// раунда превращаются в личные цели игроков и связываются с раундом.
+func openNextRaceRound(tx *sql.Tx, triggerID int, force bool) (int, error) {
+	var requiredTasks int
+	var isActive bool
+	err := tx.QueryRow(`
+		SELECT required_tasks_count, is_active
+		FROM goal_race_triggers
+		WHERE id = $1
+		FOR UPDATE
+	`, triggerID).Scan(&requiredTasks, &isActive)
+
+	if err == sql.ErrNoRows {
+		return 0, errRaceTriggerNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch race trigger: %w", err)
+	}
+
+	if !isActive {
+		return 0, errRaceTriggerInactive
+	}
+
+	roundNumber, pendingRoundID, err := nextRaceRound(tx, triggerID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Проверяем, что для раунда заготовлены цели
+	var goalsCount int
+	err = tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM goal_race_predefined_goals
+		WHERE trigger_id = $1 AND round_number = $2 AND player_id IS NOT NULL
+	`, triggerID, roundNumber).Scan(&goalsCount)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count predefined goals: %w", err)
+	}
+
+	if goalsCount == 0 {
+		return 0, errRaceNoMoreRounds
+	}
+
+	if !force {
+		// Считаем участников, которые ещё не набрали нужное количество задач
+		var participantsCount, notReadyCount int
+		err = tx.QueryRow(`
+			SELECT
+				COUNT(*),
+				COUNT(*) FILTER (WHERE (
+					SELECT COUNT(*) FROM tasks t
+					WHERE t.player_id = tp.player_id AND t.is_completed = true
+				) < $2)
+			FROM goal_race_trigger_participants tp
+			WHERE tp.trigger_id = $1
+		`, triggerID, requiredTasks*roundNumber).Scan(&participantsCount, &notReadyCount)
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to check participants progress: %w", err)
+		}
+
+		if participantsCount == 0 || notReadyCount > 0 {
+			return 0, errRaceNotReady
+		}
+	}
+
+	// Активируем раунд из очереди или создаём новый
+	var roundID int
+	if pendingRoundID != nil {
+		roundID = *pendingRoundID
+		_, err = tx.Exec(`
+			UPDATE goal_race_rounds
+			SET status = 'active', started_at = NOW()
+			WHERE id = $1
+		`, roundID)
+	} else {
+		err = tx.QueryRow(`
+			INSERT INTO goal_race_rounds (trigger_id, round_number, status, started_at)
+			VALUES ($1, $2, 'active', NOW())
+			RETURNING id
+		`, triggerID, roundNumber).Scan(&roundID)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to activate race round: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO goal_race_round_participants (round_id, player_id)
+		SELECT $1, player_id
+		FROM goal_race_trigger_participants
+		WHERE trigger_id = $2
+		ON CONFLICT (round_id, player_id) DO NOTHING
+	`, roundID, triggerID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to add round participants: %w", err)
+	}
+
+	// Материализуем предопределённые цели раунда в личные цели игроков
+	_, err = tx.Exec(`
+		WITH predefined AS (
+			SELECT player_id, title, description, influence_points_reward
+			FROM goal_race_predefined_goals
+			WHERE trigger_id = $1 AND round_number = $2 AND player_id IS NOT NULL
+		), created AS (
+			INSERT INTO goals (title, description, goal_type, influence_points_reward, player_id)
+			SELECT title, description, 'personal', COALESCE(influence_points_reward, 0), player_id
+			FROM predefined
+			RETURNING id, player_id
+		)
+		INSERT INTO goal_race_round_goals (round_id, goal_id, assigned_player_id)
+		SELECT $3, id, player_id
+		FROM created
+	`, triggerID, roundNumber, roundID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to create race goals: %w", err)
+	}
+
+	return roundID, nil
+}
+
+// advanceGoalRaces пробует открыть следующие раунды во всех активных гонках игрока.
+// Вызывается после изменения количества выполненных задач.
+func advanceGoalRaces(tx *sql.Tx, playerID int) error {
+	rows, err := tx.Query(`
+		SELECT tp.trigger_id
+		FROM goal_race_trigger_participants tp
+		JOIN goal_race_triggers t ON t.id = tp.trigger_id
+		WHERE tp.player_id = $1 AND t.is_active = true
+		ORDER BY tp.trigger_id
+	`, playerID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch player race triggers: %w", err)
+	}
+
+	triggerIDs := make([]int, 0)
+	for rows.Next() {
+		var triggerID int
+		if err := rows.Scan(&triggerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan race trigger: %w", err)
+		}
+		triggerIDs = append(triggerIDs, triggerID)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, triggerID := range triggerIDs {
+		if _, err := openNextRaceRound(tx, triggerID, false); err != nil && !isRaceNotReady(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockRaceGoal проверяет, относится ли цель к раунду гонки, и блокирует этот раунд.
+// Возвращает false, если цель больше нельзя менять: раунд завершён, отменён
+// или цель стала недоступна после победы другого игрока.
+func lockRaceGoal(tx *sql.Tx, goalID int) (bool, error) {
+	var roundID int
+	var isAccessible bool
+	err := tx.QueryRow(`
+		SELECT round_id, is_accessible
+		FROM goal_race_round_goals
+		WHERE goal_id = $1
+	`, goalID).Scan(&roundID, &isAccessible)
+
+	if err == sql.ErrNoRows {
+		// Обычная цель, не участвует в гонке
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch race goal: %w", err)
+	}
+
+	// ВАЖНО: блокируем раунд, чтобы два игрока не могли выиграть его одновременно
+	var status string
+	err = tx.QueryRow(`
+		SELECT status FROM goal_race_rounds WHERE id = $1 FOR UPDATE
+	`, roundID).Scan(&status)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to lock race round: %w", err)
+	}
+
+	return isAccessible && status == "active", nil
+}
+
+// finishRaceRoundIfWon завершает раунд, если игрок выполнил все свои цели в нём.
+// Цели остальных участников становятся недоступными, следующий раунд ставится в очередь.
+// Раунд должен быть заблокирован через lockRaceGoal в этой же транзакции.
+func finishRaceRoundIfWon(tx *sql.Tx, goalID, playerID int) (bool, error) {
+	var roundID, roundNumber int
+	var triggerID *int
+	err := tx.QueryRow(`
+		SELECT r.id, r.round_number, r.trigger_id
+		FROM goal_race_round_goals rg
+		JOIN goal_race_rounds r ON r.id = rg.round_id
+		WHERE rg.goal_id = $1 AND rg.assigned_player_id = $2 AND r.status = 'active'
+	`, goalID, playerID).Scan(&roundID, &roundNumber, &triggerID)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch race round: %w", err)
+	}
+
+	var remaining int
+	err = tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM goal_race_round_goals rg
+		JOIN goals g ON g.id = rg.goal_id
+		WHERE rg.round_id = $1 AND rg.assigned_player_id = $2 AND g.is_completed = false
+	`, roundID, playerID).Scan(&remaining)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check race goals: %w", err)
+	}
+
+	if remaining > 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE goal_race_rounds
+		SET status = 'completed', completed_at = NOW(), winner_player_id = $1
+		WHERE id = $2
+	`, playerID, roundID)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to complete race round: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE goal_race_round_goals
+		SET is_accessible = false, became_inaccessible_at = NOW()
+		WHERE round_id = $1 AND assigned_player_id <> $2 AND is_accessible = true
+	`, roundID, playerID)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to close race goals: %w", err)
+	}
+
+	if triggerID == nil {
+		return true, nil
+	}
+
+	// Ставим следующий раунд в очередь, если для него заготовлены цели
+	// (тем же условием, что и openNextRaceRound: цели без игрока не материализуются)
+	_, err = tx.Exec(`
+		INSERT INTO goal_race_rounds (trigger_id, round_number, status)
+		SELECT $1, $2, 'pending'
+		WHERE EXISTS (
+			SELECT 1 FROM goal_race_predefined_goals
+			WHERE trigger_id = $1 AND round_number = $2 AND player_id IS NOT NULL
+		)
+	`, *triggerID, roundNumber+1)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to queue next race round: %w", err)
+	}
+
+	// Участники могли уже набрать задачи для следующего раунда
+	if _, err := openNextRaceRound(tx, *triggerID, false); err != nil && !isRaceNotReady(err) {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// cancelRaceRound отменяет ожидающий или активный раунд, все его цели становятся недоступными
+func cancelRaceRound(tx *sql.Tx, roundID int) error {
+	_, err := tx.Exec(`
+		UPDATE goal_race_rounds
+		SET status = 'cancelled', completed_at = NOW()
+		WHERE id = $1
+	`, roundID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel race round: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE goal_race_round_goals
+		SET is_accessible = false, became_inaccessible_at = NOW()
+		WHERE round_id = $1 AND is_accessible = true
+	`, roundID)
+	if err != nil {
+		return fmt.Errorf("failed to close race goals: %w", err)
+	}
+
+	return nil
+}
+
+// GetPlayerRace возвращает состояние гонок целей, в которых участвует игрок
+func (h *RaceHandler) GetPlayerRace(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var completedTasks int
+	err := h.db.QueryRow(`
+		SELECT COUNT(*) FROM tasks WHERE player_id = $1 AND is_completed = true
+	`, *playerID).Scan(&completedTasks)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task stats"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT t.id, t.name, t.description, t.required_tasks_count
+		FROM goal_race_triggers t
+		JOIN goal_race_trigger_participants tp ON tp.trigger_id = t.id
+		WHERE tp.player_id = $1 AND t.is_active = true
+		ORDER BY t.id
+	`, *playerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch races"})
+		return
+	}
+
+	races := make([]models.PlayerRaceStatus, 0)
+	for rows.Next() {
+		var race models.PlayerRaceStatus
+		if err := rows.Scan(&race.TriggerID, &race.TriggerName, &race.Description, &race.RequiredTasksCount); err != nil {
+			rows.Close()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan race"})
+			return
+		}
+		race.CompletedTasks = completedTasks
+		races = append(races, race)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	for i := range races {
+		if err := h.fillPlayerRace(&races[i], *playerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch race progress"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, models.PlayerRaceResponse{Races: races})
+}
+
+// fillPlayerRace заполняет текущий раунд, цели игрока, прогресс участников и историю раундов
+func (h *RaceHandler) fillPlayerRace(race *models.PlayerRaceStatus, playerID int) error {
+	race.MyGoals = make([]models.RaceGoal, 0)
+	race.Participants = make([]models.RaceParticipantProgress, 0)
+
+	rounds, err := fetchRaceRounds(h.db, &race.TriggerID)
+	if err != nil {
+		return err
+	}
+
+	race.FinishedRounds = make([]models.RaceRound, 0)
+	for i := range rounds {
+		switch rounds[i].Status {
+		case "active", "pending":
+			race.CurrentRound = &rounds[i]
+		default:
+			race.FinishedRounds = append(race.FinishedRounds, rounds[i])
+		}
+	}
+
+	// Сколько задач нужно для открытия следующего раунда
+	if race.CurrentRound == nil || race.CurrentRound.Status == "pending" {
+		roundNumber, _, err := nextRaceRound(h.db, race.TriggerID)
+		if err != nil && !isRaceNotReady(err) {
+			return err
+		}
+		required := race.RequiredTasksCount * roundNumber
+		race.RequiredForRound = &required
+	}
+
+	if race.CurrentRound == nil || race.CurrentRound.Status != "active" {
+		return nil
+	}
+
+	roundID := race.CurrentRound.ID
+
+	goalRows, err := h.db.Query(`
+		SELECT
+			g.id,
+			g.title,
+			g.description,
+			g.influence_points_reward,
+			g.is_completed,
+			g.completed_at,
+			rg.is_accessible,
+			rg.became_inaccessible_at
+		FROM goal_race_round_goals rg
+		JOIN goals g ON g.id = rg.goal_id
+		WHERE rg.round_id = $1 AND rg.assigned_player_id = $2
+		ORDER BY g.id
+	`, roundID, playerID)
+	if err != nil {
+		return err
+	}
+	defer goalRows.Close()
+
+	for goalRows.Next() {
+		var goal models.RaceGoal
+		err := goalRows.Scan(
+			&goal.GoalID,
+			&goal.Title,
+			&goal.Description,
+			&goal.InfluencePointsReward,
+			&goal.IsCompleted,
+			&goal.CompletedAt,
+			&goal.IsAccessible,
+			&goal.BecameInaccessibleAt,
+		)
+		if err != nil {
+			return err
+		}
+		race.MyGoals = append(race.MyGoals, goal)
+	}
+
+	if err = goalRows.Err(); err != nil {
+		return err
+	}
+
+	progressRows, err := h.db.Query(`
+		SELECT
+			prp.player_id,
+			p.character_name,
+			prp.total_goals,
+			prp.completed_goals,
+			prp.accessible_goals
+		FROM player_race_progress prp
+		JOIN players p ON p.id = prp.player_id
+		WHERE prp.round_id = $1
+		ORDER BY prp.completed_goals DESC, p.character_name
+	`, roundID)
+	if err != nil {
+		return err
+	}
+	defer progressRows.Close()
+
+	for progressRows.Next() {
+		var progress models.RaceParticipantProgress
+		err := progressRows.Scan(
+			&progress.PlayerID,
+			&progress.PlayerName,
+			&progress.TotalGoals,
+			&progress.CompletedGoals,
+			&progress.AccessibleGoals,
+		)
+		if err != nil {
+			return err
+		}
+		race.Participants = append(race.Participants, progress)
+	}
+
+	return progressRows.Err()
+}
+
+// fetchRaceRounds возвращает раунды гонок (опционально - одного триггера) со статистикой
+func fetchRaceRounds(db *sql.DB, triggerID *int) ([]models.RaceRound, error) {
+	rows, err := db.Query(`
+		SELECT
+			r.id,
+			r.trigger_id,
+			t.name,
+			r.round_number,
+			r.status,
+			r.started_at,
+			r.completed_at,
+			r.winner_player_id,
+			w.character_name,
+			r.created_at,
+			(SELECT COUNT(*) FROM goal_race_round_participants rp WHERE rp.round_id = r.id),
+			(SELECT COUNT(*) FROM goal_race_round_goals rg WHERE rg.round_id = r.id AND rg.is_accessible = true)
+		FROM goal_race_rounds r
+		LEFT JOIN goal_race_triggers t ON t.id = r.trigger_id
+		LEFT JOIN players w ON w.id = r.winner_player_id
+		WHERE $1::INTEGER IS NULL OR r.trigger_id = $1
+		ORDER BY r.trigger_id, r.round_number, r.id
+	`, triggerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rounds := make([]models.RaceRound, 0)
+	for rows.Next() {
+		var round models.RaceRound
+		err := rows.Scan(
+			&round.ID,
+			&round.TriggerID,
+			&round.TriggerName,
+			&round.RoundNumber,
+			&round.Status,
+			&round.StartedAt,
+			&round.CompletedAt,
+			&round.WinnerPlayerID,
+			&round.WinnerPlayerName,
+			&round.CreatedAt,
+			&round.ParticipantsCount,
+			&round.AccessibleGoalsCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		rounds = append(rounds, round)
+	}
+
+	return rounds, rows.Err()
+}
@@ -0,0 +1,226 @@
+// internal/handlers/task.go
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TaskHandler struct {
+	db *sql.DB
+}
+
+func NewTaskHandler(db *sql.DB) *TaskHandler {
+	return &TaskHandler{db: db}
+}
+
+// GetPlayerTasks возвращает задачи игрока и статистику их выполнения
+func (h *TaskHandler) GetPlayerTasks(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	rows, err := h.db.Query(`
+		SELECT
+			id,
+			player_id,
+			title,
+			description,
+			is_completed,
+			completed_at,
+			created_at
+		FROM tasks
+		WHERE player_id = $1
+		ORDER BY is_completed ASC, created_at ASC
+	`, *playerID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tasks"})
+		return
+	}
+	defer rows.Close()
+
+	tasks := make([]models.Task, 0)
+	for rows.Next() {
+		var task models.Task
+		err := rows.Scan(
+			&task.ID,
+			&task.PlayerID,
+			&task.Title,
+			&task.Description,
+			&task.IsCompleted,
+			&task.CompletedAt,
+			&task.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan task"})
+			return
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Статистика из представления (строки нет, если у игрока нет задач)
+	var stats models.TaskStats
+	err = h.db.QueryRow(`
+		SELECT total_tasks, completed_tasks, pending_tasks
+		FROM player_tasks_stats
+		WHERE player_id = $1
+	`, *playerID).Scan(&stats.TotalTasks, &stats.CompletedTasks, &stats.PendingTasks)
+
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch task stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PlayerTasksResponse{Tasks: tasks, Stats: stats})
+}
+
+// ToggleTaskCompletion отмечает задачу как выполненную или невыполненную
+func (h *TaskHandler) ToggleTaskCompletion(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	var req models.CompleteTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if req.IsCompleted == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "is_completed is required"})
+		return
+	}
+
+	isCompleted := *req.IsCompleted
+
+	// Начинаем транзакцию
+	tx, err := h.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	// Получаем задачу и блокируем её
+	var ownerID *int
+	var currentCompleted bool
+	err = tx.QueryRow(`
+		SELECT player_id, is_completed
+		FROM tasks
+		WHERE id = $1
+		FOR UPDATE
+	`, taskID).Scan(&ownerID, &currentCompleted)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Задачу может отмечать только владелец
+	if ownerID == nil || *ownerID != *playerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to modify this task"})
+		return
+	}
+
+	// Проверяем, что статус действительно меняется
+	if currentCompleted == isCompleted {
+		state := "completed"
+		if !isCompleted {
+			state = "incomplete"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task is already " + state})
+		return
+	}
+
+	var completedAt *time.Time
+	action := "uncompleted"
+	if isCompleted {
+		now := time.Now()
+		completedAt = &now
+		action = "completed"
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tasks
+		SET is_completed = $1, completed_at = $2
+		WHERE id = $3
+	`, isCompleted, completedAt, taskID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
+		return
+	}
+
+	// Записываем в историю выполнения задач
+	_, err = tx.Exec(`
+		INSERT INTO task_completion_history (task_id, player_id, action)
+		VALUES ($1, $2, $3)
+	`, taskID, *playerID, action)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record task completion history"})
+		return
+	}
+
+	// Выполненная задача может открыть следующий раунд гонки целей
+	if isCompleted {
+		if err = advanceGoalRaces(tx, *playerID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal race"})
+			return
+		}
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	message := "Task marked as completed"
+	if !isCompleted {
+		message = "Task marked as incomplete"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      message,
+		"task_id":      taskID,
+		"is_completed": isCompleted,
+	})
+}
@@ -0,0 +1,385 @@
+// internal/handlers/trade.go
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/trades"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// TradeHandler - предложения обмена между игроками. Проверки и перенос
+// предметов и денег живут в trades.Service, который вызывает и scheduler
+type TradeHandler struct {
+	db     *sql.DB
+	store  storage.Store
+	trades *trades.Service
+}
+
+func NewTradeHandler(db *sql.DB, store storage.Store, service *trades.Service) *TradeHandler {
+	return &TradeHandler{
+		db:     db,
+		store:  store,
+		trades: service,
+	}
+}
+
+// GetPlayerTrades возвращает входящие и исходящие предложения игрока.
+// ?status=pending - только ожидающие ответа
+func (h *TradeHandler) GetPlayerTrades(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var status *string
+	if statusParam := c.Query("status"); statusParam != "" {
+		status = &statusParam
+	}
+
+	rows, err := h.db.Query(`
+		SELECT
+			t.id,
+			t.from_player_id,
+			pf.character_name,
+			t.to_player_id,
+			pt.character_name,
+			t.offer_money,
+			t.request_money,
+			t.message,
+			t.status,
+			t.parent_offer_id,
+			t.created_at,
+			t.expires_at,
+			t.closed_at
+		FROM trade_offers t
+		JOIN players pf ON pf.id = t.from_player_id
+		JOIN players pt ON pt.id = t.to_player_id
+		WHERE (t.from_player_id = $1 OR t.to_player_id = $1)
+			AND ($2::VARCHAR IS NULL OR t.status = $2)
+		ORDER BY t.created_at DESC, t.id DESC
+	`, *playerID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trade offers"})
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	offers := make([]models.TradeOffer, 0)
+	offerIDs := make([]int64, 0)
+	for rows.Next() {
+		var offer models.TradeOffer
+		err := rows.Scan(
+			&offer.ID,
+			&offer.FromPlayerID,
+			&offer.FromPlayerName,
+			&offer.ToPlayerID,
+			&offer.ToPlayerName,
+			&offer.OfferMoney,
+			&offer.RequestMoney,
+			&offer.Message,
+			&offer.Status,
+			&offer.ParentOfferID,
+			&offer.CreatedAt,
+			&offer.ExpiresAt,
+			&offer.ClosedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan trade offer"})
+			return
+		}
+
+		offer.IsIncoming = offer.ToPlayerID == *playerID
+		offer.OfferItems = make([]models.TradeOfferItem, 0)
+		offer.RequestItems = make([]models.TradeOfferItem, 0)
+		if offer.Status == "pending" {
+			remaining := int(offer.ExpiresAt.Sub(now).Seconds())
+			if remaining < 0 {
+				remaining = 0
+			}
+			offer.TimeRemaining = &remaining
+		}
+
+		offers = append(offers, offer)
+		offerIDs = append(offerIDs, int64(offer.ID))
+	}
+
+	if err = rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trade offers"})
+		return
+	}
+
+	// Предметы всех предложений одним запросом
+	itemRows, err := h.db.Query(`
+		SELECT toi.offer_id, toi.side, i.id, i.name, i.description
+		FROM trade_offer_items toi
+		JOIN items i ON i.id = toi.item_id
+		WHERE toi.offer_id = ANY($1)
+		ORDER BY i.id
+	`, pq.Array(offerIDs))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trade items"})
+		return
+	}
+	defer itemRows.Close()
+
+	index := make(map[int]int, len(offers))
+	for i := range offers {
+		index[offers[i].ID] = i
+	}
+
+	for itemRows.Next() {
+		var offerID int
+		var side string
+		var item models.TradeOfferItem
+		if err := itemRows.Scan(&offerID, &side, &item.ItemID, &item.ItemName, &item.ItemDescription); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan trade item"})
+			return
+		}
+
+		offer := &offers[index[offerID]]
+		if side == "offer" {
+			offer.OfferItems = append(offer.OfferItems, item)
+		} else {
+			offer.RequestItems = append(offer.RequestItems, item)
+		}
+	}
+
+	if err = itemRows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trade items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TradeOffersResponse{Offers: offers})
+}
+
+// CreateTrade отправляет предложение обмена другому игроку
+func (h *TradeHandler) CreateTrade(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	var req models.CreateTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	offer, err := h.trades.Propose(tx, trades.Proposal{
+		FromPlayerID: *playerID,
+		ToPlayerID:   req.ToPlayerID,
+		OfferMoney:   req.OfferMoney,
+		RequestMoney: req.RequestMoney,
+		OfferItems:   req.OfferItemIDs,
+		RequestItems: req.RequestItemIDs,
+		Message:      req.Message,
+		Duration:     time.Duration(req.DurationMinutes) * time.Minute,
+	}, time.Now())
+	if err != nil {
+		respondTradeError(c, err, "Failed to create trade offer")
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Trade offer created successfully",
+		"offer_id":   offer.ID,
+		"expires_at": offer.ExpiresAt,
+	})
+}
+
+// AcceptTrade - получатель принимает предложение, обмен выполняется целиком или не выполняется
+func (h *TradeHandler) AcceptTrade(c *gin.Context) {
+	h.closeTrade(c, h.trades.Accept, "Trade completed successfully", "Failed to accept trade offer")
+}
+
+// DeclineTrade - получатель отклоняет предложение
+func (h *TradeHandler) DeclineTrade(c *gin.Context) {
+	h.closeTrade(c, h.trades.Decline, "Trade offer declined", "Failed to decline trade offer")
+}
+
+// CancelTrade - автор отзывает предложение
+func (h *TradeHandler) CancelTrade(c *gin.Context) {
+	h.closeTrade(c, h.trades.Cancel, "Trade offer cancelled", "Failed to cancel trade offer")
+}
+
+// closeTrade выполняет переход предложения, общий для принятия, отказа и отмены
+func (h *TradeHandler) closeTrade(c *gin.Context,
+	action func(tx storage.Tx, offerID, playerID int, now time.Time) (*storage.TradeOffer, error),
+	message, failure string) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	offerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trade offer ID"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	offer, err := action(tx, offerID, *playerID, time.Now())
+	if err != nil {
+		respondTradeError(c, err, failure)
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  message,
+		"offer_id": offer.ID,
+		"status":   offer.Status,
+	})
+}
+
+// CounterTrade - получатель отвечает встречным предложением
+func (h *TradeHandler) CounterTrade(c *gin.Context) {
+	playerIDInterface, exists := c.Get("player_id")
+	if !exists || playerIDInterface == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Player ID not found in token"})
+		return
+	}
+
+	playerID := playerIDInterface.(*int)
+	if playerID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User is not associated with a player"})
+		return
+	}
+
+	offerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trade offer ID"})
+		return
+	}
+
+	var req models.CounterTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Начинаем транзакцию
+	tx, err := h.store.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	offer, err := h.trades.Counter(tx, offerID, *playerID, trades.Proposal{
+		OfferMoney:   req.OfferMoney,
+		RequestMoney: req.RequestMoney,
+		OfferItems:   req.OfferItemIDs,
+		RequestItems: req.RequestItemIDs,
+		Message:      req.Message,
+		Duration:     time.Duration(req.DurationMinutes) * time.Minute,
+	}, time.Now())
+	if err != nil {
+		respondTradeError(c, err, "Failed to counter trade offer")
+		return
+	}
+
+	// Фиксируем транзакцию
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":         "Counter offer created successfully",
+		"offer_id":        offer.ID,
+		"parent_offer_id": offerID,
+		"expires_at":      offer.ExpiresAt,
+	})
+}
+
+// respondTradeError переводит ошибки trades.Service в ответы API
+func respondTradeError(c *gin.Context, err error, failure string) {
+	switch err {
+	case trades.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Trade offer not found"})
+	case trades.ErrPlayerNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Player not found"})
+	case trades.ErrSelfTrade:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot trade with yourself"})
+	case trades.ErrEmptyOffer:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trade offer must contain items or money"})
+	case trades.ErrDuplicateItem:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item is listed more than once"})
+	case trades.ErrItemNotOwned:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in your inventory"})
+	case trades.ErrRequestedItemNotOwned:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Requested item not found in player's inventory"})
+	case trades.ErrItemsUnavailable:
+		c.JSON(http.StatusConflict, gin.H{"error": "Items of the offer are no longer available"})
+	case trades.ErrInsufficientFunds:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient funds"})
+	case trades.ErrProposerFunds:
+		c.JSON(http.StatusConflict, gin.H{"error": "Proposer has insufficient funds"})
+	case trades.ErrNotRecipient:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only recipient can respond to the offer"})
+	case trades.ErrNotProposer:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only proposer can cancel the offer"})
+	case trades.ErrNotPending:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Trade offer is not pending"})
+	default:
+		log.Printf("%s: %v", failure, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+	}
+}
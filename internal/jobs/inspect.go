@@ -0,0 +1,173 @@
+// internal/jobs/inspect.go
+package jobs
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// driftTolerance - допустимое расхождение времени задачи и срока из игровых таблиц
+// (округление TIMESTAMP, задержка между записью срока и постановкой задачи)
+const driftTolerance = 2 * time.Second
+
+// Timer - ожидающая задача очереди
+type Timer struct {
+	Key       string     `json:"key"`
+	RunAt     time.Time  `json:"run_at"`
+	Attempts  int        `json:"attempts"`
+	LastError *string    `json:"last_error,omitempty"` // ошибка последней попытки (задача ждёт повтора)
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastRunBy *string    `json:"last_run_by,omitempty"`
+}
+
+// Expected - задача, которая должна стоять в очереди по данным игровых таблиц
+type Expected struct {
+	Key   string
+	RunAt *time.Time // nil - время заранее не известно (эффект ещё ни разу не выполнялся)
+}
+
+// Mismatch - задача стоит в очереди, но не на то время
+type Mismatch struct {
+	Key      string    `json:"key"`
+	Expected time.Time `json:"expected_run_at"`
+	Actual   time.Time `json:"actual_run_at"`
+}
+
+// Drift - расхождение очереди с игровыми таблицами
+type Drift struct {
+	Expected   int        `json:"expected"`
+	Pending    int        `json:"pending"`
+	Missing    []string   `json:"missing"`    // должны быть в очереди, но их там нет
+	Orphaned   []string   `json:"orphaned"`   // стоят в очереди, но по таблицам уже не нужны
+	Mismatched []Mismatch `json:"mismatched"` // стоят в очереди на другое время
+	InSync     bool       `json:"in_sync"`
+}
+
+// Inspection - состояние задач одного типа
+type Inspection struct {
+	JobType     string     `json:"job_type"`
+	Running     bool       `json:"running"`
+	Pending     int        `json:"pending"`
+	Failed      int        `json:"failed"` // задачи, исчерпавшие max_attempts
+	LastFiredAt *time.Time `json:"last_fired_at"`
+	LastError   *string    `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+	Timers      []Timer    `json:"timers"`
+	Drift       Drift      `json:"drift"`
+}
+
+// Inspect возвращает ожидающие задачи типа, время последнего запуска, последнюю ошибку
+// и сравнение очереди с expected - тем, что по игровым таблицам должно быть запланировано
+func (q *Queue) Inspect(jobType string, running bool, expected []Expected) (*Inspection, error) {
+	inspection := &Inspection{
+		JobType: jobType,
+		Running: running,
+		Timers:  make([]Timer, 0),
+	}
+
+	rows, err := q.db.Query(`
+		SELECT job_key, run_at, attempts, last_error, last_run_at, last_run_by
+		FROM scheduled_jobs
+		WHERE job_type = $1 AND status = 'pending'
+		ORDER BY run_at, job_key
+	`, jobType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s jobs: %w", jobType, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timer Timer
+		if err := rows.Scan(&timer.Key, &timer.RunAt, &timer.Attempts,
+			&timer.LastError, &timer.LastRunAt, &timer.LastRunBy); err != nil {
+			return nil, fmt.Errorf("failed to scan %s job: %w", jobType, err)
+		}
+		inspection.Timers = append(inspection.Timers, timer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load %s jobs: %w", jobType, err)
+	}
+	inspection.Pending = len(inspection.Timers)
+
+	// Последний запуск и последняя ошибка - по всем задачам типа, включая завершённые
+	err = q.db.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'failed'),
+			MAX(last_run_at)
+		FROM scheduled_jobs
+		WHERE job_type = $1
+	`, jobType).Scan(&inspection.Failed, &inspection.LastFiredAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s job stats: %w", jobType, err)
+	}
+
+	err = q.db.QueryRow(`
+		SELECT last_error, last_run_at
+		FROM scheduled_jobs
+		WHERE job_type = $1 AND last_error IS NOT NULL
+		ORDER BY last_run_at DESC NULLS LAST
+		LIMIT 1
+	`, jobType).Scan(&inspection.LastError, &inspection.LastErrorAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load %s job errors: %w", jobType, err)
+	}
+
+	inspection.Drift = CompareTimers(expected, inspection.Timers)
+	return inspection, nil
+}
+
+// CompareTimers сравнивает ожидающие задачи с тем, что должно быть запланировано
+func CompareTimers(expected []Expected, pending []Timer) Drift {
+	drift := Drift{
+		Expected:   len(expected),
+		Pending:    len(pending),
+		Missing:    make([]string, 0),
+		Orphaned:   make([]string, 0),
+		Mismatched: make([]Mismatch, 0),
+	}
+
+	timers := make(map[string]Timer, len(pending))
+	for _, timer := range pending {
+		timers[timer.Key] = timer
+	}
+
+	wanted := make(map[string]bool, len(expected))
+	for _, exp := range expected {
+		wanted[exp.Key] = true
+
+		timer, ok := timers[exp.Key]
+		if !ok {
+			drift.Missing = append(drift.Missing, exp.Key)
+			continue
+		}
+
+		// Задача после неудачной попытки ждёт повтора - её время сдвинуто намеренно
+		if exp.RunAt == nil || timer.Attempts > 0 {
+			continue
+		}
+
+		diff := timer.RunAt.Sub(*exp.RunAt)
+		if diff > driftTolerance || diff < -driftTolerance {
+			drift.Mismatched = append(drift.Mismatched, Mismatch{
+				Key:      exp.Key,
+				Expected: *exp.RunAt,
+				Actual:   timer.RunAt,
+			})
+		}
+	}
+
+	for _, timer := range pending {
+		if !wanted[timer.Key] {
+			drift.Orphaned = append(drift.Orphaned, timer.Key)
+		}
+	}
+
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Orphaned)
+
+	drift.InSync = len(drift.Missing) == 0 && len(drift.Orphaned) == 0 && len(drift.Mismatched) == 0
+	return drift
+}
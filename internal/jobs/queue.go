@@ -0,0 +1,404 @@
+// internal/jobs/queue.go
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/metrics"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Статусы задач
+const (
+	StatusPending   = "pending"
+	StatusDone      = "done"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Задержка перед повторной попыткой: 5с, 10с, 20с, ... но не больше maxBackoff
+const (
+	baseBackoff = 5 * time.Second
+	maxBackoff  = 10 * time.Minute
+)
+
+// Job - захваченная задача
+type Job struct {
+	ID          int
+	Type        string
+	Key         string
+	Payload     json.RawMessage
+	RunAt       time.Time
+	Attempts    int
+	MaxAttempts int
+}
+
+// Handler выполняет задачу в транзакции, в которой она захвачена.
+// Если next != nil, задача остаётся в очереди и повторится в это время (периодические задачи)
+type Handler func(tx *sql.Tx, job Job) (next *time.Time, err error)
+
+// Execer - *sql.DB или *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Queue - очередь отложенных задач в таблице scheduled_jobs
+type Queue struct {
+	db           *sql.DB
+	workerID     string
+	pollInterval time.Duration
+	handlers     map[string]Handler
+	mu           sync.RWMutex
+	wake         chan struct{}
+	done         chan struct{}
+	wg           sync.WaitGroup
+	running      bool
+}
+
+func NewQueue(db *sql.DB, pollInterval time.Duration) *Queue {
+	hostname, _ := os.Hostname()
+
+	return &Queue{
+		db:           db,
+		workerID:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		pollInterval: pollInterval,
+		handlers:     make(map[string]Handler),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Register назначает обработчик для типа задач
+func (q *Queue) Register(jobType string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Start запускает фоновый обработчик очереди
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running {
+		return
+	}
+
+	q.done = make(chan struct{})
+	q.running = true
+
+	q.wg.Add(1)
+	go q.run(q.done)
+
+	log.Printf("Job queue started (worker %s, poll interval %v)", q.workerID, q.pollInterval)
+}
+
+// Stop останавливает обработчик и ждёт завершения текущей задачи
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	if !q.running {
+		q.mu.Unlock()
+		return
+	}
+	close(q.done)
+	q.running = false
+	q.mu.Unlock()
+
+	q.wg.Wait()
+	log.Println("Job queue stopped")
+}
+
+// Wake будит обработчик, не дожидаясь следующего опроса
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Schedule ставит задачу в очередь. Если задача с таким ключом уже есть,
+// она заменяется (новое время, payload, сброс попыток)
+func (q *Queue) Schedule(ex Execer, jobType, key string, runAt time.Time, payload interface{}) error {
+	return q.upsert(ex, jobType, key, runAt, payload, true)
+}
+
+// Ensure ставит задачу в очередь, только если с таким ключом нет ожидающей задачи.
+// Используется при восстановлении очереди из игровых таблиц
+func (q *Queue) Ensure(ex Execer, jobType, key string, runAt time.Time, payload interface{}) error {
+	return q.upsert(ex, jobType, key, runAt, payload, false)
+}
+
+func (q *Queue) upsert(ex Execer, jobType, key string, runAt time.Time, payload interface{}, replace bool) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	// Ожидающую задачу Ensure не трогает, а завершённую/отменённую - возвращает в очередь
+	condition := "WHERE scheduled_jobs.status <> 'pending'"
+	if replace {
+		condition = ""
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO scheduled_jobs (job_type, job_key, payload, run_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (job_key) DO UPDATE
+		SET job_type = EXCLUDED.job_type,
+		    payload = EXCLUDED.payload,
+		    run_at = EXCLUDED.run_at,
+		    status = 'pending',
+		    attempts = 0,
+		    last_error = NULL,
+		    completed_at = NULL,
+		    updated_at = NOW()
+		`+condition, jobType, key, string(raw), runAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule job %s: %w", key, err)
+	}
+
+	if !runAt.After(time.Now()) {
+		q.Wake()
+	}
+
+	return nil
+}
+
+// Cancel отменяет ожидающую задачу
+func (q *Queue) Cancel(ex Execer, key string) (int64, error) {
+	result, err := ex.Exec(`
+		UPDATE scheduled_jobs
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE job_key = $1 AND status = 'pending'
+	`, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel job %s: %w", key, err)
+	}
+	return result.RowsAffected()
+}
+
+// CancelByPrefix отменяет ожидающие задачи типа, ключ которых начинается с prefix
+func (q *Queue) CancelByPrefix(ex Execer, jobType, prefix string) (int64, error) {
+	result, err := ex.Exec(`
+		UPDATE scheduled_jobs
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE job_type = $1 AND status = 'pending' AND starts_with(job_key, $2)
+	`, jobType, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel %s jobs: %w", jobType, err)
+	}
+	return result.RowsAffected()
+}
+
+// CancelAll отменяет все ожидающие задачи типа
+func (q *Queue) CancelAll(ex Execer, jobType string) (int64, error) {
+	return q.CancelByPrefix(ex, jobType, "")
+}
+
+// CountPending возвращает количество ожидающих задач типа
+func (q *Queue) CountPending(jobType string) (int, error) {
+	var count int
+	err := q.db.QueryRow(`
+		SELECT COUNT(*) FROM scheduled_jobs WHERE job_type = $1 AND status = 'pending'
+	`, jobType).Scan(&count)
+	return count, err
+}
+
+func (q *Queue) run(done chan struct{}) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.processDue(done)
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// processDue выполняет все задачи, время которых наступило
+func (q *Queue) processDue(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		processed, err := q.processNext()
+		if err != nil {
+			log.Printf("Job queue error: %v", err)
+			return
+		}
+		if !processed {
+			return
+		}
+	}
+}
+
+// processNext захватывает одну задачу и выполняет её в той же транзакции.
+// Возвращает false, если выполнять нечего
+func (q *Queue) processNext() (bool, error) {
+	q.mu.RLock()
+	jobTypes := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		jobTypes = append(jobTypes, jobType)
+	}
+	q.mu.RUnlock()
+
+	if len(jobTypes) == 0 {
+		return false, nil
+	}
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// ВАЖНО: SKIP LOCKED - задачу, которую уже выполняет другой экземпляр, пропускаем.
+	// Блокировка держится до конца транзакции и снимается сама, если процесс упадёт
+	var job Job
+	var payload []byte
+	err = tx.QueryRow(`
+		SELECT id, job_type, job_key, payload, run_at, attempts, max_attempts
+		FROM scheduled_jobs
+		WHERE status = 'pending' AND run_at <= NOW() AND job_type = ANY($1)
+		ORDER BY run_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, pq.Array(jobTypes)).Scan(
+		&job.ID,
+		&job.Type,
+		&job.Key,
+		&payload,
+		&job.RunAt,
+		&job.Attempts,
+		&job.MaxAttempts,
+	)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	job.Payload = payload
+
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	// Ошибка обработчика откатывает только его изменения, захват задачи остаётся
+	if _, err = tx.Exec(`SAVEPOINT job`); err != nil {
+		return false, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	started := time.Now()
+	next, jobErr := q.execute(handler, tx, job)
+	duration := time.Since(started)
+
+	result := "ok"
+	if jobErr != nil {
+		result = "retry"
+		if job.Attempts+1 >= job.MaxAttempts {
+			result = "failed"
+		}
+	}
+
+	if jobErr != nil {
+		if _, err = tx.Exec(`ROLLBACK TO SAVEPOINT job`); err != nil {
+			return false, fmt.Errorf("failed to roll back job %s: %w", job.Key, err)
+		}
+		err = q.fail(tx, job, jobErr)
+	} else if next != nil {
+		_, err = tx.Exec(`
+			UPDATE scheduled_jobs
+			SET run_at = $1, attempts = 0, last_error = NULL,
+			    last_run_at = NOW(), last_run_by = $2, updated_at = NOW()
+			WHERE id = $3
+		`, *next, q.workerID, job.ID)
+	} else {
+		_, err = tx.Exec(`
+			UPDATE scheduled_jobs
+			SET status = 'done', last_error = NULL, completed_at = NOW(),
+			    last_run_at = NOW(), last_run_by = $1, updated_at = NOW()
+			WHERE id = $2
+		`, q.workerID, job.ID)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("failed to update job %s: %w", job.Key, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit job %s: %w", job.Key, err)
+	}
+
+	metrics.JobsExecuted.Inc(job.Type, result)
+	metrics.JobDuration.Observe(duration, job.Type)
+
+	return true, nil
+}
+
+// execute вызывает обработчик, превращая panic в ошибку
+func (q *Queue) execute(handler Handler, tx *sql.Tx, job Job) (next *time.Time, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(tx, job)
+}
+
+// fail записывает ошибку и откладывает задачу с экспоненциальной задержкой,
+// а после max_attempts неудач подряд помечает её как failed
+func (q *Queue) fail(tx *sql.Tx, job Job, jobErr error) error {
+	attempts := job.Attempts + 1
+
+	if attempts >= job.MaxAttempts {
+		log.Printf("Job %s failed permanently after %d attempts: %v", job.Key, attempts, jobErr)
+		_, err := tx.Exec(`
+			UPDATE scheduled_jobs
+			SET status = 'failed', attempts = $1, last_error = $2,
+			    last_run_at = NOW(), last_run_by = $3, updated_at = NOW()
+			WHERE id = $4
+		`, attempts, jobErr.Error(), q.workerID, job.ID)
+		return err
+	}
+
+	retryAt := time.Now().Add(Backoff(attempts))
+	log.Printf("Job %s failed (attempt %d/%d), retrying at %v: %v",
+		job.Key, attempts, job.MaxAttempts, retryAt.Format("15:04:05"), jobErr)
+
+	_, err := tx.Exec(`
+		UPDATE scheduled_jobs
+		SET run_at = $1, attempts = $2, last_error = $3,
+		    last_run_at = NOW(), last_run_by = $4, updated_at = NOW()
+		WHERE id = $5
+	`, retryAt, attempts, jobErr.Error(), q.workerID, job.ID)
+	return err
+}
+
+// Backoff возвращает задержку перед повторной попыткой номер attempts
+func Backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
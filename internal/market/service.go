@@ -0,0 +1,328 @@
+// internal/market/service.go
+package market
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+// Рынок предметов с хранением: выставленный предмет убирается из инвентаря продавца
+// и его эффекты перестают работать. Покупка переводит деньги продавцу и предмет покупателю
+// в одной транзакции, отмена и истечение срока возвращают предмет продавцу.
+// Все переходы лота выполняются только здесь, поэтому handlers и scheduler ведут себя одинаково
+
+// JobType - тип задач очереди для истечения срока лотов
+const JobType = "market"
+
+// Job - payload задачи лота
+type Job struct {
+	ListingID int `json:"listing_id"`
+}
+
+// JobKey - ключ задачи истечения лота в очереди
+func JobKey(listingID int) string {
+	return fmt.Sprintf("listing:%d", listingID)
+}
+
+var (
+	ErrNotFound          = errors.New("listing not found")
+	ErrItemNotOwned      = errors.New("item not found in your inventory")
+	ErrNotSeller         = errors.New("only seller can perform this action")
+	ErrOwnListing        = errors.New("cannot buy your own listing")
+	ErrNotActive         = errors.New("listing is not active")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// ItemTimers переносит таймеры эффектов предмета при смене владельца
+type ItemTimers interface {
+	ReleaseItemEffects(tx storage.Tx, playerID, itemID int) error
+	AttachItemEffects(tx storage.Tx, playerID, itemID int, baseTime time.Time) error
+}
+
+// Service - операции над лотами в транзакции вызывающего
+type Service struct {
+	timers ItemTimers
+}
+
+func NewService(timers ItemTimers) *Service {
+	return &Service{timers: timers}
+}
+
+// List выставляет предмет продавца на рынок и планирует истечение лота
+func (s *Service) List(tx storage.Tx, sellerID, itemID, price int, duration time.Duration, now time.Time) (*storage.Listing, error) {
+	owned, err := tx.Items().IsOwnedBy(sellerID, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check item owner: %w", err)
+	}
+	if !owned {
+		return nil, ErrItemNotOwned
+	}
+
+	item, err := tx.Items().Get(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch item: %w", err)
+	}
+
+	// Предмет уходит на хранение рынку: у продавца его больше нет и эффекты не срабатывают
+	if err = tx.Items().Release(itemID, sellerID); err != nil {
+		return nil, fmt.Errorf("failed to put item in escrow: %w", err)
+	}
+	if err = s.timers.ReleaseItemEffects(tx, sellerID, itemID); err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(duration)
+	listingID, err := tx.Market().Create(sellerID, itemID, price, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create listing: %w", err)
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		FromPlayerID:    &sellerID,
+		ItemID:          itemID,
+		TransactionType: "market",
+		ReferenceID:     listingID,
+		ReferenceType:   "market_listing",
+		Description:     fmt.Sprintf("Item listed on market: %s for %d", item.Name, price),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	if err = tx.Timers().Schedule(JobType, JobKey(listingID), expiresAt, Job{ListingID: listingID}); err != nil {
+		return nil, fmt.Errorf("failed to schedule listing expiration: %w", err)
+	}
+
+	listing := &storage.Listing{
+		ID:             listingID,
+		Status:         "active",
+		SellerPlayerID: sellerID,
+		ItemID:         itemID,
+		Price:          price,
+		ExpiresAt:      expiresAt,
+	}
+
+	if err = tx.Events().PublishItemMoved(itemID, &sellerID, nil, "market"); err != nil {
+		return nil, fmt.Errorf("failed to publish events: %w", err)
+	}
+	if err = s.publish(tx, events.TypeListingCreated, listing); err != nil {
+		return nil, err
+	}
+
+	return listing, nil
+}
+
+// Buy покупает лот: деньги переходят продавцу, предмет - покупателю
+func (s *Service) Buy(tx storage.Tx, listingID, buyerID int, now time.Time) (*storage.Listing, error) {
+	listing, err := s.load(tx, listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if listing.SellerPlayerID == buyerID {
+		return nil, ErrOwnListing
+	}
+	// Лот с истёкшим сроком ждёт задачу очереди (например, во время паузы), купить его уже нельзя
+	if listing.Status != "active" || !now.Before(listing.ExpiresAt) {
+		return nil, ErrNotActive
+	}
+
+	buyer, err := tx.Players().GetForUpdate(buyerID)
+	if err == storage.ErrNotFound {
+		return nil, fmt.Errorf("buyer %d not found", buyerID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch buyer: %w", err)
+	}
+	if buyer.Money < listing.Price {
+		return nil, ErrInsufficientFunds
+	}
+
+	seller, err := tx.Players().GetForUpdate(listing.SellerPlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seller: %w", err)
+	}
+
+	item, err := tx.Items().Get(listing.ItemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch item: %w", err)
+	}
+
+	if _, err = tx.Players().TakeMoney(buyerID, listing.Price); err != nil {
+		return nil, fmt.Errorf("failed to deduct money from buyer: %w", err)
+	}
+	if err = tx.Players().AddMoney(seller.ID, listing.Price); err != nil {
+		return nil, fmt.Errorf("failed to pay seller: %w", err)
+	}
+
+	if err = tx.Items().Give(listing.ItemID, buyerID); err != nil {
+		return nil, fmt.Errorf("failed to give item to buyer: %w", err)
+	}
+	if err = s.timers.AttachItemEffects(tx, buyerID, listing.ItemID, now); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Market().Close(listingID, "sold", &buyerID, now); err != nil {
+		return nil, fmt.Errorf("failed to close listing: %w", err)
+	}
+
+	err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		FromPlayerID:    &buyerID,
+		ToPlayerID:      &seller.ID,
+		Amount:          listing.Price,
+		TransactionType: "market",
+		ReferenceID:     listingID,
+		ReferenceType:   "market_listing",
+		Description: fmt.Sprintf("%s bought %s from %s for %d",
+			buyer.CharacterName, item.Name, seller.CharacterName, listing.Price),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record money transaction: %w", err)
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		FromPlayerID:    &seller.ID,
+		ToPlayerID:      &buyerID,
+		ItemID:          listing.ItemID,
+		TransactionType: "market",
+		ReferenceID:     listingID,
+		ReferenceType:   "market_listing",
+		Description:     fmt.Sprintf("Item sold on market: %s", item.Name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	if err = tx.Timers().Cancel(JobKey(listingID)); err != nil {
+		return nil, fmt.Errorf("failed to cancel listing timer: %w", err)
+	}
+
+	listing.Status = "sold"
+	listing.BuyerPlayerID = &buyerID
+
+	if err = tx.Events().PublishItemMoved(listing.ItemID, nil, &buyerID, "market"); err != nil {
+		return nil, fmt.Errorf("failed to publish events: %w", err)
+	}
+	if err = tx.Events().PublishBalances(buyerID, seller.ID); err != nil {
+		return nil, fmt.Errorf("failed to publish events: %w", err)
+	}
+	if err = s.publish(tx, events.TypeListingClosed, listing); err != nil {
+		return nil, err
+	}
+
+	return listing, nil
+}
+
+// Cancel снимает лот по просьбе продавца и возвращает ему предмет
+func (s *Service) Cancel(tx storage.Tx, listingID, sellerID int, now time.Time) (*storage.Listing, error) {
+	listing, err := s.load(tx, listingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if listing.SellerPlayerID != sellerID {
+		return nil, ErrNotSeller
+	}
+	if listing.Status != "active" {
+		return nil, ErrNotActive
+	}
+
+	if err = s.close(tx, listing, "cancelled", now); err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
+
+// ExpireListing - истечение срока лота по таймеру очереди.
+// Лот, который уже продан или снят, пропускается без ошибки
+func (s *Service) ExpireListing(tx storage.Tx, listingID int, now time.Time) error {
+	listing, err := s.load(tx, listingID)
+	if err == ErrNotFound {
+		log.Printf("Listing #%d no longer exists, skipping", listingID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if listing.Status != "active" {
+		log.Printf("Listing #%d is no longer active (status: %s), skipping", listingID, listing.Status)
+		return nil
+	}
+
+	return s.close(tx, listing, "expired", now)
+}
+
+// close возвращает предмет продавцу, закрывает лот со статусом status и снимает его таймер
+func (s *Service) close(tx storage.Tx, listing *storage.Listing, status string, now time.Time) error {
+	item, err := tx.Items().Get(listing.ItemID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch item for listing #%d: %w", listing.ID, err)
+	}
+
+	if err = tx.Items().Give(listing.ItemID, listing.SellerPlayerID); err != nil {
+		return fmt.Errorf("failed to return item from escrow: %w", err)
+	}
+	if err = s.timers.AttachItemEffects(tx, listing.SellerPlayerID, listing.ItemID, now); err != nil {
+		return err
+	}
+
+	if err = tx.Market().Close(listing.ID, status, nil, now); err != nil {
+		return fmt.Errorf("failed to close listing #%d: %w", listing.ID, err)
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		ToPlayerID:      &listing.SellerPlayerID,
+		ItemID:          listing.ItemID,
+		TransactionType: "market",
+		ReferenceID:     listing.ID,
+		ReferenceType:   "market_listing",
+		Description:     fmt.Sprintf("Item returned from market (%s): %s", status, item.Name),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	// При отмене таймер ещё в очереди; для задачи очереди отмена ничего не меняет
+	if err = tx.Timers().Cancel(JobKey(listing.ID)); err != nil {
+		return fmt.Errorf("failed to cancel listing timer: %w", err)
+	}
+
+	listing.Status = status
+
+	if err = tx.Events().PublishItemMoved(listing.ItemID, nil, &listing.SellerPlayerID, "market"); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return s.publish(tx, events.TypeListingClosed, listing)
+}
+
+func (s *Service) load(tx storage.Tx, listingID int) (*storage.Listing, error) {
+	listing, err := tx.Market().GetForUpdate(listingID)
+	if err == storage.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch listing #%d: %w", listingID, err)
+	}
+	return listing, nil
+}
+
+// publish отправляет изменение лота всем игрокам: рынок общий
+func (s *Service) publish(tx storage.Tx, eventType string, listing *storage.Listing) error {
+	err := tx.Events().Publish(eventType, nil, events.ListingChanged{
+		ListingID: listing.ID,
+		ItemID:    listing.ItemID,
+		SellerID:  listing.SellerPlayerID,
+		BuyerID:   listing.BuyerPlayerID,
+		Price:     listing.Price,
+		Status:    listing.Status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Метрики в текстовом формате Prometheus (text/plain; version=0.0.4).
+// Счётчики и гистограммы копятся в памяти процесса, а значения из БД
+// (очередь задач, экономика, пул соединений) считаются при каждом запросе /metrics
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Границы гистограммы длительностей, в секундах
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HTTP-запросы по маршруту (шаблону gin, например /api/contracts/:id/sign) и статусу
+var (
+	HTTPRequests = NewCounterVec("http_requests_total",
+		"HTTP requests by method, route and status", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("http_request_duration_seconds",
+		"HTTP request latency by method and route", DefaultBuckets, "method", "route")
+)
+
+// Выполнение задач очереди: effect, contract, debt.
+// result: ok - выполнена, retry - ошибка, будет повтор, failed - исчерпаны попытки
+var (
+	JobsExecuted = NewCounterVec("scheduler_jobs_executed_total",
+		"Scheduled jobs executed by type and result", "type", "result")
+	JobDuration = NewHistogramVec("scheduler_job_duration_seconds",
+		"Scheduled job execution time by type", DefaultBuckets, "type")
+)
+
+var registry = []collector{HTTPRequests, HTTPDuration, JobsExecuted, JobDuration}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// WriteAll записывает все счётчики и гистограммы процесса
+func WriteAll(w io.Writer) {
+	for _, c := range registry {
+		c.write(w)
+	}
+}
+
+// CounterVec - счётчик с метками
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+// Inc увеличивает счётчик для значений меток (в порядке, заданном при создании)
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	key := formatLabels(c.labels, labelValues)
+
+	c.mu.Lock()
+	c.values[key] += value
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, key, formatValue(c.values[key]))
+	}
+}
+
+// HistogramVec - гистограмма с метками
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // по границам buckets, без накопления
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+// Observe добавляет длительность в гистограмму
+func (h *HistogramVec) Observe(d time.Duration, labelValues ...string) {
+	key := formatLabels(h.labels, labelValues)
+	seconds := d.Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	value, ok := h.values[key]
+	if !ok {
+		value = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = value
+	}
+
+	for i, bound := range h.buckets {
+		if seconds <= bound {
+			value.counts[i]++
+			break
+		}
+	}
+	value.count++
+	value.sum += seconds
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += value.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, withLabel(key, "le", "+Inf"), value.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, key, formatValue(value.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, key, value.count)
+	}
+}
+
+// Gauge - значение, посчитанное в момент запроса
+type Gauge struct {
+	Labels []string // пары имя, значение
+	Value  float64
+}
+
+// WriteGauge записывает gauge с набором значений
+func WriteGauge(w io.Writer, name, help string, values ...Gauge) {
+	writeHeader(w, name, help, "gauge")
+	for _, g := range values {
+		names := make([]string, 0, len(g.Labels)/2)
+		labelValues := make([]string, 0, len(g.Labels)/2)
+		for i := 0; i+1 < len(g.Labels); i += 2 {
+			names = append(names, g.Labels[i])
+			labelValues = append(labelValues, g.Labels[i+1])
+		}
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(names, labelValues), formatValue(g.Value))
+	}
+}
+
+func writeHeader(w io.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// formatLabels возвращает {a="1",b="2"} или пустую строку, если меток нет
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		parts[i] = name + `="` + escapeLabel(value) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel добавляет метку к уже отформатированному набору
+func withLabel(labels, name, value string) string {
+	label := name + `="` + escapeLabel(value) + `"`
+	if labels == "" {
+		return "{" + label + "}"
+	}
+	return labels[:len(labels)-1] + "," + label + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
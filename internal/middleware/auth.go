@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"database/sql"
 	"net/http"
 	"strings"
 
@@ -10,34 +11,61 @@ import (
 )
 
 type Claims struct {
-	UserID   int  `json:"user_id"`
-	PlayerID *int `json:"player_id,omitempty"`
-	IsAdmin  bool `json:"is_admin"`
+	UserID    int  `json:"user_id"`
+	PlayerID  *int `json:"player_id,omitempty"`
+	IsAdmin   bool `json:"is_admin"`
+	SessionID int  `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func AuthMiddleware(jwtKey string) gin.HandlerFunc {
+// AuthMiddleware проверяет access-токен и то, что его сессия не отозвана
+func AuthMiddleware(jwtKey string, db *sql.DB) gin.HandlerFunc {
+	return authMiddleware(jwtKey, db, false)
+}
+
+// StreamTicketAudience - audience билета на поток событий. Билет - короткоживущий одноразовый токен
+// той же сессии, который принимается только в параметре ticket потока событий, а access-токен в URL
+// не передаётся (URL попадает в логи сервера и прокси)
+const StreamTicketAudience = "events"
+
+// StreamAuthMiddleware - то же, что AuthMiddleware, но дополнительно принимает билет
+// из параметра ticket: EventSource в браузере не умеет передавать заголовки
+func StreamAuthMiddleware(jwtKey string, db *sql.DB) gin.HandlerFunc {
+	return authMiddleware(jwtKey, db, true)
+}
+
+func authMiddleware(jwtKey string, db *sql.DB, allowTicket bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
+		var options []jwt.ParserOption
+		ticket := false
+
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
-			return
+		if authHeader != "" {
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+				c.Abort()
+				return
+			}
+			tokenString = parts[1]
+		} else if allowTicket {
+			tokenString = c.Query("ticket")
+			options = append(options, jwt.WithAudience(StreamTicketAudience))
+			ticket = true
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
-		tokenString := parts[1]
 		claims := &Claims{}
 
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 			return []byte(jwtKey), nil
-		})
+		}, options...)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -45,13 +73,94 @@ func AuthMiddleware(jwtKey string) gin.HandlerFunc {
 			return
 		}
 
+		// Билет на поток событий нельзя использовать вместо access-токена
+		if !ticket && len(claims.Audience) > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		// Токены без сессии (выданные до введения сессий) не принимаем
+		if claims.SessionID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		sessionActive, err := SessionActive(db, claims.SessionID, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			c.Abort()
+			return
+		}
+
+		if !sessionActive {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		// Билет одноразовый: отмечаем его использованным, повторное подключение с ним не пройдёт
+		if ticket {
+			used, err := UseStreamTicket(db, claims.ID, claims.SessionID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				c.Abort()
+				return
+			}
+
+			if !used {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Ticket already used or expired"})
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("user_id", claims.UserID)
+		c.Set("session_id", claims.SessionID)
 		c.Set("player_id", claims.PlayerID)
 		c.Set("is_admin", claims.IsAdmin)
 		c.Next()
 	}
 }
 
+// SessionActive проверяет, что сессия пользователя существует, не отозвана и не истекла
+func SessionActive(db *sql.DB, sessionID, userID int) (bool, error) {
+	var active bool
+	err := db.QueryRow(`
+		SELECT revoked_at IS NULL AND expires_at > NOW()
+		FROM user_sessions
+		WHERE id = $1 AND user_id = $2
+	`, sessionID, userID).Scan(&active)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+// UseStreamTicket отмечает билет сессии использованным. false - билет неизвестен, истёк или уже использован
+func UseStreamTicket(db *sql.DB, ticketID string, sessionID int) (bool, error) {
+	if ticketID == "" {
+		return false, nil
+	}
+
+	result, err := db.Exec(`
+		UPDATE stream_tickets
+		SET used_at = NOW()
+		WHERE id = $1 AND session_id = $2 AND used_at IS NULL AND expires_at > NOW()
+	`, ticketID, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		isAdmin, exists := c.Get("is_admin")
@@ -0,0 +1,31 @@
+// internal/middleware/metrics.go
+package middleware
+
+import (
+	"new-year-role-game-backend/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics считает запросы и их длительность по маршрутам
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// Шаблон маршрута, а не путь: /api/contracts/:id/sign вместо /api/contracts/17/sign
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		// Поток событий держит соединение всю игру - его длительность в гистограмме не нужна
+		if route != "/api/events" {
+			metrics.HTTPDuration.Observe(time.Since(start), c.Request.Method, route)
+		}
+		metrics.HTTPRequests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
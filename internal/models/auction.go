@@ -0,0 +1,52 @@
+// internal/models/auction.go
+package models
+
+import "time"
+
+type Auction struct {
+	ID               int        `json:"id"`
+	ItemID           int        `json:"item_id"`
+	ItemName         string     `json:"item_name"`
+	ItemDescription  *string    `json:"item_description"`
+	Effects          []Effect   `json:"effects"`
+	StartPrice       int        `json:"start_price"`
+	MinIncrement     int        `json:"min_increment"`
+	Status           string     `json:"status"` // 'active', 'sold', 'unsold', 'cancelled'
+	CurrentBid       *int       `json:"current_bid"`
+	LeaderPlayerID   *int       `json:"leader_player_id"` // лидер, после продажи - победитель
+	LeaderPlayerName *string    `json:"leader_player_name"`
+	BidsCount        int        `json:"bids_count"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ClosesAt         time.Time  `json:"closes_at"`
+	ClosedAt         *time.Time `json:"closed_at,omitempty"`
+
+	// Дополнительные поля для удобства клиента
+	MinBid        *int         `json:"min_bid,omitempty"`        // наименьшая допустимая ставка (для active)
+	IsLeader      bool         `json:"is_leader"`                // true если текущий игрок лидирует или победил
+	TimeRemaining *int         `json:"time_remaining,omitempty"` // секунды до закрытия (для active)
+	Bids          []AuctionBid `json:"bids,omitempty"`           // все ставки (только для администратора)
+}
+
+type AuctionBid struct {
+	ID         int       `json:"id"`
+	PlayerID   int       `json:"player_id"`
+	PlayerName string    `json:"player_name"`
+	Amount     int       `json:"amount"`
+	Status     string    `json:"status"` // 'leading', 'outbid', 'won', 'refunded'
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AuctionsResponse struct {
+	Auctions []Auction `json:"auctions"`
+}
+
+type CreateAuctionRequest struct {
+	ItemID       int       `json:"item_id" binding:"required"`
+	StartPrice   int       `json:"start_price" binding:"required,min=1"`
+	MinIncrement int       `json:"min_increment" binding:"required,min=1"`
+	ClosesAt     time.Time `json:"closes_at" binding:"required"`
+}
+
+type PlaceBidRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
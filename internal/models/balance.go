@@ -0,0 +1,33 @@
+// internal/models/balance.go
+package models
+
+// Ресурсы, которые администратор может начислить или списать
+const (
+	ResourceMoney            = "money"
+	ResourceInfluence        = "influence"
+	ResourceFactionInfluence = "faction_influence" // только для фракции
+	ResourceFactionTreasury  = "faction_treasury"  // только для фракции
+)
+
+// AdjustBalanceRequest - ручное начисление (amount > 0) или штраф (amount < 0)
+type AdjustBalanceRequest struct {
+	Resource string `json:"resource" binding:"required,oneof=money influence faction_influence faction_treasury"`
+	Amount   int    `json:"amount" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+	Clamp    bool   `json:"clamp"` // списать сколько есть, если денег (или денег в казне) не хватает
+}
+
+// BalanceAdjustment - итог изменения баланса одного игрока или фракции
+type BalanceAdjustment struct {
+	PlayerID  *int `json:"player_id,omitempty"`
+	FactionID *int `json:"faction_id,omitempty"`
+	Amount    int  `json:"amount"`  // фактически начислено (после ограничения)
+	Balance   int  `json:"balance"` // новое значение ресурса
+}
+
+type AdjustBalanceResponse struct {
+	Resource    string              `json:"resource"`
+	Requested   int                 `json:"requested"`
+	Reason      string              `json:"reason"`
+	Adjustments []BalanceAdjustment `json:"adjustments"`
+}
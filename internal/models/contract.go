@@ -18,6 +18,7 @@ type Contract struct {
 	DurationSeconds      int        `json:"duration_seconds"`
 	MoneyRewardCustomer  int        `json:"money_reward_customer"`
 	MoneyRewardExecutor  int        `json:"money_reward_executor"`
+	MoneyRewardFaction   int        `json:"money_reward_faction"` // в казну фракции заказчика (type1)
 	CreatedAt            time.Time  `json:"created_at"`
 	SignedAt             *time.Time `json:"signed_at,omitempty"`
 	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
@@ -60,6 +61,7 @@ type ContractType1RewardSettings struct {
 	ID                  int       `json:"id"`
 	MoneyRewardCustomer int       `json:"money_reward_customer"`
 	MoneyRewardExecutor int       `json:"money_reward_executor"`
+	MoneyRewardFaction  int       `json:"money_reward_faction"` // в казну фракции заказчика
 	UpdatedAt           time.Time `json:"updated_at"`
 }
 
@@ -82,8 +84,9 @@ type ContractSettingsResponse struct {
 }
 
 type UpdateContractType1RewardsRequest struct {
-	MoneyRewardCustomer int `json:"money_reward_customer" binding:"required,min=0"`
-	MoneyRewardExecutor int `json:"money_reward_executor" binding:"required,min=0"`
+	MoneyRewardCustomer int  `json:"money_reward_customer" binding:"required,min=0"`
+	MoneyRewardExecutor int  `json:"money_reward_executor" binding:"required,min=0"`
+	MoneyRewardFaction  *int `json:"money_reward_faction,omitempty" binding:"omitempty,min=0"` // nil - не менять
 }
 
 type UpdateContractType2RewardsRequest struct {
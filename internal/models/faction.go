@@ -1,6 +1,8 @@
 // internal/models/faction.go
 package models
 
+import "time"
+
 type FactionMember struct {
 	ID            int     `json:"id"`
 	CharacterName string  `json:"character_name"`
@@ -19,7 +21,8 @@ type FactionResponse struct {
 	LeaderPlayerID            *int             `json:"leader_player_id"`
 	IsCurrentPlayerLeader     bool             `json:"is_current_player_leader"`
 	IsCurrentPlayerMember     bool             `json:"is_current_player_member"`
-	Members                   *[]FactionMember `json:"members,omitempty"` // nil если состав недоступен
+	Treasury                  *int             `json:"treasury,omitempty"` // nil если игрок не участник фракции
+	Members                   *[]FactionMember `json:"members,omitempty"`  // nil если состав недоступен
 }
 
 type FactionsListResponse struct {
@@ -29,3 +32,73 @@ type FactionsListResponse struct {
 type ChangeFactionRequest struct {
 	FactionID int `json:"faction_id" binding:"required"`
 }
+
+// Faction - фракция в том виде, в котором она хранится (для админки)
+type Faction struct {
+	ID                        int     `json:"id"`
+	Name                      string  `json:"name"`
+	Description               *string `json:"description"`
+	FactionInfluence          int     `json:"faction_influence"`
+	Treasury                  int     `json:"treasury"`
+	IsCompositionVisibleToAll bool    `json:"is_composition_visible_to_all"`
+	LeaderPlayerID            *int    `json:"leader_player_id"`
+}
+
+// CreateFactionRequest - если задан лидер, он переводится в новую фракцию
+type CreateFactionRequest struct {
+	Name                      string  `json:"name" binding:"required"`
+	Description               *string `json:"description"`
+	IsCompositionVisibleToAll bool    `json:"is_composition_visible_to_all"`
+	LeaderPlayerID            *int    `json:"leader_player_id"`
+}
+
+// UpdateFactionRequest - незаданные поля не меняются
+type UpdateFactionRequest struct {
+	Name                      *string `json:"name,omitempty"`
+	Description               *string `json:"description,omitempty"`
+	IsCompositionVisibleToAll *bool   `json:"is_composition_visible_to_all,omitempty"`
+	LeaderPlayerID            *int    `json:"leader_player_id,omitempty"` // 0 - снять лидера
+}
+
+type AdminFactionsResponse struct {
+	Factions []Faction `json:"factions"`
+}
+
+// Казна фракции
+
+// FactionTreasuryTransaction - движение денег казны. PlayerID - вносивший
+// участник или получатель выплаты (nil - начисление или штраф мастера, награда по договору)
+type FactionTreasuryTransaction struct {
+	ID              int       `json:"id"`
+	TransactionType string    `json:"transaction_type"` // 'treasury', 'contract', 'admin'
+	Direction       string    `json:"direction"`        // 'in', 'out'
+	Amount          int       `json:"amount"`           // для 'out' отрицательная
+	PlayerID        *int      `json:"player_id,omitempty"`
+	PlayerName      *string   `json:"player_name,omitempty"`
+	Description     *string   `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type FactionTreasuryResponse struct {
+	FactionID             int                          `json:"faction_id"`
+	Treasury              int                          `json:"treasury"`
+	IsCurrentPlayerLeader bool                         `json:"is_current_player_leader"`
+	Transactions          []FactionTreasuryTransaction `json:"transactions"`
+}
+
+// TreasuryDepositRequest - взнос участника в казну
+type TreasuryDepositRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
+
+// TreasuryWithdrawRequest - лидер забирает деньги из казны себе
+type TreasuryWithdrawRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
+
+// TreasuryPaymentRequest - лидер платит из казны любому игроку
+type TreasuryPaymentRequest struct {
+	ToPlayerID int     `json:"to_player_id" binding:"required"`
+	Amount     int     `json:"amount" binding:"required,min=1"`
+	Reason     *string `json:"reason,omitempty"`
+}
@@ -3,8 +3,10 @@ package models
 import "time"
 
 type GameStatusResponse struct {
-	Status    string     `json:"status"` // 'not_started', 'running', 'ended'
-	StartedAt *time.Time `json:"started_at,omitempty"`
-	EndedAt   *time.Time `json:"ended_at,omitempty"`
-	Duration  *string    `json:"duration,omitempty"`
+	Status        string     `json:"status"` // 'not_started', 'running', 'paused', 'ended'
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	EndedAt       *time.Time `json:"ended_at,omitempty"`
+	PausedAt      *time.Time `json:"paused_at,omitempty"`
+	PausedSeconds int        `json:"paused_seconds,omitempty"` // суммарная длительность пауз
+	Duration      *string    `json:"duration,omitempty"`
 }
@@ -30,8 +30,9 @@ type GoalWithLockStatus struct {
 	CompletedAt           *time.Time       `json:"completed_at,omitempty"`
 	CreatedAt             time.Time        `json:"created_at"`
 	IsVisible             bool             `json:"is_visible"`
-	IsLocked              bool             `json:"is_locked"`              // true = видна, но заблокирована
-	Dependencies          []GoalDependency `json:"dependencies,omitempty"` // все зависимости (выполненные и невыполненные)
+	IsLocked              bool             `json:"is_locked"`               // true = видна, но заблокирована
+	RaceRoundID           *int             `json:"race_round_id,omitempty"` // раунд гонки, если цель из гонки
+	Dependencies          []GoalDependency `json:"dependencies,omitempty"`  // все зависимости (выполненные и невыполненные)
 }
 
 // GoalDependency описывает зависимость цели
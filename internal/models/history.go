@@ -0,0 +1,30 @@
+// internal/models/history.go
+package models
+
+import "time"
+
+// LedgerEntry - одна запись истории игрока из money_transactions, item_transactions
+// или influence_transactions
+type LedgerEntry struct {
+	Kind             string    `json:"kind"` // 'money', 'item', 'influence'
+	ID               int       `json:"id"`   // ID записи в таблице своего вида
+	TransactionType  string    `json:"transaction_type"`
+	Direction        string    `json:"direction"`        // 'in' - получено, 'out' - списано/отдано
+	Amount           *int      `json:"amount,omitempty"` // со знаком относительно игрока (для денег и влияния)
+	ItemID           *int      `json:"item_id,omitempty"`
+	ItemName         *string   `json:"item_name,omitempty"`
+	CounterpartyID   *int      `json:"counterparty_id,omitempty"`
+	CounterpartyName *string   `json:"counterparty_name,omitempty"`
+	ReferenceID      *int      `json:"reference_id,omitempty"`
+	ReferenceType    *string   `json:"reference_type,omitempty"`
+	Description      *string   `json:"description,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type PlayerHistoryResponse struct {
+	PlayerID int           `json:"player_id"`
+	Entries  []LedgerEntry `json:"entries"`
+	Total    int           `json:"total"`
+	Limit    int           `json:"limit"`
+	Offset   int           `json:"offset"`
+}
@@ -10,12 +10,13 @@ type Effect struct {
 	GeneratedResource *string `json:"generated_resource,omitempty"` // 'money', 'influence'
 	Operation         *string `json:"operation,omitempty"` // 'add', 'mul', 'sub', 'div'
 	Value             *int    `json:"value,omitempty"`
-	SpawnedItemID     *int    `json:"spawned_item_id,omitempty"`
+	SpawnedTemplateID *int    `json:"spawned_template_id,omitempty"`
 	PeriodSeconds     int     `json:"period_seconds"`
 }
 
 type Item struct {
 	ID          int       `json:"id"`
+	TemplateID  *int      `json:"template_id"` // одинаковые экземпляры можно показывать стопкой
 	Name        string    `json:"name"`
 	Description *string   `json:"description"`
 	AcquiredAt  time.Time `json:"acquired_at"`
@@ -68,4 +69,69 @@ type EffectStatus struct {
 
 type ItemEffectsStatusResponse struct {
 	Effects []EffectStatus `json:"effects"`
-}
\ No newline at end of file
+}
+
+// CatalogItem - предмет каталога (для админки)
+type CatalogItem struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Description  *string   `json:"description"`
+	CreatedAt    time.Time `json:"created_at"`
+	Effects      []Effect  `json:"effects"`
+	HoldersCount int       `json:"holders_count"`
+}
+
+type ItemsCatalogResponse struct {
+	Items []CatalogItem `json:"items"`
+}
+
+type CreateItemRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+	EffectIDs   []int   `json:"effect_ids"`
+}
+
+type UpdateItemRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+type EffectsResponse struct {
+	Effects []Effect `json:"effects"`
+}
+
+// CreateEffectRequest - generated_resource выводится из effect_type
+type CreateEffectRequest struct {
+	Description       *string `json:"description"`
+	EffectType        string  `json:"effect_type" binding:"required"` // 'generate_money', 'generate_influence', 'spawn_item'
+	Operation         *string `json:"operation"`                      // 'add', 'mul', 'sub', 'div' (по умолчанию 'add')
+	Value             *int    `json:"value"`                          // обязательно для generate_*
+	SpawnedTemplateID *int    `json:"spawned_template_id"`            // обязательно для spawn_item
+	PeriodSeconds     int     `json:"period_seconds" binding:"required,min=1"`
+}
+
+// GrantItemRequest - задаётся ровно одно из полей
+type GrantItemRequest struct {
+	ItemID     *int `json:"item_id"`     // существующий экземпляр без владельца
+	TemplateID *int `json:"template_id"` // новый экземпляр по шаблону
+}
+
+// ItemTemplate - шаблон, по которому создаются экземпляры предметов
+type ItemTemplate struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Description    *string   `json:"description"`
+	CreatedAt      time.Time `json:"created_at"`
+	Effects        []Effect  `json:"effects"`
+	InstancesCount int       `json:"instances_count"`
+}
+
+type ItemTemplatesResponse struct {
+	Templates []ItemTemplate `json:"templates"`
+}
+
+type CreateItemTemplateRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description"`
+	EffectIDs   []int   `json:"effect_ids"`
+}
@@ -0,0 +1,60 @@
+// internal/models/leaderboard.go
+package models
+
+import "time"
+
+// FactionStanding - место фракции в таблице лидеров.
+// Деньги и численность видны, только если состав фракции виден запрашивающему
+type FactionStanding struct {
+	Rank             int    `json:"rank"`
+	FactionID        int    `json:"faction_id"`
+	Name             string `json:"name"`
+	FactionInfluence int    `json:"faction_influence"`
+	TotalInfluence   int    `json:"total_influence"`
+	PlayersMoney     *int   `json:"players_money,omitempty"`
+	MembersCount     *int   `json:"members_count,omitempty"`
+}
+
+// PlayerStanding - место игрока в таблице лидеров.
+// FactionID не заполняется для участников фракций со скрытым составом
+type PlayerStanding struct {
+	Rank          int    `json:"rank"`
+	PlayerID      int    `json:"player_id"`
+	CharacterName string `json:"character_name"`
+	FactionID     *int   `json:"faction_id,omitempty"`
+	Money         int    `json:"money"`
+	Influence     int    `json:"influence"`
+}
+
+type FactionSeriesPoint struct {
+	TakenAt        time.Time `json:"taken_at"`
+	TotalInfluence int       `json:"total_influence"`
+	PlayersMoney   *int      `json:"players_money,omitempty"`
+}
+
+type FactionSeries struct {
+	FactionID int                  `json:"faction_id"`
+	Name      string               `json:"name"`
+	Points    []FactionSeriesPoint `json:"points"`
+}
+
+type PlayerSeriesPoint struct {
+	TakenAt   time.Time `json:"taken_at"`
+	Money     int       `json:"money"`
+	Influence int       `json:"influence"`
+}
+
+type PlayerSeries struct {
+	PlayerID      int                 `json:"player_id"`
+	CharacterName string              `json:"character_name"`
+	FactionID     *int                `json:"faction_id,omitempty"`
+	Points        []PlayerSeriesPoint `json:"points"`
+}
+
+type LeaderboardResponse struct {
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Factions      []FactionStanding `json:"factions"`
+	Players       []PlayerStanding  `json:"players"`
+	FactionSeries []FactionSeries   `json:"faction_series"`
+	PlayerSeries  []PlayerSeries    `json:"player_series"`
+}
@@ -0,0 +1,35 @@
+// internal/models/market.go
+package models
+
+import "time"
+
+type MarketListing struct {
+	ID               int        `json:"id"`
+	SellerPlayerID   int        `json:"seller_player_id"`
+	SellerPlayerName string     `json:"seller_player_name"`
+	BuyerPlayerID    *int       `json:"buyer_player_id,omitempty"`
+	BuyerPlayerName  *string    `json:"buyer_player_name,omitempty"`
+	ItemID           int        `json:"item_id"`
+	ItemName         string     `json:"item_name"`
+	ItemDescription  *string    `json:"item_description"`
+	Effects          []Effect   `json:"effects"`
+	Price            int        `json:"price"`
+	Status           string     `json:"status"` // 'active', 'sold', 'cancelled', 'expired'
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	ClosedAt         *time.Time `json:"closed_at,omitempty"`
+
+	// Дополнительные поля для удобства клиента
+	IsSeller      bool `json:"is_seller"`                // true если текущий игрок - продавец
+	TimeRemaining *int `json:"time_remaining,omitempty"` // секунды до снятия лота (для active)
+}
+
+type MarketListingsResponse struct {
+	Listings []MarketListing `json:"listings"`
+}
+
+type CreateListingRequest struct {
+	ItemID          int `json:"item_id" binding:"required"`
+	Price           int `json:"price" binding:"required,min=1"`
+	DurationMinutes int `json:"duration_minutes" binding:"required,min=1"` // Срок лота в минутах
+}
@@ -26,3 +26,45 @@ type PlayerListItem struct {
 type PlayersListResponse struct {
 	Players []PlayerListItem `json:"players"`
 }
+
+// AdminPlayer - полная карточка игрока для админки
+type AdminPlayer struct {
+	ID               int     `json:"id"`
+	CharacterName    string  `json:"character_name"`
+	Role             string  `json:"role"`
+	CharacterStory   *string `json:"character_story"`
+	FactionID        *int    `json:"faction_id"`
+	FactionName      *string `json:"faction_name,omitempty"`
+	CanChangeFaction bool    `json:"can_change_faction"`
+	Money            int     `json:"money"`
+	Influence        int     `json:"influence"`
+	Avatar           *string `json:"avatar,omitempty"` // в списке не отдаётся
+}
+
+type AdminPlayersResponse struct {
+	Players []AdminPlayer `json:"players"`
+}
+
+type CreatePlayerRequest struct {
+	CharacterName    string  `json:"character_name" binding:"required"`
+	Password         string  `json:"password" binding:"required"`
+	Role             string  `json:"role" binding:"required"`
+	CharacterStory   *string `json:"character_story"`
+	FactionID        *int    `json:"faction_id"`
+	CanChangeFaction bool    `json:"can_change_faction"`
+	Money            int     `json:"money"`     // стартовые деньги
+	Influence        int     `json:"influence"` // стартовое влияние
+	Avatar           *string `json:"avatar"`
+}
+
+// UpdatePlayerRequest - незаданные поля не меняются.
+// Деньги и влияние после создания меняются только через игровые операции,
+// пароль - через POST /api/admin/players/:id/password
+type UpdatePlayerRequest struct {
+	CharacterName    *string `json:"character_name,omitempty"`
+	Role             *string `json:"role,omitempty"`
+	CharacterStory   *string `json:"character_story,omitempty"`
+	FactionID        *int    `json:"faction_id,omitempty"` // 0 - убрать из фракции
+	CanChangeFaction *bool   `json:"can_change_faction,omitempty"`
+	Avatar           *string `json:"avatar,omitempty"` // "" - удалить аватар
+}
@@ -0,0 +1,63 @@
+// internal/models/race.go
+package models
+
+import "time"
+
+// RaceRound - раунд гонки целей
+type RaceRound struct {
+	ID                   int        `json:"id"`
+	TriggerID            *int       `json:"trigger_id,omitempty"`
+	TriggerName          *string    `json:"trigger_name,omitempty"`
+	RoundNumber          int        `json:"round_number"`
+	Status               string     `json:"status"` // 'pending', 'active', 'completed', 'cancelled'
+	StartedAt            *time.Time `json:"started_at,omitempty"`
+	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	WinnerPlayerID       *int       `json:"winner_player_id,omitempty"`
+	WinnerPlayerName     *string    `json:"winner_player_name,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	ParticipantsCount    int        `json:"participants_count"`
+	AccessibleGoalsCount int        `json:"accessible_goals_count"`
+}
+
+// RaceParticipantProgress - прогресс участника в раунде (из player_race_progress)
+type RaceParticipantProgress struct {
+	PlayerID        int    `json:"player_id"`
+	PlayerName      string `json:"player_name"`
+	TotalGoals      int    `json:"total_goals"`
+	CompletedGoals  int    `json:"completed_goals"`
+	AccessibleGoals int    `json:"accessible_goals"`
+}
+
+// RaceGoal - цель игрока в раунде гонки
+type RaceGoal struct {
+	GoalID                int        `json:"goal_id"`
+	Title                 string     `json:"title"`
+	Description           *string    `json:"description"`
+	InfluencePointsReward int        `json:"influence_points_reward"`
+	IsCompleted           bool       `json:"is_completed"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty"`
+	IsAccessible          bool       `json:"is_accessible"`
+	BecameInaccessibleAt  *time.Time `json:"became_inaccessible_at,omitempty"`
+}
+
+// PlayerRaceStatus - состояние гонки для игрока в рамках одного триггера
+type PlayerRaceStatus struct {
+	TriggerID          int                       `json:"trigger_id"`
+	TriggerName        string                    `json:"trigger_name"`
+	Description        *string                   `json:"description"`
+	RequiredTasksCount int                       `json:"required_tasks_count"`
+	CompletedTasks     int                       `json:"completed_tasks"`
+	CurrentRound       *RaceRound                `json:"current_round,omitempty"`
+	RequiredForRound   *int                      `json:"required_for_round,omitempty"` // сколько задач нужно для открытия ожидающего раунда
+	MyGoals            []RaceGoal                `json:"my_goals"`
+	Participants       []RaceParticipantProgress `json:"participants"`
+	FinishedRounds     []RaceRound               `json:"finished_rounds"`
+}
+
+type PlayerRaceResponse struct {
+	Races []PlayerRaceStatus `json:"races"`
+}
+
+type RaceRoundsResponse struct {
+	Rounds []RaceRound `json:"rounds"`
+}
@@ -0,0 +1,37 @@
+// internal/models/run.go
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GameRun - архивированный прогон сценария
+type GameRun struct {
+	ID            int            `json:"id"`
+	StartedAt     *time.Time     `json:"started_at"`
+	EndedAt       *time.Time     `json:"ended_at"`
+	PausedSeconds int            `json:"paused_seconds"`
+	Note          *string        `json:"note"`
+	ArchivedAt    time.Time      `json:"archived_at"`
+	Tables        map[string]int `json:"tables"` // количество архивированных строк по таблицам
+}
+
+type GameRunsResponse struct {
+	Runs               []GameRun  `json:"runs"`
+	BaselineCapturedAt *time.Time `json:"baseline_captured_at"` // nil - стартовое состояние не сохранено
+}
+
+// GameRunRowsResponse - строки одной таблицы из архива прогона
+type GameRunRowsResponse struct {
+	RunID  int               `json:"run_id"`
+	Table  string            `json:"table"`
+	Rows   []json.RawMessage `json:"rows"`
+	Total  int               `json:"total"`
+	Limit  int               `json:"limit"`
+	Offset int               `json:"offset"`
+}
+
+type ResetGameRequest struct {
+	Note *string `json:"note"`
+}
@@ -0,0 +1,50 @@
+// internal/models/task.go
+package models
+
+import "time"
+
+type Task struct {
+	ID          int        `json:"id"`
+	PlayerID    *int       `json:"player_id,omitempty"`
+	PlayerName  *string    `json:"player_name,omitempty"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	IsCompleted bool       `json:"is_completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TaskStats - счетчики из представления player_tasks_stats
+type TaskStats struct {
+	TotalTasks     int `json:"total_tasks"`
+	CompletedTasks int `json:"completed_tasks"`
+	PendingTasks   int `json:"pending_tasks"`
+}
+
+type PlayerTasksResponse struct {
+	Tasks []Task    `json:"tasks"`
+	Stats TaskStats `json:"stats"`
+}
+
+type TasksResponse struct {
+	Tasks []Task `json:"tasks"`
+}
+
+type CompleteTaskRequest struct {
+	IsCompleted *bool `json:"is_completed" binding:"required"`
+}
+
+type CreateTaskRequest struct {
+	PlayerID    *int    `json:"player_id"` // NULL - задача пока никому не назначена
+	Title       string  `json:"title" binding:"required"`
+	Description *string `json:"description"`
+}
+
+type UpdateTaskRequest struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+type AssignTaskRequest struct {
+	PlayerID *int `json:"player_id"` // NULL - снять назначение
+}
@@ -0,0 +1,57 @@
+// internal/models/trade.go
+package models
+
+import "time"
+
+type TradeOfferItem struct {
+	ItemID          int     `json:"item_id"`
+	ItemName        string  `json:"item_name"`
+	ItemDescription *string `json:"item_description"`
+}
+
+type TradeOffer struct {
+	ID             int              `json:"id"`
+	FromPlayerID   int              `json:"from_player_id"`
+	FromPlayerName string           `json:"from_player_name"`
+	ToPlayerID     int              `json:"to_player_id"`
+	ToPlayerName   string           `json:"to_player_name"`
+	OfferMoney     int              `json:"offer_money"`   // деньги от автора предложения
+	RequestMoney   int              `json:"request_money"` // деньги от получателя
+	OfferItems     []TradeOfferItem `json:"offer_items"`
+	RequestItems   []TradeOfferItem `json:"request_items"`
+	Message        *string          `json:"message"`
+	Status         string           `json:"status"` // 'pending', 'accepted', 'declined', 'cancelled', 'countered', 'expired'
+	ParentOfferID  *int             `json:"parent_offer_id,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	ExpiresAt      time.Time        `json:"expires_at"`
+	ClosedAt       *time.Time       `json:"closed_at,omitempty"`
+
+	// Дополнительные поля для удобства клиента
+	IsIncoming    bool `json:"is_incoming"`              // true если предложение адресовано текущему игроку
+	TimeRemaining *int `json:"time_remaining,omitempty"` // секунды до истечения (для pending)
+}
+
+type TradeOffersResponse struct {
+	Offers []TradeOffer `json:"offers"`
+}
+
+type CreateTradeRequest struct {
+	ToPlayerID      int     `json:"to_player_id" binding:"required"`
+	OfferMoney      int     `json:"offer_money" binding:"min=0"`
+	RequestMoney    int     `json:"request_money" binding:"min=0"`
+	OfferItemIDs    []int   `json:"offer_item_ids"`
+	RequestItemIDs  []int   `json:"request_item_ids"`
+	Message         *string `json:"message"`
+	DurationMinutes int     `json:"duration_minutes" binding:"required,min=1"` // Срок предложения в минутах
+}
+
+// CounterTradeRequest - встречное предложение: стороны меняются местами,
+// offer_* - что отдаёт отвечающий, request_* - что он просит взамен
+type CounterTradeRequest struct {
+	OfferMoney      int     `json:"offer_money" binding:"min=0"`
+	RequestMoney    int     `json:"request_money" binding:"min=0"`
+	OfferItemIDs    []int   `json:"offer_item_ids"`
+	RequestItemIDs  []int   `json:"request_item_ids"`
+	Message         *string `json:"message"`
+	DurationMinutes int     `json:"duration_minutes" binding:"required,min=1"`
+}
@@ -1,6 +1,8 @@
 // internal/models/user.go
 package models
 
+import "time"
+
 type User struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
@@ -14,6 +16,53 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string    `json:"token"` // access-токен
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	User         User      `json:"user"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type UsersResponse struct {
+	Users []User `json:"users"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	PlayerID *int   `json:"player_id"` // NULL - учётная запись без персонажа (например, администратор)
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+// UpdateUserRequest - незаданные поля не меняются
+type UpdateUserRequest struct {
+	Username *string `json:"username,omitempty"`
+	Password *string `json:"password,omitempty"`
+	PlayerID *int    `json:"player_id,omitempty"` // 0 - отвязать от персонажа
+	IsAdmin  *bool   `json:"is_admin,omitempty"`
+}
+
+// UserSession - сессия пользователя (для админки)
+type UserSession struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	UserAgent    *string    `json:"user_agent,omitempty"`
+	IPAddress    *string    `json:"ip_address,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   time.Time  `json:"last_used_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason *string    `json:"revoke_reason,omitempty"`
+	IsActive     bool       `json:"is_active"`
+}
+
+type UserSessionsResponse struct {
+	Sessions []UserSession `json:"sessions"`
 }
@@ -0,0 +1,149 @@
+// internal/report/html.go
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+//go:embed report.html.tmpl
+var htmlSource string
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Format("02.01.2006 15:04:05") },
+	"optdatetime": func(t *time.Time) string {
+		if t == nil {
+			return "—"
+		}
+		return t.Format("02.01.2006 15:04:05")
+	},
+	"join": strings.Join,
+}).Parse(htmlSource))
+
+// Размеры графиков в пикселях SVG
+const (
+	chartWidth   = 720
+	chartHeight  = 260
+	chartPadding = 40
+)
+
+// chartColors - палитра линий; при большом числе игроков цвета повторяются
+var chartColors = []string{
+	"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4",
+	"#42d4f4", "#f032e6", "#9a6324", "#800000", "#469990",
+}
+
+type chart struct {
+	Title    string
+	Width    int
+	Height   int
+	Padding  int
+	Bottom   int // координата оси X
+	Right    int // правый край области графика
+	MinLabel int
+	MaxLabel int
+	From     string
+	To       string
+	Series   []chartSeries
+}
+
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points string // для атрибута points у polyline
+}
+
+type htmlData struct {
+	*Report
+	Charts []chart
+}
+
+// WriteHTML выводит отчёт одной страницей без внешних ресурсов: стили и графики встроены
+func (r *Report) WriteHTML(w io.Writer) error {
+	data := htmlData{Report: r}
+	if c, ok := r.curveChart("Влияние игроков", func(p CurvePoint) int { return p.Influence }); ok {
+		data.Charts = append(data.Charts, c)
+	}
+	if c, ok := r.curveChart("Деньги игроков", func(p CurvePoint) int { return p.Money }); ok {
+		data.Charts = append(data.Charts, c)
+	}
+
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+	return nil
+}
+
+// curveChart строит линейный график по кривым игроков. Без снимков графика нет
+func (r *Report) curveChart(title string, value func(CurvePoint) int) (chart, bool) {
+	var from, to time.Time
+	minValue, maxValue := 0, 0
+	found := false
+	for _, player := range r.Players {
+		for _, point := range player.Curve {
+			if !found || point.At.Before(from) {
+				from = point.At
+			}
+			if !found || point.At.After(to) {
+				to = point.At
+			}
+			if v := value(point); v < minValue {
+				minValue = v
+			} else if v > maxValue {
+				maxValue = v
+			}
+			found = true
+		}
+	}
+	if !found {
+		return chart{}, false
+	}
+	if maxValue == minValue {
+		maxValue = minValue + 1
+	}
+
+	c := chart{
+		Title:    title,
+		Width:    chartWidth,
+		Height:   chartHeight,
+		Padding:  chartPadding,
+		Bottom:   chartHeight - chartPadding,
+		Right:    chartWidth - chartPadding,
+		MinLabel: minValue,
+		MaxLabel: maxValue,
+		From:     from.Format("02.01 15:04"),
+		To:       to.Format("02.01 15:04"),
+	}
+
+	plotWidth := float64(chartWidth - 2*chartPadding)
+	plotHeight := float64(chartHeight - 2*chartPadding)
+	span := to.Sub(from).Seconds()
+
+	for i, player := range r.Players {
+		if len(player.Curve) == 0 {
+			continue
+		}
+
+		points := make([]string, 0, len(player.Curve))
+		for _, point := range player.Curve {
+			x := float64(chartPadding)
+			if span > 0 {
+				x += point.At.Sub(from).Seconds() / span * plotWidth
+			}
+			y := float64(chartHeight-chartPadding) - float64(value(point)-minValue)/float64(maxValue-minValue)*plotHeight
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+
+		c.Series = append(c.Series, chartSeries{
+			Name:   player.Name,
+			Color:  chartColors[i%len(chartColors)],
+			Points: strings.Join(points, " "),
+		})
+	}
+
+	return c, true
+}
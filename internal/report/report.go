@@ -0,0 +1,464 @@
+// internal/report/report.go
+package report
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Report - итоги прогона игры, собранные из журналов и таблиц истории.
+// Строится по данным текущего прогона, поэтому его нужно получить до сброса игры
+type Report struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Game        GameInfo         `json:"game"`
+	Factions    []FactionResult  `json:"factions"`
+	Players     []PlayerResult   `json:"players"`
+	Goals       GoalsSummary     `json:"goals"`
+	Contracts   []ContractResult `json:"contracts"`
+	Debts       []DebtResult     `json:"debts"`
+	Abilities   []AbilityUse     `json:"abilities"`
+	Secrets     []RevealedSecret `json:"secrets"`
+}
+
+type GameInfo struct {
+	StartedAt     *time.Time `json:"started_at"`
+	EndedAt       *time.Time `json:"ended_at"`
+	PausedSeconds int        `json:"paused_seconds"`
+	Duration      string     `json:"duration,omitempty"` // без учёта пауз
+}
+
+type FactionResult struct {
+	Rank             int      `json:"rank"`
+	Name             string   `json:"name"`
+	FactionInfluence int      `json:"faction_influence"`
+	PlayersInfluence int      `json:"players_influence"`
+	TotalInfluence   int      `json:"total_influence"`
+	PlayersMoney     int      `json:"players_money"`
+	Members          []string `json:"members"`
+}
+
+type PlayerResult struct {
+	Rank      int          `json:"rank"`
+	Name      string       `json:"name"`
+	Faction   string       `json:"faction,omitempty"`
+	Money     int          `json:"money"`
+	Influence int          `json:"influence"`
+	Curve     []CurvePoint `json:"curve"` // по снимкам таблицы лидеров
+}
+
+type CurvePoint struct {
+	At        time.Time `json:"at"`
+	Money     int       `json:"money"`
+	Influence int       `json:"influence"`
+}
+
+type GoalsSummary struct {
+	Total     int         `json:"total"`
+	Completed int         `json:"completed"`
+	History   []GoalEvent `json:"history"` // из goal_completion_history
+}
+
+type GoalEvent struct {
+	At              time.Time `json:"at"`
+	Goal            string    `json:"goal"`
+	GoalType        string    `json:"goal_type"` // 'personal', 'faction'
+	Owner           string    `json:"owner"`     // игрок или фракция
+	Action          string    `json:"action"`    // 'completed', 'uncompleted'
+	By              string    `json:"by,omitempty"`
+	InfluenceChange int       `json:"influence_change"`
+}
+
+type ContractResult struct {
+	ID                  int        `json:"id"`
+	ContractType        string     `json:"contract_type"`
+	Customer            string     `json:"customer"`
+	Executor            string     `json:"executor"`
+	Status              string     `json:"status"`
+	Outcome             string     `json:"outcome"` // 'honored', 'defaulted', 'terminated', 'active', 'unsigned'
+	MoneyRewardCustomer int        `json:"money_reward_customer"`
+	MoneyRewardExecutor int        `json:"money_reward_executor"`
+	SignedAt            *time.Time `json:"signed_at,omitempty"`
+	CompletedAt         *time.Time `json:"completed_at,omitempty"`
+	TerminatedAt        *time.Time `json:"terminated_at,omitempty"`
+	TerminationReason   string     `json:"termination_reason,omitempty"`
+	MoneyPenalties      int        `json:"money_penalties"`
+	InfluencePenalties  int        `json:"influence_penalties"`
+}
+
+type DebtResult struct {
+	ID               int        `json:"id"`
+	Lender           string     `json:"lender"`
+	Borrower         string     `json:"borrower"`
+	LoanAmount       int        `json:"loan_amount"`
+	ReturnAmount     int        `json:"return_amount"`
+	ReturnDeadline   time.Time  `json:"return_deadline"`
+	Outcome          string     `json:"outcome"` // 'returned', 'defaulted', 'overdue', 'outstanding'
+	ReturnedAt       *time.Time `json:"returned_at,omitempty"`
+	PenaltyAppliedAt *time.Time `json:"penalty_applied_at,omitempty"`
+}
+
+type AbilityUse struct {
+	At           time.Time `json:"at"`
+	Player       string    `json:"player"`
+	Ability      string    `json:"ability"`
+	AbilityType  string    `json:"ability_type"`
+	Target       string    `json:"target,omitempty"`
+	InfoCategory string    `json:"info_category,omitempty"`
+}
+
+type RevealedSecret struct {
+	At       time.Time       `json:"at"`
+	Revealer string          `json:"revealer"`
+	Target   string          `json:"target"`
+	InfoType string          `json:"info_type"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// Build собирает отчёт по текущему прогону из одного снимка базы
+func Build(db *sql.DB) (*Report, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Все разделы отчёта должны описывать один и тот же момент
+	if _, err = tx.Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`); err != nil {
+		return nil, fmt.Errorf("failed to set transaction isolation: %w", err)
+	}
+
+	r := &Report{
+		GeneratedAt: time.Now(),
+		Factions:    make([]FactionResult, 0),
+		Players:     make([]PlayerResult, 0),
+		Goals:       GoalsSummary{History: make([]GoalEvent, 0)},
+		Contracts:   make([]ContractResult, 0),
+		Debts:       make([]DebtResult, 0),
+		Abilities:   make([]AbilityUse, 0),
+		Secrets:     make([]RevealedSecret, 0),
+	}
+
+	steps := []func(*sql.Tx, *Report) error{
+		loadGame,
+		loadFactions,
+		loadPlayers,
+		loadGoals,
+		loadContracts,
+		loadDebts,
+		loadAbilities,
+		loadSecrets,
+	}
+	for _, step := range steps {
+		if err := step(tx, r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func loadGame(tx *sql.Tx, r *Report) error {
+	err := tx.QueryRow(`
+		SELECT game_started_at, game_ended_at, total_paused_seconds
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&r.Game.StartedAt, &r.Game.EndedAt, &r.Game.PausedSeconds)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch game timeline: %w", err)
+	}
+
+	if r.Game.StartedAt != nil {
+		end := r.GeneratedAt
+		if r.Game.EndedAt != nil {
+			end = *r.Game.EndedAt
+		}
+		played := end.Sub(*r.Game.StartedAt) - time.Duration(r.Game.PausedSeconds)*time.Second
+		r.Game.Duration = played.Round(time.Second).String()
+	}
+
+	return nil
+}
+
+func loadFactions(tx *sql.Tx, r *Report) error {
+	rows, err := tx.Query(`
+		SELECT
+			f.name,
+			COALESCE(f.faction_influence, 0),
+			COALESCE(SUM(p.influence), 0),
+			COALESCE(f.faction_influence, 0) + COALESCE(SUM(p.influence), 0) AS total_influence,
+			COALESCE(SUM(p.money), 0),
+			COALESCE(array_agg(p.character_name ORDER BY p.character_name) FILTER (WHERE p.id IS NOT NULL), '{}')
+		FROM factions f
+		LEFT JOIN players p ON p.faction_id = f.id
+		GROUP BY f.id, f.name, f.faction_influence
+		ORDER BY total_influence DESC, f.name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch factions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var faction FactionResult
+		if err := rows.Scan(&faction.Name, &faction.FactionInfluence, &faction.PlayersInfluence,
+			&faction.TotalInfluence, &faction.PlayersMoney, pq.Array(&faction.Members)); err != nil {
+			return fmt.Errorf("failed to scan faction: %w", err)
+		}
+
+		faction.Rank = len(r.Factions) + 1
+		if last := len(r.Factions) - 1; last >= 0 && r.Factions[last].TotalInfluence == faction.TotalInfluence {
+			faction.Rank = r.Factions[last].Rank
+		}
+		r.Factions = append(r.Factions, faction)
+	}
+
+	return rows.Err()
+}
+
+func loadPlayers(tx *sql.Tx, r *Report) error {
+	rows, err := tx.Query(`
+		SELECT p.id, p.character_name, COALESCE(f.name, ''), COALESCE(p.money, 0), COALESCE(p.influence, 0)
+		FROM players p
+		LEFT JOIN factions f ON f.id = p.faction_id
+		ORDER BY p.influence DESC, p.money DESC, p.character_name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch players: %w", err)
+	}
+	defer rows.Close()
+
+	playerIndex := make(map[int]int)
+	for rows.Next() {
+		var id int
+		player := PlayerResult{Curve: make([]CurvePoint, 0)}
+		if err := rows.Scan(&id, &player.Name, &player.Faction, &player.Money, &player.Influence); err != nil {
+			return fmt.Errorf("failed to scan player: %w", err)
+		}
+
+		player.Rank = len(r.Players) + 1
+		if last := len(r.Players) - 1; last >= 0 && r.Players[last].Influence == player.Influence {
+			player.Rank = r.Players[last].Rank
+		}
+		playerIndex[id] = len(r.Players)
+		r.Players = append(r.Players, player)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(`
+		SELECT h.player_id, s.taken_at, h.money, h.influence
+		FROM player_standings_history h
+		JOIN leaderboard_snapshots s ON s.id = h.snapshot_id
+		ORDER BY s.taken_at, h.player_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch player history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var playerID int
+		var point CurvePoint
+		if err := rows.Scan(&playerID, &point.At, &point.Money, &point.Influence); err != nil {
+			return fmt.Errorf("failed to scan player history: %w", err)
+		}
+		if index, exists := playerIndex[playerID]; exists {
+			r.Players[index].Curve = append(r.Players[index].Curve, point)
+		}
+	}
+
+	return rows.Err()
+}
+
+func loadGoals(tx *sql.Tx, r *Report) error {
+	err := tx.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE is_completed)
+		FROM goals
+	`).Scan(&r.Goals.Total, &r.Goals.Completed)
+	if err != nil {
+		return fmt.Errorf("failed to count goals: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT h.created_at, g.title, g.goal_type, COALESCE(owner.character_name, f.name, ''),
+		       h.action, COALESCE(p.character_name, ''), h.influence_change
+		FROM goal_completion_history h
+		JOIN goals g ON g.id = h.goal_id
+		LEFT JOIN players owner ON owner.id = g.player_id
+		LEFT JOIN factions f ON f.id = g.faction_id
+		LEFT JOIN players p ON p.id = h.player_id
+		ORDER BY h.created_at, h.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch goal history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event GoalEvent
+		if err := rows.Scan(&event.At, &event.Goal, &event.GoalType, &event.Owner,
+			&event.Action, &event.By, &event.InfluenceChange); err != nil {
+			return fmt.Errorf("failed to scan goal history: %w", err)
+		}
+		r.Goals.History = append(r.Goals.History, event)
+	}
+
+	return rows.Err()
+}
+
+func loadContracts(tx *sql.Tx, r *Report) error {
+	// Причина расторжения записана в журнал денег нулевой записью по договору
+	rows, err := tx.Query(`
+		SELECT c.id, c.contract_type, COALESCE(cu.character_name, ''), COALESCE(ex.character_name, ''),
+		       c.status, COALESCE(c.money_reward_customer, 0), COALESCE(c.money_reward_executor, 0),
+		       c.signed_at, c.completed_at, c.terminated_at,
+		       COALESCE((
+		           SELECT mt.description FROM money_transactions mt
+		           WHERE mt.reference_type = 'contract' AND mt.reference_id = c.id AND mt.amount = 0
+		           ORDER BY mt.id DESC LIMIT 1
+		       ), ''),
+		       COALESCE((SELECT SUM(cp.money_penalty) FROM contract_penalties cp WHERE cp.contract_id = c.id), 0),
+		       COALESCE((SELECT SUM(cp.influence_penalty) FROM contract_penalties cp WHERE cp.contract_id = c.id), 0)
+		FROM contracts c
+		LEFT JOIN players cu ON cu.id = c.customer_player_id
+		LEFT JOIN players ex ON ex.id = c.executor_player_id
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch contracts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var contract ContractResult
+		if err := rows.Scan(&contract.ID, &contract.ContractType, &contract.Customer, &contract.Executor,
+			&contract.Status, &contract.MoneyRewardCustomer, &contract.MoneyRewardExecutor,
+			&contract.SignedAt, &contract.CompletedAt, &contract.TerminatedAt, &contract.TerminationReason,
+			&contract.MoneyPenalties, &contract.InfluencePenalties); err != nil {
+			return fmt.Errorf("failed to scan contract: %w", err)
+		}
+
+		switch contract.Status {
+		case "completed":
+			contract.Outcome = "honored"
+		case "terminated":
+			// Расторжение со штрафом считается нарушением договора
+			contract.Outcome = "terminated"
+			if contract.MoneyPenalties > 0 || contract.InfluencePenalties > 0 {
+				contract.Outcome = "defaulted"
+			}
+		case "signed":
+			contract.Outcome = "active"
+		default:
+			contract.Outcome = "unsigned"
+		}
+		r.Contracts = append(r.Contracts, contract)
+	}
+
+	return rows.Err()
+}
+
+func loadDebts(tx *sql.Tx, r *Report) error {
+	rows, err := tx.Query(`
+		SELECT d.id, COALESCE(l.character_name, ''), COALESCE(b.character_name, ''),
+		       d.loan_amount, d.return_amount, d.return_deadline,
+		       COALESCE(d.is_returned, false), d.returned_at,
+		       COALESCE(d.penalty_applied, false), d.penalty_applied_at
+		FROM debt_receipts d
+		LEFT JOIN players l ON l.id = d.lender_player_id
+		LEFT JOIN players b ON b.id = d.borrower_player_id
+		ORDER BY d.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch debts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var debt DebtResult
+		var isReturned, penaltyApplied bool
+		if err := rows.Scan(&debt.ID, &debt.Lender, &debt.Borrower, &debt.LoanAmount, &debt.ReturnAmount,
+			&debt.ReturnDeadline, &isReturned, &debt.ReturnedAt, &penaltyApplied, &debt.PenaltyAppliedAt); err != nil {
+			return fmt.Errorf("failed to scan debt: %w", err)
+		}
+
+		switch {
+		case isReturned:
+			debt.Outcome = "returned"
+		case penaltyApplied:
+			debt.Outcome = "defaulted"
+		case debt.ReturnDeadline.Before(r.GeneratedAt):
+			debt.Outcome = "overdue"
+		default:
+			debt.Outcome = "outstanding"
+		}
+		r.Debts = append(r.Debts, debt)
+	}
+
+	return rows.Err()
+}
+
+func loadAbilities(tx *sql.Tx, r *Report) error {
+	rows, err := tx.Query(`
+		SELECT u.used_at, COALESCE(p.character_name, ''), COALESCE(a.name, ''), COALESCE(a.ability_type, ''),
+		       COALESCE(t.character_name, ''), COALESCE(u.info_category, '')
+		FROM ability_usage u
+		LEFT JOIN players p ON p.id = u.player_id
+		LEFT JOIN abilities a ON a.id = u.ability_id
+		LEFT JOIN players t ON t.id = u.target_player_id
+		ORDER BY u.used_at, u.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch ability usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var use AbilityUse
+		if err := rows.Scan(&use.At, &use.Player, &use.Ability, &use.AbilityType,
+			&use.Target, &use.InfoCategory); err != nil {
+			return fmt.Errorf("failed to scan ability usage: %w", err)
+		}
+		r.Abilities = append(r.Abilities, use)
+	}
+
+	return rows.Err()
+}
+
+func loadSecrets(tx *sql.Tx, r *Report) error {
+	rows, err := tx.Query(`
+		SELECT ri.revealed_at, COALESCE(rv.character_name, ''), COALESCE(t.character_name, ''),
+		       ri.info_type, ri.revealed_data
+		FROM revealed_info ri
+		LEFT JOIN players rv ON rv.id = ri.revealer_player_id
+		LEFT JOIN players t ON t.id = ri.target_player_id
+		ORDER BY ri.revealed_at, ri.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch revealed info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var secret RevealedSecret
+		var data []byte
+		if err := rows.Scan(&secret.At, &secret.Revealer, &secret.Target, &secret.InfoType, &data); err != nil {
+			return fmt.Errorf("failed to scan revealed info: %w", err)
+		}
+		if len(data) > 0 {
+			secret.Data = json.RawMessage(data)
+		}
+		r.Secrets = append(r.Secrets, secret)
+	}
+
+	return rows.Err()
+}
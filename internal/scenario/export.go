@@ -0,0 +1,568 @@
+// internal/scenario/export.go
+package scenario
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Export выгружает текущее содержимое базы в формат сценария.
+// Балансы, влияние и инвентари берутся текущими: чтобы получить стартовое состояние,
+// экспорт нужно делать до старта игры или после сброса прогона.
+// Не выгружаются экземпляры предметов без владельца, цели гонки, договоры, долги и журналы
+func Export(db *sql.DB) (*Scenario, error) {
+	// Все разделы читаются из одного снимка базы
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	exp := &exporter{
+		tx:        tx,
+		factions:  make(map[int]string),
+		players:   make(map[int]string),
+		templates: make(map[int]string),
+		goals:     make(map[int]string),
+	}
+	s := &Scenario{}
+
+	steps := []func(*Scenario) error{
+		exp.exportFactions,
+		exp.exportPlayers,
+		exp.exportItems,
+		exp.exportInventories,
+		exp.exportUsers,
+		exp.exportAbilities,
+		exp.exportGoals,
+		exp.exportSettings,
+	}
+	for _, step := range steps {
+		if err := step(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// exporter хранит соответствие ID базы и имён, под которыми записи попадают в сценарий
+type exporter struct {
+	tx        *sql.Tx
+	factions  map[int]string
+	players   map[int]string
+	templates map[int]string
+	goals     map[int]string
+}
+
+// name возвращает имя по nullable ID
+func name(names map[int]string, id sql.NullInt64) string {
+	if !id.Valid {
+		return ""
+	}
+	return names[int(id.Int64)]
+}
+
+// intPtr превращает nullable значение в указатель для полей с omitempty
+func intPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
+
+func (exp *exporter) exportFactions(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT id, name, COALESCE(description, ''), COALESCE(faction_influence, 0), treasury,
+		       COALESCE(is_composition_visible_to_all, false)
+		FROM factions
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch factions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var faction Faction
+		if err := rows.Scan(&id, &faction.Name, &faction.Description, &faction.FactionInfluence,
+			&faction.Treasury, &faction.IsCompositionVisibleToAll); err != nil {
+			return fmt.Errorf("failed to scan faction: %w", err)
+		}
+		exp.factions[id] = faction.Name
+		s.Factions = append(s.Factions, faction)
+	}
+
+	return rows.Err()
+}
+
+func (exp *exporter) exportPlayers(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT id, character_name, password, role, COALESCE(character_story, ''),
+		       faction_id, COALESCE(can_change_faction, false), COALESCE(money, 0),
+		       COALESCE(influence, 0), COALESCE(avatar, '')
+		FROM players
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch players: %w", err)
+	}
+	defer rows.Close()
+
+	playerIndex := make(map[int]int)
+	duplicates := make([]string, 0)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id int
+		var factionID sql.NullInt64
+		var player Player
+		if err := rows.Scan(&id, &player.Name, &player.Password, &player.Role, &player.CharacterStory,
+			&factionID, &player.CanChangeFaction, &player.Money, &player.Influence, &player.Avatar); err != nil {
+			return fmt.Errorf("failed to scan player: %w", err)
+		}
+		player.Faction = name(exp.factions, factionID)
+
+		// На игроков ссылаются по имени, поэтому одинаковые имена нельзя выгрузить без потерь
+		if seen[player.Name] {
+			duplicates = append(duplicates, player.Name)
+		}
+		seen[player.Name] = true
+
+		exp.players[id] = player.Name
+		playerIndex[id] = len(s.Players)
+		s.Players = append(s.Players, player)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("players with duplicate names cannot be exported, rename them first: %s",
+			strings.Join(duplicates, ", "))
+	}
+
+	rows, err = exp.tx.Query(`
+		SELECT player_id, description
+		FROM info_about_other_players
+		WHERE player_id IS NOT NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch player info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var playerID int
+		var description sql.NullString
+		if err := rows.Scan(&playerID, &description); err != nil {
+			return fmt.Errorf("failed to scan player info: %w", err)
+		}
+		index := playerIndex[playerID]
+		s.Players[index].InfoAboutOtherPlayers = append(s.Players[index].InfoAboutOtherPlayers, description.String)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Лидер - тоже ссылка по имени, поэтому заполняется после игроков
+	rows, err = exp.tx.Query(`SELECT leader_player_id FROM factions ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch faction leaders: %w", err)
+	}
+	defer rows.Close()
+
+	for i := 0; rows.Next(); i++ {
+		var leaderID sql.NullInt64
+		if err := rows.Scan(&leaderID); err != nil {
+			return fmt.Errorf("failed to scan faction leader: %w", err)
+		}
+		s.Factions[i].Leader = name(exp.players, leaderID)
+	}
+
+	return rows.Err()
+}
+
+func (exp *exporter) exportItems(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT id, name, COALESCE(description, '')
+		FROM item_templates
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch item templates: %w", err)
+	}
+	defer rows.Close()
+
+	templateIDs := make([]int, 0)
+	duplicates := make([]string, 0)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id int
+		var item Item
+		if err := rows.Scan(&id, &item.Name, &item.Description); err != nil {
+			return fmt.Errorf("failed to scan item template: %w", err)
+		}
+		if seen[item.Name] {
+			duplicates = append(duplicates, item.Name)
+		}
+		seen[item.Name] = true
+
+		exp.templates[id] = item.Name
+		templateIDs = append(templateIDs, id)
+		s.Items = append(s.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("item templates with duplicate names cannot be exported, rename them first: %s",
+			strings.Join(duplicates, ", "))
+	}
+
+	for i, templateID := range templateIDs {
+		effects, err := exp.effects(`SELECT effect_id FROM item_template_effects WHERE template_id = $1`, templateID)
+		if err != nil {
+			return err
+		}
+		s.Items[i].Effects = effects
+	}
+
+	return nil
+}
+
+// effects читает эффекты по запросу, возвращающему их ID
+func (exp *exporter) effects(idsQuery string, ownerID int) ([]Effect, error) {
+	rows, err := exp.tx.Query(`
+		SELECT COALESCE(description, ''), effect_type, COALESCE(operation, 'add'), value,
+		       spawned_template_id, period_seconds
+		FROM effects
+		WHERE id IN (`+idsQuery+`)
+		ORDER BY id
+	`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch effects: %w", err)
+	}
+	defer rows.Close()
+
+	effects := make([]Effect, 0)
+	for rows.Next() {
+		var effect Effect
+		var value, spawnedTemplateID sql.NullInt64
+		if err := rows.Scan(&effect.Description, &effect.EffectType, &effect.Operation, &value,
+			&spawnedTemplateID, &effect.PeriodSeconds); err != nil {
+			return nil, fmt.Errorf("failed to scan effect: %w", err)
+		}
+		if effect.Operation == "add" {
+			effect.Operation = ""
+		}
+		effect.Value = intPtr(value)
+		effect.SpawnedItem = name(exp.templates, spawnedTemplateID)
+		effects = append(effects, effect)
+	}
+
+	return effects, rows.Err()
+}
+
+// exportInventories выгружает предметы игроков как названия шаблонов.
+// Предметы, созданные без шаблона (POST /api/admin/items), становятся шаблонами по названию:
+// первый такой экземпляр задаёт описание и эффекты, а экземпляр с названием существующего
+// шаблона считается экземпляром этого шаблона
+func (exp *exporter) exportInventories(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT pi.player_id, i.id, i.name, COALESCE(i.description, ''), i.template_id
+		FROM player_items pi
+		JOIN items i ON i.id = pi.item_id
+		ORDER BY pi.player_id, pi.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch inventories: %w", err)
+	}
+	defer rows.Close()
+
+	type ownedItem struct {
+		playerID    int
+		itemID      int
+		name        string
+		description string
+		templateID  sql.NullInt64
+	}
+	owned := make([]ownedItem, 0)
+	for rows.Next() {
+		var item ownedItem
+		if err := rows.Scan(&item.playerID, &item.itemID, &item.name, &item.description, &item.templateID); err != nil {
+			return fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		owned = append(owned, item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	items := make(map[string]bool)
+	for _, item := range s.Items {
+		items[item.Name] = true
+	}
+
+	playerIndex := make(map[string]int)
+	for i, player := range s.Players {
+		playerIndex[player.Name] = i
+	}
+
+	for _, item := range owned {
+		itemName := name(exp.templates, item.templateID)
+		if itemName == "" {
+			itemName = item.name
+			if !items[itemName] {
+				effects, err := exp.effects(`SELECT effect_id FROM item_effects WHERE item_id = $1`, item.itemID)
+				if err != nil {
+					return err
+				}
+				s.Items = append(s.Items, Item{Name: itemName, Description: item.description, Effects: effects})
+				items[itemName] = true
+			}
+		}
+
+		index := playerIndex[exp.players[item.playerID]]
+		s.Players[index].Items = append(s.Players[index].Items, itemName)
+	}
+
+	return nil
+}
+
+func (exp *exporter) exportUsers(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT username, password, player_id, COALESCE(is_admin, false)
+		FROM users
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user User
+		var playerID sql.NullInt64
+		if err := rows.Scan(&user.Username, &user.Password, &playerID, &user.IsAdmin); err != nil {
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		user.Player = name(exp.players, playerID)
+		s.Users = append(s.Users, user)
+	}
+
+	return rows.Err()
+}
+
+func (exp *exporter) exportAbilities(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT player_id, name, COALESCE(description, ''), ability_type, cooldown_minutes,
+		       start_delay_minutes, required_influence_points, COALESCE(is_unlocked, true),
+		       influence_points_to_add, influence_points_to_remove, influence_points_to_self
+		FROM abilities
+		WHERE player_id IS NOT NULL
+		ORDER BY id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch abilities: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ability Ability
+		var playerID int
+		var isUnlocked bool
+		var cooldown, startDelay, required, toAdd, toRemove, toSelf sql.NullInt64
+		if err := rows.Scan(&playerID, &ability.Name, &ability.Description, &ability.AbilityType,
+			&cooldown, &startDelay, &required, &isUnlocked, &toAdd, &toRemove, &toSelf); err != nil {
+			return fmt.Errorf("failed to scan ability: %w", err)
+		}
+
+		ability.Player = exp.players[playerID]
+		ability.CooldownMinutes = intPtr(cooldown)
+		ability.StartDelayMinutes = intPtr(startDelay)
+		ability.RequiredInfluencePoints = intPtr(required)
+		ability.InfluencePointsToAdd = intPtr(toAdd)
+		ability.InfluencePointsToRemove = intPtr(toRemove)
+		ability.InfluencePointsToSelf = intPtr(toSelf)
+		if !isUnlocked {
+			ability.IsUnlocked = &isUnlocked
+		}
+		s.Abilities = append(s.Abilities, ability)
+	}
+
+	return rows.Err()
+}
+
+// exportGoals выгружает цели сценария (без целей, выданных раундами гонки).
+// Ключ задаётся только целям с повторяющимся названием
+func (exp *exporter) exportGoals(s *Scenario) error {
+	rows, err := exp.tx.Query(`
+		SELECT g.id, g.title, COALESCE(g.description, ''), g.player_id, g.faction_id,
+		       COALESCE(g.influence_points_reward, 0)
+		FROM goals g
+		WHERE NOT EXISTS (SELECT 1 FROM goal_race_round_goals rg WHERE rg.goal_id = g.id)
+		ORDER BY g.id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch goals: %w", err)
+	}
+	defer rows.Close()
+
+	goalIDs := make([]int, 0)
+	titles := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var goal Goal
+		var playerID, factionID sql.NullInt64
+		if err := rows.Scan(&id, &goal.Title, &goal.Description, &playerID, &factionID,
+			&goal.InfluencePointsReward); err != nil {
+			return fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goal.Player = name(exp.players, playerID)
+		goal.Faction = name(exp.factions, factionID)
+
+		titles[goal.Title]++
+		goalIDs = append(goalIDs, id)
+		s.Goals = append(s.Goals, goal)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	goalIndex := make(map[int]int)
+	for i, id := range goalIDs {
+		if titles[s.Goals[i].Title] > 1 {
+			s.Goals[i].Key = fmt.Sprintf("%s #%d", s.Goals[i].Title, id)
+		}
+		exp.goals[id] = s.Goals[i].GoalKey()
+		goalIndex[id] = i
+	}
+
+	rows, err = exp.tx.Query(`
+		SELECT goal_id, required_goal_id, influence_player_id, required_influence_points,
+		       COALESCE(is_visible_before_completion, false)
+		FROM goal_dependencies
+		ORDER BY goal_id, id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch goal dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var goalID int
+		var requiredGoalID, influencePlayerID, requiredInfluence sql.NullInt64
+		var dependency GoalDependency
+		if err := rows.Scan(&goalID, &requiredGoalID, &influencePlayerID, &requiredInfluence,
+			&dependency.IsVisibleBeforeCompletion); err != nil {
+			return fmt.Errorf("failed to scan goal dependency: %w", err)
+		}
+
+		index, exported := goalIndex[goalID]
+		if !exported {
+			continue
+		}
+		dependency.Goal = name(exp.goals, requiredGoalID)
+		dependency.InfluencePlayer = name(exp.players, influencePlayerID)
+		dependency.RequiredInfluencePoints = int(requiredInfluence.Int64)
+		s.Goals[index].Dependencies = append(s.Goals[index].Dependencies, dependency)
+	}
+
+	return rows.Err()
+}
+
+// exportSettings выгружает действующие (последние) строки таблиц настроек
+func (exp *exporter) exportSettings(s *Scenario) error {
+	contracts := &ContractSettings{}
+
+	var type1 ContractRewards
+	err := exp.tx.QueryRow(`
+		SELECT COALESCE(money_reward_customer, 0), COALESCE(money_reward_executor, 0), money_reward_faction
+		FROM contract_type1_reward_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&type1.MoneyRewardCustomer, &type1.MoneyRewardExecutor, &type1.MoneyRewardFaction)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch contract type1 settings: %w", err)
+	}
+	if err == nil {
+		contracts.Type1 = &type1
+	}
+
+	var type2 ContractRewards
+	err = exp.tx.QueryRow(`
+		SELECT COALESCE(money_reward_executor, 0)
+		FROM contract_type2_reward_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&type2.MoneyRewardExecutor)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch contract type2 settings: %w", err)
+	}
+	if err == nil {
+		contracts.Type2 = &type2
+	}
+
+	var penalty ContractPenalty
+	err = exp.tx.QueryRow(`
+		SELECT COALESCE(money_penalty, 0), COALESCE(influence_penalty, 0)
+		FROM contract_penalty_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&penalty.MoneyPenalty, &penalty.InfluencePenalty)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch contract penalty settings: %w", err)
+	}
+	if err == nil {
+		contracts.Penalty = &penalty
+	}
+
+	rows, err := exp.tx.Query(`
+		SELECT faction_id, customer_item_reward_template_id
+		FROM contract_type1_settings
+		WHERE faction_id IS NOT NULL AND customer_item_reward_template_id IS NOT NULL
+		ORDER BY faction_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fetch contract item rewards: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var factionID, templateID int
+		if err := rows.Scan(&factionID, &templateID); err != nil {
+			return fmt.Errorf("failed to scan contract item reward: %w", err)
+		}
+		contracts.FactionItemRewards = append(contracts.FactionItemRewards, FactionItemReward{
+			Faction: exp.factions[factionID],
+			Item:    exp.templates[templateID],
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if contracts.Type1 != nil || contracts.Type2 != nil || contracts.Penalty != nil || len(contracts.FactionItemRewards) > 0 {
+		s.Contracts = contracts
+	}
+
+	var debts DebtSettings
+	err = exp.tx.QueryRow(`
+		SELECT COALESCE(penalty_influence_points, 0)
+		FROM debt_penalty_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&debts.PenaltyInfluencePoints)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to fetch debt settings: %w", err)
+	}
+	if err == nil {
+		s.Debts = &debts
+	}
+
+	return nil
+}
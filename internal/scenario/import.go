@@ -0,0 +1,405 @@
+// internal/scenario/import.go
+package scenario
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"new-year-role-game-backend/internal/auth"
+	"new-year-role-game-backend/internal/storage/postgres"
+)
+
+// ErrNotEmpty - сценарий импортируется только в пустую базу (после ./api migrate up)
+var ErrNotEmpty = errors.New("database already contains game data")
+
+// Summary - количество созданных записей по разделам сценария
+type Summary struct {
+	Factions  int `json:"factions"`
+	Players   int `json:"players"`
+	Users     int `json:"users"`
+	Items     int `json:"items"`
+	Abilities int `json:"abilities"`
+	Goals     int `json:"goals"`
+}
+
+// contentTables - таблицы, которые должны быть пустыми перед импортом
+var contentTables = []string{
+	"factions", "players", "users", "item_templates", "items", "effects", "abilities", "goals",
+}
+
+// Import проверяет сценарий и создаёт его в одной транзакции: либо весь, либо ничего
+func Import(db *sql.DB, s *Scenario) (*Summary, error) {
+	if err := Validate(s); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range contentTables {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM ` + table + `)`).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: table %s is not empty", ErrNotEmpty, table)
+		}
+	}
+
+	imp := &importer{
+		tx:        tx,
+		factions:  make(map[string]int),
+		players:   make(map[string]int),
+		templates: make(map[string]int),
+		goals:     make(map[string]int),
+	}
+
+	steps := []func(*Scenario) error{
+		imp.importFactions,
+		imp.importPlayers,
+		imp.importUsers,
+		imp.importItems,
+		imp.importInventories,
+		imp.importAbilities,
+		imp.importGoals,
+		imp.importSettings,
+	}
+	for _, step := range steps {
+		if err := step(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &Summary{
+		Factions:  len(s.Factions),
+		Players:   len(s.Players),
+		Users:     len(s.Users),
+		Items:     len(s.Items),
+		Abilities: len(s.Abilities),
+		Goals:     len(s.Goals),
+	}, nil
+}
+
+// importer хранит соответствие имён из сценария и ID, выданных базой
+type importer struct {
+	tx        *sql.Tx
+	factions  map[string]int
+	players   map[string]int
+	templates map[string]int
+	goals     map[string]int
+}
+
+// nullString превращает пустую строку в NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullID возвращает ID по имени или NULL для пустого имени
+func nullID(ids map[string]int, name string) sql.NullInt64 {
+	if name == "" {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(ids[name]), Valid: true}
+}
+
+// hashPassword хеширует пароль из сценария. Экспорт выгружает уже готовые хеши,
+// они сохраняются как есть
+func hashPassword(password string) (string, error) {
+	if auth.IsHashed(password) {
+		return password, nil
+	}
+	return auth.HashPassword(password)
+}
+
+func (imp *importer) importFactions(s *Scenario) error {
+	for _, faction := range s.Factions {
+		var id int
+		err := imp.tx.QueryRow(`
+			INSERT INTO factions (name, description, faction_influence, treasury, is_composition_visible_to_all)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, faction.Name, nullString(faction.Description), faction.FactionInfluence, faction.Treasury,
+			faction.IsCompositionVisibleToAll).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create faction %q: %w", faction.Name, err)
+		}
+		imp.factions[faction.Name] = id
+	}
+	return nil
+}
+
+func (imp *importer) importPlayers(s *Scenario) error {
+	for _, player := range s.Players {
+		hash, err := hashPassword(player.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password of %q: %w", player.Name, err)
+		}
+
+		var id int
+		err = imp.tx.QueryRow(`
+			INSERT INTO players (character_name, password, character_story, role, money, influence,
+			                     faction_id, can_change_faction, avatar)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, player.Name, hash, nullString(player.CharacterStory), player.Role, player.Money, player.Influence,
+			nullID(imp.factions, player.Faction), player.CanChangeFaction, nullString(player.Avatar)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create player %q: %w", player.Name, err)
+		}
+		imp.players[player.Name] = id
+
+		for _, info := range player.InfoAboutOtherPlayers {
+			_, err := imp.tx.Exec(`
+				INSERT INTO info_about_other_players (player_id, description)
+				VALUES ($1, $2)
+			`, id, info)
+			if err != nil {
+				return fmt.Errorf("failed to add info of %q: %w", player.Name, err)
+			}
+		}
+	}
+
+	// Лидеров назначаем после создания игроков: factions и players ссылаются друг на друга
+	for _, faction := range s.Factions {
+		if faction.Leader == "" {
+			continue
+		}
+		_, err := imp.tx.Exec(`UPDATE factions SET leader_player_id = $1 WHERE id = $2`,
+			imp.players[faction.Leader], imp.factions[faction.Name])
+		if err != nil {
+			return fmt.Errorf("failed to set leader of %q: %w", faction.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (imp *importer) importUsers(s *Scenario) error {
+	for _, user := range s.Users {
+		hash, err := hashPassword(user.Password)
+		if err != nil {
+			return fmt.Errorf("failed to hash password of %q: %w", user.Username, err)
+		}
+
+		_, err = imp.tx.Exec(`
+			INSERT INTO users (username, password, player_id, is_admin)
+			VALUES ($1, $2, $3, $4)
+		`, user.Username, hash, nullID(imp.players, user.Player), user.IsAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to create user %q: %w", user.Username, err)
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importItems(s *Scenario) error {
+	// Сначала все шаблоны: эффект порождения может ссылаться на шаблон, описанный ниже
+	for _, item := range s.Items {
+		var id int
+		err := imp.tx.QueryRow(`
+			INSERT INTO item_templates (name, description)
+			VALUES ($1, $2)
+			RETURNING id
+		`, item.Name, nullString(item.Description)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create item %q: %w", item.Name, err)
+		}
+		imp.templates[item.Name] = id
+	}
+
+	for _, item := range s.Items {
+		for _, effect := range item.Effects {
+			operation := effect.Operation
+			if operation == "" {
+				operation = "add"
+			}
+
+			var resource sql.NullString
+			switch effect.EffectType {
+			case "generate_money":
+				resource = nullString("money")
+			case "generate_influence":
+				resource = nullString("influence")
+			}
+
+			var effectID int
+			err := imp.tx.QueryRow(`
+				INSERT INTO effects (description, effect_type, generated_resource, operation, value,
+				                     spawned_template_id, period_seconds)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id
+			`, nullString(effect.Description), effect.EffectType, resource, operation, effect.Value,
+				nullID(imp.templates, effect.SpawnedItem), effect.PeriodSeconds).Scan(&effectID)
+			if err != nil {
+				return fmt.Errorf("failed to create effect of %q: %w", item.Name, err)
+			}
+
+			_, err = imp.tx.Exec(`
+				INSERT INTO item_template_effects (template_id, effect_id)
+				VALUES ($1, $2)
+			`, imp.templates[item.Name], effectID)
+			if err != nil {
+				return fmt.Errorf("failed to attach effect to %q: %w", item.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// importInventories выдаёт игрокам экземпляры предметов. Таймеры эффектов запустит старт игры
+func (imp *importer) importInventories(s *Scenario) error {
+	items := postgres.WrapTx(imp.tx, nil).Items()
+	for _, player := range s.Players {
+		for _, name := range player.Items {
+			if _, err := items.Spawn(imp.templates[name], imp.players[player.Name]); err != nil {
+				return fmt.Errorf("failed to give %q to %q: %w", name, player.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importAbilities(s *Scenario) error {
+	for _, ability := range s.Abilities {
+		isUnlocked := true
+		if ability.IsUnlocked != nil {
+			isUnlocked = *ability.IsUnlocked
+		}
+
+		_, err := imp.tx.Exec(`
+			INSERT INTO abilities (player_id, name, description, ability_type, cooldown_minutes,
+			                       start_delay_minutes, required_influence_points, is_unlocked,
+			                       influence_points_to_add, influence_points_to_remove, influence_points_to_self)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, imp.players[ability.Player], ability.Name, nullString(ability.Description), ability.AbilityType,
+			ability.CooldownMinutes, ability.StartDelayMinutes, ability.RequiredInfluencePoints, isUnlocked,
+			ability.InfluencePointsToAdd, ability.InfluencePointsToRemove, ability.InfluencePointsToSelf)
+		if err != nil {
+			return fmt.Errorf("failed to create ability %q: %w", ability.Name, err)
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importGoals(s *Scenario) error {
+	for _, goal := range s.Goals {
+		goalType := "personal"
+		if goal.Faction != "" {
+			goalType = "faction"
+		}
+
+		var id int
+		err := imp.tx.QueryRow(`
+			INSERT INTO goals (title, description, goal_type, influence_points_reward, player_id, faction_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, goal.Title, nullString(goal.Description), goalType, goal.InfluencePointsReward,
+			nullID(imp.players, goal.Player), nullID(imp.factions, goal.Faction)).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create goal %q: %w", goal.GoalKey(), err)
+		}
+		imp.goals[goal.GoalKey()] = id
+	}
+
+	for _, goal := range s.Goals {
+		for _, dependency := range goal.Dependencies {
+			var err error
+			if dependency.Goal != "" {
+				_, err = imp.tx.Exec(`
+					INSERT INTO goal_dependencies (goal_id, dependency_type, required_goal_id, is_visible_before_completion)
+					VALUES ($1, 'goal_completion', $2, $3)
+				`, imp.goals[goal.GoalKey()], imp.goals[dependency.Goal], dependency.IsVisibleBeforeCompletion)
+			} else {
+				_, err = imp.tx.Exec(`
+					INSERT INTO goal_dependencies (goal_id, dependency_type, influence_player_id,
+					                               required_influence_points, is_visible_before_completion)
+					VALUES ($1, 'influence_threshold', $2, $3, $4)
+				`, imp.goals[goal.GoalKey()], imp.players[dependency.InfluencePlayer],
+					dependency.RequiredInfluencePoints, dependency.IsVisibleBeforeCompletion)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to add dependency of goal %q: %w", goal.GoalKey(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// importSettings заменяет настройки договоров и долгов, если они заданы в сценарии
+func (imp *importer) importSettings(s *Scenario) error {
+	if contracts := s.Contracts; contracts != nil {
+		if contracts.Type1 != nil {
+			if err := imp.replace("contract_type1_reward_settings", `
+				INSERT INTO contract_type1_reward_settings (money_reward_customer, money_reward_executor, money_reward_faction)
+				VALUES ($1, $2, $3)
+			`, contracts.Type1.MoneyRewardCustomer, contracts.Type1.MoneyRewardExecutor,
+				contracts.Type1.MoneyRewardFaction); err != nil {
+				return err
+			}
+		}
+
+		if contracts.Type2 != nil {
+			if err := imp.replace("contract_type2_reward_settings", `
+				INSERT INTO contract_type2_reward_settings (money_reward_executor)
+				VALUES ($1)
+			`, contracts.Type2.MoneyRewardExecutor); err != nil {
+				return err
+			}
+		}
+
+		if contracts.Penalty != nil {
+			if err := imp.replace("contract_penalty_settings", `
+				INSERT INTO contract_penalty_settings (money_penalty, influence_penalty)
+				VALUES ($1, $2)
+			`, contracts.Penalty.MoneyPenalty, contracts.Penalty.InfluencePenalty); err != nil {
+				return err
+			}
+		}
+
+		if len(contracts.FactionItemRewards) > 0 {
+			if _, err := imp.tx.Exec(`DELETE FROM contract_type1_settings`); err != nil {
+				return fmt.Errorf("failed to clear contract_type1_settings: %w", err)
+			}
+			for _, reward := range contracts.FactionItemRewards {
+				_, err := imp.tx.Exec(`
+					INSERT INTO contract_type1_settings (faction_id, customer_item_reward_template_id)
+					VALUES ($1, $2)
+				`, imp.factions[reward.Faction], imp.templates[reward.Item])
+				if err != nil {
+					return fmt.Errorf("failed to set contract reward of %q: %w", reward.Faction, err)
+				}
+			}
+		}
+	}
+
+	if s.Debts != nil {
+		if err := imp.replace("debt_penalty_settings", `
+			INSERT INTO debt_penalty_settings (penalty_influence_points)
+			VALUES ($1)
+		`, s.Debts.PenaltyInfluencePoints); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replace оставляет в таблице настроек единственную строку
+func (imp *importer) replace(table, insert string, args ...any) error {
+	if _, err := imp.tx.Exec(`DELETE FROM ` + table); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", table, err)
+	}
+	if _, err := imp.tx.Exec(insert, args...); err != nil {
+		return fmt.Errorf("failed to fill %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+// internal/scenario/scenario.go
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Форматы файла сценария
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Scenario - декларативное описание игры. Разделы ссылаются друг на друга по именам
+// (фракция, имя персонажа, название предмета, ключ цели), ID назначает база при импорте
+type Scenario struct {
+	Factions  []Faction         `json:"factions,omitempty"`
+	Players   []Player          `json:"players,omitempty"`
+	Users     []User            `json:"users,omitempty"`
+	Items     []Item            `json:"items,omitempty"`
+	Abilities []Ability         `json:"abilities,omitempty"`
+	Goals     []Goal            `json:"goals,omitempty"`
+	Contracts *ContractSettings `json:"contracts,omitempty"`
+	Debts     *DebtSettings     `json:"debts,omitempty"`
+}
+
+type Faction struct {
+	Name                      string `json:"name"`
+	Description               string `json:"description,omitempty"`
+	FactionInfluence          int    `json:"faction_influence,omitempty"`
+	Treasury                  int    `json:"treasury,omitempty"`
+	IsCompositionVisibleToAll bool   `json:"is_composition_visible_to_all,omitempty"`
+	Leader                    string `json:"leader,omitempty"` // имя персонажа из этой фракции
+}
+
+type Player struct {
+	Name                  string   `json:"name"` // character_name, уникально в сценарии
+	Password              string   `json:"password"`
+	Role                  string   `json:"role"`
+	CharacterStory        string   `json:"character_story,omitempty"`
+	Faction               string   `json:"faction,omitempty"`
+	CanChangeFaction      bool     `json:"can_change_faction,omitempty"`
+	Money                 int      `json:"money,omitempty"`
+	Influence             int      `json:"influence,omitempty"`
+	Avatar                string   `json:"avatar,omitempty"`                   // base64
+	InfoAboutOtherPlayers []string `json:"info_about_other_players,omitempty"` // что персонаж знает о других
+	Items                 []string `json:"items,omitempty"`                    // по экземпляру каждого предмета из items
+}
+
+type User struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Player   string `json:"player,omitempty"`
+	IsAdmin  bool   `json:"is_admin,omitempty"`
+}
+
+// Item - шаблон предмета. Экземпляры создаются по нему для инвентарей игроков
+type Item struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Effects     []Effect `json:"effects,omitempty"`
+}
+
+type Effect struct {
+	Description   string `json:"description,omitempty"`
+	EffectType    string `json:"effect_type"`         // 'generate_money', 'generate_influence', 'spawn_item'
+	Operation     string `json:"operation,omitempty"` // 'add' (по умолчанию), 'mul', 'sub', 'div'
+	Value         *int   `json:"value,omitempty"`     // для generate_*
+	SpawnedItem   string `json:"spawned_item,omitempty"`
+	PeriodSeconds int    `json:"period_seconds"`
+}
+
+type Ability struct {
+	Player                  string `json:"player"`
+	Name                    string `json:"name"`
+	Description             string `json:"description,omitempty"`
+	AbilityType             string `json:"ability_type"` // 'reveal_info', 'add_influence', 'transfer_influence'
+	CooldownMinutes         *int   `json:"cooldown_minutes,omitempty"`
+	StartDelayMinutes       *int   `json:"start_delay_minutes,omitempty"`
+	RequiredInfluencePoints *int   `json:"required_influence_points,omitempty"`
+	IsUnlocked              *bool  `json:"is_unlocked,omitempty"` // по умолчанию true
+	InfluencePointsToAdd    *int   `json:"influence_points_to_add,omitempty"`
+	InfluencePointsToRemove *int   `json:"influence_points_to_remove,omitempty"`
+	InfluencePointsToSelf   *int   `json:"influence_points_to_self,omitempty"`
+}
+
+// Goal - личная (player) или фракционная (faction) цель
+type Goal struct {
+	Key                   string           `json:"key,omitempty"` // по умолчанию title
+	Title                 string           `json:"title"`
+	Description           string           `json:"description,omitempty"`
+	Player                string           `json:"player,omitempty"`
+	Faction               string           `json:"faction,omitempty"`
+	InfluencePointsReward int              `json:"influence_points_reward,omitempty"`
+	Dependencies          []GoalDependency `json:"dependencies,omitempty"`
+}
+
+// GoalDependency - выполнение другой цели (goal) или порог влияния игрока (influence_player)
+type GoalDependency struct {
+	Goal                      string `json:"goal,omitempty"`
+	InfluencePlayer           string `json:"influence_player,omitempty"`
+	RequiredInfluencePoints   int    `json:"required_influence_points,omitempty"`
+	IsVisibleBeforeCompletion bool   `json:"is_visible_before_completion,omitempty"`
+}
+
+type ContractSettings struct {
+	Type1              *ContractRewards    `json:"type1,omitempty"`
+	Type2              *ContractRewards    `json:"type2,omitempty"` // money_reward_customer для type2 всегда 0
+	Penalty            *ContractPenalty    `json:"penalty,omitempty"`
+	FactionItemRewards []FactionItemReward `json:"faction_item_rewards,omitempty"`
+}
+
+type ContractRewards struct {
+	MoneyRewardCustomer int `json:"money_reward_customer,omitempty"`
+	MoneyRewardExecutor int `json:"money_reward_executor,omitempty"`
+	MoneyRewardFaction  int `json:"money_reward_faction,omitempty"` // в казну фракции заказчика, только type1
+}
+
+type ContractPenalty struct {
+	MoneyPenalty     int `json:"money_penalty,omitempty"`
+	InfluencePenalty int `json:"influence_penalty,omitempty"`
+}
+
+// FactionItemReward - предмет, который заказчик из фракции получает по договору type1
+type FactionItemReward struct {
+	Faction string `json:"faction"`
+	Item    string `json:"item"`
+}
+
+type DebtSettings struct {
+	PenaltyInfluencePoints int `json:"penalty_influence_points"`
+}
+
+// GoalKey возвращает ключ, по которому на цель ссылаются зависимости
+func (g Goal) GoalKey() string {
+	if g.Key != "" {
+		return g.Key
+	}
+	return g.Title
+}
+
+// FormatFromPath определяет формат по расширению файла
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unknown scenario format of %q (expected .json, .yaml or .yml)", path)
+	}
+}
+
+// Load читает сценарий из файла
+func Load(path string) (*Scenario, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data, format)
+}
+
+// Parse разбирает сценарий. Неизвестные поля считаются ошибкой, чтобы опечатка
+// в названии поля не превращалась молча в значение по умолчанию
+func Parse(data []byte, format string) (*Scenario, error) {
+	var s Scenario
+
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&s); err != nil {
+			return nil, fmt.Errorf("invalid scenario: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.UnmarshalWithOptions(data, &s, yaml.Strict()); err != nil {
+			return nil, fmt.Errorf("invalid scenario: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown scenario format %q", format)
+	}
+
+	return &s, nil
+}
+
+// Encode сериализует сценарий в указанный формат
+func Encode(s *Scenario, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(s)
+	default:
+		return nil, fmt.Errorf("unknown scenario format %q", format)
+	}
+}
@@ -0,0 +1,338 @@
+// internal/scenario/validate.go
+package scenario
+
+import (
+	"fmt"
+	"new-year-role-game-backend/internal/auth"
+	"strings"
+)
+
+// ValidationError перечисляет все найденные проблемы сценария, а не только первую
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("scenario has %d problems:\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...any) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+// Validate проверяет сценарий без обращения к базе: ссылки по именам, уникальность
+// и те же ограничения, что накладывает схема (CHECK в migrations)
+func Validate(s *Scenario) error {
+	v := &validator{}
+
+	factions := make(map[string]bool)
+	for i, faction := range s.Factions {
+		switch {
+		case faction.Name == "":
+			v.addf("factions[%d]: name is required", i)
+		case factions[faction.Name]:
+			v.addf("factions[%d]: duplicate faction %q", i, faction.Name)
+		}
+		factions[faction.Name] = true
+
+		if faction.Treasury < 0 {
+			v.addf("factions[%d] %q: treasury cannot be negative", i, faction.Name)
+		}
+	}
+
+	items := make(map[string]bool)
+	for i, item := range s.Items {
+		switch {
+		case item.Name == "":
+			v.addf("items[%d]: name is required", i)
+		case items[item.Name]:
+			v.addf("items[%d]: duplicate item %q", i, item.Name)
+		}
+		items[item.Name] = true
+	}
+
+	players := make(map[string]string) // имя -> фракция
+	for i, player := range s.Players {
+		where := fmt.Sprintf("players[%d] %q", i, player.Name)
+		if player.Name == "" {
+			v.addf("players[%d]: name is required", i)
+		} else if _, exists := players[player.Name]; exists {
+			v.addf("%s: duplicate player name", where)
+		}
+		players[player.Name] = player.Faction
+
+		if player.Password == "" {
+			v.addf("%s: password is required", where)
+		} else if len(player.Password) > auth.MaxPasswordLength {
+			v.addf("%s: password must be at most %d bytes", where, auth.MaxPasswordLength)
+		}
+		if player.Role == "" {
+			v.addf("%s: role is required", where)
+		}
+		if player.Faction != "" && !factions[player.Faction] {
+			v.addf("%s: unknown faction %q", where, player.Faction)
+		}
+		if player.Money < 0 {
+			v.addf("%s: money cannot be negative", where)
+		}
+		for _, item := range player.Items {
+			if !items[item] {
+				v.addf("%s: unknown item %q", where, item)
+			}
+		}
+	}
+
+	for i, faction := range s.Factions {
+		if faction.Leader == "" {
+			continue
+		}
+		leaderFaction, exists := players[faction.Leader]
+		if !exists {
+			v.addf("factions[%d] %q: unknown leader %q", i, faction.Name, faction.Leader)
+		} else if leaderFaction != faction.Name {
+			v.addf("factions[%d] %q: leader %q is not a member of the faction", i, faction.Name, faction.Leader)
+		}
+	}
+
+	usernames := make(map[string]bool)
+	for i, user := range s.Users {
+		where := fmt.Sprintf("users[%d] %q", i, user.Username)
+		if user.Username == "" {
+			v.addf("users[%d]: username is required", i)
+		} else if usernames[user.Username] {
+			v.addf("%s: duplicate username", where)
+		}
+		usernames[user.Username] = true
+
+		if user.Password == "" {
+			v.addf("%s: password is required", where)
+		} else if len(user.Password) > auth.MaxPasswordLength {
+			v.addf("%s: password must be at most %d bytes", where, auth.MaxPasswordLength)
+		}
+		if user.Player != "" {
+			if _, exists := players[user.Player]; !exists {
+				v.addf("%s: unknown player %q", where, user.Player)
+			}
+		}
+	}
+
+	for i, item := range s.Items {
+		for j, effect := range item.Effects {
+			v.validateEffect(fmt.Sprintf("items[%d] %q effects[%d]", i, item.Name, j), effect, items)
+		}
+	}
+
+	for i, ability := range s.Abilities {
+		v.validateAbility(fmt.Sprintf("abilities[%d] %q", i, ability.Name), ability, players)
+	}
+
+	v.validateGoals(s.Goals, players, factions)
+
+	if s.Contracts != nil {
+		v.validateContracts(s.Contracts, factions, items)
+	}
+
+	if s.Debts != nil && s.Debts.PenaltyInfluencePoints < 0 {
+		v.addf("debts: penalty_influence_points cannot be negative")
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func (v *validator) validateEffect(where string, effect Effect, items map[string]bool) {
+	switch effect.Operation {
+	case "", "add", "mul", "sub", "div":
+	default:
+		v.addf("%s: invalid operation %q (expected add, mul, sub or div)", where, effect.Operation)
+	}
+
+	if effect.PeriodSeconds <= 0 {
+		v.addf("%s: period_seconds must be positive", where)
+	}
+
+	switch effect.EffectType {
+	case "generate_money", "generate_influence":
+		if effect.Value == nil {
+			v.addf("%s: value is required for %s", where, effect.EffectType)
+		}
+		if effect.SpawnedItem != "" {
+			v.addf("%s: spawned_item is only allowed for spawn_item", where)
+		}
+	case "spawn_item":
+		if effect.SpawnedItem == "" {
+			v.addf("%s: spawned_item is required for spawn_item", where)
+		} else if !items[effect.SpawnedItem] {
+			v.addf("%s: unknown spawned_item %q", where, effect.SpawnedItem)
+		}
+	default:
+		v.addf("%s: invalid effect_type %q (expected generate_money, generate_influence or spawn_item)", where, effect.EffectType)
+	}
+}
+
+func (v *validator) validateAbility(where string, ability Ability, players map[string]string) {
+	if ability.Name == "" {
+		v.addf("%s: name is required", where)
+	}
+	if _, exists := players[ability.Player]; !exists {
+		v.addf("%s: unknown player %q", where, ability.Player)
+	}
+
+	add := ability.InfluencePointsToAdd != nil
+	remove := ability.InfluencePointsToRemove != nil
+	self := ability.InfluencePointsToSelf != nil
+
+	switch ability.AbilityType {
+	case "reveal_info":
+		if add || remove || self {
+			v.addf("%s: reveal_info takes no influence_points_* fields", where)
+		}
+	case "add_influence":
+		if !add || remove || self {
+			v.addf("%s: add_influence requires only influence_points_to_add", where)
+		}
+	case "transfer_influence":
+		if add || !remove || !self {
+			v.addf("%s: transfer_influence requires influence_points_to_remove and influence_points_to_self", where)
+		}
+	default:
+		v.addf("%s: invalid ability_type %q (expected reveal_info, add_influence or transfer_influence)", where, ability.AbilityType)
+	}
+}
+
+func (v *validator) validateGoals(goals []Goal, players map[string]string, factions map[string]bool) {
+	keys := make(map[string]bool)
+	for i, goal := range goals {
+		key := goal.GoalKey()
+		where := fmt.Sprintf("goals[%d] %q", i, key)
+
+		if goal.Title == "" {
+			v.addf("goals[%d]: title is required", i)
+		}
+		if key != "" && keys[key] {
+			v.addf("%s: duplicate goal key (set a distinct key for goals with the same title)", where)
+		}
+		keys[key] = true
+
+		if (goal.Player == "") == (goal.Faction == "") {
+			v.addf("%s: exactly one of player or faction is required", where)
+		}
+		if goal.Player != "" {
+			if _, exists := players[goal.Player]; !exists {
+				v.addf("%s: unknown player %q", where, goal.Player)
+			}
+		}
+		if goal.Faction != "" && !factions[goal.Faction] {
+			v.addf("%s: unknown faction %q", where, goal.Faction)
+		}
+	}
+
+	// Граф зависимостей по выполнению целей: цикл означает, что цели никогда не откроются
+	requires := make(map[string][]string)
+	for i, goal := range goals {
+		key := goal.GoalKey()
+		where := fmt.Sprintf("goals[%d] %q", i, key)
+		seen := make(map[string]bool)
+
+		for j, dependency := range goal.Dependencies {
+			depWhere := fmt.Sprintf("%s dependencies[%d]", where, j)
+
+			switch {
+			case dependency.Goal != "" && dependency.InfluencePlayer == "":
+				if dependency.RequiredInfluencePoints != 0 {
+					v.addf("%s: required_influence_points is only allowed with influence_player", depWhere)
+				}
+				if dependency.Goal == key {
+					v.addf("%s: goal cannot depend on itself", depWhere)
+				} else if !keys[dependency.Goal] {
+					v.addf("%s: unknown goal %q", depWhere, dependency.Goal)
+				}
+				if seen["goal:"+dependency.Goal] {
+					v.addf("%s: duplicate dependency on goal %q", depWhere, dependency.Goal)
+				}
+				seen["goal:"+dependency.Goal] = true
+				requires[key] = append(requires[key], dependency.Goal)
+
+			case dependency.InfluencePlayer != "" && dependency.Goal == "":
+				if _, exists := players[dependency.InfluencePlayer]; !exists {
+					v.addf("%s: unknown influence_player %q", depWhere, dependency.InfluencePlayer)
+				}
+				if dependency.RequiredInfluencePoints <= 0 {
+					v.addf("%s: required_influence_points must be positive", depWhere)
+				}
+				if seen["player:"+dependency.InfluencePlayer] {
+					v.addf("%s: duplicate dependency on influence of %q", depWhere, dependency.InfluencePlayer)
+				}
+				seen["player:"+dependency.InfluencePlayer] = true
+
+			default:
+				v.addf("%s: exactly one of goal or influence_player is required", depWhere)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(key string, path []string)
+	visit = func(key string, path []string) {
+		switch state[key] {
+		case visiting:
+			v.addf("goals: dependency cycle %s", strings.Join(append(path, key), " -> "))
+			return
+		case done:
+			return
+		}
+
+		state[key] = visiting
+		for _, required := range requires[key] {
+			visit(required, append(path, key))
+		}
+		state[key] = done
+	}
+	for _, goal := range goals {
+		visit(goal.GoalKey(), nil)
+	}
+}
+
+func (v *validator) validateContracts(settings *ContractSettings, factions, items map[string]bool) {
+	if settings.Type1 != nil && (settings.Type1.MoneyRewardCustomer < 0 || settings.Type1.MoneyRewardExecutor < 0 ||
+		settings.Type1.MoneyRewardFaction < 0) {
+		v.addf("contracts.type1: rewards cannot be negative")
+	}
+	if settings.Type2 != nil {
+		if settings.Type2.MoneyRewardCustomer != 0 {
+			v.addf("contracts.type2: money_reward_customer is always 0 for type2")
+		}
+		if settings.Type2.MoneyRewardFaction != 0 {
+			v.addf("contracts.type2: money_reward_faction is only paid for type1")
+		}
+		if settings.Type2.MoneyRewardExecutor < 0 {
+			v.addf("contracts.type2: rewards cannot be negative")
+		}
+	}
+
+	rewarded := make(map[string]bool)
+	for i, reward := range settings.FactionItemRewards {
+		where := fmt.Sprintf("contracts.faction_item_rewards[%d]", i)
+		if !factions[reward.Faction] {
+			v.addf("%s: unknown faction %q", where, reward.Faction)
+		}
+		if !items[reward.Item] {
+			v.addf("%s: unknown item %q", where, reward.Item)
+		}
+		if rewarded[reward.Faction] {
+			v.addf("%s: duplicate reward for faction %q", where, reward.Faction)
+		}
+		rewarded[reward.Faction] = true
+	}
+}
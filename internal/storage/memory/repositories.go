@@ -0,0 +1,739 @@
+// internal/storage/memory/repositories.go
+package memory
+
+import (
+	"fmt"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================
+// ИГРОКИ
+// ============================================
+
+type playerRepository struct {
+	tx *Tx
+}
+
+func (r playerRepository) Exists(playerID int) (bool, error) {
+	_, ok := r.tx.data.players[playerID]
+	return ok, nil
+}
+
+func (r playerRepository) Get(playerID int) (*storage.Player, error) {
+	player, ok := r.tx.data.players[playerID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+
+	player.FactionName = nil
+	if player.FactionID != nil {
+		if name, ok := r.tx.data.factions[*player.FactionID]; ok {
+			player.FactionName = &name
+		}
+	}
+
+	return &player, nil
+}
+
+func (r playerRepository) GetForUpdate(playerID int) (*storage.Player, error) {
+	return r.Get(playerID)
+}
+
+func (r playerRepository) change(playerID int, fn func(player *storage.Player)) error {
+	player, ok := r.tx.data.players[playerID]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	fn(&player)
+	r.tx.data.players[playerID] = player
+	return nil
+}
+
+// AddMoney и AddInfluence, как UPDATE в PostgreSQL, не трогают несуществующих игроков
+func (r playerRepository) AddMoney(playerID, amount int) error {
+	player, ok := r.tx.data.players[playerID]
+	if !ok {
+		return nil
+	}
+
+	// В БД на money стоит CHECK (money >= 0)
+	if player.Money+amount < 0 {
+		return fmt.Errorf("player %d money cannot be negative", playerID)
+	}
+
+	player.Money += amount
+	r.tx.data.players[playerID] = player
+	return nil
+}
+
+func (r playerRepository) AddInfluence(playerID, amount int) error {
+	if player, ok := r.tx.data.players[playerID]; ok {
+		player.Influence += amount
+		r.tx.data.players[playerID] = player
+	}
+	return nil
+}
+
+func (r playerRepository) TakeMoney(playerID, amount int) (int, error) {
+	taken := 0
+	err := r.change(playerID, func(player *storage.Player) {
+		taken = min(amount, player.Money)
+		player.Money -= taken
+	})
+	return taken, err
+}
+
+func (r playerRepository) TakeInfluence(playerID, amount int) (int, error) {
+	taken := 0
+	err := r.change(playerID, func(player *storage.Player) {
+		taken = min(amount, max(player.Influence, 0))
+		player.Influence -= taken
+	})
+	return taken, err
+}
+
+// ============================================
+// ФРАКЦИИ
+// ============================================
+
+type factionRepository struct {
+	tx *Tx
+}
+
+func (r factionRepository) AddTreasury(factionID, amount int) error {
+	if _, ok := r.tx.data.factions[factionID]; !ok {
+		return storage.ErrNotFound
+	}
+
+	// В БД на treasury стоит CHECK (treasury >= 0)
+	treasury := r.tx.data.treasuries[factionID]
+	if treasury+amount < 0 {
+		return fmt.Errorf("faction %d treasury cannot be negative", factionID)
+	}
+
+	r.tx.data.treasuries[factionID] = treasury + amount
+	return nil
+}
+
+// ============================================
+// ПРЕДМЕТЫ
+// ============================================
+
+type itemRepository struct {
+	tx *Tx
+}
+
+func (r itemRepository) Get(itemID int) (*storage.Item, error) {
+	item, ok := r.tx.data.items[itemID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &item, nil
+}
+
+func (r itemRepository) IsOwnedBy(playerID, itemID int) (bool, error) {
+	ownerID, ok := r.tx.data.owners[itemID]
+	return ok && ownerID == playerID, nil
+}
+
+func (r itemRepository) LockIfFree(itemID int) (bool, error) {
+	if _, ok := r.tx.data.items[itemID]; !ok {
+		return false, storage.ErrNotFound
+	}
+	if _, owned := r.tx.data.owners[itemID]; owned {
+		return false, nil
+	}
+	for _, listing := range r.tx.data.listings {
+		if listing.ItemID == itemID && listing.Status == "active" {
+			return false, nil
+		}
+	}
+	for _, auction := range r.tx.data.auctions {
+		if auction.ItemID == itemID && auction.Status == "active" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r itemRepository) LockOwned(playerID, itemID int) (bool, error) {
+	return r.IsOwnedBy(playerID, itemID)
+}
+
+// RandomOwned возвращает предмет игрока с наименьшим id - так результат предсказуем
+func (r itemRepository) RandomOwned(playerID int) (*storage.Item, error) {
+	found := false
+	var result storage.Item
+	for itemID, ownerID := range r.tx.data.owners {
+		if ownerID == playerID && (!found || itemID < result.ID) {
+			result = r.tx.data.items[itemID]
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+	return &result, nil
+}
+
+func (r itemRepository) Move(itemID, fromPlayerID, toPlayerID int) error {
+	if ownerID, ok := r.tx.data.owners[itemID]; ok && ownerID == fromPlayerID {
+		r.tx.data.owners[itemID] = toPlayerID
+	}
+	return nil
+}
+
+func (r itemRepository) Release(itemID, playerID int) error {
+	if ownerID, ok := r.tx.data.owners[itemID]; ok && ownerID == playerID {
+		delete(r.tx.data.owners, itemID)
+	}
+	return nil
+}
+
+func (r itemRepository) Give(itemID, playerID int) error {
+	if _, ok := r.tx.data.items[itemID]; !ok {
+		return fmt.Errorf("item %d not found", itemID)
+	}
+	if _, owned := r.tx.data.owners[itemID]; owned {
+		return fmt.Errorf("item %d already has an owner", itemID)
+	}
+	r.tx.data.owners[itemID] = playerID
+	return nil
+}
+
+func (r itemRepository) Spawn(templateID, playerID int) (*storage.Item, error) {
+	template, ok := r.tx.data.templates[templateID]
+	if !ok {
+		return nil, fmt.Errorf("item template %d not found", templateID)
+	}
+
+	item := storage.Item{
+		ID:          r.tx.newID(),
+		Name:        template.Name,
+		Description: template.Description,
+		TemplateID:  &template.ID,
+	}
+
+	r.tx.data.items[item.ID] = item
+	r.tx.data.itemEffects[item.ID] = append([]int(nil), template.EffectIDs...)
+	r.tx.data.owners[item.ID] = playerID
+
+	return &item, nil
+}
+
+func (r itemRepository) Effects(itemID int) ([]storage.Effect, error) {
+	effects := make([]storage.Effect, 0)
+	for _, effectID := range r.tx.data.itemEffects[itemID] {
+		if effect, ok := r.tx.data.effects[effectID]; ok {
+			effects = append(effects, effect)
+		}
+	}
+	sort.Slice(effects, func(i, j int) bool { return effects[i].ID < effects[j].ID })
+	return effects, nil
+}
+
+func (r itemRepository) GetEffect(effectID int) (*storage.Effect, error) {
+	effect, ok := r.tx.data.effects[effectID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &effect, nil
+}
+
+func (r itemRepository) SetEffectExecuted(playerID, itemID, effectID int, at time.Time) error {
+	r.tx.data.executions[executionKey{playerID, itemID, effectID}] = at
+	return nil
+}
+
+func (r itemRepository) ClearEffectExecutions(playerID, itemID int) error {
+	for key := range r.tx.data.executions {
+		if key.playerID == playerID && key.itemID == itemID {
+			delete(r.tx.data.executions, key)
+		}
+	}
+	return nil
+}
+
+// ============================================
+// ДОГОВОРЫ
+// ============================================
+
+type contractRepository struct {
+	tx *Tx
+}
+
+func (r contractRepository) GetForUpdate(contractID int) (*storage.Contract, error) {
+	contract, ok := r.tx.data.contracts[contractID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &contract, nil
+}
+
+func (r contractRepository) ConflictingFaction(playerID, factionID int) (*int, error) {
+	ids := make([]int, 0, len(r.tx.data.contracts))
+	for id := range r.tx.data.contracts {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		contract := r.tx.data.contracts[id]
+		if contract.Status != "signed" {
+			continue
+		}
+
+		var otherID int
+		switch playerID {
+		case contract.CustomerPlayerID:
+			otherID = contract.ExecutorPlayerID
+		case contract.ExecutorPlayerID:
+			otherID = contract.CustomerPlayerID
+		default:
+			continue
+		}
+
+		other, ok := r.tx.data.players[otherID]
+		if ok && other.FactionID != nil && *other.FactionID != factionID {
+			conflicting := *other.FactionID
+			return &conflicting, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r contractRepository) PenaltySettings() (int, int, error) {
+	return r.tx.data.penaltyMoney, r.tx.data.penaltyInfluence, nil
+}
+
+func (r contractRepository) RecordPenalty(penalty storage.ContractPenalty) error {
+	r.tx.data.contractPenalties = append(r.tx.data.contractPenalties, penalty)
+	return nil
+}
+
+func (r contractRepository) Type1ItemReward(factionID int) (*int, error) {
+	templateID, ok := r.tx.data.type1Rewards[factionID]
+	if !ok {
+		return nil, nil
+	}
+	return &templateID, nil
+}
+
+func (r contractRepository) change(contractID int, fn func(contract *storage.Contract)) error {
+	contract, ok := r.tx.data.contracts[contractID]
+	if !ok {
+		return nil
+	}
+	fn(&contract)
+	r.tx.data.contracts[contractID] = contract
+	return nil
+}
+
+func (r contractRepository) Sign(contractID int, signedAt, expiresAt time.Time, customerFactionID *int) error {
+	return r.change(contractID, func(contract *storage.Contract) {
+		contract.Status = "signed"
+		contract.ExpiresAt = &expiresAt
+		contract.CustomerFactionID = customerFactionID
+	})
+}
+
+func (r contractRepository) Complete(contractID int, at time.Time) error {
+	return r.change(contractID, func(contract *storage.Contract) { contract.Status = "completed" })
+}
+
+func (r contractRepository) Terminate(contractID int, at time.Time) error {
+	return r.change(contractID, func(contract *storage.Contract) { contract.Status = "terminated" })
+}
+
+// ============================================
+// ДОЛГОВЫЕ РАСПИСКИ
+// ============================================
+
+type debtRepository struct {
+	tx *Tx
+}
+
+func (r debtRepository) GetForUpdate(debtID int) (*storage.Debt, error) {
+	debt, ok := r.tx.data.debts[debtID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &debt, nil
+}
+
+func (r debtRepository) MarkReturned(debtID int, at time.Time) error {
+	if debt, ok := r.tx.data.debts[debtID]; ok {
+		debt.IsReturned = true
+		r.tx.data.debts[debtID] = debt
+	}
+	return nil
+}
+
+func (r debtRepository) MarkPenaltyApplied(debtID int, at time.Time) error {
+	if debt, ok := r.tx.data.debts[debtID]; ok {
+		debt.PenaltyApplied = true
+		r.tx.data.debts[debtID] = debt
+	}
+	return nil
+}
+
+func (r debtRepository) PenaltyInfluence() (int, error) {
+	return r.tx.data.debtPenaltyInfluence, nil
+}
+
+// ============================================
+// РЫНОК
+// ============================================
+
+type marketRepository struct {
+	tx *Tx
+}
+
+func (r marketRepository) Create(sellerID, itemID, price int, expiresAt time.Time) (int, error) {
+	for _, listing := range r.tx.data.listings {
+		if listing.ItemID == itemID && listing.Status == "active" {
+			return 0, fmt.Errorf("item %d is already listed", itemID)
+		}
+	}
+
+	listing := storage.Listing{
+		ID:             r.tx.newID(),
+		Status:         "active",
+		SellerPlayerID: sellerID,
+		ItemID:         itemID,
+		Price:          price,
+		ExpiresAt:      expiresAt,
+	}
+	r.tx.data.listings[listing.ID] = listing
+	return listing.ID, nil
+}
+
+func (r marketRepository) GetForUpdate(listingID int) (*storage.Listing, error) {
+	listing, ok := r.tx.data.listings[listingID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &listing, nil
+}
+
+func (r marketRepository) Close(listingID int, status string, buyerID *int, at time.Time) error {
+	listing, ok := r.tx.data.listings[listingID]
+	if !ok {
+		return nil
+	}
+	listing.Status = status
+	listing.BuyerPlayerID = buyerID
+	r.tx.data.listings[listingID] = listing
+	return nil
+}
+
+// ============================================
+// ОБМЕН
+// ============================================
+
+type tradeRepository struct {
+	tx *Tx
+}
+
+func (r tradeRepository) Create(offer storage.TradeOffer) (int, error) {
+	offer.ID = r.tx.newID()
+	offer.Status = "pending"
+	offer.OfferItems = append([]int(nil), offer.OfferItems...)
+	offer.RequestItems = append([]int(nil), offer.RequestItems...)
+	r.tx.data.trades[offer.ID] = offer
+	return offer.ID, nil
+}
+
+func (r tradeRepository) GetForUpdate(offerID int) (*storage.TradeOffer, error) {
+	offer, ok := r.tx.data.trades[offerID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &offer, nil
+}
+
+func (r tradeRepository) Close(offerID int, status string, at time.Time) error {
+	offer, ok := r.tx.data.trades[offerID]
+	if !ok {
+		return nil
+	}
+	offer.Status = status
+	r.tx.data.trades[offerID] = offer
+	return nil
+}
+
+// ============================================
+// АУКЦИОНЫ
+// ============================================
+
+type auctionRepository struct {
+	tx *Tx
+}
+
+func (r auctionRepository) Create(auction storage.Auction) (int, error) {
+	for _, existing := range r.tx.data.auctions {
+		if existing.ItemID == auction.ItemID && existing.Status == "active" {
+			return 0, fmt.Errorf("item %d is already at auction", auction.ItemID)
+		}
+	}
+
+	auction.ID = r.tx.newID()
+	auction.Status = "active"
+	r.tx.data.auctions[auction.ID] = auction
+	return auction.ID, nil
+}
+
+func (r auctionRepository) GetForUpdate(auctionID int) (*storage.Auction, error) {
+	auction, ok := r.tx.data.auctions[auctionID]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return &auction, nil
+}
+
+func (r auctionRepository) LeadingBid(auctionID int) (*storage.AuctionBid, error) {
+	for _, bid := range r.tx.data.bids {
+		if bid.AuctionID == auctionID && bid.Status == "leading" {
+			return &bid, nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (r auctionRepository) AddBid(auctionID, playerID, amount int) (int, error) {
+	bid := storage.AuctionBid{
+		ID:        r.tx.newID(),
+		AuctionID: auctionID,
+		PlayerID:  playerID,
+		Amount:    amount,
+		Status:    "leading",
+	}
+	r.tx.data.bids[bid.ID] = bid
+	return bid.ID, nil
+}
+
+func (r auctionRepository) SetBidStatus(bidID int, status string) error {
+	bid, ok := r.tx.data.bids[bidID]
+	if !ok {
+		return nil
+	}
+	bid.Status = status
+	r.tx.data.bids[bidID] = bid
+	return nil
+}
+
+func (r auctionRepository) Close(auctionID int, status string, winnerID, finalPrice *int, at time.Time) error {
+	auction, ok := r.tx.data.auctions[auctionID]
+	if !ok {
+		return nil
+	}
+	auction.Status = status
+	r.tx.data.auctions[auctionID] = auction
+	return nil
+}
+
+// ============================================
+// ЦЕЛИ И СПОСОБНОСТИ
+// ============================================
+
+type goalRepository struct {
+	tx *Tx
+}
+
+// RandomPersonal возвращает личную цель игрока с наименьшим id - так результат предсказуем
+func (r goalRepository) RandomPersonal(playerID int) (*storage.Goal, error) {
+	found := false
+	var result storage.Goal
+	for _, goal := range r.tx.data.goals {
+		if goal.PlayerID == playerID && (!found || goal.ID < result.ID) {
+			result = goal.Goal
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, storage.ErrNotFound
+	}
+	return &result, nil
+}
+
+type abilityRepository struct {
+	tx *Tx
+}
+
+func (r abilityRepository) GetForUpdate(abilityID, playerID int) (*models.Ability, *time.Time, error) {
+	ability, ok := r.tx.data.abilities[abilityID]
+	if !ok || ability.PlayerID != playerID {
+		return nil, nil, storage.ErrNotFound
+	}
+
+	var lastUsedAt *time.Time
+	for _, usage := range r.tx.data.usages {
+		if usage.AbilityID == abilityID && usage.PlayerID == playerID &&
+			(lastUsedAt == nil || usage.UsedAt.After(*lastUsedAt)) {
+			usedAt := usage.UsedAt
+			lastUsedAt = &usedAt
+		}
+	}
+
+	return &ability, lastUsedAt, nil
+}
+
+func (r abilityRepository) RecordUsage(usage storage.AbilityUsage) (int, error) {
+	id := r.tx.newID()
+	r.tx.data.usages = append(r.tx.data.usages, abilityUsage{
+		AbilityUsage: usage,
+		ID:           id,
+		UsedAt:       time.Now(),
+	})
+	return id, nil
+}
+
+func (r abilityRepository) SaveRevealedInfo(info storage.RevealedInfo) error {
+	r.tx.data.revealed = append(r.tx.data.revealed, info)
+	return nil
+}
+
+// ============================================
+// ЖУРНАЛЫ
+// ============================================
+
+type ledgerRepository struct {
+	tx *Tx
+}
+
+func (r ledgerRepository) RecordMoney(entry storage.MoneyTransaction) error {
+	r.tx.data.money = append(r.tx.data.money, entry)
+	return nil
+}
+
+func (r ledgerRepository) RecordInfluence(entry storage.InfluenceTransaction) error {
+	r.tx.data.influence = append(r.tx.data.influence, entry)
+	return nil
+}
+
+func (r ledgerRepository) RecordItem(entry storage.ItemTransaction) error {
+	r.tx.data.itemLog = append(r.tx.data.itemLog, entry)
+	return nil
+}
+
+// ============================================
+// ИГРА, ТАЙМЕРЫ И СОБЫТИЯ
+// ============================================
+
+type gameRepository struct {
+	tx *Tx
+}
+
+func (r gameRepository) IsPaused() (bool, error) {
+	return r.tx.data.pausedAt != nil, nil
+}
+
+func (r gameRepository) StartedAt(now time.Time) (*time.Time, error) {
+	if r.tx.data.gameStartedAt == nil {
+		return nil, nil
+	}
+
+	startedAt := r.tx.data.gameStartedAt.Add(time.Duration(r.tx.data.pausedSeconds) * time.Second)
+	if r.tx.data.pausedAt != nil {
+		startedAt = startedAt.Add(now.Sub(*r.tx.data.pausedAt))
+	}
+	return &startedAt, nil
+}
+
+type timerRepository struct {
+	tx *Tx
+}
+
+func (r timerRepository) Schedule(jobType, key string, runAt time.Time, payload interface{}) error {
+	r.tx.data.timers[key] = Timer{JobType: jobType, Key: key, RunAt: runAt, Payload: payload}
+	return nil
+}
+
+func (r timerRepository) Cancel(key string) error {
+	delete(r.tx.data.timers, key)
+	return nil
+}
+
+func (r timerRepository) CancelByPrefix(jobType, prefix string) error {
+	for key, timer := range r.tx.data.timers {
+		if timer.JobType == jobType && strings.HasPrefix(key, prefix) {
+			delete(r.tx.data.timers, key)
+		}
+	}
+	return nil
+}
+
+type eventPublisher struct {
+	tx *Tx
+}
+
+func (p eventPublisher) Publish(eventType string, playerIDs []int, data interface{}) error {
+	p.tx.events = append(p.tx.events, Event{Type: eventType, PlayerIDs: playerIDs, Data: data})
+	return nil
+}
+
+func (p eventPublisher) PublishBalances(playerIDs ...int) error {
+	for _, playerID := range playerIDs {
+		player, ok := p.tx.data.players[playerID]
+		if !ok {
+			continue
+		}
+		p.Publish(events.TypeBalanceChanged, []int{playerID}, events.BalanceChanged{
+			PlayerID:  player.ID,
+			Money:     player.Money,
+			Influence: player.Influence,
+		})
+	}
+	return nil
+}
+
+// PublishTreasury - как events.PublishTreasury: участникам фракции
+func (p eventPublisher) PublishTreasury(factionID int) error {
+	memberIDs := make([]int, 0)
+	for _, player := range p.tx.data.players {
+		if player.FactionID != nil && *player.FactionID == factionID {
+			memberIDs = append(memberIDs, player.ID)
+		}
+	}
+
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	sort.Ints(memberIDs)
+	return p.Publish(events.TypeTreasuryChanged, memberIDs, events.TreasuryChanged{
+		FactionID: factionID,
+		Treasury:  p.tx.data.treasuries[factionID],
+	})
+}
+
+// PublishItemMoved - как events.PublishItemMoved: получателю и прежнему владельцу
+func (p eventPublisher) PublishItemMoved(itemID int, fromPlayerID, toPlayerID *int, source string) error {
+	data := events.ItemMoved{
+		ItemID:       itemID,
+		FromPlayerID: fromPlayerID,
+		ToPlayerID:   toPlayerID,
+		Source:       source,
+	}
+
+	if toPlayerID != nil {
+		p.Publish(events.TypeItemReceived, []int{*toPlayerID}, data)
+	}
+	if fromPlayerID != nil {
+		p.Publish(events.TypeItemTransferred, []int{*fromPlayerID}, data)
+	}
+	return nil
+}
+
+// PublishGoalUnlocks - разблокировку целей хранилище в памяти не моделирует
+func (p eventPublisher) PublishGoalUnlocks() error {
+	return nil
+}
@@ -0,0 +1,482 @@
+// internal/storage/memory/store.go
+package memory
+
+import (
+	"errors"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Хранилище в памяти для проверки handlers и schedulers без PostgreSQL.
+// Транзакции выполняются по одной (это заменяет блокировки строк) над копией данных:
+// Commit подменяет данные копией, Rollback её отбрасывает
+
+var errTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Timer - задача, поставленная в очередь
+type Timer struct {
+	JobType string
+	Key     string
+	RunAt   time.Time
+	Payload interface{}
+}
+
+// Event - событие, опубликованное в зафиксированной транзакции
+type Event struct {
+	Type      string
+	PlayerIDs []int
+	Data      interface{}
+}
+
+// Template - шаблон предмета
+type Template struct {
+	ID          int
+	Name        string
+	Description *string
+	EffectIDs   []int
+}
+
+type executionKey struct {
+	playerID, itemID, effectID int
+}
+
+type personalGoal struct {
+	storage.Goal
+	PlayerID int
+}
+
+type abilityUsage struct {
+	storage.AbilityUsage
+	ID     int
+	UsedAt time.Time
+}
+
+type state struct {
+	nextID int
+
+	factions   map[int]string
+	treasuries map[int]int // фракция -> казна
+	players    map[int]storage.Player
+
+	effects     map[int]storage.Effect
+	templates   map[int]Template
+	items       map[int]storage.Item
+	itemEffects map[int][]int
+	owners      map[int]int // предмет -> игрок
+	executions  map[executionKey]time.Time
+
+	contracts         map[int]storage.Contract
+	contractPenalties []storage.ContractPenalty
+	penaltyMoney      int
+	penaltyInfluence  int
+	type1Rewards      map[int]int // фракция -> шаблон
+
+	debts                map[int]storage.Debt
+	debtPenaltyInfluence int
+
+	listings map[int]storage.Listing
+	trades   map[int]storage.TradeOffer
+	auctions map[int]storage.Auction
+	bids     map[int]storage.AuctionBid
+
+	goals     map[int]personalGoal
+	abilities map[int]models.Ability
+	usages    []abilityUsage
+	revealed  []storage.RevealedInfo
+
+	money     []storage.MoneyTransaction
+	influence []storage.InfluenceTransaction
+	itemLog   []storage.ItemTransaction
+
+	gameStartedAt *time.Time
+	pausedAt      *time.Time
+	pausedSeconds int
+
+	timers map[string]Timer
+}
+
+// Store - хранилище в памяти
+type Store struct {
+	txMu   sync.Mutex // держится всё время транзакции
+	mu     sync.Mutex // защищает state и events
+	state  *state
+	events []Event
+}
+
+func New() *Store {
+	return &Store{
+		state: &state{
+			nextID:       1000,
+			factions:     make(map[int]string),
+			treasuries:   make(map[int]int),
+			players:      make(map[int]storage.Player),
+			effects:      make(map[int]storage.Effect),
+			templates:    make(map[int]Template),
+			items:        make(map[int]storage.Item),
+			itemEffects:  make(map[int][]int),
+			owners:       make(map[int]int),
+			executions:   make(map[executionKey]time.Time),
+			contracts:    make(map[int]storage.Contract),
+			type1Rewards: make(map[int]int),
+			debts:        make(map[int]storage.Debt),
+			listings:     make(map[int]storage.Listing),
+			trades:       make(map[int]storage.TradeOffer),
+			auctions:     make(map[int]storage.Auction),
+			bids:         make(map[int]storage.AuctionBid),
+			goals:        make(map[int]personalGoal),
+			abilities:    make(map[int]models.Ability),
+			timers:       make(map[string]Timer),
+		},
+	}
+}
+
+func (s *Store) Begin() (storage.Tx, error) {
+	s.txMu.Lock()
+
+	s.mu.Lock()
+	data := s.state.clone()
+	s.mu.Unlock()
+
+	return &Tx{store: s, data: data}, nil
+}
+
+// Tx - транзакция над копией данных
+type Tx struct {
+	store  *Store
+	data   *state
+	events []Event
+	done   bool
+}
+
+func (t *Tx) Players() storage.PlayerRepository     { return playerRepository{t} }
+func (t *Tx) Factions() storage.FactionRepository   { return factionRepository{t} }
+func (t *Tx) Items() storage.ItemRepository         { return itemRepository{t} }
+func (t *Tx) Contracts() storage.ContractRepository { return contractRepository{t} }
+func (t *Tx) Debts() storage.DebtRepository         { return debtRepository{t} }
+func (t *Tx) Market() storage.MarketRepository      { return marketRepository{t} }
+func (t *Tx) Trades() storage.TradeRepository       { return tradeRepository{t} }
+func (t *Tx) Auctions() storage.AuctionRepository   { return auctionRepository{t} }
+func (t *Tx) Goals() storage.GoalRepository         { return goalRepository{t} }
+func (t *Tx) Abilities() storage.AbilityRepository  { return abilityRepository{t} }
+func (t *Tx) Ledger() storage.LedgerRepository      { return ledgerRepository{t} }
+func (t *Tx) Game() storage.GameRepository          { return gameRepository{t} }
+func (t *Tx) Timers() storage.TimerRepository       { return timerRepository{t} }
+func (t *Tx) Events() storage.EventPublisher        { return eventPublisher{t} }
+
+func (t *Tx) Commit() error {
+	if t.done {
+		return errTxDone
+	}
+	t.done = true
+
+	t.store.mu.Lock()
+	t.store.state = t.data
+	t.store.events = append(t.store.events, t.events...)
+	t.store.mu.Unlock()
+
+	t.store.txMu.Unlock()
+	return nil
+}
+
+// Rollback после Commit ничего не делает, поэтому его можно вызывать через defer
+func (t *Tx) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.store.txMu.Unlock()
+	return nil
+}
+
+func (t *Tx) newID() int {
+	t.data.nextID++
+	return t.data.nextID
+}
+
+func (s *state) clone() *state {
+	c := *s
+
+	c.factions = cloneMap(s.factions)
+	c.treasuries = cloneMap(s.treasuries)
+	c.players = cloneMap(s.players)
+	c.effects = cloneMap(s.effects)
+	c.templates = cloneMap(s.templates)
+	c.items = cloneMap(s.items)
+	c.owners = cloneMap(s.owners)
+	c.executions = cloneMap(s.executions)
+	c.contracts = cloneMap(s.contracts)
+	c.type1Rewards = cloneMap(s.type1Rewards)
+	c.debts = cloneMap(s.debts)
+	c.listings = cloneMap(s.listings)
+	c.trades = cloneMap(s.trades)
+	c.auctions = cloneMap(s.auctions)
+	c.bids = cloneMap(s.bids)
+	c.goals = cloneMap(s.goals)
+	c.abilities = cloneMap(s.abilities)
+	c.timers = cloneMap(s.timers)
+
+	c.itemEffects = make(map[int][]int, len(s.itemEffects))
+	for itemID, effectIDs := range s.itemEffects {
+		c.itemEffects[itemID] = append([]int(nil), effectIDs...)
+	}
+
+	c.contractPenalties = append([]storage.ContractPenalty(nil), s.contractPenalties...)
+	c.usages = append([]abilityUsage(nil), s.usages...)
+	c.revealed = append([]storage.RevealedInfo(nil), s.revealed...)
+	c.money = append([]storage.MoneyTransaction(nil), s.money...)
+	c.influence = append([]storage.InfluenceTransaction(nil), s.influence...)
+	c.itemLog = append([]storage.ItemTransaction(nil), s.itemLog...)
+
+	return &c
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// ============================================
+// НАПОЛНЕНИЕ ДАННЫМИ
+// ============================================
+
+func (s *Store) update(fn func(data *state)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.state)
+}
+
+func (s *Store) AddFaction(factionID int, name string) {
+	s.update(func(data *state) { data.factions[factionID] = name })
+}
+
+func (s *Store) SetTreasury(factionID, amount int) {
+	s.update(func(data *state) { data.treasuries[factionID] = amount })
+}
+
+// AddPlayer добавляет игрока (FactionName берётся из AddFaction)
+func (s *Store) AddPlayer(player storage.Player) {
+	s.update(func(data *state) { data.players[player.ID] = player })
+}
+
+func (s *Store) AddEffect(effect storage.Effect) {
+	s.update(func(data *state) { data.effects[effect.ID] = effect })
+}
+
+func (s *Store) AddTemplate(template Template) {
+	s.update(func(data *state) { data.templates[template.ID] = template })
+}
+
+// AddItem добавляет экземпляр предмета с эффектами. ownerID = 0 - предмет без владельца
+func (s *Store) AddItem(item storage.Item, ownerID int, effectIDs ...int) {
+	s.update(func(data *state) {
+		data.items[item.ID] = item
+		data.itemEffects[item.ID] = append([]int(nil), effectIDs...)
+		if ownerID != 0 {
+			data.owners[item.ID] = ownerID
+		}
+	})
+}
+
+func (s *Store) AddContract(contract storage.Contract) {
+	s.update(func(data *state) { data.contracts[contract.ID] = contract })
+}
+
+func (s *Store) SetContractPenalties(money, influence int) {
+	s.update(func(data *state) {
+		data.penaltyMoney = money
+		data.penaltyInfluence = influence
+	})
+}
+
+func (s *Store) SetType1ItemReward(factionID, templateID int) {
+	s.update(func(data *state) { data.type1Rewards[factionID] = templateID })
+}
+
+func (s *Store) AddDebt(debt storage.Debt) {
+	s.update(func(data *state) { data.debts[debt.ID] = debt })
+}
+
+func (s *Store) SetDebtPenaltyInfluence(points int) {
+	s.update(func(data *state) { data.debtPenaltyInfluence = points })
+}
+
+// AddListing добавляет лот. Предмет активного лота не должен принадлежать игроку
+func (s *Store) AddListing(listing storage.Listing) {
+	s.update(func(data *state) { data.listings[listing.ID] = listing })
+}
+
+// AddTradeOffer добавляет предложение обмена
+func (s *Store) AddTradeOffer(offer storage.TradeOffer) {
+	s.update(func(data *state) { data.trades[offer.ID] = offer })
+}
+
+// AddAuction добавляет аукцион. Предмет аукциона не должен принадлежать игроку
+func (s *Store) AddAuction(auction storage.Auction) {
+	s.update(func(data *state) { data.auctions[auction.ID] = auction })
+}
+
+// AddGoal добавляет личную цель игрока
+func (s *Store) AddGoal(playerID int, goal storage.Goal) {
+	s.update(func(data *state) { data.goals[goal.ID] = personalGoal{Goal: goal, PlayerID: playerID} })
+}
+
+func (s *Store) AddAbility(ability models.Ability) {
+	s.update(func(data *state) { data.abilities[ability.ID] = ability })
+}
+
+// AddAbilityUsage добавляет прошлое использование способности (для проверки перезарядки)
+func (s *Store) AddAbilityUsage(playerID, abilityID int, usedAt time.Time) {
+	s.update(func(data *state) {
+		data.nextID++
+		data.usages = append(data.usages, abilityUsage{
+			AbilityUsage: storage.AbilityUsage{PlayerID: playerID, AbilityID: abilityID},
+			ID:           data.nextID,
+			UsedAt:       usedAt,
+		})
+	})
+}
+
+// SetGame задаёт время начала игры и текущую паузу (pausedAt = nil - игра идёт)
+func (s *Store) SetGame(startedAt time.Time, pausedAt *time.Time, pausedSeconds int) {
+	s.update(func(data *state) {
+		data.gameStartedAt = &startedAt
+		data.pausedAt = pausedAt
+		data.pausedSeconds = pausedSeconds
+	})
+}
+
+// ============================================
+// ПРОВЕРКА РЕЗУЛЬТАТА
+// ============================================
+
+func (s *Store) read(fn func(data *state)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.state)
+}
+
+func (s *Store) Player(playerID int) (player storage.Player, ok bool) {
+	s.read(func(data *state) { player, ok = data.players[playerID] })
+	return
+}
+
+func (s *Store) Treasury(factionID int) (treasury int) {
+	s.read(func(data *state) { treasury = data.treasuries[factionID] })
+	return
+}
+
+// Owner возвращает владельца предмета (0 - без владельца)
+func (s *Store) Owner(itemID int) (ownerID int) {
+	s.read(func(data *state) { ownerID = data.owners[itemID] })
+	return
+}
+
+// ItemsOf возвращает предметы игрока по возрастанию id
+func (s *Store) ItemsOf(playerID int) []storage.Item {
+	items := make([]storage.Item, 0)
+	s.read(func(data *state) {
+		for itemID, ownerID := range data.owners {
+			if ownerID == playerID {
+				items = append(items, data.items[itemID])
+			}
+		}
+	})
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+func (s *Store) EffectExecutedAt(playerID, itemID, effectID int) (at time.Time, ok bool) {
+	s.read(func(data *state) { at, ok = data.executions[executionKey{playerID, itemID, effectID}] })
+	return
+}
+
+func (s *Store) Contract(contractID int) (contract storage.Contract, ok bool) {
+	s.read(func(data *state) { contract, ok = data.contracts[contractID] })
+	return
+}
+
+func (s *Store) ContractPenalties() (penalties []storage.ContractPenalty) {
+	s.read(func(data *state) { penalties = append(penalties, data.contractPenalties...) })
+	return
+}
+
+func (s *Store) Debt(debtID int) (debt storage.Debt, ok bool) {
+	s.read(func(data *state) { debt, ok = data.debts[debtID] })
+	return
+}
+
+func (s *Store) Listing(listingID int) (listing storage.Listing, ok bool) {
+	s.read(func(data *state) { listing, ok = data.listings[listingID] })
+	return
+}
+
+func (s *Store) TradeOffer(offerID int) (offer storage.TradeOffer, ok bool) {
+	s.read(func(data *state) { offer, ok = data.trades[offerID] })
+	return
+}
+
+func (s *Store) Auction(auctionID int) (auction storage.Auction, ok bool) {
+	s.read(func(data *state) { auction, ok = data.auctions[auctionID] })
+	return
+}
+
+// Bids возвращает ставки аукциона по порядку поступления
+func (s *Store) Bids(auctionID int) []storage.AuctionBid {
+	bids := make([]storage.AuctionBid, 0)
+	s.read(func(data *state) {
+		for _, bid := range data.bids {
+			if bid.AuctionID == auctionID {
+				bids = append(bids, bid)
+			}
+		}
+	})
+	sort.Slice(bids, func(i, j int) bool { return bids[i].ID < bids[j].ID })
+	return bids
+}
+
+func (s *Store) AbilityUsages() (usages []storage.AbilityUsage) {
+	s.read(func(data *state) {
+		for _, usage := range data.usages {
+			usages = append(usages, usage.AbilityUsage)
+		}
+	})
+	return
+}
+
+func (s *Store) RevealedInfo() (revealed []storage.RevealedInfo) {
+	s.read(func(data *state) { revealed = append(revealed, data.revealed...) })
+	return
+}
+
+func (s *Store) MoneyTransactions() (entries []storage.MoneyTransaction) {
+	s.read(func(data *state) { entries = append(entries, data.money...) })
+	return
+}
+
+func (s *Store) InfluenceTransactions() (entries []storage.InfluenceTransaction) {
+	s.read(func(data *state) { entries = append(entries, data.influence...) })
+	return
+}
+
+func (s *Store) ItemTransactions() (entries []storage.ItemTransaction) {
+	s.read(func(data *state) { entries = append(entries, data.itemLog...) })
+	return
+}
+
+// Timers возвращает ожидающие задачи по ключу
+func (s *Store) Timers() (timers map[string]Timer) {
+	s.read(func(data *state) { timers = cloneMap(data.timers) })
+	return
+}
+
+// Events возвращает события зафиксированных транзакций
+func (s *Store) Events() (published []Event) {
+	s.read(func(data *state) { published = append(published, s.events...) })
+	return
+}
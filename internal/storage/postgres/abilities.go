@@ -0,0 +1,83 @@
+// internal/storage/postgres/abilities.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/models"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type abilityRepository struct {
+	tx *sql.Tx
+}
+
+func (r abilityRepository) GetForUpdate(abilityID, playerID int) (*models.Ability, *time.Time, error) {
+	var ability models.Ability
+	var lastUsedAt *time.Time
+	err := r.tx.QueryRow(`
+		SELECT
+			a.id,
+			a.player_id,
+			a.name,
+			a.ability_type,
+			a.cooldown_minutes,
+			a.start_delay_minutes,
+			a.required_influence_points,
+			a.is_unlocked,
+			a.influence_points_to_add,
+			a.influence_points_to_remove,
+			a.influence_points_to_self,
+			au.used_at
+		FROM abilities a
+		LEFT JOIN LATERAL (
+			SELECT used_at
+			FROM ability_usage
+			WHERE ability_id = a.id AND player_id = a.player_id
+			ORDER BY used_at DESC
+			LIMIT 1
+		) au ON true
+		WHERE a.id = $1 AND a.player_id = $2
+		FOR UPDATE OF a
+	`, abilityID, playerID).Scan(
+		&ability.ID,
+		&ability.PlayerID,
+		&ability.Name,
+		&ability.AbilityType,
+		&ability.CooldownMinutes,
+		&ability.StartDelayMinutes,
+		&ability.RequiredInfluencePoints,
+		&ability.IsUnlocked,
+		&ability.InfluencePointsToAdd,
+		&ability.InfluencePointsToRemove,
+		&ability.InfluencePointsToSelf,
+		&lastUsedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &ability, lastUsedAt, nil
+}
+
+func (r abilityRepository) RecordUsage(usage storage.AbilityUsage) (int, error) {
+	var usageID int
+	err := r.tx.QueryRow(`
+		INSERT INTO ability_usage (player_id, ability_id, target_player_id, info_category, used_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id
+	`, usage.PlayerID, usage.AbilityID, usage.TargetPlayerID, usage.InfoCategory).Scan(&usageID)
+	return usageID, err
+}
+
+func (r abilityRepository) SaveRevealedInfo(info storage.RevealedInfo) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO revealed_info (revealer_player_id, target_player_id, info_type, revealed_data, ability_usage_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, info.RevealerPlayerID, info.TargetPlayerID, info.InfoType, info.Data, info.AbilityUsageID)
+	return err
+}
@@ -0,0 +1,94 @@
+// internal/storage/postgres/auctions.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type auctionRepository struct {
+	tx *sql.Tx
+}
+
+func (r auctionRepository) Create(auction storage.Auction) (int, error) {
+	var auctionID int
+	err := r.tx.QueryRow(`
+		INSERT INTO auctions (item_id, start_price, min_increment, closes_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, auction.ItemID, auction.StartPrice, auction.MinIncrement, auction.ClosesAt).Scan(&auctionID)
+	return auctionID, err
+}
+
+func (r auctionRepository) GetForUpdate(auctionID int) (*storage.Auction, error) {
+	var auction storage.Auction
+	err := r.tx.QueryRow(`
+		SELECT id, status, item_id, start_price, min_increment, closes_at
+		FROM auctions
+		WHERE id = $1
+		FOR UPDATE
+	`, auctionID).Scan(
+		&auction.ID,
+		&auction.Status,
+		&auction.ItemID,
+		&auction.StartPrice,
+		&auction.MinIncrement,
+		&auction.ClosesAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &auction, nil
+}
+
+func (r auctionRepository) LeadingBid(auctionID int) (*storage.AuctionBid, error) {
+	var bid storage.AuctionBid
+	err := r.tx.QueryRow(`
+		SELECT id, auction_id, player_id, amount, status
+		FROM auction_bids
+		WHERE auction_id = $1 AND status = 'leading'
+	`, auctionID).Scan(&bid.ID, &bid.AuctionID, &bid.PlayerID, &bid.Amount, &bid.Status)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &bid, nil
+}
+
+func (r auctionRepository) AddBid(auctionID, playerID, amount int) (int, error) {
+	var bidID int
+	err := r.tx.QueryRow(`
+		INSERT INTO auction_bids (auction_id, player_id, amount)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, auctionID, playerID, amount).Scan(&bidID)
+	return bidID, err
+}
+
+func (r auctionRepository) SetBidStatus(bidID int, status string) error {
+	_, err := r.tx.Exec(`
+		UPDATE auction_bids
+		SET status = $1
+		WHERE id = $2
+	`, status, bidID)
+	return err
+}
+
+func (r auctionRepository) Close(auctionID int, status string, winnerID, finalPrice *int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE auctions
+		SET status = $1, winner_player_id = $2, final_price = $3, closed_at = $4
+		WHERE id = $5
+	`, status, winnerID, finalPrice, at, auctionID)
+	return err
+}
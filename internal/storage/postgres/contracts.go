@@ -0,0 +1,136 @@
+// internal/storage/postgres/contracts.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type contractRepository struct {
+	tx *sql.Tx
+}
+
+func (r contractRepository) GetForUpdate(contractID int) (*storage.Contract, error) {
+	var contract storage.Contract
+	err := r.tx.QueryRow(`
+		SELECT id, status, contract_type, customer_player_id, executor_player_id,
+		       customer_faction_id, duration_seconds, expires_at,
+		       money_reward_customer, money_reward_executor, money_reward_faction
+		FROM contracts
+		WHERE id = $1
+		FOR UPDATE
+	`, contractID).Scan(
+		&contract.ID,
+		&contract.Status,
+		&contract.ContractType,
+		&contract.CustomerPlayerID,
+		&contract.ExecutorPlayerID,
+		&contract.CustomerFactionID,
+		&contract.DurationSeconds,
+		&contract.ExpiresAt,
+		&contract.MoneyRewardCustomer,
+		&contract.MoneyRewardExecutor,
+		&contract.MoneyRewardFaction,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &contract, nil
+}
+
+func (r contractRepository) ConflictingFaction(playerID, factionID int) (*int, error) {
+	var conflictingFactionID *int
+	err := r.tx.QueryRow(`
+		SELECT DISTINCT p.faction_id
+		FROM contracts c
+		JOIN players p ON (
+			CASE
+				WHEN c.customer_player_id = $1 THEN c.executor_player_id = p.id
+				ELSE c.customer_player_id = p.id
+			END
+		)
+		WHERE (c.customer_player_id = $1 OR c.executor_player_id = $1)
+		  AND c.status = 'signed'
+		  AND p.faction_id IS NOT NULL
+		  AND p.faction_id != $2
+		LIMIT 1
+	`, playerID, factionID).Scan(&conflictingFactionID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return conflictingFactionID, err
+}
+
+func (r contractRepository) PenaltySettings() (int, int, error) {
+	var moneyPenalty, influencePenalty int
+	err := r.tx.QueryRow(`
+		SELECT money_penalty, influence_penalty
+		FROM contract_penalty_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&moneyPenalty, &influencePenalty)
+
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return moneyPenalty, influencePenalty, err
+}
+
+func (r contractRepository) RecordPenalty(penalty storage.ContractPenalty) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO contract_penalties (player_id, contract_id, violation_type, money_penalty, influence_penalty)
+		VALUES ($1, $2, $3, $4, $5)
+	`, penalty.PlayerID, penalty.ContractID, penalty.ViolationType, penalty.MoneyPenalty, penalty.InfluencePenalty)
+	return err
+}
+
+func (r contractRepository) Type1ItemReward(factionID int) (*int, error) {
+	var templateID *int
+	err := r.tx.QueryRow(`
+		SELECT customer_item_reward_template_id
+		FROM contract_type1_settings
+		WHERE faction_id = $1
+	`, factionID).Scan(&templateID)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return templateID, err
+}
+
+func (r contractRepository) Sign(contractID int, signedAt, expiresAt time.Time, customerFactionID *int) error {
+	_, err := r.tx.Exec(`
+		UPDATE contracts
+		SET status = 'signed',
+		    signed_at = $1,
+		    expires_at = $2,
+		    customer_faction_id = $3
+		WHERE id = $4
+	`, signedAt, expiresAt, customerFactionID, contractID)
+	return err
+}
+
+func (r contractRepository) Complete(contractID int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE contracts
+		SET status = 'completed', completed_at = $1
+		WHERE id = $2
+	`, at, contractID)
+	return err
+}
+
+func (r contractRepository) Terminate(contractID int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE contracts
+		SET status = 'terminated', terminated_at = $1
+		WHERE id = $2
+	`, at, contractID)
+	return err
+}
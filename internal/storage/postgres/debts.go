@@ -0,0 +1,75 @@
+// internal/storage/postgres/debts.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type debtRepository struct {
+	tx *sql.Tx
+}
+
+func (r debtRepository) GetForUpdate(debtID int) (*storage.Debt, error) {
+	var debt storage.Debt
+	err := r.tx.QueryRow(`
+		SELECT id, lender_player_id, borrower_player_id, return_amount, return_deadline,
+		       is_returned, penalty_applied
+		FROM debt_receipts
+		WHERE id = $1
+		FOR UPDATE
+	`, debtID).Scan(
+		&debt.ID,
+		&debt.LenderID,
+		&debt.BorrowerID,
+		&debt.ReturnAmount,
+		&debt.ReturnDeadline,
+		&debt.IsReturned,
+		&debt.PenaltyApplied,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &debt, nil
+}
+
+func (r debtRepository) MarkReturned(debtID int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE debt_receipts
+		SET is_returned = true,
+		    returned_at = $1
+		WHERE id = $2
+	`, at, debtID)
+	return err
+}
+
+func (r debtRepository) MarkPenaltyApplied(debtID int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE debt_receipts
+		SET penalty_applied = true,
+		    penalty_applied_at = $1
+		WHERE id = $2
+	`, at, debtID)
+	return err
+}
+
+func (r debtRepository) PenaltyInfluence() (int, error) {
+	var influencePenalty int
+	err := r.tx.QueryRow(`
+		SELECT penalty_influence_points
+		FROM debt_penalty_settings
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&influencePenalty)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return influencePenalty, err
+}
@@ -0,0 +1,23 @@
+// internal/storage/postgres/factions.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+)
+
+type factionRepository struct {
+	tx *sql.Tx
+}
+
+func (r factionRepository) AddTreasury(factionID, amount int) error {
+	var id int
+	err := r.tx.QueryRow(`
+		UPDATE factions SET treasury = treasury + $1 WHERE id = $2 RETURNING id
+	`, amount, factionID).Scan(&id)
+
+	if err == sql.ErrNoRows {
+		return storage.ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+// internal/storage/postgres/game.go
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/jobs"
+	"time"
+)
+
+type gameRepository struct {
+	tx *sql.Tx
+}
+
+// IsPaused проверяет, стоит ли игра на паузе
+func (r gameRepository) IsPaused() (bool, error) {
+	var pausedAt *time.Time
+	err := r.tx.QueryRow(`
+		SELECT paused_at
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&pausedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return pausedAt != nil, nil
+}
+
+// StartedAt - начало игры плюс все паузы, включая текущую
+func (r gameRepository) StartedAt(now time.Time) (*time.Time, error) {
+	var startedAt *time.Time
+	err := r.tx.QueryRow(`
+		SELECT game_started_at
+			+ make_interval(secs => total_paused_seconds)
+			+ COALESCE($1::TIMESTAMP - paused_at, INTERVAL '0')
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+	`, now).Scan(&startedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return startedAt, err
+}
+
+type timerRepository struct {
+	tx    *sql.Tx
+	queue *jobs.Queue
+}
+
+var errNoQueue = errors.New("job queue is not configured for this transaction")
+
+func (r timerRepository) Schedule(jobType, key string, runAt time.Time, payload interface{}) error {
+	if r.queue == nil {
+		return errNoQueue
+	}
+	return r.queue.Schedule(r.tx, jobType, key, runAt, payload)
+}
+
+func (r timerRepository) Cancel(key string) error {
+	if r.queue == nil {
+		return errNoQueue
+	}
+	_, err := r.queue.Cancel(r.tx, key)
+	return err
+}
+
+func (r timerRepository) CancelByPrefix(jobType, prefix string) error {
+	if r.queue == nil {
+		return errNoQueue
+	}
+	_, err := r.queue.CancelByPrefix(r.tx, jobType, prefix)
+	return err
+}
+
+// eventPublisher отправляет события через pg_notify в транзакции
+type eventPublisher struct {
+	tx *sql.Tx
+}
+
+func (p eventPublisher) Publish(eventType string, playerIDs []int, data interface{}) error {
+	return events.Publish(p.tx, eventType, playerIDs, data)
+}
+
+func (p eventPublisher) PublishBalances(playerIDs ...int) error {
+	return events.PublishBalances(p.tx, playerIDs...)
+}
+
+func (p eventPublisher) PublishTreasury(factionID int) error {
+	return events.PublishTreasury(p.tx, factionID)
+}
+
+func (p eventPublisher) PublishItemMoved(itemID int, fromPlayerID, toPlayerID *int, source string) error {
+	return events.PublishItemMoved(p.tx, itemID, fromPlayerID, toPlayerID, source)
+}
+
+func (p eventPublisher) PublishGoalUnlocks() error {
+	return events.PublishGoalUnlocks(p.tx)
+}
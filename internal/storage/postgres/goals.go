@@ -0,0 +1,31 @@
+// internal/storage/postgres/goals.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+)
+
+type goalRepository struct {
+	tx *sql.Tx
+}
+
+func (r goalRepository) RandomPersonal(playerID int) (*storage.Goal, error) {
+	var goal storage.Goal
+	err := r.tx.QueryRow(`
+		SELECT id, title, description
+		FROM goals
+		WHERE player_id = $1 AND goal_type = 'personal'
+		ORDER BY RANDOM()
+		LIMIT 1
+	`, playerID).Scan(&goal.ID, &goal.Title, &goal.Description)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &goal, nil
+}
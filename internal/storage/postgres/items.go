@@ -0,0 +1,228 @@
+// internal/storage/postgres/items.go
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type itemRepository struct {
+	tx *sql.Tx
+}
+
+func (r itemRepository) Get(itemID int) (*storage.Item, error) {
+	var item storage.Item
+	err := r.tx.QueryRow(`
+		SELECT id, name, description, template_id FROM items WHERE id = $1
+	`, itemID).Scan(&item.ID, &item.Name, &item.Description, &item.TemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (r itemRepository) IsOwnedBy(playerID, itemID int) (bool, error) {
+	var owned bool
+	err := r.tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM player_items
+			WHERE player_id = $1 AND item_id = $2
+		)
+	`, playerID, itemID).Scan(&owned)
+	return owned, err
+}
+
+func (r itemRepository) LockIfFree(itemID int) (bool, error) {
+	var free bool
+	err := r.tx.QueryRow(`
+		SELECT NOT (
+			EXISTS(SELECT 1 FROM player_items WHERE item_id = i.id)
+			OR EXISTS(SELECT 1 FROM market_listings WHERE item_id = i.id AND status = 'active')
+			OR EXISTS(SELECT 1 FROM auctions WHERE item_id = i.id AND status = 'active')
+		)
+		FROM items i
+		WHERE i.id = $1
+		FOR UPDATE
+	`, itemID).Scan(&free)
+
+	if err == sql.ErrNoRows {
+		return false, storage.ErrNotFound
+	}
+	return free, err
+}
+
+func (r itemRepository) LockOwned(playerID, itemID int) (bool, error) {
+	var one int
+	err := r.tx.QueryRow(`
+		SELECT 1 FROM player_items
+		WHERE player_id = $1 AND item_id = $2
+		FOR UPDATE
+	`, playerID, itemID).Scan(&one)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r itemRepository) RandomOwned(playerID int) (*storage.Item, error) {
+	var item storage.Item
+	err := r.tx.QueryRow(`
+		SELECT i.id, i.name, i.description, i.template_id
+		FROM player_items pi
+		JOIN items i ON pi.item_id = i.id
+		WHERE pi.player_id = $1
+		ORDER BY RANDOM()
+		LIMIT 1
+	`, playerID).Scan(&item.ID, &item.Name, &item.Description, &item.TemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// Move передаёт экземпляр другому игроку (у экземпляра всегда один владелец)
+func (r itemRepository) Move(itemID, fromPlayerID, toPlayerID int) error {
+	_, err := r.tx.Exec(`
+		UPDATE player_items
+		SET player_id = $1, acquired_at = CURRENT_TIMESTAMP
+		WHERE player_id = $2 AND item_id = $3
+	`, toPlayerID, fromPlayerID, itemID)
+	return err
+}
+
+func (r itemRepository) Release(itemID, playerID int) error {
+	_, err := r.tx.Exec(`
+		DELETE FROM player_items
+		WHERE player_id = $1 AND item_id = $2
+	`, playerID, itemID)
+	return err
+}
+
+func (r itemRepository) Give(itemID, playerID int) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO player_items (player_id, item_id)
+		VALUES ($1, $2)
+	`, playerID, itemID)
+	return err
+}
+
+func (r itemRepository) Spawn(templateID, playerID int) (*storage.Item, error) {
+	var item storage.Item
+	err := r.tx.QueryRow(`
+		INSERT INTO items (name, description, template_id)
+		SELECT name, description, id
+		FROM item_templates
+		WHERE id = $1
+		RETURNING id, name, description, template_id
+	`, templateID).Scan(&item.ID, &item.Name, &item.Description, &item.TemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item template %d not found", templateID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create item instance: %w", err)
+	}
+
+	_, err = r.tx.Exec(`
+		INSERT INTO item_effects (item_id, effect_id)
+		SELECT $1, effect_id
+		FROM item_template_effects
+		WHERE template_id = $2
+	`, item.ID, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy template effects: %w", err)
+	}
+
+	_, err = r.tx.Exec(`
+		INSERT INTO player_items (player_id, item_id)
+		VALUES ($1, $2)
+	`, playerID, item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add item to inventory: %w", err)
+	}
+
+	return &item, nil
+}
+
+func (r itemRepository) Effects(itemID int) ([]storage.Effect, error) {
+	rows, err := r.tx.Query(`
+		SELECT e.id, e.effect_type, e.period_seconds, e.generated_resource,
+		       e.operation, e.value, e.spawned_template_id
+		FROM item_effects ie
+		JOIN effects e ON ie.effect_id = e.id
+		WHERE ie.item_id = $1
+		ORDER BY e.id
+	`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// ВАЖНО: строки дочитываются здесь же - в одной транзакции нельзя выполнять
+	// запросы, пока открыт курсор
+	effects := make([]storage.Effect, 0)
+	for rows.Next() {
+		var effect storage.Effect
+		if err := rows.Scan(&effect.ID, &effect.EffectType, &effect.PeriodSeconds, &effect.GeneratedResource,
+			&effect.Operation, &effect.Value, &effect.SpawnedTemplateID); err != nil {
+			return nil, err
+		}
+		effects = append(effects, effect)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effects, nil
+}
+
+func (r itemRepository) GetEffect(effectID int) (*storage.Effect, error) {
+	var effect storage.Effect
+	err := r.tx.QueryRow(`
+		SELECT id, effect_type, period_seconds, generated_resource, operation, value, spawned_template_id
+		FROM effects
+		WHERE id = $1
+	`, effectID).Scan(&effect.ID, &effect.EffectType, &effect.PeriodSeconds, &effect.GeneratedResource,
+		&effect.Operation, &effect.Value, &effect.SpawnedTemplateID)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &effect, nil
+}
+
+func (r itemRepository) SetEffectExecuted(playerID, itemID, effectID int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO item_effect_executions (player_id, item_id, effect_id, last_executed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (player_id, item_id, effect_id)
+		DO UPDATE SET last_executed_at = $4
+	`, playerID, itemID, effectID, at)
+	return err
+}
+
+func (r itemRepository) ClearEffectExecutions(playerID, itemID int) error {
+	_, err := r.tx.Exec(`
+		DELETE FROM item_effect_executions
+		WHERE player_id = $1 AND item_id = $2
+	`, playerID, itemID)
+	return err
+}
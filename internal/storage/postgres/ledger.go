@@ -0,0 +1,39 @@
+// internal/storage/postgres/ledger.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+)
+
+type ledgerRepository struct {
+	tx *sql.Tx
+}
+
+func (r ledgerRepository) RecordMoney(entry storage.MoneyTransaction) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO money_transactions (from_player_id, to_player_id, from_faction_id, to_faction_id,
+		                                amount, transaction_type, reference_id, reference_type, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, entry.FromPlayerID, entry.ToPlayerID, entry.FromFactionID, entry.ToFactionID, entry.Amount, entry.TransactionType,
+		nullInt(entry.ReferenceID), nullString(entry.ReferenceType), entry.Description)
+	return err
+}
+
+func (r ledgerRepository) RecordInfluence(entry storage.InfluenceTransaction) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, entry.PlayerID, entry.Amount, entry.TransactionType,
+		nullInt(entry.ReferenceID), nullString(entry.ReferenceType), entry.Description)
+	return err
+}
+
+func (r ledgerRepository) RecordItem(entry storage.ItemTransaction) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO item_transactions (from_player_id, to_player_id, item_id, transaction_type, reference_id, reference_type, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, entry.FromPlayerID, entry.ToPlayerID, entry.ItemID, entry.TransactionType,
+		nullInt(entry.ReferenceID), nullString(entry.ReferenceType), entry.Description)
+	return err
+}
@@ -0,0 +1,58 @@
+// internal/storage/postgres/market.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type marketRepository struct {
+	tx *sql.Tx
+}
+
+func (r marketRepository) Create(sellerID, itemID, price int, expiresAt time.Time) (int, error) {
+	var listingID int
+	err := r.tx.QueryRow(`
+		INSERT INTO market_listings (seller_player_id, item_id, price, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, sellerID, itemID, price, expiresAt).Scan(&listingID)
+	return listingID, err
+}
+
+func (r marketRepository) GetForUpdate(listingID int) (*storage.Listing, error) {
+	var listing storage.Listing
+	err := r.tx.QueryRow(`
+		SELECT id, status, seller_player_id, buyer_player_id, item_id, price, expires_at
+		FROM market_listings
+		WHERE id = $1
+		FOR UPDATE
+	`, listingID).Scan(
+		&listing.ID,
+		&listing.Status,
+		&listing.SellerPlayerID,
+		&listing.BuyerPlayerID,
+		&listing.ItemID,
+		&listing.Price,
+		&listing.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &listing, nil
+}
+
+func (r marketRepository) Close(listingID int, status string, buyerID *int, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE market_listings
+		SET status = $1, buyer_player_id = $2, closed_at = $3
+		WHERE id = $4
+	`, status, buyerID, at, listingID)
+	return err
+}
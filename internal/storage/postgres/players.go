@@ -0,0 +1,105 @@
+// internal/storage/postgres/players.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+)
+
+type playerRepository struct {
+	tx *sql.Tx
+}
+
+func (r playerRepository) Exists(playerID int) (bool, error) {
+	var exists bool
+	err := r.tx.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM players WHERE id = $1)
+	`, playerID).Scan(&exists)
+	return exists, err
+}
+
+func (r playerRepository) Get(playerID int) (*storage.Player, error) {
+	return r.get(playerID, "")
+}
+
+func (r playerRepository) GetForUpdate(playerID int) (*storage.Player, error) {
+	return r.get(playerID, "FOR UPDATE OF p")
+}
+
+func (r playerRepository) get(playerID int, lock string) (*storage.Player, error) {
+	var player storage.Player
+	err := r.tx.QueryRow(`
+		SELECT p.id, p.character_name, p.faction_id, f.name, p.money, p.influence
+		FROM players p
+		LEFT JOIN factions f ON p.faction_id = f.id
+		WHERE p.id = $1
+		`+lock, playerID).Scan(
+		&player.ID,
+		&player.CharacterName,
+		&player.FactionID,
+		&player.FactionName,
+		&player.Money,
+		&player.Influence,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &player, nil
+}
+
+func (r playerRepository) AddMoney(playerID, amount int) error {
+	_, err := r.tx.Exec(`
+		UPDATE players SET money = money + $1 WHERE id = $2
+	`, amount, playerID)
+	return err
+}
+
+func (r playerRepository) AddInfluence(playerID, amount int) error {
+	_, err := r.tx.Exec(`
+		UPDATE players SET influence = influence + $1 WHERE id = $2
+	`, amount, playerID)
+	return err
+}
+
+func (r playerRepository) TakeMoney(playerID, amount int) (int, error) {
+	var taken int
+	err := r.tx.QueryRow(`
+		WITH old AS (
+			SELECT money FROM players WHERE id = $2 FOR UPDATE
+		)
+		UPDATE players p
+		SET money = GREATEST(0, p.money - $1)
+		FROM old
+		WHERE p.id = $2
+		RETURNING old.money - p.money
+	`, amount, playerID).Scan(&taken)
+
+	if err == sql.ErrNoRows {
+		return 0, storage.ErrNotFound
+	}
+	return taken, err
+}
+
+func (r playerRepository) TakeInfluence(playerID, amount int) (int, error) {
+	var taken int
+	err := r.tx.QueryRow(`
+		WITH old AS (
+			SELECT influence FROM players WHERE id = $2 FOR UPDATE
+		)
+		UPDATE players p
+		SET influence = GREATEST(0, p.influence - $1)
+		FROM old
+		WHERE p.id = $2
+		RETURNING old.influence - p.influence
+	`, amount, playerID).Scan(&taken)
+
+	if err == sql.ErrNoRows {
+		return 0, storage.ErrNotFound
+	}
+	return taken, err
+}
@@ -0,0 +1,89 @@
+// internal/storage/postgres/store.go
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+)
+
+// Store - хранилище в PostgreSQL
+type Store struct {
+	db    *sql.DB
+	queue *jobs.Queue
+}
+
+// New создаёт хранилище. queue нужна для таймеров (Tx.Timers)
+func New(db *sql.DB, queue *jobs.Queue) *Store {
+	return &Store{db: db, queue: queue}
+}
+
+func (s *Store) Begin() (storage.Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	return WrapTx(tx, s.queue), nil
+}
+
+// Tx - транзакция PostgreSQL
+type Tx struct {
+	tx    *sql.Tx
+	queue *jobs.Queue
+}
+
+// WrapTx оборачивает уже открытую транзакцию (например, транзакцию задачи очереди).
+// queue может быть nil, если таймеры в транзакции не нужны
+func WrapTx(tx *sql.Tx, queue *jobs.Queue) *Tx {
+	return &Tx{tx: tx, queue: queue}
+}
+
+// SQL возвращает исходную транзакцию для запросов, которых нет в репозиториях
+func (t *Tx) SQL() *sql.Tx {
+	return t.tx
+}
+
+func (t *Tx) Players() storage.PlayerRepository     { return playerRepository{t.tx} }
+func (t *Tx) Factions() storage.FactionRepository   { return factionRepository{t.tx} }
+func (t *Tx) Items() storage.ItemRepository         { return itemRepository{t.tx} }
+func (t *Tx) Contracts() storage.ContractRepository { return contractRepository{t.tx} }
+func (t *Tx) Debts() storage.DebtRepository         { return debtRepository{t.tx} }
+func (t *Tx) Market() storage.MarketRepository      { return marketRepository{t.tx} }
+func (t *Tx) Trades() storage.TradeRepository       { return tradeRepository{t.tx} }
+func (t *Tx) Auctions() storage.AuctionRepository   { return auctionRepository{t.tx} }
+func (t *Tx) Goals() storage.GoalRepository         { return goalRepository{t.tx} }
+func (t *Tx) Abilities() storage.AbilityRepository  { return abilityRepository{t.tx} }
+func (t *Tx) Ledger() storage.LedgerRepository      { return ledgerRepository{t.tx} }
+func (t *Tx) Game() storage.GameRepository          { return gameRepository{t.tx} }
+func (t *Tx) Timers() storage.TimerRepository       { return timerRepository{t.tx, t.queue} }
+func (t *Tx) Events() storage.EventPublisher        { return eventPublisher{t.tx} }
+
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback после Commit ничего не делает, поэтому его можно вызывать через defer
+func (t *Tx) Rollback() error {
+	err := t.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
+
+// nullInt - 0 записывается как NULL
+func nullInt(value int) *int {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// nullString - пустая строка записывается как NULL
+func nullString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
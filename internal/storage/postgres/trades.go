@@ -0,0 +1,114 @@
+// internal/storage/postgres/trades.go
+package postgres
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+type tradeRepository struct {
+	tx *sql.Tx
+}
+
+func (r tradeRepository) Create(offer storage.TradeOffer) (int, error) {
+	var offerID int
+	err := r.tx.QueryRow(`
+		INSERT INTO trade_offers (from_player_id, to_player_id, offer_money, request_money, message, parent_offer_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, offer.FromPlayerID, offer.ToPlayerID, offer.OfferMoney, offer.RequestMoney,
+		offer.Message, offer.ParentOfferID, offer.ExpiresAt).Scan(&offerID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, itemID := range offer.OfferItems {
+		if err = r.addItem(offerID, itemID, "offer"); err != nil {
+			return 0, err
+		}
+	}
+	for _, itemID := range offer.RequestItems {
+		if err = r.addItem(offerID, itemID, "request"); err != nil {
+			return 0, err
+		}
+	}
+
+	return offerID, nil
+}
+
+func (r tradeRepository) addItem(offerID, itemID int, side string) error {
+	_, err := r.tx.Exec(`
+		INSERT INTO trade_offer_items (offer_id, item_id, side)
+		VALUES ($1, $2, $3)
+	`, offerID, itemID, side)
+	return err
+}
+
+func (r tradeRepository) GetForUpdate(offerID int) (*storage.TradeOffer, error) {
+	var offer storage.TradeOffer
+	err := r.tx.QueryRow(`
+		SELECT id, status, from_player_id, to_player_id, parent_offer_id, offer_money, request_money, message, expires_at
+		FROM trade_offers
+		WHERE id = $1
+		FOR UPDATE
+	`, offerID).Scan(
+		&offer.ID,
+		&offer.Status,
+		&offer.FromPlayerID,
+		&offer.ToPlayerID,
+		&offer.ParentOfferID,
+		&offer.OfferMoney,
+		&offer.RequestMoney,
+		&offer.Message,
+		&offer.ExpiresAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.tx.Query(`
+		SELECT item_id, side
+		FROM trade_offer_items
+		WHERE offer_id = $1
+		ORDER BY item_id
+	`, offerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offer.OfferItems = make([]int, 0)
+	offer.RequestItems = make([]int, 0)
+	for rows.Next() {
+		var itemID int
+		var side string
+		if err := rows.Scan(&itemID, &side); err != nil {
+			return nil, err
+		}
+		if side == "offer" {
+			offer.OfferItems = append(offer.OfferItems, itemID)
+		} else {
+			offer.RequestItems = append(offer.RequestItems, itemID)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &offer, nil
+}
+
+func (r tradeRepository) Close(offerID int, status string, at time.Time) error {
+	_, err := r.tx.Exec(`
+		UPDATE trade_offers
+		SET status = $1, closed_at = $2
+		WHERE id = $3
+	`, status, at, offerID)
+	return err
+}
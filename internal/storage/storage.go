@@ -0,0 +1,188 @@
+// internal/storage/storage.go
+package storage
+
+import (
+	"errors"
+	"new-year-role-game-backend/internal/models"
+	"time"
+)
+
+// Хранилище игровых данных. Handlers и schedulers работают с ним через интерфейсы,
+// поэтому их логику можно проверять на хранилище в памяти (пакет storage/memory),
+// а в рабочем режиме используется PostgreSQL (пакет storage/postgres)
+
+// ErrNotFound - запись не найдена
+var ErrNotFound = errors.New("not found")
+
+// Store открывает транзакции
+type Store interface {
+	Begin() (Tx, error)
+}
+
+// Tx - транзакция. Все репозитории транзакции видят и меняют данные только в ней;
+// таймеры и события вступают в силу только после Commit
+type Tx interface {
+	Players() PlayerRepository
+	Factions() FactionRepository
+	Items() ItemRepository
+	Contracts() ContractRepository
+	Debts() DebtRepository
+	Market() MarketRepository
+	Trades() TradeRepository
+	Auctions() AuctionRepository
+	Goals() GoalRepository
+	Abilities() AbilityRepository
+	Ledger() LedgerRepository
+	Game() GameRepository
+	Timers() TimerRepository
+	Events() EventPublisher
+
+	Commit() error
+	Rollback() error
+}
+
+// PlayerRepository - игроки и их балансы
+type PlayerRepository interface {
+	Exists(playerID int) (bool, error)
+	Get(playerID int) (*Player, error)
+	// GetForUpdate блокирует строку игрока до конца транзакции
+	GetForUpdate(playerID int) (*Player, error)
+
+	AddMoney(playerID, amount int) error
+	AddInfluence(playerID, amount int) error
+	// TakeMoney и TakeInfluence списывают не больше, чем есть у игрока,
+	// и возвращают фактически списанное
+	TakeMoney(playerID, amount int) (int, error)
+	TakeInfluence(playerID, amount int) (int, error)
+}
+
+// FactionRepository - фракции и их казна
+type FactionRepository interface {
+	// AddTreasury пополняет казну фракции (ErrNotFound - фракции нет)
+	AddTreasury(factionID, amount int) error
+}
+
+// ItemRepository - экземпляры предметов, инвентари и выполнение эффектов
+type ItemRepository interface {
+	Get(itemID int) (*Item, error)
+	IsOwnedBy(playerID, itemID int) (bool, error)
+	// LockIfFree блокирует предмет и проверяет, что он ни у кого не находится:
+	// не в инвентаре игрока, не на рынке и не на аукционе (ErrNotFound - предмета нет)
+	LockIfFree(itemID int) (bool, error)
+	// LockOwned - то же, что IsOwnedBy, но блокирует предмет в инвентаре игрока до конца транзакции
+	LockOwned(playerID, itemID int) (bool, error)
+	// RandomOwned возвращает случайный предмет игрока (ErrNotFound - предметов нет)
+	RandomOwned(playerID int) (*Item, error)
+	Move(itemID, fromPlayerID, toPlayerID int) error
+	// Release убирает предмет из инвентаря игрока (например, на хранение рынку),
+	// Give кладёт предмет без владельца игроку
+	Release(itemID, playerID int) error
+	Give(itemID, playerID int) error
+	// Spawn создаёт экземпляр по шаблону с эффектами шаблона и кладёт его игроку
+	Spawn(templateID, playerID int) (*Item, error)
+
+	Effects(itemID int) ([]Effect, error)
+	GetEffect(effectID int) (*Effect, error)
+	SetEffectExecuted(playerID, itemID, effectID int, at time.Time) error
+	ClearEffectExecutions(playerID, itemID int) error
+}
+
+// ContractRepository - договоры и их настройки
+type ContractRepository interface {
+	GetForUpdate(contractID int) (*Contract, error)
+	// ConflictingFaction возвращает фракцию другой стороны подписанного договора игрока,
+	// отличную от factionID (nil - конфликта нет)
+	ConflictingFaction(playerID, factionID int) (*int, error)
+	PenaltySettings() (money, influence int, err error)
+	RecordPenalty(penalty ContractPenalty) error
+	// Type1ItemReward - шаблон предмета-награды заказчику type1 для фракции (nil - не задан)
+	Type1ItemReward(factionID int) (*int, error)
+
+	Sign(contractID int, signedAt, expiresAt time.Time, customerFactionID *int) error
+	Complete(contractID int, at time.Time) error
+	Terminate(contractID int, at time.Time) error
+}
+
+// DebtRepository - долговые расписки
+type DebtRepository interface {
+	GetForUpdate(debtID int) (*Debt, error)
+	MarkReturned(debtID int, at time.Time) error
+	MarkPenaltyApplied(debtID int, at time.Time) error
+	// PenaltyInfluence - штраф по влиянию за просрочку из debt_penalty_settings
+	PenaltyInfluence() (int, error)
+}
+
+// MarketRepository - лоты рынка предметов
+type MarketRepository interface {
+	Create(sellerID, itemID, price int, expiresAt time.Time) (int, error)
+	GetForUpdate(listingID int) (*Listing, error)
+	// Close закрывает лот со статусом 'sold', 'cancelled' или 'expired' (buyerID - только для 'sold')
+	Close(listingID int, status string, buyerID *int, at time.Time) error
+}
+
+// TradeRepository - предложения обмена
+type TradeRepository interface {
+	// Create сохраняет предложение вместе с предметами обеих сторон
+	Create(offer TradeOffer) (int, error)
+	GetForUpdate(offerID int) (*TradeOffer, error)
+	// Close закрывает предложение со статусом 'accepted', 'declined', 'cancelled', 'countered' или 'expired'
+	Close(offerID int, status string, at time.Time) error
+}
+
+// AuctionRepository - аукционы и ставки
+type AuctionRepository interface {
+	Create(auction Auction) (int, error)
+	GetForUpdate(auctionID int) (*Auction, error)
+	// LeadingBid - текущая лидирующая ставка (ErrNotFound - ставок нет)
+	LeadingBid(auctionID int) (*AuctionBid, error)
+	AddBid(auctionID, playerID, amount int) (int, error)
+	// SetBidStatus меняет статус ставки на 'outbid', 'won' или 'refunded'
+	SetBidStatus(bidID int, status string) error
+	// Close закрывает аукцион со статусом 'sold', 'unsold' или 'cancelled' (победитель и цена - только для 'sold')
+	Close(auctionID int, status string, winnerID, finalPrice *int, at time.Time) error
+}
+
+// GoalRepository - цели
+type GoalRepository interface {
+	// RandomPersonal возвращает случайную личную цель игрока (ErrNotFound - целей нет)
+	RandomPersonal(playerID int) (*Goal, error)
+}
+
+// AbilityRepository - уникальные способности
+type AbilityRepository interface {
+	// GetForUpdate блокирует способность игрока и возвращает время её последнего использования
+	GetForUpdate(abilityID, playerID int) (*models.Ability, *time.Time, error)
+	RecordUsage(usage AbilityUsage) (int, error)
+	SaveRevealedInfo(info RevealedInfo) error
+}
+
+// LedgerRepository - журналы денег, влияния и предметов
+type LedgerRepository interface {
+	RecordMoney(entry MoneyTransaction) error
+	RecordInfluence(entry InfluenceTransaction) error
+	RecordItem(entry ItemTransaction) error
+}
+
+// GameRepository - состояние игры
+type GameRepository interface {
+	IsPaused() (bool, error)
+	// StartedAt - время начала игры, сдвинутое на все паузы (nil - игра не начиналась)
+	StartedAt(now time.Time) (*time.Time, error)
+}
+
+// TimerRepository - отложенные задачи очереди. Задачи попадают в очередь вместе с транзакцией
+type TimerRepository interface {
+	Schedule(jobType, key string, runAt time.Time, payload interface{}) error
+	Cancel(key string) error
+	CancelByPrefix(jobType, prefix string) error
+}
+
+// EventPublisher - события для игроков; доставляются только после фиксации транзакции
+type EventPublisher interface {
+	Publish(eventType string, playerIDs []int, data interface{}) error
+	PublishBalances(playerIDs ...int) error
+	// PublishTreasury сообщает участникам фракции новый баланс казны
+	PublishTreasury(factionID int) error
+	PublishItemMoved(itemID int, fromPlayerID, toPlayerID *int, source string) error
+	PublishGoalUnlocks() error
+}
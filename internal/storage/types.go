@@ -0,0 +1,157 @@
+// internal/storage/types.go
+package storage
+
+import "time"
+
+type Player struct {
+	ID            int
+	CharacterName string
+	FactionID     *int
+	FactionName   *string
+	Money         int
+	Influence     int
+}
+
+type Item struct {
+	ID          int
+	Name        string
+	Description *string
+	TemplateID  *int
+}
+
+type Effect struct {
+	ID                int
+	EffectType        string // 'generate_money', 'generate_influence', 'spawn_item'
+	PeriodSeconds     int
+	GeneratedResource *string
+	Operation         *string
+	Value             *int
+	SpawnedTemplateID *int
+}
+
+type Contract struct {
+	ID                  int
+	Status              string // 'pending', 'signed', 'completed', 'terminated'
+	ContractType        string // 'type1', 'type2'
+	CustomerPlayerID    int
+	ExecutorPlayerID    int
+	CustomerFactionID   *int
+	DurationSeconds     int
+	ExpiresAt           *time.Time
+	MoneyRewardCustomer int
+	MoneyRewardExecutor int
+	MoneyRewardFaction  int // в казну фракции заказчика, только type1
+}
+
+type ContractPenalty struct {
+	PlayerID         int
+	ContractID       int
+	ViolationType    string // 'faction_conflict'
+	MoneyPenalty     int
+	InfluencePenalty int
+}
+
+type Debt struct {
+	ID             int
+	LenderID       int
+	BorrowerID     int
+	ReturnAmount   int
+	ReturnDeadline time.Time
+	IsReturned     bool
+	PenaltyApplied bool
+}
+
+type Listing struct {
+	ID             int
+	Status         string // 'active', 'sold', 'cancelled', 'expired'
+	SellerPlayerID int
+	BuyerPlayerID  *int
+	ItemID         int
+	Price          int
+	ExpiresAt      time.Time
+}
+
+type TradeOffer struct {
+	ID            int
+	Status        string // 'pending', 'accepted', 'declined', 'cancelled', 'countered', 'expired'
+	FromPlayerID  int
+	ToPlayerID    int
+	ParentOfferID *int
+	OfferMoney    int
+	RequestMoney  int
+	OfferItems    []int // предметы FromPlayerID
+	RequestItems  []int // предметы ToPlayerID
+	Message       *string
+	ExpiresAt     time.Time
+}
+
+type Auction struct {
+	ID           int
+	Status       string // 'active', 'sold', 'unsold', 'cancelled'
+	ItemID       int
+	StartPrice   int
+	MinIncrement int
+	ClosesAt     time.Time
+}
+
+type AuctionBid struct {
+	ID        int
+	AuctionID int
+	PlayerID  int
+	Amount    int
+	Status    string // 'leading', 'outbid', 'won', 'refunded'
+}
+
+type Goal struct {
+	ID          int
+	Title       string
+	Description *string
+}
+
+type AbilityUsage struct {
+	PlayerID       int
+	AbilityID      int
+	TargetPlayerID int
+	InfoCategory   *string
+}
+
+type RevealedInfo struct {
+	RevealerPlayerID int
+	TargetPlayerID   int
+	InfoType         string
+	Data             []byte // JSON
+	AbilityUsageID   int
+}
+
+// Записи журналов. ReferenceID = 0 и пустой ReferenceType записываются как NULL
+
+type MoneyTransaction struct {
+	FromPlayerID    *int
+	ToPlayerID      *int
+	FromFactionID   *int // казна фракции вместо игрока-отправителя
+	ToFactionID     *int // казна фракции вместо игрока-получателя
+	Amount          int
+	TransactionType string
+	ReferenceID     int
+	ReferenceType   string
+	Description     string
+}
+
+type InfluenceTransaction struct {
+	PlayerID        int
+	Amount          int
+	TransactionType string
+	ReferenceID     int
+	ReferenceType   string
+	Description     string
+}
+
+type ItemTransaction struct {
+	FromPlayerID    *int
+	ToPlayerID      *int
+	ItemID          int
+	TransactionType string
+	ReferenceID     int
+	ReferenceType   string
+	Description     string
+}
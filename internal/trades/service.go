@@ -0,0 +1,438 @@
+// internal/trades/service.go
+package trades
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/storage"
+	"time"
+)
+
+// Обмен между двумя игроками: предложение перечисляет предметы и деньги обеих сторон.
+// Ничего не резервируется - при принятии владение и балансы проверяются под блокировками,
+// и всё переходит в одной транзакции. Все переходы предложения выполняются только здесь,
+// поэтому handlers и scheduler ведут себя одинаково
+
+// JobType - тип задач очереди для истечения предложений
+const JobType = "trade"
+
+// Job - payload задачи предложения
+type Job struct {
+	OfferID int `json:"offer_id"`
+}
+
+// JobKey - ключ задачи истечения предложения в очереди
+func JobKey(offerID int) string {
+	return fmt.Sprintf("trade:%d", offerID)
+}
+
+var (
+	ErrNotFound              = errors.New("trade offer not found")
+	ErrPlayerNotFound        = errors.New("player not found")
+	ErrSelfTrade             = errors.New("cannot trade with yourself")
+	ErrEmptyOffer            = errors.New("trade offer is empty")
+	ErrDuplicateItem         = errors.New("item is listed twice")
+	ErrItemNotOwned          = errors.New("item not found in your inventory")
+	ErrRequestedItemNotOwned = errors.New("requested item not found in counterparty inventory")
+	ErrItemsUnavailable      = errors.New("items of the offer are no longer available")
+	ErrInsufficientFunds     = errors.New("insufficient funds")
+	ErrProposerFunds         = errors.New("proposer has insufficient funds")
+	ErrNotRecipient          = errors.New("only recipient can perform this action")
+	ErrNotProposer           = errors.New("only proposer can perform this action")
+	ErrNotPending            = errors.New("trade offer is not pending")
+)
+
+// ItemTimers переносит таймеры эффектов предмета новому владельцу
+type ItemTimers interface {
+	MoveItemEffects(tx storage.Tx, fromPlayerID, toPlayerID, itemID int, baseTime time.Time) error
+}
+
+// Service - операции над предложениями обмена в транзакции вызывающего
+type Service struct {
+	timers ItemTimers
+}
+
+func NewService(timers ItemTimers) *Service {
+	return &Service{timers: timers}
+}
+
+// Proposal - условия предложения: FromPlayerID отдаёт OfferMoney и OfferItems,
+// ToPlayerID - RequestMoney и RequestItems
+type Proposal struct {
+	FromPlayerID int
+	ToPlayerID   int
+	OfferMoney   int
+	RequestMoney int
+	OfferItems   []int
+	RequestItems []int
+	Message      *string
+	Duration     time.Duration
+}
+
+// Propose создаёт предложение и планирует его истечение
+func (s *Service) Propose(tx storage.Tx, proposal Proposal, now time.Time) (*storage.TradeOffer, error) {
+	return s.propose(tx, proposal, nil, now)
+}
+
+func (s *Service) propose(tx storage.Tx, proposal Proposal, parentOfferID *int, now time.Time) (*storage.TradeOffer, error) {
+	if proposal.FromPlayerID == proposal.ToPlayerID {
+		return nil, ErrSelfTrade
+	}
+	if proposal.OfferMoney == 0 && proposal.RequestMoney == 0 &&
+		len(proposal.OfferItems) == 0 && len(proposal.RequestItems) == 0 {
+		return nil, ErrEmptyOffer
+	}
+
+	seen := make(map[int]bool)
+	for _, itemID := range append(append([]int(nil), proposal.OfferItems...), proposal.RequestItems...) {
+		if seen[itemID] {
+			return nil, ErrDuplicateItem
+		}
+		seen[itemID] = true
+	}
+
+	exists, err := tx.Players().Exists(proposal.ToPlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check player: %w", err)
+	}
+	if !exists {
+		return nil, ErrPlayerNotFound
+	}
+
+	// Условия проверяются и при создании, чтобы не отправлять заведомо невыполнимое предложение
+	if err = checkItems(tx, proposal.FromPlayerID, proposal.OfferItems, ErrItemNotOwned); err != nil {
+		return nil, err
+	}
+	if err = checkItems(tx, proposal.ToPlayerID, proposal.RequestItems, ErrRequestedItemNotOwned); err != nil {
+		return nil, err
+	}
+
+	proposer, err := tx.Players().Get(proposal.FromPlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch proposer: %w", err)
+	}
+	if proposer.Money < proposal.OfferMoney {
+		return nil, ErrInsufficientFunds
+	}
+
+	offer := storage.TradeOffer{
+		Status:        "pending",
+		FromPlayerID:  proposal.FromPlayerID,
+		ToPlayerID:    proposal.ToPlayerID,
+		ParentOfferID: parentOfferID,
+		OfferMoney:    proposal.OfferMoney,
+		RequestMoney:  proposal.RequestMoney,
+		OfferItems:    proposal.OfferItems,
+		RequestItems:  proposal.RequestItems,
+		Message:       proposal.Message,
+		ExpiresAt:     now.Add(proposal.Duration),
+	}
+
+	offer.ID, err = tx.Trades().Create(offer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trade offer: %w", err)
+	}
+
+	if err = tx.Timers().Schedule(JobType, JobKey(offer.ID), offer.ExpiresAt, Job{OfferID: offer.ID}); err != nil {
+		return nil, fmt.Errorf("failed to schedule trade offer expiration: %w", err)
+	}
+
+	if err = s.publish(tx, events.TypeTradeProposed, &offer); err != nil {
+		return nil, err
+	}
+
+	return &offer, nil
+}
+
+func checkItems(tx storage.Tx, playerID int, itemIDs []int, notOwned error) error {
+	for _, itemID := range itemIDs {
+		owned, err := tx.Items().IsOwnedBy(playerID, itemID)
+		if err != nil {
+			return fmt.Errorf("failed to check item owner: %w", err)
+		}
+		if !owned {
+			return notOwned
+		}
+	}
+	return nil
+}
+
+// Accept принимает предложение: предметы и деньги обеих сторон переходят в одной транзакции
+func (s *Service) Accept(tx storage.Tx, offerID, playerID int, now time.Time) (*storage.TradeOffer, error) {
+	offer, err := s.load(tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if offer.ToPlayerID != playerID {
+		return nil, ErrNotRecipient
+	}
+	// Истёкшее предложение ждёт задачу очереди (например, во время паузы), принять его уже нельзя
+	if offer.Status != "pending" || !now.Before(offer.ExpiresAt) {
+		return nil, ErrNotPending
+	}
+
+	// Блокируем игроков всегда в одном порядке, чтобы встречные обмены не взаимоблокировались
+	firstID, secondID := offer.FromPlayerID, offer.ToPlayerID
+	if firstID > secondID {
+		firstID, secondID = secondID, firstID
+	}
+	players := make(map[int]*storage.Player, 2)
+	for _, id := range []int{firstID, secondID} {
+		player, err := tx.Players().GetForUpdate(id)
+		if err == storage.ErrNotFound {
+			return nil, ErrPlayerNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch player: %w", err)
+		}
+		players[id] = player
+	}
+	proposer, recipient := players[offer.FromPlayerID], players[offer.ToPlayerID]
+
+	if err = lockItems(tx, proposer.ID, offer.OfferItems); err != nil {
+		return nil, err
+	}
+	if err = lockItems(tx, recipient.ID, offer.RequestItems); err != nil {
+		return nil, err
+	}
+
+	if proposer.Money < offer.OfferMoney {
+		return nil, ErrProposerFunds
+	}
+	if recipient.Money < offer.RequestMoney {
+		return nil, ErrInsufficientFunds
+	}
+
+	if err = s.pay(tx, offer.ID, proposer, recipient, offer.OfferMoney); err != nil {
+		return nil, err
+	}
+	if err = s.pay(tx, offer.ID, recipient, proposer, offer.RequestMoney); err != nil {
+		return nil, err
+	}
+
+	for _, itemID := range offer.OfferItems {
+		if err = s.give(tx, offer.ID, proposer.ID, recipient.ID, itemID, now); err != nil {
+			return nil, err
+		}
+	}
+	for _, itemID := range offer.RequestItems {
+		if err = s.give(tx, offer.ID, recipient.ID, proposer.ID, itemID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if offer.OfferMoney > 0 || offer.RequestMoney > 0 {
+		if err = tx.Events().PublishBalances(proposer.ID, recipient.ID); err != nil {
+			return nil, fmt.Errorf("failed to publish events: %w", err)
+		}
+	}
+
+	if err = s.close(tx, offer, "accepted", now); err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+func lockItems(tx storage.Tx, playerID int, itemIDs []int) error {
+	for _, itemID := range itemIDs {
+		owned, err := tx.Items().LockOwned(playerID, itemID)
+		if err != nil {
+			return fmt.Errorf("failed to lock item: %w", err)
+		}
+		if !owned {
+			return ErrItemsUnavailable
+		}
+	}
+	return nil
+}
+
+// pay переводит деньги одной стороны обмена другой
+func (s *Service) pay(tx storage.Tx, offerID int, from, to *storage.Player, amount int) error {
+	if amount == 0 {
+		return nil
+	}
+
+	if _, err := tx.Players().TakeMoney(from.ID, amount); err != nil {
+		return fmt.Errorf("failed to deduct money: %w", err)
+	}
+	if err := tx.Players().AddMoney(to.ID, amount); err != nil {
+		return fmt.Errorf("failed to add money: %w", err)
+	}
+
+	err := tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		FromPlayerID:    &from.ID,
+		ToPlayerID:      &to.ID,
+		Amount:          amount,
+		TransactionType: "trade",
+		ReferenceID:     offerID,
+		ReferenceType:   "trade_offer",
+		Description:     fmt.Sprintf("%s paid %d to %s in trade", from.CharacterName, amount, to.CharacterName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record money transaction: %w", err)
+	}
+	return nil
+}
+
+// give передаёт предмет и переносит таймеры его эффектов, как TransferItem
+func (s *Service) give(tx storage.Tx, offerID, fromID, toID, itemID int, now time.Time) error {
+	item, err := tx.Items().Get(itemID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch item: %w", err)
+	}
+
+	if err = tx.Items().Move(itemID, fromID, toID); err != nil {
+		return fmt.Errorf("failed to move item %d: %w", itemID, err)
+	}
+	if err = s.timers.MoveItemEffects(tx, fromID, toID, itemID, now); err != nil {
+		return err
+	}
+
+	err = tx.Ledger().RecordItem(storage.ItemTransaction{
+		FromPlayerID:    &fromID,
+		ToPlayerID:      &toID,
+		ItemID:          itemID,
+		TransactionType: "trade",
+		ReferenceID:     offerID,
+		ReferenceType:   "trade_offer",
+		Description:     "Item traded: " + item.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record item transaction: %w", err)
+	}
+
+	if err = tx.Events().PublishItemMoved(itemID, &fromID, &toID, "trade"); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
+
+// Decline - получатель отклоняет предложение
+func (s *Service) Decline(tx storage.Tx, offerID, playerID int, now time.Time) (*storage.TradeOffer, error) {
+	offer, err := s.loadPending(tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.ToPlayerID != playerID {
+		return nil, ErrNotRecipient
+	}
+
+	if err = s.close(tx, offer, "declined", now); err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+// Cancel - автор отзывает предложение
+func (s *Service) Cancel(tx storage.Tx, offerID, playerID int, now time.Time) (*storage.TradeOffer, error) {
+	offer, err := s.loadPending(tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.FromPlayerID != playerID {
+		return nil, ErrNotProposer
+	}
+
+	if err = s.close(tx, offer, "cancelled", now); err != nil {
+		return nil, err
+	}
+	return offer, nil
+}
+
+// Counter - получатель отвечает своими условиями: исходное предложение закрывается,
+// создаётся новое в обратную сторону со ссылкой на него
+func (s *Service) Counter(tx storage.Tx, offerID, playerID int, proposal Proposal, now time.Time) (*storage.TradeOffer, error) {
+	offer, err := s.load(tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.ToPlayerID != playerID {
+		return nil, ErrNotRecipient
+	}
+	if offer.Status != "pending" || !now.Before(offer.ExpiresAt) {
+		return nil, ErrNotPending
+	}
+
+	if err = s.close(tx, offer, "countered", now); err != nil {
+		return nil, err
+	}
+
+	proposal.FromPlayerID = offer.ToPlayerID
+	proposal.ToPlayerID = offer.FromPlayerID
+	return s.propose(tx, proposal, &offer.ID, now)
+}
+
+// ExpireOffer - истечение срока предложения по таймеру очереди.
+// Предложение, которое уже закрыто, пропускается без ошибки
+func (s *Service) ExpireOffer(tx storage.Tx, offerID int, now time.Time) error {
+	offer, err := s.load(tx, offerID)
+	if err == ErrNotFound {
+		log.Printf("Trade offer #%d no longer exists, skipping", offerID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if offer.Status != "pending" {
+		log.Printf("Trade offer #%d is no longer pending (status: %s), skipping", offerID, offer.Status)
+		return nil
+	}
+
+	return s.close(tx, offer, "expired", now)
+}
+
+// close закрывает предложение со статусом status и снимает его таймер
+func (s *Service) close(tx storage.Tx, offer *storage.TradeOffer, status string, now time.Time) error {
+	if err := tx.Trades().Close(offer.ID, status, now); err != nil {
+		return fmt.Errorf("failed to close trade offer #%d: %w", offer.ID, err)
+	}
+
+	// Для задачи очереди отмена ничего не меняет
+	if err := tx.Timers().Cancel(JobKey(offer.ID)); err != nil {
+		return fmt.Errorf("failed to cancel trade offer timer: %w", err)
+	}
+
+	offer.Status = status
+	return s.publish(tx, events.TypeTradeClosed, offer)
+}
+
+func (s *Service) load(tx storage.Tx, offerID int) (*storage.TradeOffer, error) {
+	offer, err := tx.Trades().GetForUpdate(offerID)
+	if err == storage.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trade offer #%d: %w", offerID, err)
+	}
+	return offer, nil
+}
+
+func (s *Service) loadPending(tx storage.Tx, offerID int) (*storage.TradeOffer, error) {
+	offer, err := s.load(tx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.Status != "pending" {
+		return nil, ErrNotPending
+	}
+	return offer, nil
+}
+
+// publish отправляет изменение предложения обеим сторонам
+func (s *Service) publish(tx storage.Tx, eventType string, offer *storage.TradeOffer) error {
+	err := tx.Events().Publish(eventType, []int{offer.FromPlayerID, offer.ToPlayerID}, events.TradeChanged{
+		OfferID:       offer.ID,
+		FromPlayerID:  offer.FromPlayerID,
+		ToPlayerID:    offer.ToPlayerID,
+		ParentOfferID: offer.ParentOfferID,
+		Status:        offer.Status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// internal/workers/auction_scheduler.go
+package workers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/auctions"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
+	"sync"
+	"time"
+)
+
+// AuctionScheduler закрывает аукционы в срок через общую очередь задач.
+// Сами задачи ставит и снимает auctions.Service в транзакциях создания и отмены
+type AuctionScheduler struct {
+	db       *sql.DB
+	queue    *jobs.Queue
+	auctions *auctions.Service
+	mu       sync.Mutex
+	running  bool
+}
+
+func NewAuctionScheduler(db *sql.DB, queue *jobs.Queue, service *auctions.Service) *AuctionScheduler {
+	s := &AuctionScheduler{
+		db:       db,
+		queue:    queue,
+		auctions: service,
+		running:  false,
+	}
+	queue.Register(auctions.JobType, s.runAuctionJob)
+	return s
+}
+
+// Start восстанавливает задачи для всех активных аукционов.
+// Аукционы с уже прошедшим сроком очередь закроет сразу
+func (s *AuctionScheduler) Start() error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("auction scheduler already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, closes_at
+		FROM auctions
+		WHERE status = 'active'
+		ORDER BY closes_at
+	`)
+	if err != nil {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		return fmt.Errorf("failed to load auctions: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+
+	for rows.Next() {
+		var auctionID int
+		var closesAt time.Time
+
+		if err := rows.Scan(&auctionID, &closesAt); err != nil {
+			log.Printf("Error scanning auction: %v", err)
+			continue
+		}
+
+		err := s.queue.Ensure(s.db, auctions.JobType, auctions.JobKey(auctionID), closesAt,
+			auctions.Job{AuctionID: auctionID})
+		if err != nil {
+			log.Printf("Error scheduling auction #%d: %v", auctionID, err)
+			continue
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load auctions: %w", err)
+	}
+
+	log.Printf("Auction scheduler started, %d active auctions in queue", count)
+	return nil
+}
+
+// runAuctionJob - обработчик задачи закрытия аукциона
+func (s *AuctionScheduler) runAuctionJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload auctions.Job
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid auction job payload: %w", err)
+	}
+
+	return nil, s.runAuction(postgres.WrapTx(tx, s.queue), payload.AuctionID, time.Now())
+}
+
+// runAuction закрывает аукцион поверх хранилища, если игра не на паузе
+func (s *AuctionScheduler) runAuction(tx storage.Tx, auctionID int, now time.Time) error {
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return err
+	} else if paused {
+		log.Printf("Game is paused, auction #%d will be rescheduled on resume", auctionID)
+		return nil
+	}
+
+	if err := s.auctions.CloseAuction(tx, auctionID, now); err != nil {
+		return err
+	}
+
+	log.Printf("Auction #%d closed", auctionID)
+	return nil
+}
+
+// CloseActive досрочно закрывает все активные аукционы в транзакции завершения игры:
+// предмет получает текущий лидер, деньги не остаются в резерве после конца игры
+func (s *AuctionScheduler) CloseActive(tx *sql.Tx, now time.Time) (int, error) {
+	rows, err := tx.Query(`SELECT id FROM auctions WHERE status = 'active' ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load auctions: %w", err)
+	}
+	defer rows.Close()
+
+	// ВАЖНО: дочитываем id до изменений - в одной транзакции нельзя выполнять запросы, пока открыт курсор
+	auctionIDs := make([]int, 0)
+	for rows.Next() {
+		var auctionID int
+		if err := rows.Scan(&auctionID); err != nil {
+			return 0, fmt.Errorf("failed to scan auction: %w", err)
+		}
+		auctionIDs = append(auctionIDs, auctionID)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load auctions: %w", err)
+	}
+
+	store := postgres.WrapTx(tx, s.queue)
+	for _, auctionID := range auctionIDs {
+		if err := s.auctions.CloseAuction(store, auctionID, now); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(auctionIDs), nil
+}
+
+// GetScheduledCount возвращает количество запланированных аукционов
+func (s *AuctionScheduler) GetScheduledCount() int {
+	count, err := s.queue.CountPending(auctions.JobType)
+	if err != nil {
+		log.Printf("Error counting scheduled auctions: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Inspect возвращает задачи аукционов в очереди и их расхождение с таблицей auctions.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *AuctionScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT id, closes_at
+			FROM auctions
+			WHERE status = 'active'
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load auctions: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var auctionID int
+			var closesAt time.Time
+			if err := rows.Scan(&auctionID, &closesAt); err != nil {
+				return nil, fmt.Errorf("failed to scan auction: %w", err)
+			}
+			expected = append(expected, jobs.Expected{Key: auctions.JobKey(auctionID), RunAt: &closesAt})
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load auctions: %w", err)
+		}
+	}
+
+	return s.queue.Inspect(auctions.JobType, running, expected)
+}
+
+// Stop отменяет все задачи аукционов
+func (s *AuctionScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.queue.CancelAll(s.db, auctions.JobType); err != nil {
+		log.Printf("Error cancelling auction jobs: %v", err)
+	}
+	s.running = false
+
+	log.Println("Auction scheduler stopped")
+}
@@ -0,0 +1,60 @@
+// internal/workers/contract_scheduler_test.go
+package workers
+
+import (
+	"new-year-role-game-backend/internal/contracts"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestRunContract(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		paused   bool
+		runs     int
+		executor int // деньги исполнителя после завершения
+		status   string
+	}{
+		{name: "completes expired contract", runs: 1, executor: 15, status: "completed"},
+		{name: "replayed timer pays once", runs: 2, executor: 15, status: "completed"},
+		{name: "game paused", paused: true, runs: 1, status: "signed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			var pausedAt *time.Time
+			if tt.paused {
+				pausedAt = &now
+			}
+			store.SetGame(now.Add(-time.Hour), pausedAt, 0)
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Customer"})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Executor"})
+			expiresAt := now.Add(-time.Minute)
+			store.AddContract(storage.Contract{ID: 3, Status: "signed", ContractType: "type2",
+				CustomerPlayerID: 1, ExecutorPlayerID: 2, ExpiresAt: &expiresAt, MoneyRewardExecutor: 15})
+
+			s := &ContractScheduler{contracts: contracts.NewService(&EffectsScheduler{})}
+			for i := 0; i < tt.runs; i++ {
+				tx, _ := store.Begin()
+				if err := s.runContract(tx, 3, now); err != nil {
+					tx.Rollback()
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+				tx.Commit()
+			}
+
+			executor, _ := store.Player(2)
+			if executor.Money != tt.executor {
+				t.Errorf("executor money = %d, want %d", executor.Money, tt.executor)
+			}
+			if contract, _ := store.Contract(3); contract.Status != tt.status {
+				t.Errorf("status = %s, want %s", contract.Status, tt.status)
+			}
+		})
+	}
+}
@@ -3,29 +3,40 @@ package workers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"new-year-role-game-backend/internal/contracts"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
 	"sync"
 	"time"
 )
 
-// ContractScheduler управляет точными таймерами для каждого договора
+// ContractScheduler планирует завершение договоров через общую очередь задач.
+// Сами задачи ставит и снимает contracts.Service в транзакциях подписания и завершения
 type ContractScheduler struct {
-	db      *sql.DB
-	timers  map[int]*time.Timer // contract_id -> timer
-	mu      sync.RWMutex
-	running bool
+	db        *sql.DB
+	queue     *jobs.Queue
+	contracts *contracts.Service
+	mu        sync.Mutex
+	running   bool
 }
 
-func NewContractScheduler(db *sql.DB) *ContractScheduler {
-	return &ContractScheduler{
-		db:      db,
-		timers:  make(map[int]*time.Timer),
-		running: false,
+func NewContractScheduler(db *sql.DB, queue *jobs.Queue, service *contracts.Service) *ContractScheduler {
+	s := &ContractScheduler{
+		db:        db,
+		queue:     queue,
+		contracts: service,
+		running:   false,
 	}
+	queue.Register(contracts.JobType, s.runContractJob)
+	return s
 }
 
-// Start загружает все активные договоры и создаёт для них таймеры
+// Start восстанавливает задачи для всех подписанных договоров.
+// Истёкшие договоры (например, если сервер лежал в момент истечения) очередь завершит сразу
 func (s *ContractScheduler) Start() error {
 	s.mu.Lock()
 	if s.running {
@@ -39,17 +50,18 @@ func (s *ContractScheduler) Start() error {
 	rows, err := s.db.Query(`
 		SELECT id, expires_at
 		FROM contracts
-		WHERE status = 'signed' AND expires_at > NOW()
+		WHERE status = 'signed' AND expires_at IS NOT NULL
 		ORDER BY expires_at ASC
 	`)
 	if err != nil {
+		s.mu.Lock()
 		s.running = false
+		s.mu.Unlock()
 		return fmt.Errorf("failed to load contracts: %w", err)
 	}
 	defer rows.Close()
 
 	count := 0
-	now := time.Now()
 
 	for rows.Next() {
 		var contractID int
@@ -60,289 +72,106 @@ func (s *ContractScheduler) Start() error {
 			continue
 		}
 
-		// Создаём таймер только для будущих договоров
-		if expiresAt.After(now) {
-			s.scheduleContract(contractID, expiresAt)
-			count++
+		err := s.queue.Ensure(s.db, contracts.JobType, contracts.JobKey(contractID), expiresAt,
+			contracts.Job{ContractID: contractID})
+		if err != nil {
+			log.Printf("Error scheduling contract #%d: %v", contractID, err)
+			continue
 		}
+		count++
 	}
 
-	log.Printf("Contract scheduler started, loaded %d active contracts", count)
-	return nil
-}
-
-// ScheduleContract создаёт точный таймер для конкретного договора
-func (s *ContractScheduler) ScheduleContract(contractID int, expiresAt time.Time) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.scheduleContract(contractID, expiresAt)
-}
-
-// scheduleContract - внутренний метод (без блокировки)
-func (s *ContractScheduler) scheduleContract(contractID int, expiresAt time.Time) {
-	// Отменяем существующий таймер если есть
-	if existingTimer, exists := s.timers[contractID]; exists {
-		existingTimer.Stop()
-		delete(s.timers, contractID)
-	}
-
-	now := time.Now()
-	duration := expiresAt.Sub(now)
-
-	// Если договор уже истёк, выполняем сразу
-	if duration <= 0 {
-		log.Printf("Contract #%d already expired, completing immediately", contractID)
-		go s.completeContract(contractID)
-		return
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load contracts: %w", err)
 	}
 
-	// Создаём точный таймер
-	timer := time.AfterFunc(duration, func() {
-		s.completeContract(contractID)
-
-		// Удаляем таймер из карты после выполнения
-		s.mu.Lock()
-		delete(s.timers, contractID)
-		s.mu.Unlock()
-	})
-
-	s.timers[contractID] = timer
-
-	log.Printf("Scheduled contract #%d to complete at %v (in %v)",
-		contractID, expiresAt.Format("2006-01-02 15:04:05"), duration.Round(time.Second))
-}
-
-// CancelContract отменяет таймер для договора (при ручном завершении или расторжении)
-func (s *ContractScheduler) CancelContract(contractID int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if timer, exists := s.timers[contractID]; exists {
-		timer.Stop()
-		delete(s.timers, contractID)
-		log.Printf("Cancelled scheduled completion for contract #%d", contractID)
-	}
+	log.Printf("Contract scheduler started, %d active contracts in queue", count)
+	return nil
 }
 
-// completeContract автоматически завершает договор в точное время
-func (s *ContractScheduler) completeContract(contractID int) {
-	log.Printf("Auto-completing contract #%d at exact time", contractID)
-
-	// Начинаем транзакцию
-	tx, err := s.db.Begin()
-	if err != nil {
-		log.Printf("Error starting transaction for contract #%d: %v", contractID, err)
-		return
+// runContractJob - обработчик задачи завершения договора
+func (s *ContractScheduler) runContractJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload contracts.Job
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid contract job payload: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Получаем информацию о договоре
-	var contract struct {
-		Status              string
-		ContractType        string
-		CustomerPlayerID    int
-		ExecutorPlayerID    int
-		CustomerFactionID   *int
-		MoneyRewardCustomer int
-		MoneyRewardExecutor int
-	}
-
-	err = tx.QueryRow(`
-		SELECT status, contract_type, customer_player_id, executor_player_id, 
-		       customer_faction_id, money_reward_customer, money_reward_executor
-		FROM contracts
-		WHERE id = $1
-		FOR UPDATE
-	`, contractID).Scan(
-		&contract.Status,
-		&contract.ContractType,
-		&contract.CustomerPlayerID,
-		&contract.ExecutorPlayerID,
-		&contract.CustomerFactionID,
-		&contract.MoneyRewardCustomer,
-		&contract.MoneyRewardExecutor,
-	)
+	return nil, s.runContract(postgres.WrapTx(tx, s.queue), payload.ContractID, time.Now())
+}
 
-	if err != nil {
-		log.Printf("Error fetching contract #%d: %v", contractID, err)
-		return
+// runContract завершает договор поверх хранилища, если игра не на паузе
+func (s *ContractScheduler) runContract(tx storage.Tx, contractID int, now time.Time) error {
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return err
+	} else if paused {
+		log.Printf("Game is paused, contract #%d will be rescheduled on resume", contractID)
+		return nil
 	}
 
-	// Проверяем, что договор ещё в статусе signed
-	if contract.Status != "signed" {
-		log.Printf("Contract #%d is no longer signed (status: %s), skipping", contractID, contract.Status)
-		return
+	log.Printf("Auto-completing contract #%d", contractID)
+	if err := s.contracts.CompleteExpired(tx, contractID, now); err != nil {
+		return err
 	}
 
-	now := time.Now()
-
-	// Выдаём награды в зависимости от типа
-	if err := s.distributeRewards(tx, contractID, &contract); err != nil {
-		log.Printf("Error distributing rewards for contract #%d: %v", contractID, err)
-		return
-	}
-
-	// Обновляем статус договора
-	_, err = tx.Exec(`
-		UPDATE contracts
-		SET status = 'completed', completed_at = $1
-		WHERE id = $2
-	`, now, contractID)
+	log.Printf("Successfully auto-completed contract #%d", contractID)
+	return nil
+}
 
+// GetScheduledCount возвращает количество запланированных договоров
+func (s *ContractScheduler) GetScheduledCount() int {
+	count, err := s.queue.CountPending(contracts.JobType)
 	if err != nil {
-		log.Printf("Error updating contract #%d status: %v", contractID, err)
-		return
-	}
-
-	// Фиксируем транзакцию
-	if err = tx.Commit(); err != nil {
-		log.Printf("Error committing transaction for contract #%d: %v", contractID, err)
-		return
+		log.Printf("Error counting scheduled contracts: %v", err)
+		return 0
 	}
-
-	log.Printf("Successfully auto-completed contract #%d", contractID)
+	return count
 }
 
-// distributeRewards выдаёт награды согласно типу договора
-func (s *ContractScheduler) distributeRewards(tx *sql.Tx, contractID int, contract *struct {
-	Status              string
-	ContractType        string
-	CustomerPlayerID    int
-	ExecutorPlayerID    int
-	CustomerFactionID   *int
-	MoneyRewardCustomer int
-	MoneyRewardExecutor int
-}) error {
-
-	if contract.ContractType == "type1" {
-		// Type 1: заказчик получает деньги + предмет, исполнитель получает деньги
-
-		// Даём деньги заказчику
-		if contract.MoneyRewardCustomer > 0 {
-			_, err := tx.Exec(`
-				UPDATE players SET money = money + $1 WHERE id = $2
-			`, contract.MoneyRewardCustomer, contract.CustomerPlayerID)
-			if err != nil {
-				return fmt.Errorf("failed to give money to customer: %w", err)
-			}
-
-			_, err = tx.Exec(`
-				INSERT INTO money_transactions (to_player_id, amount, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'contract', $3, 'contract', $4)
-			`, contract.CustomerPlayerID, contract.MoneyRewardCustomer, contractID,
-				fmt.Sprintf("Auto-completed contract %d reward", contractID))
-			if err != nil {
-				return fmt.Errorf("failed to record customer money transaction: %w", err)
-			}
-		}
-
-		// Даём деньги исполнителю
-		if contract.MoneyRewardExecutor > 0 {
-			_, err := tx.Exec(`
-				UPDATE players SET money = money + $1 WHERE id = $2
-			`, contract.MoneyRewardExecutor, contract.ExecutorPlayerID)
-			if err != nil {
-				return fmt.Errorf("failed to give money to executor: %w", err)
-			}
+// Inspect возвращает задачи договоров в очереди и их расхождение с таблицей contracts.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *ContractScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
 
-			_, err = tx.Exec(`
-				INSERT INTO money_transactions (to_player_id, amount, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'contract', $3, 'contract', $4)
-			`, contract.ExecutorPlayerID, contract.MoneyRewardExecutor, contractID,
-				fmt.Sprintf("Auto-completed contract %d reward", contractID))
-			if err != nil {
-				return fmt.Errorf("failed to record executor money transaction: %w", err)
-			}
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT id, expires_at
+			FROM contracts
+			WHERE status = 'signed' AND expires_at IS NOT NULL
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load contracts: %w", err)
 		}
+		defer rows.Close()
 
-		// Даём предмет заказчику (если у него есть фракция)
-		if contract.CustomerFactionID != nil {
-			var itemID *int
-			err := tx.QueryRow(`
-				SELECT customer_item_reward_id
-				FROM contract_type1_settings
-				WHERE faction_id = $1
-			`, *contract.CustomerFactionID).Scan(&itemID)
-
-			if err != nil && err != sql.ErrNoRows {
-				return fmt.Errorf("failed to fetch item reward settings: %w", err)
-			}
-
-			if itemID != nil && *itemID > 0 {
-				_, err = tx.Exec(`
-					INSERT INTO player_items (player_id, item_id)
-					VALUES ($1, $2)
-					ON CONFLICT (player_id, item_id) DO NOTHING
-				`, contract.CustomerPlayerID, *itemID)
-				if err != nil {
-					return fmt.Errorf("failed to give item to customer: %w", err)
-				}
-
-				// Инициализируем таймеры эффектов
-				_, err = tx.Exec(`
-					INSERT INTO item_effect_executions (player_id, item_id, effect_id, last_executed_at)
-					SELECT $1, ie.item_id, ie.effect_id, NOW()
-					FROM item_effects ie
-					WHERE ie.item_id = $2
-					ON CONFLICT (player_id, item_id, effect_id) 
-					DO UPDATE SET last_executed_at = NOW()
-				`, contract.CustomerPlayerID, *itemID)
-				if err != nil {
-					return fmt.Errorf("failed to initialize item effect timers: %w", err)
-				}
-
-				_, err = tx.Exec(`
-					INSERT INTO item_transactions (to_player_id, item_id, transaction_type, reference_id, reference_type, description)
-					VALUES ($1, $2, 'contract', $3, 'contract', $4)
-				`, contract.CustomerPlayerID, *itemID, contractID,
-					fmt.Sprintf("Auto-completed contract %d reward", contractID))
-				if err != nil {
-					return fmt.Errorf("failed to record item transaction: %w", err)
-				}
+		for rows.Next() {
+			var contractID int
+			var expiresAt time.Time
+			if err := rows.Scan(&contractID, &expiresAt); err != nil {
+				return nil, fmt.Errorf("failed to scan contract: %w", err)
 			}
+			expected = append(expected, jobs.Expected{Key: contracts.JobKey(contractID), RunAt: &expiresAt})
 		}
 
-	} else if contract.ContractType == "type2" {
-		// Type 2: исполнитель получает деньги
-
-		if contract.MoneyRewardExecutor > 0 {
-			_, err := tx.Exec(`
-				UPDATE players SET money = money + $1 WHERE id = $2
-			`, contract.MoneyRewardExecutor, contract.ExecutorPlayerID)
-			if err != nil {
-				return fmt.Errorf("failed to give money to executor: %w", err)
-			}
-
-			_, err = tx.Exec(`
-				INSERT INTO money_transactions (to_player_id, amount, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'contract', $3, 'contract', $4)
-			`, contract.ExecutorPlayerID, contract.MoneyRewardExecutor, contractID,
-				fmt.Sprintf("Auto-completed contract %d reward", contractID))
-			if err != nil {
-				return fmt.Errorf("failed to record executor money transaction: %w", err)
-			}
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load contracts: %w", err)
 		}
 	}
 
-	return nil
-}
-
-// GetScheduledCount возвращает количество запланированных договоров
-func (s *ContractScheduler) GetScheduledCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.timers)
+	return s.queue.Inspect(contracts.JobType, running, expected)
 }
 
-// Stop останавливает все таймеры
+// Stop отменяет все задачи договоров (при завершении игры)
 func (s *ContractScheduler) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for id, timer := range s.timers {
-		timer.Stop()
-		delete(s.timers, id)
+	if _, err := s.queue.CancelAll(s.db, contracts.JobType); err != nil {
+		log.Printf("Error cancelling contract jobs: %v", err)
 	}
 	s.running = false
 
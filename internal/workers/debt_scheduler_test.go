@@ -0,0 +1,127 @@
+// internal/workers/debt_scheduler_test.go
+package workers
+
+import (
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func TestRunDebt(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		debt      storage.Debt
+		borrower  int // деньги заёмщика
+		paused    bool
+		runs      int
+		lender    int // деньги кредитора после штрафа
+		left      int // деньги заёмщика после штрафа
+		influence int // влияние заёмщика после штрафа
+		applied   bool
+	}{
+		{
+			name:      "collects full amount and influence",
+			debt:      storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower:  50,
+			runs:      1,
+			lender:    30,
+			left:      20,
+			influence: 7,
+			applied:   true,
+		},
+		{
+			name:      "collects no more than borrower has",
+			debt:      storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower:  12,
+			runs:      1,
+			lender:    12,
+			left:      0,
+			influence: 7,
+			applied:   true,
+		},
+		{
+			name:      "replayed timer applies penalty once",
+			debt:      storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower:  50,
+			runs:      3,
+			lender:    30,
+			left:      20,
+			influence: 7,
+			applied:   true,
+		},
+		{
+			name:      "returned debt is skipped",
+			debt:      storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30, IsReturned: true},
+			borrower:  50,
+			runs:      1,
+			left:      50,
+			influence: 10,
+		},
+		{
+			name:      "game paused",
+			debt:      storage.Debt{ID: 7, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower:  50,
+			paused:    true,
+			runs:      1,
+			left:      50,
+			influence: 10,
+		},
+		{
+			name:      "missing debt is skipped",
+			debt:      storage.Debt{ID: 8, LenderID: 1, BorrowerID: 2, ReturnAmount: 30},
+			borrower:  50,
+			runs:      1,
+			left:      50,
+			influence: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			var pausedAt *time.Time
+			if tt.paused {
+				pausedAt = &now
+			}
+			store.SetGame(now.Add(-time.Hour), pausedAt, 0)
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Lender"})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Borrower", Money: tt.borrower, Influence: 10})
+			store.SetDebtPenaltyInfluence(3)
+			tt.debt.ReturnDeadline = now.Add(-time.Minute)
+			store.AddDebt(tt.debt)
+
+			s := &DebtScheduler{}
+			for i := 0; i < tt.runs; i++ {
+				tx, _ := store.Begin()
+				if err := s.runDebt(tx, 7, now); err != nil {
+					tx.Rollback()
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+				tx.Commit()
+			}
+
+			lender, _ := store.Player(1)
+			borrower, _ := store.Player(2)
+			if lender.Money != tt.lender || borrower.Money != tt.left || borrower.Influence != tt.influence {
+				t.Errorf("lender money = %d, borrower money = %d, influence = %d, want %d, %d, %d",
+					lender.Money, borrower.Money, borrower.Influence, tt.lender, tt.left, tt.influence)
+			}
+
+			debt, _ := store.Debt(7)
+			if debt.PenaltyApplied != tt.applied {
+				t.Errorf("penalty applied = %v, want %v", debt.PenaltyApplied, tt.applied)
+			}
+
+			wantEntries := 0
+			if tt.applied {
+				wantEntries = 1
+			}
+			if got := len(store.MoneyTransactions()); got != wantEntries {
+				t.Errorf("money transactions = %d, want %d", got, wantEntries)
+			}
+		})
+	}
+}
@@ -3,29 +3,49 @@ package workers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"new-year-role-game-backend/internal/events"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
 	"sync"
 	"time"
 )
 
-// DebtScheduler управляет точными таймерами для истечения долговых расписок
+// debtJobType - тип задач очереди для штрафов по просроченным долгам
+const debtJobType = "debt"
+
+// debtJob - payload задачи долговой расписки
+type debtJob struct {
+	DebtID int `json:"debt_id"`
+}
+
+func debtJobKey(debtID int) string {
+	return fmt.Sprintf("debt:%d", debtID)
+}
+
+// DebtScheduler планирует штрафы по долговым распискам через общую очередь задач
 type DebtScheduler struct {
 	db      *sql.DB
-	timers  map[int]*time.Timer // map[debtReceiptID]*Timer
-	mu      sync.RWMutex
+	queue   *jobs.Queue
+	mu      sync.Mutex
 	running bool
 }
 
-func NewDebtScheduler(db *sql.DB) *DebtScheduler {
-	return &DebtScheduler{
+func NewDebtScheduler(db *sql.DB, queue *jobs.Queue) *DebtScheduler {
+	s := &DebtScheduler{
 		db:      db,
-		timers:  make(map[int]*time.Timer),
+		queue:   queue,
 		running: false,
 	}
+	queue.Register(debtJobType, s.runDebtJob)
+	return s
 }
 
-// Start загружает все активные долговые расписки и создаёт таймеры
+// Start восстанавливает задачи для всех активных долговых расписок.
+// Уже просроченные расписки без штрафа очередь обработает сразу
 func (s *DebtScheduler) Start() error {
 	s.mu.Lock()
 	if s.running {
@@ -35,23 +55,23 @@ func (s *DebtScheduler) Start() error {
 	s.running = true
 	s.mu.Unlock()
 
-	// Загружаем все активные долговые расписки (не возвращенные и не просроченные с примененным штрафом)
+	// Загружаем все активные долговые расписки (не возвращенные и без примененного штрафа)
 	rows, err := s.db.Query(`
 		SELECT id, return_deadline
 		FROM debt_receipts
 		WHERE is_returned = false 
 		  AND penalty_applied = false
-		  AND return_deadline > NOW()
 		ORDER BY return_deadline
 	`)
 	if err != nil {
+		s.mu.Lock()
 		s.running = false
+		s.mu.Unlock()
 		return fmt.Errorf("failed to load debt receipts: %w", err)
 	}
 	defer rows.Close()
 
 	count := 0
-	now := time.Now()
 
 	for rows.Next() {
 		var debtID int
@@ -62,191 +82,136 @@ func (s *DebtScheduler) Start() error {
 			continue
 		}
 
-		// Создаём таймер
-		s.scheduleDebtExpiration(debtID, deadline)
+		if err := s.queue.Ensure(s.db, debtJobType, debtJobKey(debtID), deadline, debtJob{DebtID: debtID}); err != nil {
+			log.Printf("Error scheduling debt #%d: %v", debtID, err)
+			continue
+		}
 		count++
+	}
 
-		log.Printf("Scheduled debt #%d to expire at %v (in %v)",
-			debtID, deadline.Format("2006-01-02 15:04:05"), deadline.Sub(now).Round(time.Second))
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load debt receipts: %w", err)
 	}
 
-	log.Printf("Debt scheduler started, loaded %d active debt receipts", count)
+	log.Printf("Debt scheduler started, %d active debt receipts in queue", count)
 	return nil
 }
 
-// ScheduleDebt создаёт точный таймер для долговой расписки
+// ScheduleDebt планирует штраф на момент истечения срока расписки
 func (s *DebtScheduler) ScheduleDebt(debtID int, deadline time.Time) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.scheduleDebtExpiration(debtID, deadline)
-}
-
-// scheduleDebtExpiration - внутренний метод (без блокировки)
-func (s *DebtScheduler) scheduleDebtExpiration(debtID int, deadline time.Time) {
-	// Отменяем существующий таймер если есть
-	if existingTimer, exists := s.timers[debtID]; exists {
-		existingTimer.Stop()
-		delete(s.timers, debtID)
-	}
-
-	now := time.Now()
-	duration := deadline.Sub(now)
-
-	// Если срок уже истёк
-	if duration <= 0 {
-		log.Printf("Debt #%d already expired, applying penalty immediately", debtID)
-		go s.applyPenalty(debtID)
+	if err := s.queue.Schedule(s.db, debtJobType, debtJobKey(debtID), deadline, debtJob{DebtID: debtID}); err != nil {
+		log.Printf("Error scheduling debt #%d: %v", debtID, err)
 		return
 	}
 
-	// Создаём точный таймер
-	timer := time.AfterFunc(duration, func() {
-		log.Printf("Debt #%d expired at exact time, applying penalty", debtID)
-		s.applyPenalty(debtID)
-	})
-
-	s.timers[debtID] = timer
+	log.Printf("Scheduled debt #%d to expire at %v (in %v)",
+		debtID, deadline.Format("2006-01-02 15:04:05"), time.Until(deadline).Round(time.Second))
 }
 
-// CancelDebt отменяет таймер для долговой расписки (при возврате долга)
+// CancelDebt отменяет штраф по расписке (при возврате долга)
 func (s *DebtScheduler) CancelDebt(debtID int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	cancelled, err := s.queue.Cancel(s.db, debtJobKey(debtID))
+	if err != nil {
+		log.Printf("Error cancelling debt #%d: %v", debtID, err)
+		return
+	}
 
-	if timer, exists := s.timers[debtID]; exists {
-		timer.Stop()
-		delete(s.timers, debtID)
+	if cancelled > 0 {
 		log.Printf("Cancelled debt timer #%d (debt returned)", debtID)
 	}
 }
 
-// applyPenalty применяет штраф за просроченный долг
-func (s *DebtScheduler) applyPenalty(debtID int) {
-	// Удаляем таймер из map
-	s.mu.Lock()
-	delete(s.timers, debtID)
-	s.mu.Unlock()
+// CancelDebtTx отменяет штраф по расписке в транзакции возврата долга
+func (s *DebtScheduler) CancelDebtTx(tx storage.Tx, debtID int) error {
+	return tx.Timers().Cancel(debtJobKey(debtID))
+}
 
-	// Начинаем транзакцию
-	tx, err := s.db.Begin()
-	if err != nil {
-		log.Printf("Error starting transaction for debt #%d: %v", debtID, err)
-		return
+// runDebtJob - обработчик задачи просроченного долга
+func (s *DebtScheduler) runDebtJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload debtJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid debt job payload: %w", err)
 	}
-	defer tx.Rollback()
 
-	// Получаем информацию о долговой расписке
-	var debt struct {
-		ID             int
-		LenderID       int
-		BorrowerID     int
-		ReturnAmount   int
-		IsReturned     bool
-		PenaltyApplied bool
+	return nil, s.runDebt(postgres.WrapTx(tx, s.queue), payload.DebtID, time.Now())
+}
+
+// runDebt применяет штраф поверх хранилища, если игра не на паузе
+func (s *DebtScheduler) runDebt(tx storage.Tx, debtID int, now time.Time) error {
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return err
+	} else if paused {
+		log.Printf("Game is paused, debt #%d will be rescheduled on resume", debtID)
+		return nil
 	}
 
-	err = tx.QueryRow(`
-		SELECT id, lender_player_id, borrower_player_id, return_amount, is_returned, penalty_applied
-		FROM debt_receipts
-		WHERE id = $1
-		FOR UPDATE
-	`, debtID).Scan(
-		&debt.ID,
-		&debt.LenderID,
-		&debt.BorrowerID,
-		&debt.ReturnAmount,
-		&debt.IsReturned,
-		&debt.PenaltyApplied,
-	)
+	return s.applyPenalty(tx, debtID, now)
+}
 
+// applyPenalty применяет штраф за просроченный долг
+func (s *DebtScheduler) applyPenalty(tx storage.Tx, debtID int, now time.Time) error {
+	log.Printf("Debt #%d expired, applying penalty", debtID)
+
+	// Получаем информацию о долговой расписке
+	debt, err := tx.Debts().GetForUpdate(debtID)
+	if err == storage.ErrNotFound {
+		log.Printf("Debt #%d no longer exists, skipping penalty", debtID)
+		return nil
+	}
 	if err != nil {
-		log.Printf("Error fetching debt #%d: %v", debtID, err)
-		return
+		return fmt.Errorf("failed to fetch debt #%d: %w", debtID, err)
 	}
 
 	// Проверяем, что долг ещё не возвращен и штраф не применён
 	if debt.IsReturned {
 		log.Printf("Debt #%d already returned, skipping penalty", debtID)
-		return
+		return nil
 	}
 
 	if debt.PenaltyApplied {
 		log.Printf("Penalty for debt #%d already applied, skipping", debtID)
-		return
+		return nil
 	}
 
-	// Получаем баланс заемщика
-	var borrowerMoney int
-	var borrowerName string
-	err = tx.QueryRow(`
-		SELECT money, character_name
-		FROM players
-		WHERE id = $1
-		FOR UPDATE
-	`, debt.BorrowerID).Scan(&borrowerMoney, &borrowerName)
-
+	// Получаем заемщика и кредитора
+	borrower, err := tx.Players().GetForUpdate(debt.BorrowerID)
 	if err != nil {
-		log.Printf("Error fetching borrower for debt #%d: %v", debtID, err)
-		return
+		return fmt.Errorf("failed to fetch borrower for debt #%d: %w", debtID, err)
 	}
 
-	// Получаем имя кредитора
 	var lenderName string
-	tx.QueryRow(`SELECT character_name FROM players WHERE id = $1`, debt.LenderID).Scan(&lenderName)
-
-	// Вычисляем сумму списания (минимум из того что должен и того что есть)
-	amountToDeduct := debt.ReturnAmount
-	if borrowerMoney < amountToDeduct {
-		amountToDeduct = borrowerMoney
+	if lender, err := tx.Players().Get(debt.LenderID); err == nil {
+		lenderName = lender.CharacterName
 	}
 
-	// Списываем деньги с заемщика
-	_, err = tx.Exec(`
-		UPDATE players
-		SET money = money - $1
-		WHERE id = $2
-	`, amountToDeduct, debt.BorrowerID)
-
+	// Списываем с заемщика минимум из того, что должен, и того, что есть
+	amountToDeduct, err := tx.Players().TakeMoney(debt.BorrowerID, debt.ReturnAmount)
 	if err != nil {
-		log.Printf("Error deducting money from borrower for debt #%d: %v", debtID, err)
-		return
+		return fmt.Errorf("failed to deduct money from borrower for debt #%d: %w", debtID, err)
 	}
 
 	// Переводим деньги кредитору
-	_, err = tx.Exec(`
-		UPDATE players
-		SET money = money + $1
-		WHERE id = $2
-	`, amountToDeduct, debt.LenderID)
-
-	if err != nil {
-		log.Printf("Error transferring money to lender for debt #%d: %v", debtID, err)
-		return
+	if err = tx.Players().AddMoney(debt.LenderID, amountToDeduct); err != nil {
+		return fmt.Errorf("failed to transfer money to lender for debt #%d: %w", debtID, err)
 	}
 
 	// Записываем транзакцию
-	description := fmt.Sprintf("Automatic debt collection: %s → %s (overdue debt #%d, amount: %d)",
-		borrowerName, lenderName, debtID, amountToDeduct)
-	_, err = tx.Exec(`
-		INSERT INTO money_transactions (from_player_id, to_player_id, amount, transaction_type, reference_id, reference_type, description)
-		VALUES ($1, $2, $3, 'debt', $4, 'debt_receipt', $5)
-	`, debt.BorrowerID, debt.LenderID, amountToDeduct, debtID, description)
-
+	err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+		FromPlayerID:    &debt.BorrowerID,
+		ToPlayerID:      &debt.LenderID,
+		Amount:          amountToDeduct,
+		TransactionType: "debt",
+		ReferenceID:     debtID,
+		ReferenceType:   "debt_receipt",
+		Description: fmt.Sprintf("Automatic debt collection: %s → %s (overdue debt #%d, amount: %d)",
+			borrower.CharacterName, lenderName, debtID, amountToDeduct),
+	})
 	if err != nil {
-		log.Printf("Error recording money transaction for debt #%d: %v", debtID, err)
-		return
+		return fmt.Errorf("failed to record money transaction for debt #%d: %w", debtID, err)
 	}
 
 	// Получаем настройки штрафа по влиянию
-	var influencePenalty int
-	err = tx.QueryRow(`
-		SELECT penalty_influence_points
-		FROM debt_penalty_settings
-		ORDER BY id DESC
-		LIMIT 1
-	`).Scan(&influencePenalty)
-
+	influencePenalty, err := tx.Debts().PenaltyInfluence()
 	if err != nil {
 		log.Printf("Warning: Failed to fetch penalty settings for debt #%d: %v", debtID, err)
 		influencePenalty = 0 // По умолчанию нет штрафа
@@ -254,71 +219,115 @@ func (s *DebtScheduler) applyPenalty(debtID int) {
 
 	// Применяем штраф по влиянию (если настроен)
 	if influencePenalty > 0 {
-		_, err = tx.Exec(`
-			UPDATE players
-			SET influence = GREATEST(0, influence - $1)
-			WHERE id = $2
-		`, influencePenalty, debt.BorrowerID)
-
-		if err != nil {
-			log.Printf("Error applying influence penalty for debt #%d: %v", debtID, err)
-			return
+		if _, err = tx.Players().TakeInfluence(debt.BorrowerID, influencePenalty); err != nil {
+			return fmt.Errorf("failed to apply influence penalty for debt #%d: %w", debtID, err)
 		}
 
 		// Записываем транзакцию влияния
-		_, err = tx.Exec(`
-			INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
-			VALUES ($1, $2, 'penalty', $3, 'debt_receipt', $4)
-		`, debt.BorrowerID, -influencePenalty, debtID,
-			fmt.Sprintf("Penalty for overdue debt #%d: -%d influence", debtID, influencePenalty))
-
+		err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+			PlayerID:        debt.BorrowerID,
+			Amount:          -influencePenalty,
+			TransactionType: "penalty",
+			ReferenceID:     debtID,
+			ReferenceType:   "debt_receipt",
+			Description:     fmt.Sprintf("Penalty for overdue debt #%d: -%d influence", debtID, influencePenalty),
+		})
 		if err != nil {
-			log.Printf("Error recording influence transaction for debt #%d: %v", debtID, err)
-			return
+			return fmt.Errorf("failed to record influence transaction for debt #%d: %w", debtID, err)
 		}
 
 		log.Printf("Applied influence penalty to player %d: -%d points", debt.BorrowerID, influencePenalty)
 	}
 
 	// Отмечаем расписку как с примененным штрафом
-	now := time.Now()
-	_, err = tx.Exec(`
-		UPDATE debt_receipts
-		SET penalty_applied = true,
-		    penalty_applied_at = $1
-		WHERE id = $2
-	`, now, debtID)
+	if err = tx.Debts().MarkPenaltyApplied(debtID, now); err != nil {
+		return fmt.Errorf("failed to update debt receipt #%d: %w", debtID, err)
+	}
 
-	if err != nil {
-		log.Printf("Error updating debt receipt #%d: %v", debtID, err)
-		return
+	// Уведомляем заемщика и кредитора (события уйдут только после фиксации)
+	err = tx.Events().Publish(events.TypeDebtOverdue, []int{debt.BorrowerID, debt.LenderID}, events.DebtOverdue{
+		DebtID:     debtID,
+		LenderID:   debt.LenderID,
+		BorrowerID: debt.BorrowerID,
+		Collected:  amountToDeduct,
+	})
+	if err == nil && influencePenalty > 0 {
+		err = tx.Events().Publish(events.TypePenaltyApplied, []int{debt.BorrowerID}, events.PenaltyApplied{
+			PlayerID:      debt.BorrowerID,
+			Influence:     influencePenalty,
+			ReferenceID:   debtID,
+			ReferenceType: "debt_receipt",
+		})
+	}
+	if err == nil {
+		err = tx.Events().PublishBalances(debt.BorrowerID, debt.LenderID)
 	}
 
-	// Фиксируем транзакцию
-	if err = tx.Commit(); err != nil {
-		log.Printf("Error committing penalty for debt #%d: %v", debtID, err)
-		return
+	if err != nil {
+		return fmt.Errorf("failed to publish events for debt #%d: %w", debtID, err)
 	}
 
 	log.Printf("Successfully applied penalty for debt #%d: %d money transferred, %d influence penalty",
 		debtID, amountToDeduct, influencePenalty)
+	return nil
 }
 
-// GetScheduledCount возвращает количество активных таймеров долговых расписок
+// GetScheduledCount возвращает количество запланированных долговых расписок
 func (s *DebtScheduler) GetScheduledCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.timers)
+	count, err := s.queue.CountPending(debtJobType)
+	if err != nil {
+		log.Printf("Error counting scheduled debts: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Inspect возвращает задачи долговых расписок в очереди и их расхождение с таблицей debt_receipts.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *DebtScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT id, return_deadline
+			FROM debt_receipts
+			WHERE is_returned = false 
+			  AND penalty_applied = false
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load debt receipts: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var debtID int
+			var deadline time.Time
+			if err := rows.Scan(&debtID, &deadline); err != nil {
+				return nil, fmt.Errorf("failed to scan debt receipt: %w", err)
+			}
+			expected = append(expected, jobs.Expected{Key: debtJobKey(debtID), RunAt: &deadline})
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load debt receipts: %w", err)
+		}
+	}
+
+	return s.queue.Inspect(debtJobType, running, expected)
 }
 
-// Stop останавливает все таймеры
+// Stop отменяет все задачи долговых расписок
 func (s *DebtScheduler) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for id, timer := range s.timers {
-		timer.Stop()
-		delete(s.timers, id)
+	if _, err := s.queue.CancelAll(s.db, debtJobType); err != nil {
+		log.Printf("Error cancelling debt jobs: %v", err)
 	}
 	s.running = false
 
@@ -0,0 +1,125 @@
+// internal/workers/effects_scheduler_test.go
+package workers
+
+import (
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func strPtr(v string) *string {
+	return &v
+}
+
+func TestRunEffect(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		job       effectJob
+		paused    bool
+		next      bool // задача остаётся в очереди
+		money     int
+		influence int
+		items     int // предметов у игрока после выполнения
+	}{
+		{name: "generate money", job: effectJob{PlayerID: 1, ItemID: 10, EffectID: 1, PeriodSeconds: 60},
+			next: true, money: 5, items: 1},
+		{name: "generate influence", job: effectJob{PlayerID: 1, ItemID: 10, EffectID: 2, PeriodSeconds: 60},
+			next: true, influence: 2, items: 1},
+		{name: "spawn item", job: effectJob{PlayerID: 1, ItemID: 10, EffectID: 3, PeriodSeconds: 60},
+			next: true, items: 2},
+		{name: "item no longer owned", job: effectJob{PlayerID: 2, ItemID: 10, EffectID: 1, PeriodSeconds: 60},
+			items: 1},
+		{name: "effect deleted", job: effectJob{PlayerID: 1, ItemID: 10, EffectID: 99, PeriodSeconds: 60}, items: 1},
+		{name: "game paused", job: effectJob{PlayerID: 1, ItemID: 10, EffectID: 1, PeriodSeconds: 60},
+			paused: true, items: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			var pausedAt *time.Time
+			if tt.paused {
+				pausedAt = &now
+			}
+			store.SetGame(now.Add(-time.Hour), pausedAt, 0)
+			store.AddPlayer(storage.Player{ID: 1, CharacterName: "Alice"})
+			store.AddPlayer(storage.Player{ID: 2, CharacterName: "Bob"})
+			store.AddEffect(storage.Effect{ID: 1, EffectType: "generate_money", PeriodSeconds: 60,
+				Operation: strPtr("add"), Value: intPtr(5)})
+			store.AddEffect(storage.Effect{ID: 2, EffectType: "generate_influence", PeriodSeconds: 60,
+				Operation: strPtr("add"), Value: intPtr(2)})
+			store.AddEffect(storage.Effect{ID: 3, EffectType: "spawn_item", PeriodSeconds: 60,
+				SpawnedTemplateID: intPtr(4)})
+			store.AddEffect(storage.Effect{ID: 6, EffectType: "generate_money", PeriodSeconds: 30,
+				Operation: strPtr("add"), Value: intPtr(1)})
+			store.AddTemplate(memory.Template{ID: 4, Name: "Gift", EffectIDs: []int{6}})
+			store.AddItem(storage.Item{ID: 10, Name: "Lamp"}, 1, 1, 2, 3)
+
+			s := &EffectsScheduler{}
+			tx, _ := store.Begin()
+			next, err := s.runEffect(tx, tt.job, now)
+			if err != nil {
+				tx.Rollback()
+				t.Fatalf("runEffect: %v", err)
+			}
+			tx.Commit()
+
+			if tt.next {
+				if next == nil || !next.Equal(now.Add(60*time.Second)) {
+					t.Errorf("next = %v, want %v", next, now.Add(60*time.Second))
+				}
+				if at, ok := store.EffectExecutedAt(tt.job.PlayerID, tt.job.ItemID, tt.job.EffectID); !ok || !at.Equal(now) {
+					t.Errorf("last execution = %v (%v), want %v", at, ok, now)
+				}
+			} else if next != nil {
+				t.Errorf("next = %v, want job finished", next)
+			}
+
+			player, _ := store.Player(1)
+			if player.Money != tt.money || player.Influence != tt.influence {
+				t.Errorf("money = %d, influence = %d, want %d, %d", player.Money, player.Influence, tt.money, tt.influence)
+			}
+
+			items := store.ItemsOf(1)
+			if len(items) != tt.items {
+				t.Fatalf("items = %d, want %d", len(items), tt.items)
+			}
+
+			// Созданный эффектом предмет получает собственные таймеры
+			for _, item := range items {
+				if item.ID == 10 {
+					continue
+				}
+				if _, ok := store.Timers()[effectJobKey(1, item.ID, 6)]; !ok {
+					t.Errorf("spawned item %d has no effect timer", item.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateEffectValue(t *testing.T) {
+	tests := []struct {
+		operation string
+		want      int
+	}{
+		{"add", 5},
+		{"sub", -5},
+		{"mul", 5},
+		{"div", 5},
+		{"unknown", 5},
+	}
+
+	for _, tt := range tests {
+		if got := calculateEffectValue(5, tt.operation); got != tt.want {
+			t.Errorf("calculateEffectValue(5, %q) = %d, want %d", tt.operation, got, tt.want)
+		}
+	}
+}
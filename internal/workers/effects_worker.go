@@ -118,7 +118,7 @@ func (w *EffectsWorker) executeAllEffects() {
 			e.generated_resource,
 			e.operation,
 			e.value,
-			e.spawned_item_id,
+			e.spawned_template_id,
 			e.period_seconds,
 			iee.last_executed_at
 		FROM player_items pi
@@ -150,7 +150,7 @@ func (w *EffectsWorker) executeAllEffects() {
 		var playerID, itemID, effectID, periodSeconds int
 		var itemName, effectType string
 		var generatedResource, operation *string
-		var value, spawnedItemID *int
+		var value, spawnedTemplateID *int
 		var lastExecutedAt *time.Time
 
 		err := rows.Scan(
@@ -162,7 +162,7 @@ func (w *EffectsWorker) executeAllEffects() {
 			&generatedResource,
 			&operation,
 			&value,
-			&spawnedItemID,
+			&spawnedTemplateID,
 			&periodSeconds,
 			&lastExecutedAt,
 		)
@@ -174,7 +174,7 @@ func (w *EffectsWorker) executeAllEffects() {
 
 		// Выполняем эффект в отдельной транзакции
 		err = w.executeEffect(playerID, itemID, itemName, effectID, effectType,
-			generatedResource, operation, value, spawnedItemID, now)
+			generatedResource, operation, value, spawnedTemplateID, now)
 
 		if err != nil {
 			log.Printf("Error executing effect %d for player %d: %v", effectID, playerID, err)
@@ -213,7 +213,7 @@ func (w *EffectsWorker) executeAllEffects() {
 
 // executeEffect выполняет один эффект для одного игрока
 func (w *EffectsWorker) executeEffect(playerID, itemID int, itemName string, effectID int,
-	effectType string, generatedResource, operation *string, value, spawnedItemID *int,
+	effectType string, generatedResource, operation *string, value, spawnedTemplateID *int,
 	executedAt time.Time) error {
 
 	tx, err := w.db.Begin()
@@ -272,22 +272,8 @@ func (w *EffectsWorker) executeEffect(playerID, itemID int, itemName string, eff
 		}
 
 	case "spawn_item":
-		if spawnedItemID != nil {
-			var spawnedItemName string
-			err = tx.QueryRow(`
-				SELECT name FROM items WHERE id = $1
-			`, *spawnedItemID).Scan(&spawnedItemName)
-
-			if err != nil {
-				return fmt.Errorf("failed to fetch spawned item info: %w", err)
-			}
-
-			_, err = tx.Exec(`
-				INSERT INTO player_items (player_id, item_id)
-				VALUES ($1, $2)
-				ON CONFLICT (player_id, item_id) DO NOTHING
-			`, playerID, *spawnedItemID)
-
+		if spawnedTemplateID != nil {
+			spawnedItemID, spawnedItemName, err := SpawnItemInstance(tx, *spawnedTemplateID, playerID)
 			if err != nil {
 				return fmt.Errorf("failed to spawn item: %w", err)
 			}
@@ -295,7 +281,7 @@ func (w *EffectsWorker) executeEffect(playerID, itemID int, itemName string, eff
 			_, err = tx.Exec(`
 				INSERT INTO item_transactions (to_player_id, item_id, transaction_type, reference_id, reference_type, description)
 				VALUES ($1, $2, 'spawned', $3, 'effect', $4)
-			`, playerID, *spawnedItemID, effectID, fmt.Sprintf("Item effect: %s spawned %s", itemName, spawnedItemName))
+			`, playerID, spawnedItemID, effectID, fmt.Sprintf("Item effect: %s spawned %s", itemName, spawnedItemName))
 
 			if err != nil {
 				return fmt.Errorf("failed to record item transaction: %w", err)
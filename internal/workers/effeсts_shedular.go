@@ -3,36 +3,52 @@ package workers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
 	"sync"
 	"time"
 )
 
-// EffectTimerKey - уникальный ключ для таймера эффекта
-type EffectTimerKey struct {
-	PlayerID int
-	ItemID   int
-	EffectID int
+// effectJobType - тип задач очереди для периодических эффектов предметов
+const effectJobType = "effect"
+
+// effectJob - payload задачи эффекта
+type effectJob struct {
+	PlayerID      int `json:"player_id"`
+	ItemID        int `json:"item_id"`
+	EffectID      int `json:"effect_id"`
+	PeriodSeconds int `json:"period_seconds"`
+}
+
+// effectJobKey - ключ идемпотентности: у игрока один таймер на эффект предмета
+func effectJobKey(playerID, itemID, effectID int) string {
+	return fmt.Sprintf("effect:%d:%d:%d", playerID, itemID, effectID)
 }
 
-// EffectsScheduler управляет точными таймерами для каждого эффекта
+// EffectsScheduler планирует периодические эффекты предметов через общую очередь задач
 type EffectsScheduler struct {
 	db      *sql.DB
-	timers  map[EffectTimerKey]*time.Timer
-	mu      sync.RWMutex
+	queue   *jobs.Queue
+	mu      sync.Mutex
 	running bool
 }
 
-func NewEffectsScheduler(db *sql.DB) *EffectsScheduler {
-	return &EffectsScheduler{
+func NewEffectsScheduler(db *sql.DB, queue *jobs.Queue) *EffectsScheduler {
+	s := &EffectsScheduler{
 		db:      db,
-		timers:  make(map[EffectTimerKey]*time.Timer),
+		queue:   queue,
 		running: false,
 	}
+	queue.Register(effectJobType, s.runEffectJob)
+	return s
 }
 
-// Start загружает все активные эффекты и создаёт для них таймеры
+// Start восстанавливает задачи для всех эффектов предметов игроков.
+// Уже запланированные задачи не трогает, так что его можно вызывать на каждом экземпляре API
 func (s *EffectsScheduler) Start() error {
 	s.mu.Lock()
 	if s.running {
@@ -47,9 +63,7 @@ func (s *EffectsScheduler) Start() error {
 		SELECT 
 			pi.player_id,
 			i.id AS item_id,
-			i.name AS item_name,
 			e.id AS effect_id,
-			e.description,
 			e.period_seconds,
 			iee.last_executed_at
 		FROM player_items pi
@@ -63,7 +77,9 @@ func (s *EffectsScheduler) Start() error {
 		ORDER BY pi.player_id, i.id, e.id
 	`)
 	if err != nil {
+		s.mu.Lock()
 		s.running = false
+		s.mu.Unlock()
 		return fmt.Errorf("failed to load effects: %w", err)
 	}
 	defer rows.Close()
@@ -72,13 +88,11 @@ func (s *EffectsScheduler) Start() error {
 	now := time.Now()
 
 	for rows.Next() {
-		var playerID, itemID, effectID, periodSeconds int
-		var itemName string
-		var description *string
+		var job effectJob
 		var lastExecutedAt *time.Time
 
-		if err := rows.Scan(&playerID, &itemID, &itemName, &effectID,
-			&description, &periodSeconds, &lastExecutedAt); err != nil {
+		if err := rows.Scan(&job.PlayerID, &job.ItemID, &job.EffectID,
+			&job.PeriodSeconds, &lastExecutedAt); err != nil {
 			log.Printf("Error scanning effect: %v", err)
 			continue
 		}
@@ -87,172 +101,142 @@ func (s *EffectsScheduler) Start() error {
 		var nextExecutionTime time.Time
 		if lastExecutedAt == nil {
 			// Если эффект никогда не выполнялся, выполним через period_seconds
-			nextExecutionTime = now.Add(time.Duration(periodSeconds) * time.Second)
+			nextExecutionTime = now.Add(time.Duration(job.PeriodSeconds) * time.Second)
 		} else {
-			// Следующее выполнение = последнее + период
-			nextExecutionTime = lastExecutedAt.Add(time.Duration(periodSeconds) * time.Second)
-
-			// Если время уже прошло, выполним сразу
-			if nextExecutionTime.Before(now) {
-				nextExecutionTime = now
-			}
+			// Следующее выполнение = последнее + период (если время прошло - очередь выполнит сразу)
+			nextExecutionTime = lastExecutedAt.Add(time.Duration(job.PeriodSeconds) * time.Second)
 		}
 
-		// Создаём таймер
-		s.scheduleEffect(playerID, itemID, effectID, nextExecutionTime, periodSeconds)
+		err := s.queue.Ensure(s.db, effectJobType, effectJobKey(job.PlayerID, job.ItemID, job.EffectID),
+			nextExecutionTime, job)
+		if err != nil {
+			log.Printf("Error scheduling effect: %v", err)
+			continue
+		}
 		count++
 	}
 
-	log.Printf("Effects scheduler started, loaded %d active effects", count)
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load effects: %w", err)
+	}
+
+	log.Printf("Effects scheduler started, %d active effects in queue", count)
 	return nil
 }
 
-// ScheduleEffect создаёт точный таймер для конкретного эффекта
+// ScheduleEffect планирует следующее выполнение эффекта (заменяя прежнее)
 func (s *EffectsScheduler) ScheduleEffect(playerID, itemID, effectID int, nextExecutionTime time.Time, periodSeconds int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.scheduleEffect(playerID, itemID, effectID, nextExecutionTime, periodSeconds)
-}
-
-// scheduleEffect - внутренний метод (без блокировки)
-func (s *EffectsScheduler) scheduleEffect(playerID, itemID, effectID int, nextExecutionTime time.Time, periodSeconds int) {
-	key := EffectTimerKey{
-		PlayerID: playerID,
-		ItemID:   itemID,
-		EffectID: effectID,
-	}
-
-	// Отменяем существующий таймер если есть
-	if existingTimer, exists := s.timers[key]; exists {
-		existingTimer.Stop()
-		delete(s.timers, key)
-	}
-
-	now := time.Now()
-	duration := nextExecutionTime.Sub(now)
-
-	// Если эффект нужно выполнить сейчас или время прошло
-	if duration <= 0 {
-		go s.executeEffectAndReschedule(playerID, itemID, effectID, periodSeconds)
+	if err := s.scheduleEffect(s.db, playerID, itemID, effectID, nextExecutionTime, periodSeconds); err != nil {
+		log.Printf("Error scheduling effect (player=%d, item=%d, effect=%d): %v", playerID, itemID, effectID, err)
 		return
 	}
 
-	// Создаём точный таймер
-	timer := time.AfterFunc(duration, func() {
-		s.executeEffectAndReschedule(playerID, itemID, effectID, periodSeconds)
-	})
-
-	s.timers[key] = timer
-
 	log.Printf("Scheduled effect (player=%d, item=%d, effect=%d) to execute at %v (in %v)",
-		playerID, itemID, effectID, nextExecutionTime.Format("15:04:05"), duration.Round(time.Second))
+		playerID, itemID, effectID, nextExecutionTime.Format("15:04:05"),
+		time.Until(nextExecutionTime).Round(time.Second))
 }
 
-// CancelEffect отменяет таймер для эффекта (при передаче предмета)
-func (s *EffectsScheduler) CancelEffect(playerID, itemID, effectID int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// scheduleEffect ставит задачу эффекта в очередь в рамках ex (БД или транзакции)
+func (s *EffectsScheduler) scheduleEffect(ex jobs.Execer, playerID, itemID, effectID int, nextExecutionTime time.Time, periodSeconds int) error {
+	return s.queue.Schedule(ex, effectJobType, effectJobKey(playerID, itemID, effectID), nextExecutionTime, effectJob{
+		PlayerID:      playerID,
+		ItemID:        itemID,
+		EffectID:      effectID,
+		PeriodSeconds: periodSeconds,
+	})
+}
 
-	key := EffectTimerKey{
-		PlayerID: playerID,
-		ItemID:   itemID,
-		EffectID: effectID,
+// CancelEffect отменяет задачу эффекта (при передаче предмета)
+func (s *EffectsScheduler) CancelEffect(playerID, itemID, effectID int) {
+	cancelled, err := s.queue.Cancel(s.db, effectJobKey(playerID, itemID, effectID))
+	if err != nil {
+		log.Printf("Error cancelling effect (player=%d, item=%d, effect=%d): %v", playerID, itemID, effectID, err)
+		return
 	}
 
-	if timer, exists := s.timers[key]; exists {
-		timer.Stop()
-		delete(s.timers, key)
+	if cancelled > 0 {
 		log.Printf("Cancelled effect timer (player=%d, item=%d, effect=%d)", playerID, itemID, effectID)
 	}
 }
 
-// CancelAllEffectsForItem отменяет все таймеры для предмета игрока
+// CancelAllEffectsForItem отменяет все задачи эффектов предмета игрока
 func (s *EffectsScheduler) CancelAllEffectsForItem(playerID, itemID int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	keysToDelete := make([]EffectTimerKey, 0)
+	prefix := fmt.Sprintf("effect:%d:%d:", playerID, itemID)
 
-	for key, timer := range s.timers {
-		if key.PlayerID == playerID && key.ItemID == itemID {
-			timer.Stop()
-			keysToDelete = append(keysToDelete, key)
-		}
-	}
-
-	for _, key := range keysToDelete {
-		delete(s.timers, key)
+	cancelled, err := s.queue.CancelByPrefix(s.db, effectJobType, prefix)
+	if err != nil {
+		log.Printf("Error cancelling effects for item %d of player %d: %v", itemID, playerID, err)
+		return
 	}
 
-	if len(keysToDelete) > 0 {
-		log.Printf("Cancelled %d effect timers for item %d of player %d", len(keysToDelete), itemID, playerID)
+	if cancelled > 0 {
+		log.Printf("Cancelled %d effect timers for item %d of player %d", cancelled, itemID, playerID)
 	}
 }
 
-// executeEffectAndReschedule выполняет эффект и создаёт новый таймер
-func (s *EffectsScheduler) executeEffectAndReschedule(playerID, itemID, effectID, periodSeconds int) {
-	now := time.Now()
-
-	// Выполняем эффект
-	if err := s.executeEffect(playerID, itemID, effectID, now); err != nil {
-		log.Printf("Error executing effect (player=%d, item=%d, effect=%d): %v",
-			playerID, itemID, effectID, err)
-		// Не пересоздаём таймер при ошибке
-		return
+// runEffectJob выполняет эффект и планирует следующее выполнение через period_seconds
+func (s *EffectsScheduler) runEffectJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload effectJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid effect job payload: %w", err)
 	}
 
-	// Создаём новый таймер для следующего выполнения
-	nextExecutionTime := now.Add(time.Duration(periodSeconds) * time.Second)
-	s.ScheduleEffect(playerID, itemID, effectID, nextExecutionTime, periodSeconds)
+	return s.runEffect(s.wrap(tx), payload, time.Now())
 }
 
-// executeEffect выполняет один эффект
-func (s *EffectsScheduler) executeEffect(playerID, itemID, effectID int, executedAt time.Time) error {
-	tx, err := s.db.Begin()
+// runEffect - обработчик задачи эффекта поверх хранилища
+func (s *EffectsScheduler) runEffect(tx storage.Tx, payload effectJob, now time.Time) (*time.Time, error) {
+	// Задачи, созданные во время паузы, не выполняются: при возобновлении
+	// Start заново ставит их в очередь со сдвинутым временем
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return nil, fmt.Errorf("failed to check game pause: %w", err)
+	} else if paused {
+		return nil, nil
+	}
+
+	executed, err := s.executeEffect(tx, payload.PlayerID, payload.ItemID, payload.EffectID, now)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, err
 	}
-	defer tx.Rollback()
 
-	// Получаем информацию об эффекте
-	var effect struct {
-		EffectType        string
-		GeneratedResource *string
-		Operation         *string
-		Value             *int
-		SpawnedItemID     *int
-	}
-
-	err = tx.QueryRow(`
-		SELECT effect_type, generated_resource, operation, value, spawned_item_id
-		FROM effects
-		WHERE id = $1
-	`, effectID).Scan(
-		&effect.EffectType,
-		&effect.GeneratedResource,
-		&effect.Operation,
-		&effect.Value,
-		&effect.SpawnedItemID,
-	)
+	// Предмета у игрока больше нет - задача завершена
+	if !executed {
+		return nil, nil
+	}
+
+	next := now.Add(time.Duration(payload.PeriodSeconds) * time.Second)
+	return &next, nil
+}
 
+// executeEffect выполняет один эффект в транзакции задачи.
+// Возвращает false, если предмета у игрока уже нет
+func (s *EffectsScheduler) executeEffect(tx storage.Tx, playerID, itemID, effectID int, executedAt time.Time) (bool, error) {
+	// Получаем информацию об эффекте
+	effect, err := tx.Items().GetEffect(effectID)
+	if err == storage.ErrNotFound {
+		// Эффект удалён из каталога - задача завершена
+		log.Printf("Effect %d no longer exists, skipping", effectID)
+		return false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to fetch effect: %w", err)
+		return false, fmt.Errorf("failed to fetch effect: %w", err)
 	}
 
 	// Проверяем, что предмет всё ещё у игрока
-	var hasItem bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM player_items WHERE player_id = $1 AND item_id = $2)
-	`, playerID, itemID).Scan(&hasItem)
-
+	hasItem, err := tx.Items().IsOwnedBy(playerID, itemID)
 	if err != nil {
-		return fmt.Errorf("failed to check item ownership: %w", err)
+		return false, fmt.Errorf("failed to check item ownership: %w", err)
 	}
 
 	if !hasItem {
 		log.Printf("Player %d no longer has item %d, skipping effect", playerID, itemID)
-		return nil
+		return false, nil
+	}
+
+	// Название предмета для описания транзакций
+	var itemName string
+	if item, err := tx.Items().Get(itemID); err == nil {
+		itemName = item.Name
 	}
 
 	// Выполняем эффект в зависимости от типа
@@ -261,23 +245,24 @@ func (s *EffectsScheduler) executeEffect(playerID, itemID, effectID int, execute
 		if effect.Value != nil && effect.Operation != nil {
 			amount := calculateEffectValue(*effect.Value, *effect.Operation)
 
-			_, err = tx.Exec(`
-				UPDATE players SET money = money + $1 WHERE id = $2
-			`, amount, playerID)
-			if err != nil {
-				return fmt.Errorf("failed to generate money: %w", err)
+			if err = tx.Players().AddMoney(playerID, amount); err != nil {
+				return false, fmt.Errorf("failed to generate money: %w", err)
 			}
 
-			// Получаем название предмета для описания
-			var itemName string
-			tx.QueryRow(`SELECT name FROM items WHERE id = $1`, itemID).Scan(&itemName)
-
-			_, err = tx.Exec(`
-				INSERT INTO money_transactions (to_player_id, amount, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'item_effect', $3, 'effect', $4)
-			`, playerID, amount, effectID, fmt.Sprintf("Item effect: %s generated %d money", itemName, amount))
+			err = tx.Ledger().RecordMoney(storage.MoneyTransaction{
+				ToPlayerID:      &playerID,
+				Amount:          amount,
+				TransactionType: "item_effect",
+				ReferenceID:     effectID,
+				ReferenceType:   "effect",
+				Description:     fmt.Sprintf("Item effect: %s generated %d money", itemName, amount),
+			})
 			if err != nil {
-				return fmt.Errorf("failed to record money transaction: %w", err)
+				return false, fmt.Errorf("failed to record money transaction: %w", err)
+			}
+
+			if err = tx.Events().PublishBalances(playerID); err != nil {
+				return false, err
 			}
 
 			log.Printf("Effect executed: player %d received %d money from item %d", playerID, amount, itemID)
@@ -287,141 +272,320 @@ func (s *EffectsScheduler) executeEffect(playerID, itemID, effectID int, execute
 		if effect.Value != nil && effect.Operation != nil {
 			amount := calculateEffectValue(*effect.Value, *effect.Operation)
 
-			_, err = tx.Exec(`
-				UPDATE players SET influence = influence + $1 WHERE id = $2
-			`, amount, playerID)
-			if err != nil {
-				return fmt.Errorf("failed to generate influence: %w", err)
+			if err = tx.Players().AddInfluence(playerID, amount); err != nil {
+				return false, fmt.Errorf("failed to generate influence: %w", err)
 			}
 
-			var itemName string
-			tx.QueryRow(`SELECT name FROM items WHERE id = $1`, itemID).Scan(&itemName)
-
-			_, err = tx.Exec(`
-				INSERT INTO influence_transactions (player_id, amount, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'item_effect', $3, 'effect', $4)
-			`, playerID, amount, effectID, fmt.Sprintf("Item effect: %s generated %d influence", itemName, amount))
+			err = tx.Ledger().RecordInfluence(storage.InfluenceTransaction{
+				PlayerID:        playerID,
+				Amount:          amount,
+				TransactionType: "item_effect",
+				ReferenceID:     effectID,
+				ReferenceType:   "effect",
+				Description:     fmt.Sprintf("Item effect: %s generated %d influence", itemName, amount),
+			})
 			if err != nil {
-				return fmt.Errorf("failed to record influence transaction: %w", err)
+				return false, fmt.Errorf("failed to record influence transaction: %w", err)
+			}
+
+			// Рост влияния мог открыть цели - сообщаем об этом вместе с балансом
+			if err = tx.Events().PublishBalances(playerID); err != nil {
+				return false, err
+			}
+			if err = tx.Events().PublishGoalUnlocks(); err != nil {
+				return false, err
 			}
 
 			log.Printf("Effect executed: player %d received %d influence from item %d", playerID, amount, itemID)
 		}
 
 	case "spawn_item":
-		if effect.SpawnedItemID != nil {
-			var spawnedItemName string
-			err = tx.QueryRow(`SELECT name FROM items WHERE id = $1`, *effect.SpawnedItemID).Scan(&spawnedItemName)
+		if effect.SpawnedTemplateID != nil {
+			// Каждое срабатывание создаёт новый экземпляр со своими таймерами
+			spawned, err := s.SpawnItemTx(tx, *effect.SpawnedTemplateID, playerID, executedAt)
 			if err != nil {
-				return fmt.Errorf("failed to fetch spawned item info: %w", err)
+				return false, fmt.Errorf("failed to spawn item: %w", err)
 			}
 
-			_, err = tx.Exec(`
-				INSERT INTO player_items (player_id, item_id)
-				VALUES ($1, $2)
-				ON CONFLICT (player_id, item_id) DO NOTHING
-			`, playerID, *effect.SpawnedItemID)
+			err = tx.Ledger().RecordItem(storage.ItemTransaction{
+				ToPlayerID:      &playerID,
+				ItemID:          spawned.ID,
+				TransactionType: "spawned",
+				ReferenceID:     effectID,
+				ReferenceType:   "effect",
+				Description:     fmt.Sprintf("Item effect: %s spawned %s", itemName, spawned.Name),
+			})
 			if err != nil {
-				return fmt.Errorf("failed to spawn item: %w", err)
+				return false, fmt.Errorf("failed to record item transaction: %w", err)
 			}
 
-			// Инициализируем таймеры эффектов для нового предмета
-			s.initializeItemEffects(tx, playerID, *effect.SpawnedItemID, executedAt)
-
-			var itemName string
-			tx.QueryRow(`SELECT name FROM items WHERE id = $1`, itemID).Scan(&itemName)
-
-			_, err = tx.Exec(`
-				INSERT INTO item_transactions (to_player_id, item_id, transaction_type, reference_id, reference_type, description)
-				VALUES ($1, $2, 'spawned', $3, 'effect', $4)
-			`, playerID, *effect.SpawnedItemID, effectID,
-				fmt.Sprintf("Item effect: %s spawned %s", itemName, spawnedItemName))
-			if err != nil {
-				return fmt.Errorf("failed to record item transaction: %w", err)
+			if err = tx.Events().PublishItemMoved(spawned.ID, nil, &playerID, "effect"); err != nil {
+				return false, err
 			}
 
 			log.Printf("Effect executed: player %d received item %d (%s) from item %d",
-				playerID, *effect.SpawnedItemID, spawnedItemName, itemID)
+				playerID, spawned.ID, spawned.Name, itemID)
 		}
 	}
 
 	// Обновляем время последнего выполнения
-	_, err = tx.Exec(`
-		INSERT INTO item_effect_executions (player_id, item_id, effect_id, last_executed_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (player_id, item_id, effect_id) 
-		DO UPDATE SET last_executed_at = $4
-	`, playerID, itemID, effectID, executedAt)
-	if err != nil {
-		return fmt.Errorf("failed to update effect execution time: %w", err)
+	if err = tx.Items().SetEffectExecuted(playerID, itemID, effectID, executedAt); err != nil {
+		return false, fmt.Errorf("failed to update effect execution time: %w", err)
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
+	return true, nil
 }
 
 // initializeItemEffects инициализирует таймеры для эффектов нового предмета
-func (s *EffectsScheduler) initializeItemEffects(tx *sql.Tx, playerID, itemID int, baseTime time.Time) error {
-	// Получаем все эффекты предмета
-	rows, err := tx.Query(`
-		SELECT e.id, e.period_seconds
-		FROM item_effects ie
-		JOIN effects e ON ie.effect_id = e.id
-		WHERE ie.item_id = $1
-	`, itemID)
+func (s *EffectsScheduler) initializeItemEffects(tx storage.Tx, playerID, itemID int, baseTime time.Time) error {
+	effects, err := tx.Items().Effects(itemID)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var effectID, periodSeconds int
-		if err := rows.Scan(&effectID, &periodSeconds); err != nil {
+	for _, effect := range effects {
+		// Устанавливаем last_executed_at в БД
+		if err = tx.Items().SetEffectExecuted(playerID, itemID, effect.ID, baseTime); err != nil {
 			return err
 		}
 
-		// Устанавливаем last_executed_at в БД
+		// Задача попадает в очередь вместе с транзакцией: при откате её не будет
+		nextExecutionTime := baseTime.Add(time.Duration(effect.PeriodSeconds) * time.Second)
+		err = tx.Timers().Schedule(effectJobType, effectJobKey(playerID, itemID, effect.ID), nextExecutionTime,
+			effectJob{
+				PlayerID:      playerID,
+				ItemID:        itemID,
+				EffectID:      effect.ID,
+				PeriodSeconds: effect.PeriodSeconds,
+			})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InitializeItemEffects запускает таймеры эффектов предмета, выданного игроку в транзакции tx.
+// Задачи попадут в очередь только вместе с транзакцией
+func (s *EffectsScheduler) InitializeItemEffects(tx *sql.Tx, playerID, itemID int, baseTime time.Time) error {
+	return s.initializeItemEffects(s.wrap(tx), playerID, itemID, baseTime)
+}
+
+// MoveItemEffects переносит таймеры эффектов предмета от прежнего владельца к новому
+// в транзакции передачи предмета: у нового владельца отсчёт начинается с baseTime
+func (s *EffectsScheduler) MoveItemEffects(tx storage.Tx, fromPlayerID, toPlayerID, itemID int, baseTime time.Time) error {
+	if err := s.ReleaseItemEffects(tx, fromPlayerID, itemID); err != nil {
+		return err
+	}
+
+	if err := s.initializeItemEffects(tx, toPlayerID, itemID, baseTime); err != nil {
+		return fmt.Errorf("failed to initialize effect timers for recipient: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseItemEffects снимает таймеры эффектов предмета у игрока, который перестал им владеть
+// (например, выставил его на рынок)
+func (s *EffectsScheduler) ReleaseItemEffects(tx storage.Tx, playerID, itemID int) error {
+	if err := tx.Items().ClearEffectExecutions(playerID, itemID); err != nil {
+		return fmt.Errorf("failed to clean up old effect timers: %w", err)
+	}
+
+	err := tx.Timers().CancelByPrefix(effectJobType, fmt.Sprintf("effect:%d:%d:", playerID, itemID))
+	if err != nil {
+		return fmt.Errorf("failed to cancel old effect timers: %w", err)
+	}
+
+	return nil
+}
+
+// AttachItemEffects запускает таймеры эффектов предмета у нового владельца с отсчётом от baseTime
+func (s *EffectsScheduler) AttachItemEffects(tx storage.Tx, playerID, itemID int, baseTime time.Time) error {
+	if err := s.initializeItemEffects(tx, playerID, itemID, baseTime); err != nil {
+		return fmt.Errorf("failed to initialize item effects: %w", err)
+	}
+	return nil
+}
+
+// SpawnItem создаёт игроку новый экземпляр предмета по шаблону и запускает таймеры его эффектов
+func (s *EffectsScheduler) SpawnItem(tx *sql.Tx, templateID, playerID int, baseTime time.Time) (int, string, error) {
+	item, err := s.SpawnItemTx(s.wrap(tx), templateID, playerID, baseTime)
+	if err != nil {
+		return 0, "", err
+	}
+	return item.ID, item.Name, nil
+}
+
+// SpawnItemTx - SpawnItem поверх хранилища
+func (s *EffectsScheduler) SpawnItemTx(tx storage.Tx, templateID, playerID int, baseTime time.Time) (*storage.Item, error) {
+	item, err := tx.Items().Spawn(templateID, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.initializeItemEffects(tx, playerID, item.ID, baseTime); err != nil {
+		return nil, fmt.Errorf("failed to initialize item effects: %w", err)
+	}
+
+	return item, nil
+}
+
+// wrap - транзакция задачи очереди или handler как хранилище
+func (s *EffectsScheduler) wrap(tx *sql.Tx) storage.Tx {
+	return postgres.WrapTx(tx, s.queue)
+}
+
+// InitializeEffectForHolders запускает таймеры эффекта, только что привязанного к предмету,
+// у всех владельцев предмета. Возвращает количество запущенных таймеров
+func (s *EffectsScheduler) InitializeEffectForHolders(tx *sql.Tx, itemID, effectID int, baseTime time.Time) (int, error) {
+	var periodSeconds int
+	err := tx.QueryRow(`SELECT period_seconds FROM effects WHERE id = $1`, effectID).Scan(&periodSeconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch effect: %w", err)
+	}
+
+	holders, err := itemHolders(tx, itemID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, playerID := range holders {
 		_, err = tx.Exec(`
 			INSERT INTO item_effect_executions (player_id, item_id, effect_id, last_executed_at)
 			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (player_id, item_id, effect_id) 
+			ON CONFLICT (player_id, item_id, effect_id)
 			DO UPDATE SET last_executed_at = $4
 		`, playerID, itemID, effectID, baseTime)
 		if err != nil {
-			return err
+			return 0, fmt.Errorf("failed to initialize effect execution time: %w", err)
 		}
 
-		// Создаём таймер (после коммита транзакции это будет вызвано)
 		nextExecutionTime := baseTime.Add(time.Duration(periodSeconds) * time.Second)
+		if err = s.scheduleEffect(tx, playerID, itemID, effectID, nextExecutionTime, periodSeconds); err != nil {
+			return 0, err
+		}
+	}
 
-		// Планируем выполнение через горутину, чтобы не блокировать транзакцию
-		go func(pid, iid, eid int, next time.Time, period int) {
-			time.Sleep(100 * time.Millisecond) // Даём время транзакции завершиться
-			s.ScheduleEffect(pid, iid, eid, next, period)
-		}(playerID, itemID, effectID, nextExecutionTime, periodSeconds)
+	return len(holders), nil
+}
+
+// CancelEffectForHolders отменяет таймеры эффекта предмета у всех владельцев
+// (при отвязке эффекта от предмета) и возвращает количество отменённых задач
+func (s *EffectsScheduler) CancelEffectForHolders(tx *sql.Tx, itemID, effectID int) (int64, error) {
+	holders, err := itemHolders(tx, itemID)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	var cancelled int64
+	for _, playerID := range holders {
+		n, err := s.queue.Cancel(tx, effectJobKey(playerID, itemID, effectID))
+		if err != nil {
+			return 0, err
+		}
+		cancelled += n
+	}
+
+	return cancelled, nil
+}
+
+// itemHolders возвращает игроков, у которых есть предмет
+func itemHolders(tx *sql.Tx, itemID int) ([]int, error) {
+	rows, err := tx.Query(`SELECT player_id FROM player_items WHERE item_id = $1`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load item holders: %w", err)
+	}
+	defer rows.Close()
+
+	holders := make([]int, 0)
+	for rows.Next() {
+		var playerID int
+		if err := rows.Scan(&playerID); err != nil {
+			return nil, fmt.Errorf("failed to scan item holder: %w", err)
+		}
+		holders = append(holders, playerID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load item holders: %w", err)
+	}
+
+	return holders, nil
 }
 
 // GetScheduledCount возвращает количество запланированных эффектов
 func (s *EffectsScheduler) GetScheduledCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.timers)
+	count, err := s.queue.CountPending(effectJobType)
+	if err != nil {
+		log.Printf("Error counting scheduled effects: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Inspect возвращает задачи эффектов в очереди и их расхождение с предметами игроков.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *EffectsScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT 
+				pi.player_id,
+				pi.item_id,
+				e.id AS effect_id,
+				e.period_seconds,
+				iee.last_executed_at
+			FROM player_items pi
+			JOIN item_effects ie ON pi.item_id = ie.item_id
+			JOIN effects e ON ie.effect_id = e.id
+			LEFT JOIN item_effect_executions iee ON 
+				iee.player_id = pi.player_id AND 
+				iee.item_id = pi.item_id AND 
+				iee.effect_id = e.id
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load effects: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var playerID, itemID, effectID, periodSeconds int
+			var lastExecutedAt *time.Time
+			if err := rows.Scan(&playerID, &itemID, &effectID, &periodSeconds, &lastExecutedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan effect: %w", err)
+			}
+
+			exp := jobs.Expected{Key: effectJobKey(playerID, itemID, effectID)}
+			if lastExecutedAt != nil {
+				next := lastExecutedAt.Add(time.Duration(periodSeconds) * time.Second)
+				exp.RunAt = &next
+			}
+			expected = append(expected, exp)
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load effects: %w", err)
+		}
+	}
+
+	return s.queue.Inspect(effectJobType, running, expected)
 }
 
-// Stop останавливает все таймеры
+// Stop отменяет все задачи эффектов (при завершении игры)
 func (s *EffectsScheduler) Stop() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for key, timer := range s.timers {
-		timer.Stop()
-		delete(s.timers, key)
+	if _, err := s.queue.CancelAll(s.db, effectJobType); err != nil {
+		log.Printf("Error cancelling effect jobs: %v", err)
 	}
 	s.running = false
 
@@ -0,0 +1,18 @@
+// internal/workers/items.go
+package workers
+
+import (
+	"database/sql"
+	"new-year-role-game-backend/internal/storage/postgres"
+)
+
+// SpawnItemInstance создаёт новый экземпляр предмета по шаблону, копирует ему эффекты
+// шаблона и кладёт в инвентарь игрока. Таймеры эффектов не запускает -
+// для этого есть EffectsScheduler.SpawnItem
+func SpawnItemInstance(tx *sql.Tx, templateID, playerID int) (itemID int, itemName string, err error) {
+	item, err := postgres.WrapTx(tx, nil).Items().Spawn(templateID, playerID)
+	if err != nil {
+		return 0, "", err
+	}
+	return item.ID, item.Name, nil
+}
@@ -0,0 +1,107 @@
+// internal/workers/leaderboard.go
+package workers
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/jobs"
+	"time"
+)
+
+// leaderboardJobType - тип периодической задачи снимков таблицы лидеров
+const leaderboardJobType = "leaderboard"
+
+// leaderboardJobKey - задача одна на все экземпляры API
+const leaderboardJobKey = "leaderboard:snapshot"
+
+// LeaderboardSnapshotter периодически сохраняет деньги и влияние игроков и итоги фракций
+// в player_standings_history и faction_standings_history
+type LeaderboardSnapshotter struct {
+	db       *sql.DB
+	queue    *jobs.Queue
+	interval time.Duration
+}
+
+func NewLeaderboardSnapshotter(db *sql.DB, queue *jobs.Queue, interval time.Duration) *LeaderboardSnapshotter {
+	s := &LeaderboardSnapshotter{
+		db:       db,
+		queue:    queue,
+		interval: interval,
+	}
+	queue.Register(leaderboardJobType, s.runSnapshotJob)
+	return s
+}
+
+// Start ставит периодическую задачу в очередь, если её там ещё нет.
+// Задача живёт всё время работы сервера, а снимки делает, только пока игра идёт
+func (s *LeaderboardSnapshotter) Start() error {
+	if err := s.queue.Ensure(s.db, leaderboardJobType, leaderboardJobKey, time.Now(), struct{}{}); err != nil {
+		return err
+	}
+
+	log.Printf("Leaderboard snapshots scheduled every %v", s.interval)
+	return nil
+}
+
+// runSnapshotJob - обработчик задачи снимка, всегда планирует следующий запуск
+func (s *LeaderboardSnapshotter) runSnapshotJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	next := time.Now().Add(s.interval)
+
+	var running bool
+	err := tx.QueryRow(`
+		SELECT game_started_at IS NOT NULL AND game_ended_at IS NULL AND paused_at IS NULL
+		FROM game_timeline
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&running)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check game status: %w", err)
+	}
+	if !running {
+		return &next, nil
+	}
+
+	if err := TakeLeaderboardSnapshot(tx); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// TakeLeaderboardSnapshot сохраняет текущее состояние игроков и фракций одним снимком
+func TakeLeaderboardSnapshot(tx *sql.Tx) error {
+	var snapshotID int
+	if err := tx.QueryRow(`
+		INSERT INTO leaderboard_snapshots DEFAULT VALUES
+		RETURNING id
+	`).Scan(&snapshotID); err != nil {
+		return fmt.Errorf("failed to create leaderboard snapshot: %w", err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO player_standings_history (snapshot_id, player_id, faction_id, money, influence)
+		SELECT $1, id, faction_id, COALESCE(money, 0), COALESCE(influence, 0)
+		FROM players
+	`, snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to save player standings: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO faction_standings_history (snapshot_id, faction_id, faction_influence, players_influence,
+		                                       total_influence, players_money, members_count)
+		SELECT $1, f.id, COALESCE(f.faction_influence, 0),
+		       COALESCE(SUM(p.influence), 0),
+		       COALESCE(f.faction_influence, 0) + COALESCE(SUM(p.influence), 0),
+		       COALESCE(SUM(p.money), 0),
+		       COUNT(p.id)
+		FROM factions f
+		LEFT JOIN players p ON p.faction_id = f.id
+		GROUP BY f.id, f.faction_influence
+	`, snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to save faction standings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+// internal/workers/market_scheduler.go
+package workers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/market"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
+	"sync"
+	"time"
+)
+
+// MarketScheduler снимает лоты рынка по истечении срока через общую очередь задач.
+// Сами задачи ставит и снимает market.Service в транзакциях выставления, покупки и отмены
+type MarketScheduler struct {
+	db      *sql.DB
+	queue   *jobs.Queue
+	market  *market.Service
+	mu      sync.Mutex
+	running bool
+}
+
+func NewMarketScheduler(db *sql.DB, queue *jobs.Queue, service *market.Service) *MarketScheduler {
+	s := &MarketScheduler{
+		db:      db,
+		queue:   queue,
+		market:  service,
+		running: false,
+	}
+	queue.Register(market.JobType, s.runListingJob)
+	return s
+}
+
+// Start восстанавливает задачи для всех активных лотов.
+// Лоты с уже истёкшим сроком очередь закроет сразу
+func (s *MarketScheduler) Start() error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("market scheduler already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, expires_at
+		FROM market_listings
+		WHERE status = 'active'
+		ORDER BY expires_at
+	`)
+	if err != nil {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		return fmt.Errorf("failed to load market listings: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+
+	for rows.Next() {
+		var listingID int
+		var expiresAt time.Time
+
+		if err := rows.Scan(&listingID, &expiresAt); err != nil {
+			log.Printf("Error scanning market listing: %v", err)
+			continue
+		}
+
+		err := s.queue.Ensure(s.db, market.JobType, market.JobKey(listingID), expiresAt,
+			market.Job{ListingID: listingID})
+		if err != nil {
+			log.Printf("Error scheduling listing #%d: %v", listingID, err)
+			continue
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load market listings: %w", err)
+	}
+
+	log.Printf("Market scheduler started, %d active listings in queue", count)
+	return nil
+}
+
+// runListingJob - обработчик задачи истечения лота
+func (s *MarketScheduler) runListingJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload market.Job
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid market job payload: %w", err)
+	}
+
+	return nil, s.runListing(postgres.WrapTx(tx, s.queue), payload.ListingID, time.Now())
+}
+
+// runListing снимает лот поверх хранилища, если игра не на паузе
+func (s *MarketScheduler) runListing(tx storage.Tx, listingID int, now time.Time) error {
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return err
+	} else if paused {
+		log.Printf("Game is paused, listing #%d will be rescheduled on resume", listingID)
+		return nil
+	}
+
+	if err := s.market.ExpireListing(tx, listingID, now); err != nil {
+		return err
+	}
+
+	log.Printf("Listing #%d expired, item returned to seller", listingID)
+	return nil
+}
+
+// CloseActive снимает все активные лоты в транзакции завершения игры:
+// предметы не должны остаться на хранении у рынка после конца игры
+func (s *MarketScheduler) CloseActive(tx *sql.Tx, now time.Time) (int, error) {
+	rows, err := tx.Query(`SELECT id FROM market_listings WHERE status = 'active' ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load market listings: %w", err)
+	}
+	defer rows.Close()
+
+	// ВАЖНО: дочитываем id до изменений - в одной транзакции нельзя выполнять запросы, пока открыт курсор
+	listingIDs := make([]int, 0)
+	for rows.Next() {
+		var listingID int
+		if err := rows.Scan(&listingID); err != nil {
+			return 0, fmt.Errorf("failed to scan market listing: %w", err)
+		}
+		listingIDs = append(listingIDs, listingID)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to load market listings: %w", err)
+	}
+
+	store := postgres.WrapTx(tx, s.queue)
+	for _, listingID := range listingIDs {
+		if err := s.market.ExpireListing(store, listingID, now); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(listingIDs), nil
+}
+
+// GetScheduledCount возвращает количество запланированных лотов
+func (s *MarketScheduler) GetScheduledCount() int {
+	count, err := s.queue.CountPending(market.JobType)
+	if err != nil {
+		log.Printf("Error counting scheduled listings: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Inspect возвращает задачи лотов в очереди и их расхождение с таблицей market_listings.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *MarketScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT id, expires_at
+			FROM market_listings
+			WHERE status = 'active'
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load market listings: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var listingID int
+			var expiresAt time.Time
+			if err := rows.Scan(&listingID, &expiresAt); err != nil {
+				return nil, fmt.Errorf("failed to scan market listing: %w", err)
+			}
+			expected = append(expected, jobs.Expected{Key: market.JobKey(listingID), RunAt: &expiresAt})
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load market listings: %w", err)
+		}
+	}
+
+	return s.queue.Inspect(market.JobType, running, expected)
+}
+
+// Stop отменяет все задачи лотов
+func (s *MarketScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.queue.CancelAll(s.db, market.JobType); err != nil {
+		log.Printf("Error cancelling market jobs: %v", err)
+	}
+	s.running = false
+
+	log.Println("Market scheduler stopped")
+}
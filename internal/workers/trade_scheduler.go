@@ -0,0 +1,177 @@
+// internal/workers/trade_scheduler.go
+package workers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"new-year-role-game-backend/internal/jobs"
+	"new-year-role-game-backend/internal/storage"
+	"new-year-role-game-backend/internal/storage/postgres"
+	"new-year-role-game-backend/internal/trades"
+	"sync"
+	"time"
+)
+
+// TradeScheduler закрывает предложения обмена по истечении срока через общую очередь задач.
+// Сами задачи ставит и снимает trades.Service в транзакциях создания, принятия и отказа
+type TradeScheduler struct {
+	db      *sql.DB
+	queue   *jobs.Queue
+	trades  *trades.Service
+	mu      sync.Mutex
+	running bool
+}
+
+func NewTradeScheduler(db *sql.DB, queue *jobs.Queue, service *trades.Service) *TradeScheduler {
+	s := &TradeScheduler{
+		db:      db,
+		queue:   queue,
+		trades:  service,
+		running: false,
+	}
+	queue.Register(trades.JobType, s.runOfferJob)
+	return s
+}
+
+// Start восстанавливает задачи для всех ожидающих предложений.
+// Предложения с уже истёкшим сроком очередь закроет сразу
+func (s *TradeScheduler) Start() error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("trade scheduler already running")
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT id, expires_at
+		FROM trade_offers
+		WHERE status = 'pending'
+		ORDER BY expires_at
+	`)
+	if err != nil {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		return fmt.Errorf("failed to load trade offers: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+
+	for rows.Next() {
+		var offerID int
+		var expiresAt time.Time
+
+		if err := rows.Scan(&offerID, &expiresAt); err != nil {
+			log.Printf("Error scanning trade offer: %v", err)
+			continue
+		}
+
+		err := s.queue.Ensure(s.db, trades.JobType, trades.JobKey(offerID), expiresAt,
+			trades.Job{OfferID: offerID})
+		if err != nil {
+			log.Printf("Error scheduling trade offer #%d: %v", offerID, err)
+			continue
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to load trade offers: %w", err)
+	}
+
+	log.Printf("Trade scheduler started, %d pending offers in queue", count)
+	return nil
+}
+
+// runOfferJob - обработчик задачи истечения предложения
+func (s *TradeScheduler) runOfferJob(tx *sql.Tx, job jobs.Job) (*time.Time, error) {
+	var payload trades.Job
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("invalid trade job payload: %w", err)
+	}
+
+	return nil, s.runOffer(postgres.WrapTx(tx, s.queue), payload.OfferID, time.Now())
+}
+
+// runOffer закрывает предложение поверх хранилища, если игра не на паузе
+func (s *TradeScheduler) runOffer(tx storage.Tx, offerID int, now time.Time) error {
+	if paused, err := tx.Game().IsPaused(); err != nil {
+		return err
+	} else if paused {
+		log.Printf("Game is paused, trade offer #%d will be rescheduled on resume", offerID)
+		return nil
+	}
+
+	if err := s.trades.ExpireOffer(tx, offerID, now); err != nil {
+		return err
+	}
+
+	log.Printf("Trade offer #%d expired", offerID)
+	return nil
+}
+
+// GetScheduledCount возвращает количество запланированных предложений
+func (s *TradeScheduler) GetScheduledCount() int {
+	count, err := s.queue.CountPending(trades.JobType)
+	if err != nil {
+		log.Printf("Error counting scheduled trade offers: %v", err)
+		return 0
+	}
+	return count
+}
+
+// Inspect возвращает задачи предложений в очереди и их расхождение с таблицей trade_offers.
+// Пока игра не идёт (не начата, на паузе или завершена), очередь должна быть пустой
+func (s *TradeScheduler) Inspect(gameRunning bool) (*jobs.Inspection, error) {
+	s.mu.Lock()
+	running := s.running
+	s.mu.Unlock()
+
+	expected := make([]jobs.Expected, 0)
+
+	if gameRunning {
+		// Тот же набор, что восстанавливает Start
+		rows, err := s.db.Query(`
+			SELECT id, expires_at
+			FROM trade_offers
+			WHERE status = 'pending'
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load trade offers: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var offerID int
+			var expiresAt time.Time
+			if err := rows.Scan(&offerID, &expiresAt); err != nil {
+				return nil, fmt.Errorf("failed to scan trade offer: %w", err)
+			}
+			expected = append(expected, jobs.Expected{Key: trades.JobKey(offerID), RunAt: &expiresAt})
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to load trade offers: %w", err)
+		}
+	}
+
+	return s.queue.Inspect(trades.JobType, running, expected)
+}
+
+// Stop отменяет все задачи предложений
+func (s *TradeScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.queue.CancelAll(s.db, trades.JobType); err != nil {
+		log.Printf("Error cancelling trade jobs: %v", err)
+	}
+	s.running = false
+
+	log.Println("Trade scheduler stopped")
+}
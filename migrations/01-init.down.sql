@@ -0,0 +1,55 @@
+-- migrations/01-init.down.sql
+
+-- Удаляет всю базовую схему игры вместе с данными
+DROP TRIGGER IF EXISTS trigger_unlock_on_goal_completion ON goals;
+DROP TRIGGER IF EXISTS trigger_unlock_on_influence_change ON players;
+DROP FUNCTION IF EXISTS unlock_goal_dependencies_on_goal_completion();
+DROP FUNCTION IF EXISTS unlock_goal_dependencies_on_influence_change();
+
+DROP VIEW IF EXISTS player_race_progress;
+DROP VIEW IF EXISTS active_goal_race_rounds;
+DROP VIEW IF EXISTS player_tasks_stats;
+DROP VIEW IF EXISTS active_contracts;
+DROP VIEW IF EXISTS player_visible_goals;
+DROP VIEW IF EXISTS faction_total_influence;
+
+DROP TABLE IF EXISTS
+    users,
+    game_timeline,
+    game_settings,
+    influence_transactions,
+    item_transactions,
+    money_transactions,
+    debt_penalty_settings,
+    debt_receipts,
+    contract_penalties,
+    contract_penalty_settings,
+    contract_type2_reward_settings,
+    contract_type1_reward_settings,
+    contract_type1_settings,
+    contract_duration_settings,
+    contracts,
+    goal_race_round_goals,
+    goal_race_predefined_goals,
+    goal_race_round_participants,
+    goal_race_rounds,
+    goal_race_trigger_participants,
+    goal_race_triggers,
+    task_completion_history,
+    tasks,
+    goal_completion_history,
+    goal_dependency_unlocks,
+    goal_dependencies,
+    goals,
+    revealed_info,
+    ability_usage,
+    abilities,
+    item_effect_executions,
+    player_items,
+    item_effects,
+    effects,
+    items,
+    info_about_other_players,
+    players,
+    factions
+CASCADE;
@@ -0,0 +1,4 @@
+-- migrations/03-sessions.down.sql
+
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,34 @@
+-- migrations/03-sessions.sql
+
+-- ============================================
+-- СЕССИИ И REFRESH-ТОКЕНЫ
+-- ============================================
+
+-- Сессии пользователей (одна сессия = один вход с устройства)
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT,
+    ip_address VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL, -- продлевается при каждом обновлении токена
+    revoked_at TIMESTAMP,
+    revoke_reason VARCHAR(50) -- 'logout', 'admin', 'password_reset', 'token_reuse'
+);
+
+-- Refresh-токены (храним только SHA-256 хеш, каждый токен одноразовый)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP -- заполняется при ротации; повторное использование = кража токена
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions(user_id);
+CREATE INDEX idx_refresh_tokens_session ON refresh_tokens(session_id);
+
+COMMENT ON TABLE user_sessions IS 'Сессии пользователей, которые можно отозвать на сервере';
+COMMENT ON TABLE refresh_tokens IS 'Одноразовые refresh-токены с ротацией';
@@ -0,0 +1,3 @@
+-- migrations/04-scheduled-jobs.down.sql
+
+DROP TABLE IF EXISTS scheduled_jobs;
@@ -0,0 +1,31 @@
+-- migrations/04-scheduled-jobs.sql
+
+-- ============================================
+-- ОЧЕРЕДЬ ОТЛОЖЕННЫХ ЗАДАЧ
+-- ============================================
+
+-- Отложенные задачи schedulers (эффекты предметов, завершение договоров, штрафы по долгам).
+-- Задачу захватывает один экземпляр API через FOR UPDATE SKIP LOCKED и выполняет
+-- в той же транзакции, поэтому после падения или при нескольких репликах
+-- задача не теряется и не выполняется дважды
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id SERIAL PRIMARY KEY,
+    job_type VARCHAR(50) NOT NULL, -- 'effect', 'contract', 'debt'
+    job_key VARCHAR(255) NOT NULL UNIQUE, -- ключ идемпотентности, например 'contract:42'
+    payload JSONB NOT NULL DEFAULT '{}',
+    run_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'failed', 'cancelled')),
+    attempts INTEGER NOT NULL DEFAULT 0, -- неудачные попытки подряд
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    last_error TEXT,
+    last_run_at TIMESTAMP,
+    last_run_by VARCHAR(100), -- экземпляр API, который выполнял задачу последним
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_scheduled_jobs_due ON scheduled_jobs(run_at) WHERE status = 'pending';
+CREATE INDEX idx_scheduled_jobs_type_status ON scheduled_jobs(job_type, status);
+
+COMMENT ON TABLE scheduled_jobs IS 'Общая для всех экземпляров API очередь отложенных задач';
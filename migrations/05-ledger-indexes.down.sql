@@ -0,0 +1,7 @@
+-- migrations/05-ledger-indexes.down.sql
+
+DROP INDEX IF EXISTS idx_money_transactions_from_player;
+DROP INDEX IF EXISTS idx_money_transactions_to_player;
+DROP INDEX IF EXISTS idx_item_transactions_from_player;
+DROP INDEX IF EXISTS idx_item_transactions_to_player;
+DROP INDEX IF EXISTS idx_influence_transactions_player;
@@ -0,0 +1,12 @@
+-- migrations/05-ledger-indexes.sql
+
+-- ============================================
+-- ИНДЕКСЫ ДЛЯ ИСТОРИИ ИГРОКА
+-- ============================================
+
+-- /api/player/history выбирает записи журналов по игроку
+CREATE INDEX IF NOT EXISTS idx_money_transactions_from_player ON money_transactions(from_player_id);
+CREATE INDEX IF NOT EXISTS idx_money_transactions_to_player ON money_transactions(to_player_id);
+CREATE INDEX IF NOT EXISTS idx_item_transactions_from_player ON item_transactions(from_player_id);
+CREATE INDEX IF NOT EXISTS idx_item_transactions_to_player ON item_transactions(to_player_id);
+CREATE INDEX IF NOT EXISTS idx_influence_transactions_player ON influence_transactions(player_id);
@@ -0,0 +1,35 @@
+-- migrations/06-item-templates.down.sql
+
+-- Возврат к предметам без шаблонов. Ссылки на шаблон заменяются ссылкой на его первый экземпляр,
+-- поэтому эффекты порождения и награды по договорам снова выдают один и тот же предмет
+ALTER TABLE effects ADD COLUMN IF NOT EXISTS spawned_item_id INTEGER REFERENCES items(id) ON DELETE CASCADE;
+ALTER TABLE contract_type1_settings ADD COLUMN IF NOT EXISTS customer_item_reward_id INTEGER REFERENCES items(id) ON DELETE SET NULL;
+
+UPDATE effects e
+SET spawned_item_id = (SELECT MIN(i.id) FROM items i WHERE i.template_id = e.spawned_template_id)
+WHERE e.spawned_template_id IS NOT NULL;
+
+UPDATE contract_type1_settings s
+SET customer_item_reward_id = (SELECT MIN(i.id) FROM items i WHERE i.template_id = s.customer_item_reward_template_id)
+WHERE s.customer_item_reward_template_id IS NOT NULL;
+
+-- Эффекты порождения шаблонов без экземпляров восстановить нельзя
+DELETE FROM effects WHERE effect_type = 'spawn_item' AND spawned_item_id IS NULL;
+
+ALTER TABLE effects DROP CONSTRAINT IF EXISTS effects_check;
+ALTER TABLE effects ADD CONSTRAINT effects_check CHECK (
+    (effect_type IN ('generate_money', 'generate_influence') AND generated_resource IS NOT NULL AND value IS NOT NULL AND spawned_item_id IS NULL) OR
+    (effect_type = 'spawn_item' AND spawned_item_id IS NOT NULL AND generated_resource IS NULL)
+);
+
+ALTER TABLE player_items DROP CONSTRAINT IF EXISTS player_items_item_id_key;
+ALTER TABLE player_items ADD CONSTRAINT player_items_player_id_item_id_key UNIQUE (player_id, item_id);
+
+DROP INDEX IF EXISTS idx_items_template;
+
+ALTER TABLE contract_type1_settings DROP COLUMN IF EXISTS customer_item_reward_template_id;
+ALTER TABLE effects DROP COLUMN IF EXISTS spawned_template_id;
+ALTER TABLE items DROP COLUMN IF EXISTS template_id;
+
+DROP TABLE IF EXISTS item_template_effects;
+DROP TABLE IF EXISTS item_templates;
@@ -0,0 +1,106 @@
+-- migrations/06-item-templates.sql
+
+-- ============================================
+-- ШАБЛОНЫ ПРЕДМЕТОВ
+-- ============================================
+
+-- Шаблон описывает вид предмета, а строка items - конкретный экземпляр.
+-- Эффекты порождения и награды по договорам создают новый экземпляр по шаблону,
+-- поэтому у игрока может быть несколько копий одного предмета, и у каждой копии свои таймеры
+CREATE TABLE IF NOT EXISTS item_templates (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Эффекты, которые копируются в item_effects каждого нового экземпляра
+CREATE TABLE IF NOT EXISTS item_template_effects (
+    template_id INTEGER REFERENCES item_templates(id) ON DELETE CASCADE,
+    effect_id INTEGER REFERENCES effects(id) ON DELETE CASCADE,
+    PRIMARY KEY (template_id, effect_id)
+);
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS template_id INTEGER REFERENCES item_templates(id) ON DELETE SET NULL;
+ALTER TABLE effects ADD COLUMN IF NOT EXISTS spawned_template_id INTEGER REFERENCES item_templates(id) ON DELETE CASCADE;
+ALTER TABLE contract_type1_settings ADD COLUMN IF NOT EXISTS customer_item_reward_template_id INTEGER REFERENCES item_templates(id) ON DELETE SET NULL;
+
+-- ============================================
+-- ПЕРЕНОС ДАННЫХ
+-- ============================================
+
+DO $$
+DECLARE
+    item RECORD;
+    new_template_id INTEGER;
+    duplicate RECORD;
+    new_item_id INTEGER;
+BEGIN
+    -- Каждый существующий предмет становится шаблоном и первым экземпляром этого шаблона
+    FOR item IN SELECT id, name, description FROM items WHERE template_id IS NULL ORDER BY id LOOP
+        INSERT INTO item_templates (name, description)
+        VALUES (item.name, item.description)
+        RETURNING id INTO new_template_id;
+
+        UPDATE items SET template_id = new_template_id WHERE id = item.id;
+
+        INSERT INTO item_template_effects (template_id, effect_id)
+        SELECT new_template_id, effect_id FROM item_effects WHERE item_id = item.id;
+
+        UPDATE effects SET spawned_template_id = new_template_id WHERE spawned_item_id = item.id;
+
+        UPDATE contract_type1_settings
+        SET customer_item_reward_template_id = new_template_id
+        WHERE customer_item_reward_id = item.id;
+    END LOOP;
+
+    -- Один экземпляр принадлежит одному игроку: у всех владельцев, кроме первого,
+    -- предмет заменяется собственной копией
+    FOR duplicate IN
+        SELECT pi.id, pi.player_id, pi.item_id
+        FROM player_items pi
+        WHERE pi.id <> (SELECT MIN(id) FROM player_items WHERE item_id = pi.item_id)
+        ORDER BY pi.id
+    LOOP
+        INSERT INTO items (name, description, template_id)
+        SELECT name, description, template_id FROM items WHERE id = duplicate.item_id
+        RETURNING id INTO new_item_id;
+
+        INSERT INTO item_effects (item_id, effect_id)
+        SELECT new_item_id, effect_id FROM item_effects WHERE item_id = duplicate.item_id;
+
+        UPDATE player_items SET item_id = new_item_id WHERE id = duplicate.id;
+
+        UPDATE item_effect_executions
+        SET item_id = new_item_id
+        WHERE player_id = duplicate.player_id AND item_id = duplicate.item_id;
+
+        -- Старые таймеры привязаны к прежнему ID; при старте игры schedulers создадут новые
+        UPDATE scheduled_jobs
+        SET status = 'cancelled', updated_at = NOW()
+        WHERE job_type = 'effect' AND status = 'pending'
+          AND starts_with(job_key, 'effect:' || duplicate.player_id || ':' || duplicate.item_id || ':');
+    END LOOP;
+END $$;
+
+-- ============================================
+-- НОВЫЕ ОГРАНИЧЕНИЯ
+-- ============================================
+
+ALTER TABLE player_items DROP CONSTRAINT IF EXISTS player_items_player_id_item_id_key;
+ALTER TABLE player_items ADD CONSTRAINT player_items_item_id_key UNIQUE (item_id);
+
+ALTER TABLE effects DROP CONSTRAINT IF EXISTS effects_check;
+ALTER TABLE effects DROP COLUMN IF EXISTS spawned_item_id;
+ALTER TABLE effects ADD CONSTRAINT effects_check CHECK (
+    (effect_type IN ('generate_money', 'generate_influence') AND generated_resource IS NOT NULL AND value IS NOT NULL AND spawned_template_id IS NULL) OR
+    (effect_type = 'spawn_item' AND spawned_template_id IS NOT NULL AND generated_resource IS NULL)
+);
+
+ALTER TABLE contract_type1_settings DROP COLUMN IF EXISTS customer_item_reward_id;
+
+CREATE INDEX IF NOT EXISTS idx_items_template ON items(template_id);
+CREATE INDEX IF NOT EXISTS idx_player_items_player ON player_items(player_id);
+
+COMMENT ON TABLE item_templates IS 'Виды предметов, по которым создаются экземпляры в items';
+COMMENT ON COLUMN items.template_id IS 'Шаблон, по которому создан экземпляр (NULL - уникальный предмет)';
@@ -0,0 +1,4 @@
+-- migrations/07-game-pause.down.sql
+
+ALTER TABLE game_timeline DROP COLUMN IF EXISTS paused_at;
+ALTER TABLE game_timeline DROP COLUMN IF EXISTS total_paused_seconds;
@@ -0,0 +1,13 @@
+-- migrations/07-game-pause.sql
+
+-- ============================================
+-- ПАУЗА ИГРЫ
+-- ============================================
+
+-- paused_at задан, пока игра на паузе. При возобновлении длительность паузы
+-- добавляется к total_paused_seconds, а сроки договоров, долгов и эффектов сдвигаются на неё
+ALTER TABLE game_timeline ADD COLUMN IF NOT EXISTS paused_at TIMESTAMP;
+ALTER TABLE game_timeline ADD COLUMN IF NOT EXISTS total_paused_seconds INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN game_timeline.paused_at IS 'Начало текущей паузы (NULL - игра не на паузе)';
+COMMENT ON COLUMN game_timeline.total_paused_seconds IS 'Суммарная длительность завершённых пауз';
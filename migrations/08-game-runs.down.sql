@@ -0,0 +1,5 @@
+-- migrations/08-game-runs.down.sql
+
+DROP TABLE IF EXISTS game_baseline;
+DROP TABLE IF EXISTS game_run_archive;
+DROP TABLE IF EXISTS game_runs;
@@ -0,0 +1,45 @@
+-- migrations/08-game-runs.sql
+
+-- ============================================
+-- АРХИВ ПРОГОНОВ ИГРЫ
+-- ============================================
+
+-- Один прогон сценария: от старта игры до сброса POST /api/admin/game/reset
+CREATE TABLE IF NOT EXISTS game_runs (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    paused_seconds INTEGER NOT NULL DEFAULT 0,
+    note TEXT,
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Строки игровых таблиц на момент сброса. Журналы, договоры, долги и т.п.
+-- после архивации очищаются, а итоговое состояние игроков, предметов и целей сохраняется как снимок
+CREATE TABLE IF NOT EXISTS game_run_archive (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES game_runs(id) ON DELETE CASCADE,
+    table_name VARCHAR(100) NOT NULL,
+    row_data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_run_archive_run_table ON game_run_archive(run_id, table_name, id);
+
+-- ============================================
+-- СТАРТОВОЕ СОСТОЯНИЕ
+-- ============================================
+
+-- Снимок состояния перед первым стартом игры (или POST /api/admin/game/baseline).
+-- Сброс возвращает к нему игроков, фракции, предметы, цели, задачи и способности
+CREATE TABLE IF NOT EXISTS game_baseline (
+    id SERIAL PRIMARY KEY,
+    table_name VARCHAR(100) NOT NULL,
+    row_data JSONB NOT NULL,
+    captured_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_baseline_table ON game_baseline(table_name);
+
+COMMENT ON TABLE game_runs IS 'Завершённые и сброшенные прогоны сценария';
+COMMENT ON TABLE game_run_archive IS 'Архив строк игровых таблиц по прогонам (row_data - строка в формате to_jsonb)';
+COMMENT ON TABLE game_baseline IS 'Стартовое состояние сценария, к которому возвращает сброс игры';
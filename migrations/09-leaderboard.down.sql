@@ -0,0 +1,5 @@
+-- migrations/09-leaderboard.down.sql
+
+DROP TABLE IF EXISTS faction_standings_history;
+DROP TABLE IF EXISTS player_standings_history;
+DROP TABLE IF EXISTS leaderboard_snapshots;
@@ -0,0 +1,40 @@
+-- migrations/09-leaderboard.sql
+
+-- ============================================
+-- ИСТОРИЯ ТАБЛИЦЫ ЛИДЕРОВ
+-- ============================================
+
+-- Снимок делается периодически, пока игра идёт (LEADERBOARD_SNAPSHOT_INTERVAL)
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+    id SERIAL PRIMARY KEY,
+    taken_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Деньги и влияние игрока на момент снимка. faction_id - фракция игрока в тот момент
+CREATE TABLE IF NOT EXISTS player_standings_history (
+    snapshot_id INTEGER NOT NULL REFERENCES leaderboard_snapshots(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    faction_id INTEGER REFERENCES factions(id) ON DELETE SET NULL,
+    money INTEGER NOT NULL,
+    influence INTEGER NOT NULL,
+    PRIMARY KEY (snapshot_id, player_id)
+);
+
+-- Итоги фракции на момент снимка (как в faction_total_influence, плюс деньги и численность)
+CREATE TABLE IF NOT EXISTS faction_standings_history (
+    snapshot_id INTEGER NOT NULL REFERENCES leaderboard_snapshots(id) ON DELETE CASCADE,
+    faction_id INTEGER NOT NULL REFERENCES factions(id) ON DELETE CASCADE,
+    faction_influence INTEGER NOT NULL,
+    players_influence INTEGER NOT NULL,
+    total_influence INTEGER NOT NULL,
+    players_money INTEGER NOT NULL,
+    members_count INTEGER NOT NULL,
+    PRIMARY KEY (snapshot_id, faction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_leaderboard_snapshots_taken_at ON leaderboard_snapshots(taken_at);
+CREATE INDEX IF NOT EXISTS idx_player_standings_history_player ON player_standings_history(player_id);
+
+COMMENT ON TABLE leaderboard_snapshots IS 'Моменты, в которые сохранялась таблица лидеров';
+COMMENT ON TABLE player_standings_history IS 'Деньги и влияние игроков по снимкам';
+COMMENT ON TABLE faction_standings_history IS 'Влияние, деньги и численность фракций по снимкам';
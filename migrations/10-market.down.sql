@@ -0,0 +1,3 @@
+-- migrations/10-market.down.sql
+
+DROP TABLE IF EXISTS market_listings;
@@ -0,0 +1,27 @@
+-- migrations/10-market.sql
+
+-- ============================================
+-- РЫНОК ПРЕДМЕТОВ
+-- ============================================
+
+-- Лот продавца. Пока лот активен, предмет убран из player_items продавца (находится на хранении у рынка):
+-- при покупке он переходит покупателю, при отмене или истечении срока возвращается продавцу
+CREATE TABLE IF NOT EXISTS market_listings (
+    id SERIAL PRIMARY KEY,
+    seller_player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL CHECK (price > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- 'active', 'sold', 'cancelled', 'expired'
+    buyer_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP,
+    CHECK (buyer_player_id IS NULL OR buyer_player_id != seller_player_id)
+);
+
+-- Один предмет не может быть выставлен дважды
+CREATE UNIQUE INDEX IF NOT EXISTS idx_market_listings_active_item ON market_listings(item_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_market_listings_status ON market_listings(status);
+CREATE INDEX IF NOT EXISTS idx_market_listings_seller ON market_listings(seller_player_id);
+
+COMMENT ON TABLE market_listings IS 'Лоты рынка предметов; предмет активного лота находится на хранении у рынка';
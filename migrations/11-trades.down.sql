@@ -0,0 +1,4 @@
+-- migrations/11-trades.down.sql
+
+DROP TABLE IF EXISTS trade_offer_items;
+DROP TABLE IF EXISTS trade_offers;
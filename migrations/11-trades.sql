@@ -0,0 +1,37 @@
+-- migrations/11-trades.sql
+
+-- ============================================
+-- ПРЕДЛОЖЕНИЯ ОБМЕНА
+-- ============================================
+
+-- Предложение обмена между двумя игроками: from_player_id отдаёт offer_money и предметы side = 'offer',
+-- to_player_id - request_money и предметы side = 'request'. Предметы не резервируются:
+-- владение и балансы проверяются при принятии, и всё переходит в одной транзакции
+CREATE TABLE IF NOT EXISTS trade_offers (
+    id SERIAL PRIMARY KEY,
+    from_player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    to_player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    offer_money INTEGER NOT NULL DEFAULT 0 CHECK (offer_money >= 0),
+    request_money INTEGER NOT NULL DEFAULT 0 CHECK (request_money >= 0),
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'accepted', 'declined', 'cancelled', 'countered', 'expired'
+    -- Встречное предложение ссылается на предложение, в ответ на которое сделано
+    parent_offer_id INTEGER REFERENCES trade_offers(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP,
+    CHECK (from_player_id != to_player_id)
+);
+
+CREATE TABLE IF NOT EXISTS trade_offer_items (
+    offer_id INTEGER NOT NULL REFERENCES trade_offers(id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    side VARCHAR(10) NOT NULL CHECK (side IN ('offer', 'request')),
+    PRIMARY KEY (offer_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_offers_status ON trade_offers(status);
+CREATE INDEX IF NOT EXISTS idx_trade_offers_from ON trade_offers(from_player_id);
+CREATE INDEX IF NOT EXISTS idx_trade_offers_to ON trade_offers(to_player_id);
+
+COMMENT ON TABLE trade_offers IS 'Предложения обмена предметами и деньгами между двумя игроками';
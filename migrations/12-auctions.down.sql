@@ -0,0 +1,4 @@
+-- migrations/12-auctions.down.sql
+
+DROP TABLE IF EXISTS auction_bids;
+DROP TABLE IF EXISTS auctions;
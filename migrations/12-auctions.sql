@@ -0,0 +1,40 @@
+-- migrations/12-auctions.sql
+
+-- ============================================
+-- АУКЦИОНЫ
+-- ============================================
+
+-- Аукцион мастера на предмет без владельца. Деньги лидирующей ставки зарезервированы
+-- (списаны с баланса), перебитая ставка возвращается. При закрытии предмет получает
+-- лидер, его ставка остаётся списанной; без ставок предмет остаётся без владельца
+CREATE TABLE IF NOT EXISTS auctions (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL REFERENCES items(id) ON DELETE CASCADE,
+    start_price INTEGER NOT NULL CHECK (start_price > 0),
+    min_increment INTEGER NOT NULL CHECK (min_increment > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active', -- 'active', 'sold', 'unsold', 'cancelled'
+    winner_player_id INTEGER REFERENCES players(id) ON DELETE SET NULL,
+    final_price INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    closes_at TIMESTAMP NOT NULL,
+    closed_at TIMESTAMP
+);
+
+-- Ставки по порядку поступления. Ставка принимается, только если она не меньше
+-- текущей + min_increment и поступила до closes_at, поэтому равных ставок не бывает
+CREATE TABLE IF NOT EXISTS auction_bids (
+    id SERIAL PRIMARY KEY,
+    auction_id INTEGER NOT NULL REFERENCES auctions(id) ON DELETE CASCADE,
+    player_id INTEGER NOT NULL REFERENCES players(id) ON DELETE CASCADE,
+    amount INTEGER NOT NULL CHECK (amount > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'leading', -- 'leading', 'outbid', 'won', 'refunded'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Один предмет не может быть на двух аукционах сразу, у аукциона одна лидирующая ставка
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auctions_active_item ON auctions(item_id) WHERE status = 'active';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auction_bids_leading ON auction_bids(auction_id) WHERE status = 'leading';
+CREATE INDEX IF NOT EXISTS idx_auctions_status ON auctions(status);
+CREATE INDEX IF NOT EXISTS idx_auction_bids_auction ON auction_bids(auction_id);
+
+COMMENT ON TABLE auctions IS 'Аукционы мастера; деньги лидирующей ставки зарезервированы до закрытия';
@@ -0,0 +1,7 @@
+-- migrations/13-faction-treasury.down.sql
+
+ALTER TABLE contracts DROP COLUMN IF EXISTS money_reward_faction;
+ALTER TABLE contract_type1_reward_settings DROP COLUMN IF EXISTS money_reward_faction;
+ALTER TABLE money_transactions DROP COLUMN IF EXISTS to_faction_id;
+ALTER TABLE money_transactions DROP COLUMN IF EXISTS from_faction_id;
+ALTER TABLE factions DROP COLUMN IF EXISTS treasury;
@@ -0,0 +1,24 @@
+-- migrations/13-faction-treasury.sql
+
+-- ============================================
+-- КАЗНА ФРАКЦИИ
+-- ============================================
+
+-- Общие деньги фракции. Пополнять казну может любой участник, тратить - только лидер
+-- (забрать себе или заплатить игроку). Все движения пишутся в money_transactions
+-- с from_faction_id / to_faction_id вместо игрока на стороне казны
+ALTER TABLE factions ADD COLUMN IF NOT EXISTS treasury INTEGER NOT NULL DEFAULT 0 CHECK (treasury >= 0);
+
+ALTER TABLE money_transactions ADD COLUMN IF NOT EXISTS from_faction_id INTEGER REFERENCES factions(id) ON DELETE SET NULL;
+ALTER TABLE money_transactions ADD COLUMN IF NOT EXISTS to_faction_id INTEGER REFERENCES factions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_money_transactions_from_faction ON money_transactions(from_faction_id);
+CREATE INDEX IF NOT EXISTS idx_money_transactions_to_faction ON money_transactions(to_faction_id);
+
+-- Награда type1 в казну фракции заказчика (фракция фиксируется при подписании).
+-- В договор сумма копируется из настроек при создании, как и остальные награды
+ALTER TABLE contract_type1_reward_settings ADD COLUMN IF NOT EXISTS money_reward_faction INTEGER NOT NULL DEFAULT 0 CHECK (money_reward_faction >= 0);
+ALTER TABLE contracts ADD COLUMN IF NOT EXISTS money_reward_faction INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN factions.treasury IS 'Казна фракции';
+COMMENT ON COLUMN contracts.money_reward_faction IS 'Деньги в казну фракции заказчика (только type1)';
@@ -0,0 +1,17 @@
+-- migrations/14-hash-passwords.sql
+
+-- ============================================
+-- ХЕШИРОВАНИЕ ПАРОЛЕЙ
+-- ============================================
+
+-- Пароли, оставшиеся в открытом виде (старые seed-данные и записи до перехода на bcrypt),
+-- хешируются на месте. Уже захешированные ($2a$/$2b$/$2y$) не трогаются.
+-- Стоимость 10 совпадает с bcrypt.DefaultCost в internal/auth.
+-- Миграция необратима: исходные пароли восстановить нельзя
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+UPDATE users SET password = crypt(password, gen_salt('bf', 10))
+WHERE password !~ '^\$2[aby]\$';
+
+UPDATE players SET password = crypt(password, gen_salt('bf', 10))
+WHERE password !~ '^\$2[aby]\$';
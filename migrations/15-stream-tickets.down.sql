@@ -0,0 +1,3 @@
+-- migrations/15-stream-tickets.down.sql
+
+DROP TABLE IF EXISTS stream_tickets;
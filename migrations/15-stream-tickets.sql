@@ -0,0 +1,19 @@
+-- migrations/15-stream-tickets.sql
+
+-- ============================================
+-- БИЛЕТЫ НА ПОТОК СОБЫТИЙ
+-- ============================================
+
+-- Билет (JWT с audience "events") передаётся в URL и может попасть в логи,
+-- поэтому он одноразовый: id - его jti, used_at заполняется при первом подключении
+CREATE TABLE IF NOT EXISTS stream_tickets (
+    id VARCHAR(64) PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_stream_tickets_expires ON stream_tickets(expires_at);
+
+COMMENT ON TABLE stream_tickets IS 'Одноразовые билеты на подключение к /api/events';
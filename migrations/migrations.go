@@ -0,0 +1,15 @@
+// migrations/migrations.go
+package migrations
+
+import "embed"
+
+// Files - версионированные миграции схемы: NN-name.sql применяет версию NN,
+// NN-name.down.sql откатывает её. Номера не обязаны идти подряд
+//
+//go:embed *.sql
+var Files embed.FS
+
+// Seeds - демонстрационные данные, применяются только по запросу (./api migrate seed)
+//
+//go:embed seed/*.sql
+var Seeds embed.FS
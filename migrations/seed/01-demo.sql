@@ -1,5 +1,6 @@
--- migrations/02-seed.sql
--- Mock данные для тестирования ролевой игры
+-- migrations/seed/01-demo.sql
+-- Mock данные для тестирования ролевой игры.
+-- Применяется только по запросу (./api migrate seed) к базе с актуальной схемой
 
 -- ============================================
 -- ФРАКЦИИ
@@ -15,29 +16,30 @@ INSERT INTO factions (name, description, faction_influence, is_composition_visib
 -- ИГРОКИ
 -- ============================================
 
+-- Пароли - bcrypt-хеши: у всех игроков и пользователей password123, у admin - admin123
 INSERT INTO players (character_name, password, character_story, role, money, influence, faction_id, can_change_faction, avatar) VALUES
 -- Дворец
-('Король Артур', 'password123', 'Мудрый правитель королевства', 'Правитель', 1000, 100, 1, false, NULL),
-('Принцесса Элизабет', 'password123', 'Наследница престола, интересуется магией', 'Принцесса', 800, 80, 1, false, NULL),
-('Советник Мерлин', 'password123', 'Главный советник короля, обладает тайными знаниями', 'Советник', 600, 70, 1, false, NULL),
+('Король Артур', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Мудрый правитель королевства', 'Правитель', 1000, 100, 1, false, NULL),
+('Принцесса Элизабет', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Наследница престола, интересуется магией', 'Принцесса', 800, 80, 1, false, NULL),
+('Советник Мерлин', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Главный советник короля, обладает тайными знаниями', 'Советник', 600, 70, 1, false, NULL),
 
 -- Мафия
-('Дон Корлеоне', 'password123', 'Глава мафиозной семьи', 'Босс мафии', 1500, 90, 2, false, NULL),
-('Консильери Том', 'password123', 'Правая рука дона, юрист', 'Консильери', 700, 60, 2, false, NULL),
-('Киллер Винченцо', 'password123', 'Исполнитель особых поручений', 'Киллер', 500, 50, 2, false, NULL),
+('Дон Корлеоне', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Глава мафиозной семьи', 'Босс мафии', 1500, 90, 2, false, NULL),
+('Консильери Том', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Правая рука дона, юрист', 'Консильери', 700, 60, 2, false, NULL),
+('Киллер Винченцо', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Исполнитель особых поручений', 'Киллер', 500, 50, 2, false, NULL),
 
 -- Торговая гильдия
-('Купец Марко', 'password123', 'Богатый торговец экзотическими товарами', 'Купец', 2000, 75, 3, false, NULL),
-('Ювелир Сара', 'password123', 'Мастер ювелирного дела', 'Ювелир', 900, 55, 3, false, NULL),
+('Купец Марко', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Богатый торговец экзотическими товарами', 'Купец', 2000, 75, 3, false, NULL),
+('Ювелир Сара', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Мастер ювелирного дела', 'Ювелир', 900, 55, 3, false, NULL),
 
 -- Церковь
-('Архиепископ Бенедикт', 'password123', 'Глава церкви', 'Архиепископ', 800, 85, 4, false, NULL),
-('Инквизитор Даниэль', 'password123', 'Борец с ересью', 'Инквизитор', 400, 65, 4, false, NULL),
+('Архиепископ Бенедикт', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Глава церкви', 'Архиепископ', 800, 85, 4, false, NULL),
+('Инквизитор Даниэль', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Борец с ересью', 'Инквизитор', 400, 65, 4, false, NULL),
 
 -- Нейтральные
-('Доктор Ватсон', 'password123', 'Лекарь, помогающий всем без разбора', 'Врач', 500, 40, NULL, true, NULL),
-('Шпион Джеймс', 'password123', 'Тайный агент, собирающий информацию', 'Шпион', 600, 45, NULL, true, NULL),
-('Кондитер Мари', 'password123', 'Владелица лучшей кондитерской в городе', 'Кондитер', 400, 30, NULL, false, NULL);
+('Доктор Ватсон', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Лекарь, помогающий всем без разбора', 'Врач', 500, 40, NULL, true, NULL),
+('Шпион Джеймс', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Тайный агент, собирающий информацию', 'Шпион', 600, 45, NULL, true, NULL),
+('Кондитер Мари', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 'Владелица лучшей кондитерской в городе', 'Кондитер', 400, 30, NULL, false, NULL);
 
 -- Обновляем лидеров фракций
 UPDATE factions SET leader_player_id = 1 WHERE id = 1; -- Король
@@ -50,20 +52,20 @@ UPDATE factions SET leader_player_id = 9 WHERE id = 4; -- Архиепископ
 -- ============================================
 
 INSERT INTO users (username, password, player_id, is_admin) VALUES
-('admin', 'admin123', NULL, true),
-('arthur', 'password123', 1, false),
-('elizabeth', 'password123', 2, false),
-('merlin', 'password123', 3, false),
-('don', 'password123', 4, false),
-('tom', 'password123', 5, false),
-('vinny', 'password123', 6, false),
-('marco', 'password123', 7, false),
-('sarah', 'password123', 8, false),
-('benedict', 'password123', 9, false),
-('daniel', 'password123', 10, false),
-('watson', 'password123', 11, false),
-('james', 'password123', 12, false),
-('marie', 'password123', 13, false);
+('admin', '$2a$10$wyCP5twvkpwE30tbrAHB8O9PTkIHbW9AsljYPMALS2rJAV62VwyzC', NULL, true),
+('arthur', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 1, false),
+('elizabeth', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 2, false),
+('merlin', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 3, false),
+('don', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 4, false),
+('tom', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 5, false),
+('vinny', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 6, false),
+('marco', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 7, false),
+('sarah', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 8, false),
+('benedict', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 9, false),
+('daniel', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 10, false),
+('watson', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 11, false),
+('james', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 12, false),
+('marie', '$2a$10$MhJFhPTHxSUw6ah05D9nJejnDcyzENkSDVg4Mzs6bR5adwfHWwPpq', 13, false);
 
 -- ============================================
 -- ИНФОРМАЦИЯ О ДРУГИХ ИГРОКАХ
@@ -78,7 +80,7 @@ INSERT INTO info_about_other_players (player_id, description) VALUES
 -- ПРЕДМЕТЫ
 -- ============================================
 
-INSERT INTO items (name, description) VALUES
+INSERT INTO item_templates (name, description) VALUES
 ('Королевская печать', 'Позволяет издавать указы от имени короля'),
 ('Секретные документы', 'Компромат на влиятельных персон'),
 ('Золотой слиток', 'Чистое золото высшей пробы'),
@@ -90,11 +92,15 @@ INSERT INTO items (name, description) VALUES
 ('Ювелирные изделия', 'Дорогие украшения'),
 ('Святые реликвии', 'Предметы церковного культа');
 
+-- Первый экземпляр каждого шаблона (ID экземпляров совпадают с ID шаблонов)
+INSERT INTO items (name, description, template_id)
+SELECT name, description, id FROM item_templates ORDER BY id;
+
 -- ============================================
 -- ЭФФЕКТЫ
 -- ============================================
 
-INSERT INTO effects (description, effect_type, generated_resource, operation, value, spawned_item_id, period_seconds) VALUES
+INSERT INTO effects (description, effect_type, generated_resource, operation, value, spawned_template_id, period_seconds) VALUES
 -- Генерация денег
 ('Приносит 100 золотых каждый час', 'generate_money', 'money', 'add', 100, NULL, 30),
 ('Приносит 50 золотых каждые 30 минут', 'generate_money', 'money', 'add', 50, NULL, 50),
@@ -112,7 +118,7 @@ INSERT INTO effects (description, effect_type, generated_resource, operation, va
 -- СВЯЗЬ ПРЕДМЕТОВ И ЭФФЕКТОВ
 -- ============================================
 
-INSERT INTO item_effects (item_id, effect_id) VALUES
+INSERT INTO item_template_effects (template_id, effect_id) VALUES
 (1, 1), -- Королевская печать приносит деньги
 (1, 4), -- Королевская печать приносит влияние
 (5, 3), -- Ключ от сокровищницы приносит много денег
@@ -120,6 +126,12 @@ INSERT INTO item_effects (item_id, effect_id) VALUES
 (7, 5), -- Древний артефакт приносит влияние
 (9, 6); -- Ювелирные изделия генерируют золото
 
+-- Экземпляры получают эффекты своих шаблонов
+INSERT INTO item_effects (item_id, effect_id)
+SELECT i.id, te.effect_id
+FROM items i
+JOIN item_template_effects te ON te.template_id = i.template_id;
+
 -- ============================================
 -- ИНВЕНТАРЬ ИГРОКОВ
 -- ============================================
@@ -418,7 +430,7 @@ INSERT INTO contracts (contract_type, customer_player_id, executor_player_id, cu
 -- НАСТРОЙКИ НАГРАД ДЛЯ ДОГОВОРОВ ТИПА 1
 -- ============================================
 
-INSERT INTO contract_type1_settings (faction_id, customer_item_reward_id) VALUES
+INSERT INTO contract_type1_settings (faction_id, customer_item_reward_template_id) VALUES
 (1, 1),  -- Дворец получает королевскую печать
 (2, 2),  -- Мафия получает секретные документы
 (3, 9),  -- Гильдия получает ювелирные изделия